@@ -0,0 +1,169 @@
+// Package apidocs generates OpenAPI and AsyncAPI documents from the Go types
+// used on the wire, so the published contract cannot drift from the code.
+package apidocs
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema object as embedded in OpenAPI and AsyncAPI documents
+type Schema map[string]any
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Registry builds schemas for Go values and collects named struct types as
+// reusable components referenced with "#/components/schemas/<Name>"
+type Registry struct {
+	components map[string]Schema
+}
+
+// NewRegistry creates an empty schema registry
+func NewRegistry() *Registry {
+	return &Registry{components: make(map[string]Schema)}
+}
+
+// Components returns the named schemas collected so far
+func (r *Registry) Components() map[string]Schema {
+	return r.components
+}
+
+// SchemaFor returns the schema of v's type. Named structs are registered as
+// components and referenced; a nil value yields nil.
+func (r *Registry) SchemaFor(v any) Schema {
+	if v == nil {
+		return nil
+	}
+	return r.schemaForType(reflect.TypeOf(v))
+}
+
+func (r *Registry) schemaForType(t reflect.Type) Schema {
+	switch t {
+	case timeType:
+		return Schema{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := r.schemaForType(t.Elem())
+		if _, isRef := schema["$ref"]; isRef {
+			return schema
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "format": "byte"}
+		}
+		return Schema{"type": "array", "items": r.schemaForType(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": r.schemaForType(t.Elem())}
+	case reflect.Struct:
+		return r.structSchema(t)
+	default:
+		// interface{} and anything else accepts any JSON value
+		return Schema{}
+	}
+}
+
+// structSchema inlines anonymous structs and references named ones
+func (r *Registry) structSchema(t reflect.Type) Schema {
+	if t.Name() == "" {
+		return r.buildStructSchema(t)
+	}
+
+	name := ComponentName(t)
+	if _, exists := r.components[name]; !exists {
+		// Reserve the name first so recursive types terminate
+		r.components[name] = Schema{}
+		r.components[name] = r.buildStructSchema(t)
+	}
+	return Schema{"$ref": "#/components/schemas/" + name}
+}
+
+func (r *Registry) buildStructSchema(t reflect.Type) Schema {
+	properties := make(map[string]Schema)
+	required := make([]string, 0)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		// Embedded structs without a json name are flattened like encoding/json does,
+		// even when the embedded type itself is unexported
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			embedded := r.buildStructSchema(field.Type)
+			for prop, schema := range embedded["properties"].(map[string]Schema) {
+				properties[prop] = schema
+			}
+			if embeddedRequired, ok := embedded["required"].([]string); ok {
+				required = append(required, embeddedRequired...)
+			}
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		name, omitEmpty, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+
+		properties[name] = r.schemaForType(field.Type)
+		if !omitEmpty {
+			required = append(required, name)
+		}
+	}
+
+	schema := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// jsonFieldName resolves the wire name of a struct field from its json tag
+func jsonFieldName(field reflect.StructField) (name string, omitEmpty bool, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, option := range parts[1:] {
+		if option == "omitempty" || option == "omitzero" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+// ComponentName is the schema name used for a named Go type.
+// Unexported REST DTOs such as apiRoom are published as APIRoom.
+func ComponentName(t reflect.Type) string {
+	name := t.Name()
+	if rest, ok := strings.CutPrefix(name, "api"); ok {
+		return "API" + rest
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
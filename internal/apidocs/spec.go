@@ -0,0 +1,220 @@
+package apidocs
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Operation describes one HTTP route
+type Operation struct {
+	Method  string
+	Path    string // Router pattern, e.g. /api/v1/rooms/{id}
+	Summary string
+	Tag     string
+	// Request is a zero value of the JSON body type, nil when the route takes no body
+	Request any
+	// Response is a zero value of the JSON response type, nil for 204 No Content
+	Response any
+	// ContentType replaces application/json as the response media type, e.g. for plain text
+	ContentType string
+	// Status is the success status code, defaults to 200 (or 204 without Response)
+	Status int
+	// Error is a zero value of the error body type returned with non-2xx codes, if any
+	Error any
+	// Headers lists request header names the route reads, e.g. authentication tokens
+	Headers []string
+}
+
+// Message describes one WebSocket message type
+type Message struct {
+	Type    string
+	Summary string
+	// Payload is a zero value of the payload type
+	Payload any
+}
+
+// Info identifies the documented API
+type Info struct {
+	Title       string
+	Version     string
+	Description string
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}.]+)(\.\.\.)?\}`)
+
+// OpenAPI builds an OpenAPI 3.0 document for the given routes
+func OpenAPI(info Info, operations []Operation) map[string]any {
+	registry := NewRegistry()
+	paths := make(map[string]map[string]any)
+
+	for _, op := range operations {
+		path := pathParamPattern.ReplaceAllString(op.Path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+		paths[path][strings.ToLower(op.Method)] = buildOperation(registry, op)
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info":    infoObject(info),
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": registry.Components(),
+		},
+	}
+}
+
+func buildOperation(registry *Registry, op Operation) map[string]any {
+	operation := map[string]any{
+		"summary":     op.Summary,
+		"operationId": operationID(op),
+	}
+	if op.Tag != "" {
+		operation["tags"] = []string{op.Tag}
+	}
+
+	parameters := make([]map[string]any, 0)
+	for _, match := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+		parameters = append(parameters, map[string]any{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   Schema{"type": "string"},
+		})
+	}
+	for _, header := range op.Headers {
+		parameters = append(parameters, map[string]any{
+			"name":   header,
+			"in":     "header",
+			"schema": Schema{"type": "string"},
+		})
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if op.Request != nil {
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  jsonContent(registry.SchemaFor(op.Request)),
+		}
+	}
+
+	responses := make(map[string]any)
+	status := op.Status
+	if op.Response == nil {
+		if status == 0 {
+			status = http.StatusNoContent
+		}
+		responses[strconv.Itoa(status)] = map[string]any{"description": http.StatusText(status)}
+	} else {
+		if status == 0 {
+			status = http.StatusOK
+		}
+		content := jsonContent(registry.SchemaFor(op.Response))
+		if op.ContentType != "" {
+			content = map[string]any{op.ContentType: content["application/json"]}
+		}
+		responses[strconv.Itoa(status)] = map[string]any{
+			"description": http.StatusText(status),
+			"content":     content,
+		}
+	}
+	if op.Error != nil {
+		responses["default"] = map[string]any{
+			"description": "Error",
+			"content":     jsonContent(registry.SchemaFor(op.Error)),
+		}
+	}
+	operation["responses"] = responses
+
+	return operation
+}
+
+// AsyncAPI builds an AsyncAPI 2.6 document for a WebSocket channel.
+// publish lists client → server messages, subscribe server → client messages.
+func AsyncAPI(info Info, channel string, publish, subscribe []Message) map[string]any {
+	registry := NewRegistry()
+	messages := make(map[string]any)
+
+	channelParameters := make(map[string]any)
+	for _, match := range pathParamPattern.FindAllStringSubmatch(channel, -1) {
+		channelParameters[match[1]] = map[string]any{"schema": Schema{"type": "string"}}
+	}
+
+	refs := func(list []Message) []map[string]any {
+		oneOf := make([]map[string]any, 0, len(list))
+		for _, msg := range list {
+			messages[msg.Type] = map[string]any{
+				"name":    msg.Type,
+				"summary": msg.Summary,
+				"payload": envelopeSchema(registry, msg),
+			}
+			oneOf = append(oneOf, map[string]any{"$ref": "#/components/messages/" + msg.Type})
+		}
+		return oneOf
+	}
+
+	channelItem := map[string]any{
+		"publish": map[string]any{
+			"summary": "Messages sent by clients",
+			"message": map[string]any{"oneOf": refs(publish)},
+		},
+		"subscribe": map[string]any{
+			"summary": "Messages sent by the server",
+			"message": map[string]any{"oneOf": refs(subscribe)},
+		},
+	}
+	if len(channelParameters) > 0 {
+		channelItem["parameters"] = channelParameters
+	}
+
+	return map[string]any{
+		"asyncapi":           "2.6.0",
+		"info":               infoObject(info),
+		"defaultContentType": "application/json",
+		"channels":           map[string]any{channel: channelItem},
+		"components": map[string]any{
+			"messages": messages,
+			"schemas":  registry.Components(),
+		},
+	}
+}
+
+// envelopeSchema wraps a message payload in the {type, roomId, requestId, seq, payload} envelope
+func envelopeSchema(registry *Registry, msg Message) Schema {
+	properties := map[string]Schema{
+		"type":      {"type": "string", "enum": []string{msg.Type}},
+		"roomId":    {"type": "string"},
+		"requestId": {"type": "string"},
+		"seq":       {"type": "integer"},
+	}
+	required := []string{"type"}
+	if payload := registry.SchemaFor(msg.Payload); payload != nil {
+		properties["payload"] = payload
+		required = append(required, "payload")
+	}
+	return Schema{"type": "object", "properties": properties, "required": required}
+}
+
+func infoObject(info Info) map[string]any {
+	object := map[string]any{"title": info.Title, "version": info.Version}
+	if info.Description != "" {
+		object["description"] = info.Description
+	}
+	return object
+}
+
+func jsonContent(schema Schema) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// operationID derives a stable identifier such as post_api_v1_rooms_id_votes
+func operationID(op Operation) string {
+	id := strings.ToLower(op.Method) + "_" + strings.Trim(op.Path, "/")
+	replacer := strings.NewReplacer("/", "_", "{", "", "}", "", "-", "_", ".", "")
+	return replacer.Replace(id)
+}
@@ -0,0 +1,24 @@
+package config
+
+import "time"
+
+// Cross-instance broker settings
+const (
+	BrokerTimeout       = 5 * time.Second
+	BrokerChannelPrefix = "planning-poker:room:"
+
+	// A failed room subscription is retried after BrokerSubscribeRetryMin, doubling up to
+	// BrokerSubscribeRetryMax, for as long as the room has local clients
+	BrokerSubscribeRetryMin = 250 * time.Millisecond
+	BrokerSubscribeRetryMax = 30 * time.Second
+
+	// Open connections of each room's participants, one hash per room
+	BrokerConnectionsPrefix = "planning-poker:connections:"
+	// Connection counts expire once untouched for this long, so those left behind by an
+	// instance that stopped without closing its connections don't keep participants connected
+	BrokerConnectionsTTL = 24 * time.Hour
+
+	// Event log of each room: a hash of its ID and latest sequence number, and a sorted set
+	// of its latest events under BrokerEventLogPrefix + roomID + ":events"
+	BrokerEventLogPrefix = "planning-poker:events:"
+)
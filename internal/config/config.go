@@ -0,0 +1,247 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ConfigFileEnv names the optional configuration file
+const ConfigFileEnv = "CONFIG_FILE"
+
+// Config is the runtime configuration: the defaults of Default, overridden by the optional
+// file named by CONFIG_FILE, then by environment variables. Keys are the same in both,
+// see settings.
+type Config struct {
+	Limits Limits
+	Health Health
+	Broker Broker
+}
+
+// Limits are the WebSocket connection limits and constraints
+type Limits struct {
+	// Connection limits
+	MaxConnectionsPerRoom int
+	MaxRoomsPerInstance   int
+	MaxTotalConnections   int
+
+	// Rate limiting
+	MaxMessagesPerSecond int
+	RateLimitWindow      time.Duration
+
+	// Timeouts
+	WriteTimeout time.Duration
+	PingInterval time.Duration
+	PongTimeout  time.Duration // Longer than PingInterval for network delay tolerance
+
+	// Channel buffers
+	ClientSendBufferSize    int
+	HubRegisterBufferSize   int
+	HubUnregisterBufferSize int
+
+	// Command processing: each active room runs its commands in order on its own worker
+	RoomCommandQueueSize      int           // Commands waiting per room before senders block
+	RoomQueueFullTimeout      time.Duration // Longest wait of a sender on a full room queue before its command is dropped
+	MaxConcurrentRoomCommands int           // Rooms running a command at once, bounds concurrent DB work
+	RoomWorkerIdleTimeout     time.Duration
+
+	// Recent broadcasts kept per room, replayed to clients that reconnect after missing them,
+	// for EventLogRetention after the room's latest broadcast
+	EventLogSize      int
+	EventLogRetention time.Duration
+}
+
+// Health are the health check settings
+type Health struct {
+	// Share of MaxTotalConnections or MaxRoomsPerInstance in use before health degrades
+	WarningCapacity  float64
+	CriticalCapacity float64
+	// Connection and broadcast errors within ErrorWindow before health degrades to warning
+	ErrorWindow   time.Duration
+	WarningErrors int
+	// Readiness fails when the database or the hub loop takes longer than this to answer
+	ReadinessTimeout time.Duration
+}
+
+// Broker configures cross-instance broadcasts
+type Broker struct {
+	RedisURL string // Redis pub/sub when set, otherwise an in-memory broker for a single instance
+}
+
+// Default returns the built-in configuration
+func Default() *Config {
+	return &Config{
+		Limits: Limits{
+			MaxConnectionsPerRoom:     50,
+			MaxRoomsPerInstance:       1000,
+			MaxTotalConnections:       10000,
+			MaxMessagesPerSecond:      10,
+			RateLimitWindow:           time.Second,
+			WriteTimeout:              10 * time.Second,
+			PingInterval:              30 * time.Second,
+			PongTimeout:               90 * time.Second,
+			ClientSendBufferSize:      256,
+			HubRegisterBufferSize:     100,
+			HubUnregisterBufferSize:   100,
+			RoomCommandQueueSize:      64,
+			RoomQueueFullTimeout:      2 * time.Second,
+			MaxConcurrentRoomCommands: 32,
+			RoomWorkerIdleTimeout:     30 * time.Second,
+			EventLogSize:              100,
+			EventLogRetention:         5 * time.Minute,
+		},
+		Health: Health{
+			WarningCapacity:  0.8,
+			CriticalCapacity: 0.9,
+			ErrorWindow:      time.Minute,
+			WarningErrors:    100,
+			ReadinessTimeout: 2 * time.Second,
+		},
+	}
+}
+
+// Load returns the configuration of the environment and the optional CONFIG_FILE, validated
+func Load() (*Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv(ConfigFileEnv); path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := cfg.apply(values, true); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	}
+
+	env := make(map[string]string)
+	for _, s := range cfg.settings() {
+		if value, ok := os.LookupEnv(s.name); ok {
+			env[s.name] = value
+		}
+	}
+	if err := cfg.apply(env, false); err != nil {
+		return nil, fmt.Errorf("invalid environment: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readConfigFile reads KEY=value lines, the format of an .env file. Blank lines and lines
+// starting with # are skipped, values may be quoted.
+func readConfigFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("config file %s line %d: expected KEY=value", path, line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return values, nil
+}
+
+// apply sets the settings present in values. With strict, unknown keys are errors.
+func (c *Config) apply(values map[string]string, strict bool) error {
+	settings := c.settings()
+	known := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		known[s.name] = s
+	}
+
+	var errs []error
+	for key, value := range values {
+		s, ok := known[key]
+		if !ok {
+			if strict {
+				errs = append(errs, fmt.Errorf("unknown setting %s", key))
+			}
+			continue
+		}
+		if err := s.value.Set(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks that the settings are usable together
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	for _, s := range c.settings() {
+		if positive, ok := s.value.(interface{ positive() bool }); ok {
+			check(positive.positive(), "%s must be greater than zero", s.name)
+		}
+	}
+
+	l, h := c.Limits, c.Health
+	check(l.MaxConnectionsPerRoom <= l.MaxTotalConnections, "MAX_CONNECTIONS_PER_ROOM must not exceed MAX_TOTAL_CONNECTIONS")
+	check(l.PongTimeout > l.PingInterval, "PONG_TIMEOUT must be longer than PING_INTERVAL")
+	check(l.RoomQueueFullTimeout < l.WriteTimeout, "ROOM_QUEUE_FULL_TIMEOUT must be below WRITE_TIMEOUT")
+	check(l.EventLogSize < l.ClientSendBufferSize, "EVENT_LOG_SIZE must be below CLIENT_SEND_BUFFER_SIZE")
+	check(h.WarningCapacity < h.CriticalCapacity, "HEALTH_WARNING_CAPACITY must be below HEALTH_CRITICAL_CAPACITY")
+	check(h.CriticalCapacity <= 1, "HEALTH_CRITICAL_CAPACITY must be at most 1")
+
+	if c.Broker.RedisURL != "" {
+		_, err := url.Parse(c.Broker.RedisURL)
+		check(err == nil, "REDIS_URL is not a valid URL")
+	}
+	return errors.Join(errs...)
+}
+
+// Effective returns every setting with its value in effect, keyed by name. Secrets are
+// redacted: the password of a URL, or the whole value.
+func (c *Config) Effective() map[string]string {
+	settings := c.settings()
+	effective := make(map[string]string, len(settings))
+	for _, s := range settings {
+		value := s.value.String()
+		if s.secret && value != "" {
+			value = redact(value)
+		}
+		effective[s.name] = value
+	}
+	return effective
+}
+
+// redact hides the credentials of a URL, or the whole value when it isn't one
+func redact(value string) string {
+	if u, err := url.Parse(value); err == nil && u.Scheme != "" && u.Host != "" {
+		if u.User == nil {
+			return value
+		}
+		return u.Redacted()
+	}
+	return "[REDACTED]"
+}
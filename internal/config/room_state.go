@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+// In-memory room state settings
+const (
+	// Pending changes of active rooms are written to the database at this interval
+	RoomStateFlushInterval = 250 * time.Millisecond
+	// Rooms without reads or writes for this long are dropped from memory
+	RoomStateIdleTimeout = 10 * time.Minute
+	// A pending write failing this many times in a row is logged as an error; it stays queued
+	RoomStateMaxWriteAttempts = 5
+	// Participants of a room, and votes of a round, read at once
+	RoomMaxParticipants = 1000
+)
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// setting binds the name of an environment variable (or config file key) to a Config field
+type setting struct {
+	name   string
+	value  settingValue
+	secret bool // Redacted by Config.Effective
+}
+
+// settingValue parses and formats a setting, like flag.Value
+type settingValue interface {
+	Set(string) error
+	String() string
+}
+
+// settings lists the configurable fields of c
+func (c *Config) settings() []setting {
+	l, h := &c.Limits, &c.Health
+	return []setting{
+		{name: "MAX_CONNECTIONS_PER_ROOM", value: (*intValue)(&l.MaxConnectionsPerRoom)},
+		{name: "MAX_ROOMS_PER_INSTANCE", value: (*intValue)(&l.MaxRoomsPerInstance)},
+		{name: "MAX_TOTAL_CONNECTIONS", value: (*intValue)(&l.MaxTotalConnections)},
+		{name: "MAX_MESSAGES_PER_SECOND", value: (*intValue)(&l.MaxMessagesPerSecond)},
+		{name: "RATE_LIMIT_WINDOW", value: (*durationValue)(&l.RateLimitWindow)},
+		{name: "WRITE_TIMEOUT", value: (*durationValue)(&l.WriteTimeout)},
+		{name: "PING_INTERVAL", value: (*durationValue)(&l.PingInterval)},
+		{name: "PONG_TIMEOUT", value: (*durationValue)(&l.PongTimeout)},
+		{name: "CLIENT_SEND_BUFFER_SIZE", value: (*intValue)(&l.ClientSendBufferSize)},
+		{name: "HUB_REGISTER_BUFFER_SIZE", value: (*intValue)(&l.HubRegisterBufferSize)},
+		{name: "HUB_UNREGISTER_BUFFER_SIZE", value: (*intValue)(&l.HubUnregisterBufferSize)},
+		{name: "ROOM_COMMAND_QUEUE_SIZE", value: (*intValue)(&l.RoomCommandQueueSize)},
+		{name: "ROOM_QUEUE_FULL_TIMEOUT", value: (*durationValue)(&l.RoomQueueFullTimeout)},
+		{name: "MAX_CONCURRENT_ROOM_COMMANDS", value: (*intValue)(&l.MaxConcurrentRoomCommands)},
+		{name: "ROOM_WORKER_IDLE_TIMEOUT", value: (*durationValue)(&l.RoomWorkerIdleTimeout)},
+		{name: "EVENT_LOG_SIZE", value: (*intValue)(&l.EventLogSize)},
+		{name: "EVENT_LOG_RETENTION", value: (*durationValue)(&l.EventLogRetention)},
+
+		{name: "HEALTH_WARNING_CAPACITY", value: (*floatValue)(&h.WarningCapacity)},
+		{name: "HEALTH_CRITICAL_CAPACITY", value: (*floatValue)(&h.CriticalCapacity)},
+		{name: "HEALTH_ERROR_WINDOW", value: (*durationValue)(&h.ErrorWindow)},
+		{name: "HEALTH_WARNING_ERRORS", value: (*intValue)(&h.WarningErrors)},
+		{name: "READINESS_TIMEOUT", value: (*durationValue)(&h.ReadinessTimeout)},
+
+		{name: "REDIS_URL", value: (*stringValue)(&c.Broker.RedisURL), secret: true},
+	}
+}
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return errors.New("must be an integer")
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+func (v *intValue) positive() bool { return *v > 0 }
+
+type floatValue float64
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return errors.New("must be a number")
+	}
+	*v = floatValue(f)
+	return nil
+}
+
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+func (v *floatValue) positive() bool { return *v > 0 }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.New("must be a duration such as 30s or 5m")
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+func (v *durationValue) positive() bool { return *v > 0 }
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string { return string(*v) }
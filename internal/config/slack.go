@@ -0,0 +1,12 @@
+package config
+
+import "time"
+
+// Slack slash command integration
+const (
+	// Requests older than this are rejected to prevent replays
+	SlackRequestMaxAge = 5 * time.Minute
+	// Slack accepts posts to a command's response_url for 30 minutes
+	SlackResponseURLTTL = 30 * time.Minute
+	SlackPostTimeout    = 5 * time.Second
+)
@@ -0,0 +1,27 @@
+package config
+
+import "time"
+
+// Issue tracker integration settings
+const (
+	IssueTrackerTimeout = 10 * time.Second
+
+	// Import limits
+	DefaultStoryImportLimit = 50
+	MaxStoryImportLimit     = 100
+	MaxStoriesPerRoom       = 200
+
+	// Jira Software's default story points field on Jira Cloud
+	DefaultJiraStoryPointsField = "customfield_10016"
+)
+
+// GitHub Issues settings
+const (
+	DefaultGitHubAPIURL        = "https://api.github.com"
+	DefaultGitHubLabelPrefix   = "points:"
+	DefaultGitHubEstimateField = "Estimate"
+
+	// Retries of network errors, server errors and rate limits
+	GitHubMaxAttempts  = 3
+	GitHubRetryBackoff = time.Second
+)
@@ -0,0 +1,23 @@
+package config
+
+import "time"
+
+// Outgoing webhook delivery settings
+const (
+	// Registration limits
+	MaxWebhooksPerRoom = 5
+
+	// Delivery
+	WebhookTimeout      = 10 * time.Second
+	WebhookPollInterval = 5 * time.Second
+	WebhookBatchSize    = 50
+
+	// Retries: backoff doubles from the initial delay up to the maximum
+	WebhookMaxAttempts    = 8
+	WebhookInitialBackoff = 10 * time.Second
+	WebhookMaxBackoff     = time.Hour
+	WebhookDeliveryMaxAge = 7 * 24 * time.Hour // Delivery log retention
+
+	// Size of the last_error column of the delivery log, longer errors are truncated
+	WebhookMaxErrorLength = 1000
+)
@@ -0,0 +1,591 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/security"
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+// ParticipantTokenHeader carries the participant session token returned when joining through the API.
+// Browser clients can rely on the session cookie instead.
+const ParticipantTokenHeader = "X-Participant-Token"
+
+// APIHandlers serves the versioned JSON REST API under /api/v1.
+// Room commands are queued and handled like WebSocket messages, so connected clients see API actions live.
+type APIHandlers struct {
+	roomManager   *services.RoomManager
+	aclService    *services.ACLService
+	hub           *services.Hub
+	ws            *WSHandler
+	voteValidator *services.VoteValidator
+}
+
+func NewAPIHandlers(rm *services.RoomManager, acl *services.ACLService, hub *services.Hub, ws *WSHandler) *APIHandlers {
+	return &APIHandlers{
+		roomManager:   rm,
+		aclService:    acl,
+		hub:           hub,
+		ws:            ws,
+		voteValidator: services.NewVoteValidator(),
+	}
+}
+
+// API response types
+
+type apiErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type apiErrorEnvelope struct {
+	Error apiErrorBody `json:"error"`
+}
+
+type apiRoom struct {
+	ID                         string             `json:"id"`
+	Name                       string             `json:"name"`
+	PointingMethod             string             `json:"pointingMethod"`
+	Values                     []string           `json:"values"`
+	Config                     *models.RoomConfig `json:"config"`
+	State                      string             `json:"state"`
+	RoundNumber                int                `json:"roundNumber"`
+	VoteCount                  int                `json:"voteCount"`
+	ParticipantCount           int                `json:"participantCount"`
+	ConsecutiveConsensusRounds int                `json:"consecutiveConsensusRounds"`
+	CreatedAt                  time.Time          `json:"createdAt"`
+	ExpiresAt                  time.Time          `json:"expiresAt"`
+}
+
+type apiParticipant struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	Weight    float64   `json:"weight"`
+	Connected bool      `json:"connected"`
+	IsCreator bool      `json:"isCreator"`
+	JoinedAt  time.Time `json:"joinedAt"`
+}
+
+type apiVote struct {
+	ParticipantID string            `json:"participantId"`
+	HasVoted      bool              `json:"hasVoted"`
+	Value         string            `json:"value,omitempty"`      // Only once revealed
+	Dimensions    map[string]string `json:"dimensions,omitempty"` // Only once revealed
+	Confidence    int               `json:"confidence,omitempty"` // Only once revealed
+}
+
+type apiRound struct {
+	ID           string     `json:"id"`
+	RoundNumber  int        `json:"roundNumber"`
+	State        string     `json:"state"`
+	AverageScore *float64   `json:"averageScore,omitempty"` // Only for completed rounds
+	TotalVotes   int        `json:"totalVotes"`
+	Consensus    bool       `json:"consensus"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	CompletedAt  *time.Time `json:"completedAt,omitempty"`
+	Votes        []apiVote  `json:"votes,omitempty"`
+}
+
+type apiParticipantList struct {
+	Participants []apiParticipant `json:"participants"`
+}
+
+type apiRoundList struct {
+	Rounds []apiRound `json:"rounds"`
+}
+
+type apiCreateRoomRequest struct {
+	Name           string             `json:"name"`
+	PointingMethod string             `json:"pointingMethod"`
+	CustomValues   []string           `json:"customValues"`
+	Config         *models.RoomConfig `json:"config"`
+}
+
+type apiJoinRequest struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+type apiJoinResponse struct {
+	Participant apiParticipant `json:"participant"`
+	Token       string         `json:"token"`
+}
+
+// apiError writes the consistent error envelope
+func apiError(re *core.RequestEvent, status int, code, message string) error {
+	return re.JSON(status, apiErrorEnvelope{Error: apiErrorBody{Code: code, Message: message}})
+}
+
+// writeAPIError maps a command error onto an HTTP status and the error envelope
+func writeAPIError(re *core.RequestEvent, err error) error {
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return apiError(re, http.StatusInternalServerError, ErrCodeInternal, "Internal server error")
+	}
+
+	status := http.StatusInternalServerError
+	switch cmdErr.Code {
+	case ErrCodeInvalidPayload:
+		status = http.StatusBadRequest
+	case ErrCodeUnauthorized:
+		status = http.StatusUnauthorized
+	case ErrCodeForbidden:
+		status = http.StatusForbidden
+	case ErrCodeNotFound:
+		status = http.StatusNotFound
+	case ErrCodeInvalidState:
+		status = http.StatusConflict
+	case ErrCodeRoomExpired:
+		status = http.StatusGone
+	case models.ErrCodeRateLimited:
+		status = http.StatusTooManyRequests
+	case models.ErrCodeRoomBusy:
+		status = http.StatusServiceUnavailable
+	}
+	return apiError(re, status, cmdErr.Code, cmdErr.Message)
+}
+
+// loadRoom validates the {id} path value and fetches the room. Errors are *CommandError.
+func (h *APIHandlers) loadRoom(re *core.RequestEvent) (*core.Record, error) {
+	roomID := re.Request.PathValue("id")
+	if err := security.ValidateUUID(roomID); err != nil {
+		return nil, newCommandError(ErrCodeInvalidPayload, "Invalid room ID")
+	}
+
+	room, err := h.roomManager.GetRoom(roomID)
+	if err != nil {
+		return nil, newCommandError(ErrCodeNotFound, "Room not found")
+	}
+	return room, nil
+}
+
+// authenticate resolves the calling participant from the token header (or session cookie)
+// and rejects commands on expired rooms. Errors are *CommandError.
+func (h *APIHandlers) authenticate(re *core.RequestEvent, roomID string) (string, error) {
+	token := strings.TrimSpace(re.Request.Header.Get(ParticipantTokenHeader))
+	if token == "" {
+		token = getParticipantID(re.Request)
+	}
+	if token == "" {
+		return "", newCommandError(ErrCodeUnauthorized, "Missing %s header", ParticipantTokenHeader)
+	}
+
+	participant, err := h.roomManager.GetParticipantBySession(roomID, token)
+	if err != nil {
+		return "", newCommandError(ErrCodeUnauthorized, "Unknown participant token for this room")
+	}
+
+	if h.ws.isRoomExpired(roomID) {
+		return "", newCommandError(ErrCodeRoomExpired, "This room has expired")
+	}
+
+	return participant.Id, nil
+}
+
+// decodeJSON parses the request body into dst. Errors are *CommandError.
+func decodeJSON(re *core.RequestEvent, dst any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(re.Response, re.Request.Body, 64*1024))
+	if err := decoder.Decode(dst); err != nil {
+		return newCommandError(ErrCodeInvalidPayload, "Invalid JSON body")
+	}
+	return nil
+}
+
+// CreateRoom handles POST /api/v1/rooms
+func (h *APIHandlers) CreateRoom(re *core.RequestEvent) error {
+	var req apiCreateRoomRequest
+	if err := decodeJSON(re, &req); err != nil {
+		return writeAPIError(re, err)
+	}
+
+	name, err := security.ValidateRoomName(req.Name)
+	if err != nil {
+		return apiError(re, http.StatusBadRequest, ErrCodeInvalidPayload, err.Error())
+	}
+
+	var customValues []string
+	switch req.PointingMethod {
+	case "fibonacci":
+		customValues = h.voteValidator.GetFibonacciValues()
+	case "", "custom":
+		req.PointingMethod = "custom"
+		if len(req.CustomValues) == 0 {
+			customValues = h.voteValidator.GetModifiedFibonacciValues()
+			break
+		}
+		customValues, err = h.voteValidator.ParseCustomValues(strings.Join(req.CustomValues, ","))
+		if err != nil {
+			return apiError(re, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid custom values: "+err.Error())
+		}
+	default:
+		return apiError(re, http.StatusBadRequest, ErrCodeInvalidPayload, "pointingMethod must be fibonacci or custom")
+	}
+
+	config := req.Config
+	if config == nil {
+		config = models.DefaultRoomConfig()
+	}
+	if err := h.validateConfig(config); err != nil {
+		return apiError(re, http.StatusBadRequest, ErrCodeInvalidPayload, err.Error())
+	}
+
+	roomRecord, err := h.roomManager.CreateRoom(name, req.PointingMethod, customValues, config)
+	if err != nil {
+		return apiError(re, http.StatusInternalServerError, ErrCodeInternal, "Failed to create room")
+	}
+
+	room, err := h.buildRoom(roomRecord)
+	if err != nil {
+		return apiError(re, http.StatusInternalServerError, ErrCodeInternal, "Failed to load room")
+	}
+	return re.JSON(http.StatusCreated, room)
+}
+
+// validateConfig applies the same checks as room creation and update_config
+func (h *APIHandlers) validateConfig(config *models.RoomConfig) error {
+	if err := h.voteValidator.ValidateDimensions(config.Dimensions); err != nil {
+		return err
+	}
+	if err := h.voteValidator.ValidateCombineMethod(config.CombineMethod); err != nil {
+		return err
+	}
+	return security.ValidateRevealRule(config.RevealRule)
+}
+
+// GetRoom handles GET /api/v1/rooms/{id}
+func (h *APIHandlers) GetRoom(re *core.RequestEvent) error {
+	roomRecord, err := h.loadRoom(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+
+	room, err := h.buildRoom(roomRecord)
+	if err != nil {
+		return apiError(re, http.StatusInternalServerError, ErrCodeInternal, "Failed to load room")
+	}
+	return re.JSON(http.StatusOK, room)
+}
+
+// buildRoom converts a room record into its API representation
+func (h *APIHandlers) buildRoom(roomRecord *core.Record) (*apiRoom, error) {
+	room := recordToRoom(roomRecord)
+
+	state, err := h.roomManager.GetRoomState(room.ID)
+	if err != nil {
+		return nil, err
+	}
+	roundNumber, _ := h.roomManager.GetCurrentRound(room.ID)
+	votes, _ := h.roomManager.GetRoomVotes(room.ID)
+	participants, _ := h.roomManager.GetRoomParticipants(room.ID)
+
+	return &apiRoom{
+		ID:                         room.ID,
+		Name:                       room.Name,
+		PointingMethod:             room.PointingMethod,
+		Values:                     room.CustomValues,
+		Config:                     room.Config,
+		State:                      string(state),
+		RoundNumber:                roundNumber,
+		VoteCount:                  len(votes),
+		ParticipantCount:           len(participants),
+		ConsecutiveConsensusRounds: room.ConsecutiveConsensusRounds,
+		CreatedAt:                  room.CreatedAt,
+		ExpiresAt:                  room.ExpiresAt,
+	}, nil
+}
+
+// ListParticipants handles GET /api/v1/rooms/{id}/participants
+func (h *APIHandlers) ListParticipants(re *core.RequestEvent) error {
+	roomRecord, err := h.loadRoom(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+
+	records, err := h.roomManager.GetRoomParticipants(roomRecord.Id)
+	if err != nil {
+		return apiError(re, http.StatusInternalServerError, ErrCodeInternal, "Failed to load participants")
+	}
+
+	creatorID := roomRecord.GetString("creator_participant_id")
+	participants := make([]apiParticipant, 0, len(records))
+	for _, record := range records {
+		participants = append(participants, toAPIParticipant(record, creatorID))
+	}
+	return re.JSON(http.StatusOK, apiParticipantList{Participants: participants})
+}
+
+func toAPIParticipant(record *core.Record, creatorID string) apiParticipant {
+	p := recordToParticipant(record)
+	return apiParticipant{
+		ID:        p.ID,
+		Name:      p.Name,
+		Role:      string(p.Role),
+		Weight:    p.Weight,
+		Connected: p.Connected,
+		IsCreator: p.ID == creatorID,
+		JoinedAt:  p.JoinedAt,
+	}
+}
+
+// JoinRoom handles POST /api/v1/rooms/{id}/participants
+// The returned token identifies the participant in later commands (X-Participant-Token header)
+func (h *APIHandlers) JoinRoom(re *core.RequestEvent) error {
+	roomRecord, err := h.loadRoom(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+
+	var req apiJoinRequest
+	if err := decodeJSON(re, &req); err != nil {
+		return writeAPIError(re, err)
+	}
+
+	name, err := security.ValidateParticipantName(req.Name)
+	if err != nil {
+		return apiError(re, http.StatusBadRequest, ErrCodeInvalidPayload, err.Error())
+	}
+
+	role := models.RoleVoter
+	switch req.Role {
+	case "", string(models.RoleVoter):
+	case string(models.RoleSpectator):
+		role = models.RoleSpectator
+	default:
+		return apiError(re, http.StatusBadRequest, ErrCodeInvalidPayload, "role must be voter or spectator")
+	}
+
+	token := uuid.New().String()
+	participantRecord, err := h.roomManager.AddParticipant(roomRecord.Id, name, role, token)
+	if err != nil {
+		return apiError(re, http.StatusInternalServerError, ErrCodeInternal, "Failed to join room")
+	}
+
+	// Broadcast participant joined event
+	joined := models.ParticipantJoinedPayload{Participant: recordToParticipant(participantRecord)}
+	h.hub.BroadcastToRoom(re.Request.Context(), roomRecord.Id, &models.WSMessage{
+		Type:    models.MsgTypeParticipantJoined,
+		Payload: joined,
+	})
+	h.ws.notifyWebhooks(roomRecord.Id, models.WebhookEventParticipantJoined, joined)
+
+	// Creator is assigned on first join, so re-read the room
+	creatorID := participantRecord.Id
+	if refreshed, err := h.roomManager.GetRoom(roomRecord.Id); err == nil {
+		creatorID = refreshed.GetString("creator_participant_id")
+	}
+
+	return re.JSON(http.StatusCreated, apiJoinResponse{
+		Participant: toAPIParticipant(participantRecord, creatorID),
+		Token:       token,
+	})
+}
+
+// ListRounds handles GET /api/v1/rooms/{id}/rounds (round history)
+func (h *APIHandlers) ListRounds(re *core.RequestEvent) error {
+	roomRecord, err := h.loadRoom(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+
+	records, err := h.roomManager.GetRoomRounds(roomRecord.Id)
+	if err != nil {
+		return apiError(re, http.StatusInternalServerError, ErrCodeInternal, "Failed to load rounds")
+	}
+
+	rounds := make([]apiRound, 0, len(records))
+	for _, record := range records {
+		rounds = append(rounds, toAPIRound(record))
+	}
+	return re.JSON(http.StatusOK, apiRoundList{Rounds: rounds})
+}
+
+// GetCurrentRound handles GET /api/v1/rooms/{id}/rounds/current
+// Vote values are only included once the round has been revealed
+func (h *APIHandlers) GetCurrentRound(re *core.RequestEvent) error {
+	roomRecord, err := h.loadRoom(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+
+	roundRecord, err := h.roomManager.GetCurrentRoundRecord(roomRecord.Id)
+	if err != nil {
+		return apiError(re, http.StatusNotFound, ErrCodeNotFound, "Current round not found")
+	}
+
+	votes, err := h.roomManager.GetRoomVotes(roomRecord.Id)
+	if err != nil {
+		return apiError(re, http.StatusInternalServerError, ErrCodeInternal, "Failed to load votes")
+	}
+
+	round := toAPIRound(roundRecord)
+	revealed := round.State != string(models.RoundStateVoting)
+	round.TotalVotes = len(votes)
+	round.Votes = make([]apiVote, 0, len(votes))
+	for _, vote := range votes {
+		v := apiVote{
+			ParticipantID: vote.GetString("participant_id"),
+			HasVoted:      true,
+		}
+		if revealed {
+			v.Value = vote.GetString("value")
+			v.Dimensions = h.roomManager.GetVoteDimensionValues(vote)
+			v.Confidence = vote.GetInt("confidence")
+		}
+		round.Votes = append(round.Votes, v)
+	}
+	return re.JSON(http.StatusOK, round)
+}
+
+func toAPIRound(record *core.Record) apiRound {
+	round := apiRound{
+		ID:          record.Id,
+		RoundNumber: record.GetInt("round_number"),
+		State:       record.GetString("state"),
+		TotalVotes:  record.GetInt("total_votes"),
+		Consensus:   record.GetBool("consensus"),
+		CreatedAt:   record.GetDateTime("created").Time(),
+	}
+	if round.State == string(models.RoundStateCompleted) {
+		avg := record.GetFloat("average_score")
+		round.AverageScore = &avg
+		if completedAt := record.GetDateTime("completed_at"); !completedAt.IsZero() {
+			t := completedAt.Time()
+			round.CompletedAt = &t
+		}
+	}
+	return round
+}
+
+// CastVote handles POST /api/v1/rooms/{id}/votes
+// Body matches the WebSocket vote payload: {"value": "5", "confidence": "high"} or {"dimensions": {...}}
+func (h *APIHandlers) CastVote(re *core.RequestEvent) error {
+	roomRecord, err := h.loadRoom(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+	participantID, err := h.authenticate(re, roomRecord.Id)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+
+	var body json.RawMessage
+	if err := decodeJSON(re, &body); err != nil {
+		return writeAPIError(re, err)
+	}
+
+	if err := h.runCommand(re, roomRecord.Id, participantID, models.MsgTypeVote, body); err != nil {
+		return writeAPIError(re, err)
+	}
+	return re.NoContent(http.StatusNoContent)
+}
+
+// RetractVote handles DELETE /api/v1/rooms/{id}/votes
+func (h *APIHandlers) RetractVote(re *core.RequestEvent) error {
+	roomRecord, err := h.loadRoom(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+	participantID, err := h.authenticate(re, roomRecord.Id)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+
+	if err := h.runCommand(re, roomRecord.Id, participantID, models.MsgTypeUnvote, nil); err != nil {
+		return writeAPIError(re, err)
+	}
+	return re.NoContent(http.StatusNoContent)
+}
+
+// Reveal handles POST /api/v1/rooms/{id}/reveal
+func (h *APIHandlers) Reveal(re *core.RequestEvent) error {
+	roomRecord, err := h.loadRoom(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+	participantID, err := h.authenticate(re, roomRecord.Id)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+
+	if err := h.runCommand(re, roomRecord.Id, participantID, models.MsgTypeReveal, nil); err != nil {
+		return writeAPIError(re, err)
+	}
+	return h.GetCurrentRound(re)
+}
+
+// Reset handles POST /api/v1/rooms/{id}/reset
+func (h *APIHandlers) Reset(re *core.RequestEvent) error {
+	roomRecord, err := h.loadRoom(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+	participantID, err := h.authenticate(re, roomRecord.Id)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+
+	if err := h.runCommand(re, roomRecord.Id, participantID, models.MsgTypeReset, nil); err != nil {
+		return writeAPIError(re, err)
+	}
+	return re.NoContent(http.StatusNoContent)
+}
+
+// NextRound handles POST /api/v1/rooms/{id}/next-round
+func (h *APIHandlers) NextRound(re *core.RequestEvent) error {
+	roomRecord, err := h.loadRoom(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+	participantID, err := h.authenticate(re, roomRecord.Id)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+
+	if err := h.runCommand(re, roomRecord.Id, participantID, models.MsgTypeNextRound, nil); err != nil {
+		return writeAPIError(re, err)
+	}
+	newRound, err := h.roomManager.GetCurrentRoundRecord(roomRecord.Id)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+	return re.JSON(http.StatusCreated, toAPIRound(newRound))
+}
+
+// runCommand sends a room command through the room's queue, as a WebSocket message of
+// the participant, and waits for the reply. An error reply is returned as a *CommandError.
+func (h *APIHandlers) runCommand(re *core.RequestEvent, roomID, participantID, msgType string, payload json.RawMessage) error {
+	if payload == nil {
+		payload = json.RawMessage("{}")
+	}
+	message, err := json.Marshal(models.IncomingMessage{Type: msgType, RequestID: uuid.NewString(), Payload: payload})
+	if err != nil {
+		return err
+	}
+
+	reply, err := h.hub.Command(re.Request.Context(), roomID, participantID, message)
+	switch {
+	case errors.Is(err, services.ErrRateLimited):
+		return newCommandError(models.ErrCodeRateLimited, "Rate limit exceeded. Please slow down.")
+	case errors.Is(err, services.ErrRoomBusy):
+		return newCommandError(models.ErrCodeRoomBusy, "The room is busy. Please try again.")
+	case err != nil:
+		return err
+	}
+
+	if reply.Type != models.MsgTypeError {
+		return nil
+	}
+	if errPayload, ok := reply.Payload.(models.ErrorPayload); ok {
+		return &CommandError{Code: errPayload.Code, Message: errPayload.Message}
+	}
+	return newCommandError(ErrCodeInternal, "Internal server error")
+}
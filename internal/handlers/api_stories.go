@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/models"
+)
+
+const maxStoryQueryLength = 2000
+
+type apiStory struct {
+	ID         string    `json:"id"`
+	Tracker    string    `json:"tracker"`
+	IssueKey   string    `json:"issueKey"`
+	Title      string    `json:"title"`
+	URL        string    `json:"url,omitempty"`
+	Position   int       `json:"position"`
+	Status     string    `json:"status"`
+	Estimate   *float64  `json:"estimate,omitempty"`   // Only once estimated
+	RoundID    string    `json:"roundId,omitempty"`    // Round the story was estimated in
+	SyncStatus string    `json:"syncStatus,omitempty"` // Write-back of the estimate: pending, synced, failed or conflict
+	SyncError  string    `json:"syncError,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type apiStoryList struct {
+	Stories []apiStory `json:"stories"`
+}
+
+type apiImportStoriesRequest struct {
+	Tracker string `json:"tracker,omitempty"` // Defaults to the only configured tracker
+	Query   string `json:"query"`             // JQL for Jira, search qualifiers (repo:, label:, milestone:) for GitHub
+	Limit   int    `json:"limit,omitempty"`
+}
+
+func toAPIStory(record *core.Record) apiStory {
+	story := apiStory{
+		ID:         record.Id,
+		Tracker:    record.GetString("tracker"),
+		IssueKey:   record.GetString("issue_key"),
+		Title:      record.GetString("title"),
+		URL:        record.GetString("url"),
+		Position:   record.GetInt("position"),
+		Status:     record.GetString("status"),
+		RoundID:    record.GetString("round_id"),
+		SyncStatus: record.GetString("sync_status"),
+		SyncError:  record.GetString("sync_error"),
+		CreatedAt:  record.GetDateTime("created_at").Time(),
+	}
+	if story.Status == models.StoryEstimated {
+		estimate := record.GetFloat("estimate")
+		story.Estimate = &estimate
+	}
+	return story
+}
+
+// ListStories handles GET /api/v1/rooms/{id}/stories
+// Stories are listed in estimation order
+func (h *APIHandlers) ListStories(re *core.RequestEvent) error {
+	roomRecord, err := h.loadRoom(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+	if h.ws.stories == nil {
+		return re.JSON(http.StatusOK, apiStoryList{Stories: []apiStory{}})
+	}
+
+	records, err := h.ws.stories.GetRoomStories(roomRecord.Id)
+	if err != nil {
+		return apiError(re, http.StatusInternalServerError, ErrCodeInternal, "Failed to load stories")
+	}
+
+	stories := make([]apiStory, 0, len(records))
+	for _, record := range records {
+		stories = append(stories, toAPIStory(record))
+	}
+	return re.JSON(http.StatusOK, apiStoryList{Stories: stories})
+}
+
+// ImportStories handles POST /api/v1/rooms/{id}/stories/import (facilitator only)
+// Appends the issues matching the query; issues already in the room are skipped
+func (h *APIHandlers) ImportStories(re *core.RequestEvent) error {
+	roomRecord, err := h.authenticateFacilitator(re, "import stories")
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+	if h.ws.stories == nil {
+		return apiError(re, http.StatusNotFound, ErrCodeNotFound, "No issue tracker is configured")
+	}
+
+	var req apiImportStoriesRequest
+	if err := decodeJSON(re, &req); err != nil {
+		return writeAPIError(re, err)
+	}
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" || len(req.Query) > maxStoryQueryLength {
+		return apiError(re, http.StatusBadRequest, ErrCodeInvalidPayload, "query must be between 1 and "+strconv.Itoa(maxStoryQueryLength)+" characters")
+	}
+	if req.Limit == 0 {
+		req.Limit = config.DefaultStoryImportLimit
+	}
+	if req.Limit < 1 || req.Limit > config.MaxStoryImportLimit {
+		return apiError(re, http.StatusBadRequest, ErrCodeInvalidPayload, "limit must be between 1 and "+strconv.Itoa(config.MaxStoryImportLimit))
+	}
+	if _, err := h.ws.stories.Tracker(req.Tracker); err != nil {
+		return apiError(re, http.StatusBadRequest, ErrCodeInvalidPayload, err.Error())
+	}
+
+	records, err := h.ws.stories.ImportStories(roomRecord.Id, req.Tracker, req.Query, req.Limit)
+	if err != nil {
+		return apiError(re, http.StatusBadGateway, ErrCodeTrackerError, err.Error())
+	}
+
+	stories := make([]apiStory, 0, len(records))
+	for _, record := range records {
+		stories = append(stories, toAPIStory(record))
+	}
+	return re.JSON(http.StatusCreated, apiStoryList{Stories: stories})
+}
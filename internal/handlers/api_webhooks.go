@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/security"
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+const (
+	defaultDeliveryLogLimit = 50
+	maxDeliveryLogLimit     = 200
+)
+
+type apiWebhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"` // Only returned when the webhook is created
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type apiWebhookList struct {
+	Webhooks []apiWebhook `json:"webhooks"`
+}
+
+type apiCreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"` // Defaults to every event
+}
+
+type apiWebhookDelivery struct {
+	ID             string     `json:"id"`
+	WebhookID      string     `json:"webhookId"`
+	Event          string     `json:"event"`
+	URL            string     `json:"url"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"responseStatus,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	NextAttemptAt  *time.Time `json:"nextAttemptAt,omitempty"` // Only while pending
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+type apiWebhookDeliveryList struct {
+	Deliveries []apiWebhookDelivery `json:"deliveries"`
+}
+
+// authenticateFacilitator resolves the calling participant and requires them to be the room creator.
+// action completes the "Only the facilitator can ..." error message. Errors are *CommandError.
+func (h *APIHandlers) authenticateFacilitator(re *core.RequestEvent, action string) (*core.Record, error) {
+	roomRecord, err := h.loadRoom(re)
+	if err != nil {
+		return nil, err
+	}
+	participantID, err := h.authenticate(re, roomRecord.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !h.roomManager.IsRoomCreator(roomRecord.Id, participantID) {
+		return nil, newCommandError(ErrCodeForbidden, "Only the facilitator can %s", action)
+	}
+	return roomRecord, nil
+}
+
+// authenticateWebhookAdmin authenticates the facilitator and requires webhooks to be enabled
+func (h *APIHandlers) authenticateWebhookAdmin(re *core.RequestEvent) (*core.Record, error) {
+	roomRecord, err := h.authenticateFacilitator(re, "manage webhooks")
+	if err != nil {
+		return nil, err
+	}
+	if h.ws.webhooks == nil {
+		return nil, newCommandError(ErrCodeNotFound, "Webhooks are not enabled")
+	}
+	return roomRecord, nil
+}
+
+func toAPIWebhook(record *core.Record) apiWebhook {
+	return apiWebhook{
+		ID:        record.Id,
+		URL:       record.GetString("url"),
+		Events:    services.WebhookEvents(record),
+		CreatedAt: record.GetDateTime("created_at").Time(),
+	}
+}
+
+// ListWebhooks handles GET /api/v1/rooms/{id}/webhooks (facilitator only)
+func (h *APIHandlers) ListWebhooks(re *core.RequestEvent) error {
+	roomRecord, err := h.authenticateWebhookAdmin(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+
+	records, err := h.ws.webhooks.GetRoomWebhooks(roomRecord.Id)
+	if err != nil {
+		return apiError(re, http.StatusInternalServerError, ErrCodeInternal, "Failed to load webhooks")
+	}
+
+	webhooks := make([]apiWebhook, 0, len(records))
+	for _, record := range records {
+		webhooks = append(webhooks, toAPIWebhook(record))
+	}
+	return re.JSON(http.StatusOK, apiWebhookList{Webhooks: webhooks})
+}
+
+// CreateWebhook handles POST /api/v1/rooms/{id}/webhooks (facilitator only)
+// The signing secret is only returned in this response
+func (h *APIHandlers) CreateWebhook(re *core.RequestEvent) error {
+	roomRecord, err := h.authenticateWebhookAdmin(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+
+	var req apiCreateWebhookRequest
+	if err := decodeJSON(re, &req); err != nil {
+		return writeAPIError(re, err)
+	}
+	if _, err := security.ValidateWebhookURL(req.URL); err != nil {
+		return apiError(re, http.StatusBadRequest, ErrCodeInvalidPayload, err.Error())
+	}
+	if err := security.CheckWebhookTarget(re.Request.Context(), req.URL); err != nil {
+		return apiError(re, http.StatusBadRequest, ErrCodeInvalidPayload, err.Error())
+	}
+	if _, err := security.ValidateWebhookEvents(req.Events); err != nil {
+		return apiError(re, http.StatusBadRequest, ErrCodeInvalidPayload, err.Error())
+	}
+
+	record, err := h.ws.webhooks.CreateWebhook(roomRecord.Id, req.URL, req.Events)
+	if err != nil {
+		return apiError(re, http.StatusConflict, ErrCodeInvalidState, err.Error())
+	}
+
+	webhook := toAPIWebhook(record)
+	webhook.Secret = record.GetString("secret")
+	return re.JSON(http.StatusCreated, webhook)
+}
+
+// DeleteWebhook handles DELETE /api/v1/rooms/{id}/webhooks/{webhookId} (facilitator only)
+func (h *APIHandlers) DeleteWebhook(re *core.RequestEvent) error {
+	roomRecord, err := h.authenticateWebhookAdmin(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+
+	webhookID := re.Request.PathValue("webhookId")
+	if err := security.ValidateUUID(webhookID); err != nil {
+		return apiError(re, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid webhook ID")
+	}
+	if err := h.ws.webhooks.DeleteWebhook(roomRecord.Id, webhookID); err != nil {
+		return apiError(re, http.StatusNotFound, ErrCodeNotFound, "Webhook not found")
+	}
+	return re.NoContent(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries handles GET /api/v1/rooms/{id}/webhooks/deliveries (facilitator only)
+// Returns the delivery log, newest first; ?limit= caps the number of entries
+func (h *APIHandlers) ListWebhookDeliveries(re *core.RequestEvent) error {
+	roomRecord, err := h.authenticateWebhookAdmin(re)
+	if err != nil {
+		return writeAPIError(re, err)
+	}
+
+	limit := defaultDeliveryLogLimit
+	if raw := re.Request.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxDeliveryLogLimit {
+			return apiError(re, http.StatusBadRequest, ErrCodeInvalidPayload, "limit must be between 1 and "+strconv.Itoa(maxDeliveryLogLimit))
+		}
+		limit = parsed
+	}
+
+	records, err := h.ws.webhooks.GetRoomDeliveries(roomRecord.Id, limit)
+	if err != nil {
+		return apiError(re, http.StatusInternalServerError, ErrCodeInternal, "Failed to load deliveries")
+	}
+
+	deliveries := make([]apiWebhookDelivery, 0, len(records))
+	for _, record := range records {
+		deliveries = append(deliveries, toAPIWebhookDelivery(record))
+	}
+	return re.JSON(http.StatusOK, apiWebhookDeliveryList{Deliveries: deliveries})
+}
+
+func toAPIWebhookDelivery(record *core.Record) apiWebhookDelivery {
+	delivery := apiWebhookDelivery{
+		ID:             record.Id,
+		WebhookID:      record.GetString("webhook_id"),
+		Event:          record.GetString("event"),
+		URL:            record.GetString("url"),
+		Status:         record.GetString("status"),
+		Attempts:       record.GetInt("attempts"),
+		ResponseStatus: record.GetInt("response_status"),
+		LastError:      record.GetString("last_error"),
+		CreatedAt:      record.GetDateTime("created_at").Time(),
+	}
+	if delivery.Status == models.DeliveryPending {
+		t := record.GetDateTime("next_attempt_at").Time()
+		delivery.NextAttemptAt = &t
+	}
+	if deliveredAt := record.GetDateTime("delivered_at"); !deliveredAt.IsZero() {
+		t := deliveredAt.Time()
+		delivery.DeliveredAt = &t
+	}
+	return delivery
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/damione1/planning-poker/internal/apidocs"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+// Route is an HTTP route together with its documentation.
+// Routes are registered from the same list the OpenAPI document is generated from.
+type Route struct {
+	apidocs.Operation
+	Handler func(*core.RequestEvent) error
+}
+
+// Routes lists the REST API routes
+func (h *APIHandlers) Routes() []Route {
+	errorBody := apiErrorEnvelope{}
+	auth := []string{ParticipantTokenHeader}
+	route := func(method, path, summary string, handler func(*core.RequestEvent) error, request, response any, status int, headers []string) Route {
+		return Route{
+			Operation: apidocs.Operation{
+				Method:   method,
+				Path:     path,
+				Summary:  summary,
+				Tag:      "rooms",
+				Request:  request,
+				Response: response,
+				Status:   status,
+				Error:    errorBody,
+				Headers:  headers,
+			},
+			Handler: handler,
+		}
+	}
+
+	return []Route{
+		route(http.MethodPost, "/api/v1/rooms", "Create a room", h.CreateRoom, apiCreateRoomRequest{}, apiRoom{}, http.StatusCreated, nil),
+		route(http.MethodGet, "/api/v1/rooms/{id}", "Get a room", h.GetRoom, nil, apiRoom{}, 0, nil),
+		route(http.MethodGet, "/api/v1/rooms/{id}/participants", "List participants", h.ListParticipants, nil, apiParticipantList{}, 0, nil),
+		route(http.MethodPost, "/api/v1/rooms/{id}/participants", "Join a room", h.JoinRoom, apiJoinRequest{}, apiJoinResponse{}, http.StatusCreated, nil),
+		route(http.MethodGet, "/api/v1/rooms/{id}/rounds", "List rounds", h.ListRounds, nil, apiRoundList{}, 0, nil),
+		route(http.MethodGet, "/api/v1/rooms/{id}/rounds/current", "Get the current round", h.GetCurrentRound, nil, apiRound{}, 0, nil),
+		route(http.MethodPost, "/api/v1/rooms/{id}/votes", "Cast or change a vote", h.CastVote, models.VotePayload{}, nil, 0, auth),
+		route(http.MethodDelete, "/api/v1/rooms/{id}/votes", "Retract a vote", h.RetractVote, nil, nil, 0, auth),
+		route(http.MethodPost, "/api/v1/rooms/{id}/reveal", "Reveal votes", h.Reveal, nil, apiRound{}, 0, auth),
+		route(http.MethodPost, "/api/v1/rooms/{id}/reset", "Reset the current round", h.Reset, nil, nil, 0, auth),
+		route(http.MethodPost, "/api/v1/rooms/{id}/next-round", "Start the next round", h.NextRound, nil, apiRound{}, http.StatusCreated, auth),
+		route(http.MethodGet, "/api/v1/rooms/{id}/stories", "List stories", h.ListStories, nil, apiStoryList{}, 0, nil),
+		route(http.MethodPost, "/api/v1/rooms/{id}/stories/import", "Import stories from an issue tracker (facilitator only)", h.ImportStories, apiImportStoriesRequest{}, apiStoryList{}, http.StatusCreated, auth),
+		route(http.MethodGet, "/api/v1/rooms/{id}/webhooks", "List webhooks (facilitator only)", h.ListWebhooks, nil, apiWebhookList{}, 0, auth),
+		route(http.MethodPost, "/api/v1/rooms/{id}/webhooks", "Register a webhook (facilitator only)", h.CreateWebhook, apiCreateWebhookRequest{}, apiWebhook{}, http.StatusCreated, auth),
+		route(http.MethodGet, "/api/v1/rooms/{id}/webhooks/deliveries", "Webhook delivery log (facilitator only)", h.ListWebhookDeliveries, nil, apiWebhookDeliveryList{}, 0, auth),
+		route(http.MethodDelete, "/api/v1/rooms/{id}/webhooks/{webhookId}", "Remove a webhook (facilitator only)", h.DeleteWebhook, nil, nil, 0, auth),
+	}
+}
+
+// MonitoringRoutes lists the monitoring routes
+func MonitoringRoutes(app core.App, hub *services.Hub) []Route {
+	return []Route{
+		{
+			Operation: apidocs.Operation{Method: http.MethodGet, Path: "/monitoring/metrics", Summary: "WebSocket server metrics", Tag: "monitoring", Response: services.MetricsSnapshot{}},
+			Handler:   HandleMetrics(hub),
+		},
+		{
+			Operation: apidocs.Operation{Method: http.MethodGet, Path: "/monitoring/metrics/prometheus", Summary: "Metrics in the Prometheus text format", Tag: "monitoring", Response: "", ContentType: prometheusContentType},
+			Handler:   HandlePrometheusMetrics(hub),
+		},
+		{
+			Operation: apidocs.Operation{Method: http.MethodGet, Path: "/monitoring/health", Summary: "Server health", Tag: "monitoring", Response: healthResponse{}},
+			Handler:   HandleHealth(hub),
+		},
+		{
+			Operation: apidocs.Operation{Method: http.MethodGet, Path: "/monitoring/health/live", Summary: "Liveness probe", Tag: "monitoring", Response: livenessResponse{}},
+			Handler:   HandleLiveness(hub),
+		},
+		{
+			Operation: apidocs.Operation{Method: http.MethodGet, Path: "/monitoring/health/ready", Summary: "Readiness probe: database, hub loop and capacity", Tag: "monitoring", Response: readinessResponse{}},
+			Handler:   HandleReadiness(app, hub),
+		},
+		{
+			Operation: apidocs.Operation{Method: http.MethodGet, Path: "/monitoring/config", Summary: "Runtime configuration in effect, secrets redacted", Tag: "monitoring", Response: configResponse{}},
+			Handler:   HandleConfig(hub),
+		},
+	}
+}
+
+// WebSocket message catalog, used to generate the AsyncAPI document
+
+var wsClientMessages = []apidocs.Message{
+	{Type: models.MsgTypeVote, Summary: "Cast or change a vote", Payload: models.VotePayload{}},
+	{Type: models.MsgTypeUnvote, Summary: "Retract a vote before reveal", Payload: models.EmptyPayload{}},
+	{Type: models.MsgTypeReveal, Summary: "Reveal votes", Payload: models.EmptyPayload{}},
+	{Type: models.MsgTypeReset, Summary: "Clear votes of the current round", Payload: models.EmptyPayload{}},
+	{Type: models.MsgTypeNextRound, Summary: "Complete the round and start the next one", Payload: models.EmptyPayload{}},
+	{Type: models.MsgTypeUpdateName, Summary: "Change own display name", Payload: models.UpdateNamePayload{}},
+	{Type: models.MsgTypeUpdateRoomName, Summary: "Change the room name (creator only)", Payload: models.UpdateNamePayload{}},
+	{Type: models.MsgTypeUpdateConfig, Summary: "Change the room configuration (creator only)", Payload: models.UpdateConfigPayload{}},
+	{Type: models.MsgTypeUpdateWeight, Summary: "Change a participant's vote weight (creator only)", Payload: models.UpdateWeightPayload{}},
+}
+
+var wsServerMessages = []apidocs.Message{
+	{Type: models.MsgTypeRoomState, Summary: "Complete state sync on connect", Payload: models.RoomStatePayload{}},
+	{Type: models.MsgTypeParticipantJoined, Summary: "A participant joined or reconnected", Payload: models.ParticipantJoinedPayload{}},
+	{Type: models.MsgTypeParticipantLeft, Summary: "A participant disconnected their last tab or device", Payload: models.ParticipantLeftPayload{}},
+	{Type: models.MsgTypeVoteCast, Summary: "A participant voted or retracted a vote", Payload: models.VoteCastPayload{}},
+	{Type: models.MsgTypeVotesRevealed, Summary: "Votes and statistics of the revealed round", Payload: models.VotesRevealedPayload{}},
+	{Type: models.MsgTypeVoteUpdated, Summary: "A vote changed after reveal", Payload: models.VoteUpdatedPayload{}},
+	{Type: models.MsgTypeRoomReset, Summary: "Votes of the current round were cleared", Payload: models.EmptyPayload{}},
+	{Type: models.MsgTypeRoundCompleted, Summary: "A new round started", Payload: models.RoundCompletedPayload{}},
+	{Type: models.MsgTypeNameUpdated, Summary: "A participant changed name", Payload: models.NameUpdatedPayload{}},
+	{Type: models.MsgTypeRoomNameUpdated, Summary: "The room name changed", Payload: models.RoomNameUpdatedPayload{}},
+	{Type: models.MsgTypeConfigUpdated, Summary: "The room configuration changed", Payload: models.ConfigUpdatedPayload{}},
+	{Type: models.MsgTypeWeightUpdated, Summary: "A participant's vote weight changed", Payload: models.WeightUpdatedPayload{}},
+	{Type: models.MsgTypeAutoRevealCountdown, Summary: "Auto-reveal countdown started", Payload: models.AutoRevealCountdownPayload{}},
+	{Type: models.MsgTypeAutoRevealCancelled, Summary: "Pending auto-reveal no longer applies", Payload: models.EmptyPayload{}},
+	{Type: models.MsgTypeRoomExpired, Summary: "The room expired and rejects actions", Payload: models.RoomExpiredPayload{}},
+	{Type: models.MsgTypeAck, Summary: "A command of this client succeeded", Payload: models.AckPayload{}},
+	{Type: models.MsgTypeError, Summary: "A command of this client failed, with a stable error code", Payload: models.ErrorPayload{}},
+}
+
+// DocsHandlers serves the generated API documents
+type DocsHandlers struct {
+	openAPI  map[string]any
+	asyncAPI map[string]any
+}
+
+// NewDocsHandlers generates the OpenAPI document for routes and the AsyncAPI document for the WebSocket protocol
+func NewDocsHandlers(version string, routes []Route) *DocsHandlers {
+	operations := make([]apidocs.Operation, 0, len(routes))
+	for _, route := range routes {
+		operations = append(operations, route.Operation)
+	}
+
+	return &DocsHandlers{
+		openAPI: apidocs.OpenAPI(apidocs.Info{
+			Title:       "Planning Poker REST API",
+			Version:     version,
+			Description: "Commands authenticate with the " + ParticipantTokenHeader + " header returned when joining a room.",
+		}, operations),
+		asyncAPI: apidocs.AsyncAPI(apidocs.Info{
+			Title:       "Planning Poker WebSocket API",
+			Version:     version,
+			Description: "Messages are JSON objects of the form {\"type\": ..., \"payload\": {...}}. Every command is answered with an ack or an error echoing its optional requestId. Room broadcasts carry a seq; reconnect with ?eventLog=&lastSeq= to receive only the events missed. The same messages stream as Server-Sent Events from /sse/{roomId}, with commands POSTed to /sse/{roomId}/{streamId}.",
+		}, "/ws/{roomId}", wsClientMessages, wsServerMessages),
+	}
+}
+
+// OpenAPI handles GET /monitoring/openapi.json
+func (h *DocsHandlers) OpenAPI(re *core.RequestEvent) error {
+	return re.JSON(http.StatusOK, h.openAPI)
+}
+
+// AsyncAPI handles GET /monitoring/asyncapi.json
+func (h *DocsHandlers) AsyncAPI(re *core.RequestEvent) error {
+	return re.JSON(http.StatusOK, h.asyncAPI)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import "fmt"
+
+// Stable error codes for rejected room commands, returned by the REST API and in
+// WebSocket "error" replies. Messages dropped by the connection rate limit are
+// answered with models.ErrCodeRateLimited, and those dropped on a full room queue
+// with models.ErrCodeRoomBusy.
+const (
+	ErrCodeInvalidPayload = "invalid_payload"
+	ErrCodeUnauthorized   = "unauthorized"
+	ErrCodeForbidden      = "forbidden"
+	ErrCodeNotFound       = "not_found"
+	ErrCodeInvalidState   = "invalid_state"
+	ErrCodeRoomExpired    = "room_expired"
+	ErrCodeInternal       = "internal_error"
+	ErrCodeTrackerError   = "tracker_error" // The issue tracker rejected or failed a request
+)
+
+// CommandError is returned when a room command (vote, reveal, next round, ...) is rejected.
+// Code is stable and safe to expose to clients; Message is human readable.
+type CommandError struct {
+	Code    string
+	Message string
+}
+
+func (e *CommandError) Error() string {
+	return e.Message
+}
+
+// newCommandError creates a CommandError with a formatted message
+func newCommandError(code, format string, args ...any) *CommandError {
+	return &CommandError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
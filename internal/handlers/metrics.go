@@ -1,12 +1,42 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"net/http"
 
 	"github.com/damione1/planning-poker/internal/services"
 	"github.com/pocketbase/pocketbase/core"
 )
 
+// prometheusContentType is the media type of the Prometheus text exposition format
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// healthResponse is the body of /monitoring/health
+type healthResponse struct {
+	Status            string `json:"status"`
+	ActiveConnections int64  `json:"active_connections"`
+	ActiveRooms       int64  `json:"active_rooms"`
+	UptimeSeconds     int64  `json:"uptime_seconds"`
+}
+
+// livenessResponse is the body of /monitoring/health/live
+type livenessResponse struct {
+	Status        string `json:"status"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+}
+
+// configResponse is the body of /monitoring/config
+type configResponse struct {
+	Settings map[string]string `json:"settings"` // Setting name -> value in effect, secrets redacted
+}
+
+// readinessResponse is the body of /monitoring/health/ready
+type readinessResponse struct {
+	Status string            `json:"status"` // ready or not_ready
+	Checks map[string]string `json:"checks"` // Check name -> ok, or why it failed
+}
+
 // HandleMetrics returns WebSocket server metrics
 func HandleMetrics(hub *services.Hub) func(*core.RequestEvent) error {
 	return func(e *core.RequestEvent) error {
@@ -15,6 +45,17 @@ func HandleMetrics(hub *services.Hub) func(*core.RequestEvent) error {
 	}
 }
 
+// HandlePrometheusMetrics returns the metrics in the Prometheus text exposition format
+func HandlePrometheusMetrics(hub *services.Hub) func(*core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		var body bytes.Buffer
+		if err := hub.Metrics().WritePrometheus(&body); err != nil {
+			return e.InternalServerError("Failed to write metrics", err)
+		}
+		return e.Blob(http.StatusOK, prometheusContentType, body.Bytes())
+	}
+}
+
 // HandleHealth returns server health status
 func HandleHealth(hub *services.Hub) func(*core.RequestEvent) error {
 	return func(e *core.RequestEvent) error {
@@ -29,13 +70,64 @@ func HandleHealth(hub *services.Hub) func(*core.RequestEvent) error {
 			status = http.StatusOK
 		}
 
-		response := map[string]interface{}{
-			"status":             snapshot.HealthStatus,
-			"active_connections": snapshot.ActiveConnections,
-			"active_rooms":       snapshot.ActiveRooms,
-			"uptime_seconds":     snapshot.UptimeSeconds,
+		response := healthResponse{
+			Status:            snapshot.HealthStatus,
+			ActiveConnections: snapshot.ActiveConnections,
+			ActiveRooms:       snapshot.ActiveRooms,
+			UptimeSeconds:     snapshot.UptimeSeconds,
 		}
 
 		return e.JSON(status, response)
 	}
 }
+
+// HandleLiveness reports that the process is up and serving requests
+func HandleLiveness(hub *services.Hub) func(*core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		return e.JSON(http.StatusOK, livenessResponse{
+			Status:        "alive",
+			UptimeSeconds: hub.GetMetrics().UptimeSeconds,
+		})
+	}
+}
+
+// HandleReadiness reports whether the instance can take traffic: the database answers,
+// the hub loop is responsive and the instance is not at capacity
+func HandleReadiness(app core.App, hub *services.Hub) func(*core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		checks := map[string]string{
+			"database": "ok",
+			"hub":      "ok",
+			"capacity": "ok",
+		}
+
+		timeout := hub.Config().Health.ReadinessTimeout
+		ctx, cancel := context.WithTimeout(e.Request.Context(), timeout)
+		defer cancel()
+		if _, err := app.DB().NewQuery("SELECT 1").WithContext(ctx).Execute(); err != nil {
+			checks["database"] = err.Error()
+		}
+		if err := hub.Ping(timeout); err != nil {
+			checks["hub"] = err.Error()
+		}
+		if status := hub.GetMetrics().HealthStatus; status == "critical" {
+			checks["capacity"] = status
+		}
+
+		response := readinessResponse{Status: "ready", Checks: checks}
+		for _, result := range checks {
+			if result != "ok" {
+				response.Status = "not_ready"
+				return e.JSON(http.StatusServiceUnavailable, response)
+			}
+		}
+		return e.JSON(http.StatusOK, response)
+	}
+}
+
+// HandleConfig returns the runtime configuration in effect, without secrets
+func HandleConfig(hub *services.Hub) func(*core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		return e.JSON(http.StatusOK, configResponse{Settings: hub.Config().Effective()})
+	}
+}
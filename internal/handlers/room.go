@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,6 +23,7 @@ type RoomHandlers struct {
 	roomManager   *services.RoomManager
 	hub           *services.Hub
 	voteValidator *services.VoteValidator
+	webhooks      *services.WebhookService
 }
 
 func NewRoomHandlers(rm *services.RoomManager, hub *services.Hub) *RoomHandlers {
@@ -32,6 +34,11 @@ func NewRoomHandlers(rm *services.RoomManager, hub *services.Hub) *RoomHandlers
 	}
 }
 
+// SetWebhookService enables participant_joined webhooks for joins through the page
+func (h *RoomHandlers) SetWebhookService(webhooks *services.WebhookService) {
+	h.webhooks = webhooks
+}
+
 func (h *RoomHandlers) CreateRoom(re *core.RequestEvent) error {
 	name := re.Request.FormValue("name")
 	pointingMethod := re.Request.FormValue("pointingMethod")
@@ -82,6 +89,38 @@ func (h *RoomHandlers) CreateRoom(re *core.RequestEvent) error {
 	config.Permissions.AllowChangeVoteAfterReveal = re.Request.FormValue("allow_change_vote_after_reveal") == "on"
 	config.Permissions.AutoReveal = re.Request.FormValue("auto_reveal") == "on"
 
+	// Parse auto-reveal rule (only meaningful with auto-reveal enabled)
+	config.RevealRule = models.RevealRule{Mode: re.Request.FormValue("reveal_mode")}
+	switch config.RevealRule.Mode {
+	case models.RevealModeQuorum:
+		config.RevealRule.Percentage, _ = strconv.Atoi(re.Request.FormValue("reveal_percentage"))
+	case models.RevealModeMinimum:
+		config.RevealRule.MinimumVotes, _ = strconv.Atoi(re.Request.FormValue("reveal_minimum_votes"))
+	}
+	if err := security.ValidateRevealRule(config.RevealRule); err != nil {
+		component := templates.ErrorDisplay(fmt.Sprintf("Invalid reveal rule: %s", err.Error()))
+		re.Response.WriteHeader(http.StatusBadRequest)
+		return templates.Render(re.Response, re.Request, component)
+	}
+
+	// Parse optional estimation dimensions (one "Name: values" per line)
+	if dimensionsRaw := strings.TrimSpace(re.Request.FormValue("dimensions")); dimensionsRaw != "" {
+		dimensions, err := h.voteValidator.ParseDimensions(dimensionsRaw)
+		if err != nil {
+			component := templates.ErrorDisplay(fmt.Sprintf("Invalid dimensions: %s", err.Error()))
+			re.Response.WriteHeader(http.StatusBadRequest)
+			return templates.Render(re.Response, re.Request, component)
+		}
+		combineMethod := re.Request.FormValue("combine_method")
+		if err := h.voteValidator.ValidateCombineMethod(combineMethod); err != nil {
+			component := templates.ErrorDisplay(err.Error())
+			re.Response.WriteHeader(http.StatusBadRequest)
+			return templates.Render(re.Response, re.Request, component)
+		}
+		config.Dimensions = dimensions
+		config.CombineMethod = combineMethod
+	}
+
 	// Create room in database with config
 	roomRecord, err := h.roomManager.CreateRoom(name, pointingMethod, customValues, config)
 	if err != nil {
@@ -143,6 +182,10 @@ func (h *RoomHandlers) RoomView(re *core.RequestEvent) error {
 	voteRecords, _ := h.roomManager.GetRoomVotes(roomID)
 	for _, vr := range voteRecords {
 		room.Votes[vr.GetString("participant_id")] = vr.GetString("value")
+		room.Confidences[vr.GetString("participant_id")] = vr.GetInt("confidence")
+		if dimensionValues := h.roomManager.GetVoteDimensionValues(vr); dimensionValues != nil {
+			room.DimensionVotes[vr.GetString("participant_id")] = dimensionValues
+		}
 	}
 
 	component := templates.Room(room, participant, isCreator)
@@ -191,6 +234,10 @@ func (h *RoomHandlers) ParticipantGridFragment(re *core.RequestEvent) error {
 	voteRecords, _ := h.roomManager.GetRoomVotes(roomID)
 	for _, vr := range voteRecords {
 		room.Votes[vr.GetString("participant_id")] = vr.GetString("value")
+		room.Confidences[vr.GetString("participant_id")] = vr.GetInt("confidence")
+		if dimensionValues := h.roomManager.GetVoteDimensionValues(vr); dimensionValues != nil {
+			room.DimensionVotes[vr.GetString("participant_id")] = dimensionValues
+		}
 	}
 
 	// Return both participant grid and statistics fragments
@@ -200,7 +247,9 @@ func (h *RoomHandlers) ParticipantGridFragment(re *core.RequestEvent) error {
 	// Calculate statistics if in revealed state
 	var stats map[string]interface{}
 	if room.State == models.StateRevealed {
-		stats = calculateStats(room.Votes)
+		stats = calculateWeightedStats(room.Votes, participantWeights(room.Participants))
+		addConfidenceStats(stats, room.Confidences)
+		addDimensionStats(stats, room)
 	}
 	currentRound, _ := h.roomManager.GetCurrentRound(roomID)
 	statistics := templates.Statistics(room.State, stats, currentRound, room.ConsecutiveConsensusRounds)
@@ -212,35 +261,52 @@ func (h *RoomHandlers) ParticipantGridFragment(re *core.RequestEvent) error {
 
 // calculateStats computes vote statistics (supports float values)
 func calculateStats(votes map[string]string) map[string]interface{} {
+	return calculateWeightedStats(votes, nil)
+}
+
+// calculateWeightedStats computes vote statistics where each participant's vote counts
+// by its weight (participantID -> weight). Participants missing from weights count as 1.
+func calculateWeightedStats(votes map[string]string, weights map[string]float64) map[string]interface{} {
 	if len(votes) == 0 {
 		return nil
 	}
 
 	stats := make(map[string]interface{})
 	valueBreakdown := make(map[string]int)
-	var sum float64
-	var count int
+	weightBreakdown := make(map[string]float64)
+	var sum, numericWeight, totalWeight float64
+	weighted := false
 
 	// Use validator for consistent numeric parsing
 	validator := services.NewVoteValidator()
 
-	// Track most common value for agreement percentage
+	// Track most common value (by weight) for agreement percentage
 	var mostCommonValue string
-	var mostCommonCount int
+	var mostCommonWeight float64
+
+	for participantID, vote := range votes {
+		weight := models.DefaultParticipantWeight
+		if w, ok := weights[participantID]; ok && w > 0 {
+			weight = w
+		}
+		if weight != models.DefaultParticipantWeight {
+			weighted = true
+		}
 
-	for _, vote := range votes {
 		valueBreakdown[vote]++
+		weightBreakdown[vote] += weight
+		totalWeight += weight
 
 		// Track most common value
-		if valueBreakdown[vote] > mostCommonCount {
-			mostCommonCount = valueBreakdown[vote]
+		if weightBreakdown[vote] > mostCommonWeight {
+			mostCommonWeight = weightBreakdown[vote]
 			mostCommonValue = vote
 		}
 
 		// Try to parse as number for average (supports floats)
 		if num, ok := validator.ParseNumericValue(vote); ok {
-			sum += num
-			count++
+			sum += num * weight
+			numericWeight += weight
 		}
 	}
 
@@ -248,8 +314,8 @@ func calculateStats(votes map[string]string) map[string]interface{} {
 	stats["valueBreakdown"] = valueBreakdown
 
 	// Calculate agreement percentage
-	if len(votes) > 0 && mostCommonCount > 0 {
-		agreementPercentage := (float64(mostCommonCount) / float64(len(votes))) * 100
+	if totalWeight > 0 && mostCommonWeight > 0 {
+		agreementPercentage := (mostCommonWeight / totalWeight) * 100
 		stats["agreementPercentage"] = agreementPercentage
 		stats["mostCommonValue"] = mostCommonValue
 
@@ -257,18 +323,66 @@ func calculateStats(votes map[string]string) map[string]interface{} {
 		stats["consensus"] = agreementPercentage == 100.0
 	}
 
-	if count > 0 {
-		stats["average"] = sum / float64(count)
+	if numericWeight > 0 {
+		stats["average"] = sum / numericWeight
+	}
+
+	if weighted {
+		stats["weighted"] = true
+		stats["totalWeight"] = totalWeight
 	}
 
 	return stats
 }
 
+// addConfidenceStats adds the aggregated vote confidence to stats computed by calculateStats
+// Flags low-confidence consensus: everyone agreed, but the team is not sure about it
+func addConfidenceStats(stats map[string]interface{}, confidences map[string]int) {
+	if stats == nil {
+		return
+	}
+
+	levels := make([]int, 0, len(confidences))
+	for _, level := range confidences {
+		levels = append(levels, level)
+	}
+
+	summary := models.SummarizeConfidence(levels)
+	if summary == nil {
+		return
+	}
+
+	stats["confidence"] = summary
+	consensus, _ := stats["consensus"].(bool)
+	stats["lowConfidenceConsensus"] = consensus && summary.Low
+}
+
+// addDimensionStats adds per-dimension statistics for multi-dimensional rooms
+func addDimensionStats(stats map[string]interface{}, room *models.Room) {
+	if stats == nil || !room.Config.IsMultiDimensional() {
+		return
+	}
+
+	dimensionVotes := make([]map[string]string, 0, len(room.DimensionVotes))
+	for _, values := range room.DimensionVotes {
+		dimensionVotes = append(dimensionVotes, values)
+	}
+
+	validator := services.NewVoteValidator()
+	stats["dimensions"] = validator.CalculateDimensionStats(dimensionVotes, room.Config.Dimensions)
+	stats["combineMethod"] = room.Config.GetCombineMethod()
+}
+
 // CalculateStatsForTest is a test helper that exposes calculateStats for testing
 func CalculateStatsForTest(votes map[string]string) map[string]interface{} {
 	return calculateStats(votes)
 }
 
+// CalculateWeightedStatsForTest is a test helper that exposes calculateWeightedStats for testing
+func CalculateWeightedStatsForTest(votes map[string]string, weights map[string]float64) map[string]interface{} {
+	return calculateWeightedStats(votes, weights)
+}
+
 func (h *RoomHandlers) JoinRoom(re *core.RequestEvent) error {
 	roomID := re.Request.PathValue("id")
 	name := re.Request.FormValue("name")
@@ -322,12 +436,14 @@ func (h *RoomHandlers) JoinRoom(re *core.RequestEvent) error {
 	participant := recordToParticipant(participantRecord)
 
 	// Broadcast participant joined event
-	h.hub.BroadcastToRoom(roomID, &models.WSMessage{
-		Type: models.MsgTypeParticipantJoined,
-		Payload: map[string]interface{}{
-			"participant": participant,
-		},
+	joined := models.ParticipantJoinedPayload{Participant: participant}
+	h.hub.BroadcastToRoom(re.Request.Context(), roomID, &models.WSMessage{
+		Type:    models.MsgTypeParticipantJoined,
+		Payload: joined,
 	})
+	if h.webhooks != nil {
+		h.webhooks.Dispatch(roomID, models.WebhookEventParticipantJoined, joined)
+	}
 
 	// Redirect back to room page - will reload with participant context
 	re.Response.Header().Set("HX-Redirect", "/room/"+roomID)
@@ -373,11 +489,13 @@ func recordToRoom(record *core.Record) *models.Room {
 		PointingMethod:             record.GetString("pointing_method"),
 		ConsecutiveConsensusRounds: record.GetInt("consecutive_consensus_rounds"),
 		// State will be derived from CurrentRound after it's populated
-		Participants: make(map[string]*models.Participant),
-		Votes:        make(map[string]string),
-		CreatedAt:    record.GetDateTime("created").Time(),
-		LastActivity: record.GetDateTime("last_activity").Time(),
-		ExpiresAt:    record.GetDateTime("expires_at").Time(),
+		Participants:   make(map[string]*models.Participant),
+		Votes:          make(map[string]string),
+		Confidences:    make(map[string]int),
+		DimensionVotes: make(map[string]map[string]string),
+		CreatedAt:      record.GetDateTime("created").Time(),
+		LastActivity:   record.GetDateTime("last_activity").Time(),
+		ExpiresAt:      record.GetDateTime("expires_at").Time(),
 	}
 
 	// Parse custom values if present
@@ -431,11 +549,21 @@ func recordToParticipant(record *core.Record) *models.Participant {
 		ID:        record.Id,
 		Name:      record.GetString("name"),
 		Role:      models.ParticipantRole(record.GetString("role")),
+		Weight:    services.ParticipantWeight(record),
 		Connected: record.GetBool("connected"),
 		JoinedAt:  record.GetDateTime("joined_at").Time(),
 	}
 }
 
+// participantWeights extracts participant vote weights for weighted statistics
+func participantWeights(participants map[string]*models.Participant) map[string]float64 {
+	weights := make(map[string]float64, len(participants))
+	for id, p := range participants {
+		weights[id] = p.Weight
+	}
+	return weights
+}
+
 // QRCodeHandler generates a QR code for the room URL
 func (h *RoomHandlers) QRCodeHandler(re *core.RequestEvent) error {
 	roomID := re.Request.PathValue("id")
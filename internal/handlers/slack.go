@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/damione1/planning-poker/internal/logging"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/security"
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+const (
+	defaultSlackRoomName = "Planning Poker"
+	maxSlackCommandBody  = 16 * 1024
+	slackUsage           = "Usage: `/poker [deck] [room name]` where deck is `fibonacci`, `modified-fibonacci`, `t-shirt` or comma-separated values like `1,2,3,5,8`."
+)
+
+// SlackHandlers serves the Slack-compatible slash command endpoint
+type SlackHandlers struct {
+	roomManager   *services.RoomManager
+	slack         *services.SlackService
+	voteValidator *services.VoteValidator
+	signingSecret string
+	baseURL       string // Public URL used in links, derived from the request when empty
+}
+
+func NewSlackHandlers(rm *services.RoomManager, slack *services.SlackService, signingSecret, baseURL string) *SlackHandlers {
+	return &SlackHandlers{
+		roomManager:   rm,
+		slack:         slack,
+		voteValidator: services.NewVoteValidator(),
+		signingSecret: signingSecret,
+		baseURL:       strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// slackDeck is the voting deck parsed from the command text
+type slackDeck struct {
+	pointingMethod string
+	values         []string
+}
+
+// HandleCommand handles POST /integrations/slack/command
+// Creates a room from "/poker [deck] [room name]" and replies with the room link and QR code
+func (h *SlackHandlers) HandleCommand(re *core.RequestEvent) error {
+	body, err := io.ReadAll(http.MaxBytesReader(re.Response, re.Request.Body, maxSlackCommandBody))
+	if err != nil {
+		return re.String(http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := services.VerifySlackSignature(
+		h.signingSecret,
+		re.Request.Header.Get(services.SlackTimestampHeader),
+		re.Request.Header.Get(services.SlackSignatureHeader),
+		body,
+		time.Now(),
+	); err != nil {
+		slog.Warn("Slack command rejected", logging.Err(err))
+		return re.String(http.StatusUnauthorized, "Invalid signature")
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return re.String(http.StatusBadRequest, "Invalid form body")
+	}
+
+	text := strings.TrimSpace(form.Get("text"))
+	if strings.EqualFold(text, "help") {
+		return re.JSON(http.StatusOK, ephemeralSlackMessage(slackUsage))
+	}
+
+	deck, name, err := h.parseCommandText(text)
+	if err != nil {
+		return re.JSON(http.StatusOK, ephemeralSlackMessage(err.Error()+"\n"+slackUsage))
+	}
+
+	roomRecord, err := h.roomManager.CreateRoom(name, deck.pointingMethod, deck.values, models.DefaultRoomConfig())
+	if err != nil {
+		slog.Error("Failed to create room from Slack command", logging.Err(err))
+		return re.JSON(http.StatusOK, ephemeralSlackMessage("Failed to create room. Please try again."))
+	}
+
+	// Reveal summaries are posted back to the channel through the response_url
+	if responseURL := form.Get("response_url"); responseURL != "" {
+		if _, err := security.ValidateWebhookURL(responseURL); err != nil {
+			logging.ForRoom(roomRecord.Id, "").Warn("Ignoring invalid Slack response_url", logging.Err(err))
+		} else if err := h.slack.RememberResponseURL(roomRecord.Id, responseURL); err != nil {
+			logging.ForRoom(roomRecord.Id, "").Error("Failed to store Slack response_url", logging.Err(err))
+		}
+	}
+
+	baseURL := h.publicBaseURL(re.Request)
+	roomURL := fmt.Sprintf("%s/room/%s", baseURL, roomRecord.Id)
+	qrURL := roomURL + "/qr"
+
+	text = fmt.Sprintf("Planning poker room *%s* is ready (%s): <%s|Join the room>",
+		name, strings.Join(deck.values, ", "), roomURL)
+	if user := form.Get("user_name"); user != "" {
+		text = fmt.Sprintf("%s\nStarted by %s", text, user)
+	}
+
+	return re.JSON(http.StatusOK, services.SlackMessage{
+		ResponseType: "in_channel",
+		Text:         text,
+		Blocks: []services.SlackBlock{
+			services.SlackSection(text),
+			{Type: "image", ImageURL: qrURL, AltText: "QR code to join " + name},
+		},
+	})
+}
+
+// parseCommandText reads an optional deck followed by an optional room name
+// Without a recognized deck the whole text is the room name and the modified Fibonacci deck is used
+func (h *SlackHandlers) parseCommandText(text string) (*slackDeck, string, error) {
+	deckToken, rest, _ := strings.Cut(text, " ")
+
+	deck, err := h.parseDeck(deckToken)
+	if err != nil {
+		return nil, "", err
+	}
+	if deck == nil {
+		deck = &slackDeck{pointingMethod: "custom", values: h.voteValidator.GetModifiedFibonacciValues()}
+		rest = text
+	}
+
+	name := strings.TrimSpace(rest)
+	if name == "" {
+		name = defaultSlackRoomName
+	}
+	name, err = security.ValidateRoomName(name)
+	if err != nil {
+		return nil, "", fmt.Errorf("Invalid room name: %v", err)
+	}
+
+	return deck, name, nil
+}
+
+// parseDeck returns nil when the token is not a deck
+func (h *SlackHandlers) parseDeck(token string) (*slackDeck, error) {
+	switch strings.ToLower(token) {
+	case services.TemplateFibonacci:
+		return &slackDeck{pointingMethod: "fibonacci", values: h.voteValidator.GetFibonacciValues()}, nil
+	case services.TemplateModifiedFibonacci:
+		return &slackDeck{pointingMethod: "custom", values: h.voteValidator.GetModifiedFibonacciValues()}, nil
+	case services.TemplateTShirt, "tshirt":
+		return &slackDeck{pointingMethod: "custom", values: h.voteValidator.GetTShirtValues()}, nil
+	}
+
+	if !strings.Contains(token, ",") {
+		return nil, nil
+	}
+
+	values, err := h.voteValidator.ParseCustomValues(token)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid deck: %v", err)
+	}
+	return &slackDeck{pointingMethod: "custom", values: values}, nil
+}
+
+// publicBaseURL returns the configured public URL or the one the request was made to
+func (h *SlackHandlers) publicBaseURL(r *http.Request) string {
+	if h.baseURL != "" {
+		return h.baseURL
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
+
+func ephemeralSlackMessage(text string) services.SlackMessage {
+	return services.SlackMessage{ResponseType: "ephemeral", Text: text}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/damione1/planning-poker/internal/logging"
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+// maxSSECommandSize bounds the body of a command posted to an event stream
+const maxSSECommandSize = 32 << 10
+
+// SSEStreamEvent names the first event of a stream, carrying an SSEStreamPayload
+const SSEStreamEvent = "stream"
+
+// SSEStreamPayload tells a client where to POST its commands
+type SSEStreamPayload struct {
+	StreamID string `json:"streamId"`
+}
+
+// sseStream is an open event stream, found by the commands posted to it
+type sseStream struct {
+	transport     *services.SSETransport
+	roomID        string
+	participantID string
+}
+
+// HandleSSE handles GET /sse/{roomId}: the room's messages as Server-Sent Events, for
+// clients that can't upgrade to WebSocket. The stream starts with a "stream" event whose
+// streamId names the command endpoint; otherwise it carries the WebSocket messages.
+func (h *WSHandler) HandleSSE(re *core.RequestEvent) error {
+	roomID, participantID, status, rejection := h.checkConnection(re)
+	if status != 0 {
+		return re.JSON(status, map[string]string{"error": rejection})
+	}
+
+	transport, err := services.NewSSETransport(re.Response, re.Request)
+	if err != nil {
+		logging.ForRoom(roomID, participantID).Warn("Event stream failed", logging.Err(err))
+		return err
+	}
+
+	// The unguessable stream ID only reaches this client, so it also guards commands against CSRF
+	streamID := uuid.NewString()
+	payload, _ := json.Marshal(SSEStreamPayload{StreamID: streamID})
+	if err := transport.WriteEvent(re.Request.Context(), SSEStreamEvent, payload); err != nil {
+		return err
+	}
+	h.streams.Store(streamID, &sseStream{transport: transport, roomID: roomID, participantID: participantID})
+	defer h.streams.Delete(streamID)
+
+	h.serveClient(re, services.NewClient(h.hub.Config(), transport, h.hub, roomID, participantID), roomID, participantID)
+	return nil
+}
+
+// HandleSSECommand handles POST /sse/{roomId}/{streamId}: one client message, as sent over
+// WebSocket, for the client of the stream. The reply arrives on the stream as "ack" or "error".
+func (h *WSHandler) HandleSSECommand(re *core.RequestEvent) error {
+	roomID := re.Request.PathValue("roomId")
+	value, ok := h.streams.Load(re.Request.PathValue("streamId"))
+	if !ok || value.(*sseStream).roomID != roomID {
+		return re.JSON(http.StatusNotFound, map[string]string{"error": "Event stream not found"})
+	}
+	stream := value.(*sseStream)
+
+	// Commands run as the stream's participant, so they must come from the same session
+	if h.sessionParticipant(re.Request, roomID) != stream.participantID {
+		return re.JSON(http.StatusForbidden, map[string]string{"error": "Event stream belongs to another session"})
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(re.Request.Header.Get("Content-Type")); mediaType != "application/json" {
+		return re.JSON(http.StatusUnsupportedMediaType, map[string]string{"error": "Commands must be application/json"})
+	}
+	message, err := io.ReadAll(http.MaxBytesReader(re.Response, re.Request.Body, maxSSECommandSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return re.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "Command too large"})
+		}
+		return re.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to read command"})
+	}
+
+	if err := stream.transport.Deliver(re.Request.Context(), message); err != nil {
+		if errors.Is(err, services.ErrStreamClosed) {
+			return re.JSON(http.StatusNotFound, map[string]string{"error": "Event stream not found"})
+		}
+		return err
+	}
+	return re.NoContent(http.StatusAccepted)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/damione1/planning-poker/internal/tracing"
+)
+
+// TraceRequests is a router middleware starting a server span per HTTP request, continuing
+// the trace of an incoming traceparent header. Handlers find the span in the request context.
+func TraceRequests(e *core.RequestEvent) error {
+	ctx := otel.GetTextMapPropagator().Extract(e.Request.Context(), propagation.HeaderCarrier(e.Request.Header))
+
+	// The route pattern (e.g. "GET /api/v1/rooms/{id}") keeps span names low-cardinality
+	name := e.Request.Pattern
+	if name == "" {
+		name = e.Request.Method
+	}
+	ctx, span := tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", e.Request.Method),
+			attribute.String("http.route", e.Request.Pattern),
+			attribute.String("url.path", e.Request.URL.Path),
+		),
+	)
+	defer span.End()
+
+	e.Request = e.Request.WithContext(ctx)
+	err := e.Next()
+
+	// Errors are written by the router after the middleware returns
+	status := e.Status()
+	var apiErr *router.ApiError
+	if errors.As(err, &apiErr) {
+		status = apiErr.Status
+	} else if err != nil && status == 0 {
+		status = http.StatusInternalServerError
+	}
+	if status != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+	}
+	// Client errors are the caller's: only server errors fail the span
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
@@ -1,16 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coder/websocket"
+	"github.com/damione1/planning-poker/internal/logging"
 	"github.com/damione1/planning-poker/internal/models"
 	"github.com/damione1/planning-poker/internal/security"
 	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/internal/tracing"
 	"github.com/pocketbase/pocketbase/core"
 )
 
@@ -19,6 +26,10 @@ type WSHandler struct {
 	roomManager     *services.RoomManager
 	aclService      *services.ACLService
 	originValidator *security.OriginValidator
+	webhooks        *services.WebhookService
+	slack           *services.SlackService
+	stories         *services.StoryService
+	streams         sync.Map // Open event streams: stream ID -> *sseStream, see sse.go
 }
 
 func NewWSHandler(hub *services.Hub, rm *services.RoomManager, acl *services.ACLService) *WSHandler {
@@ -39,6 +50,28 @@ func NewWSHandler(hub *services.Hub, rm *services.RoomManager, acl *services.ACL
 	return handler
 }
 
+// SetWebhookService enables outgoing webhooks for room events
+func (h *WSHandler) SetWebhookService(webhooks *services.WebhookService) {
+	h.webhooks = webhooks
+}
+
+// notifyWebhooks queues a room event for the room's webhooks, if enabled
+func (h *WSHandler) notifyWebhooks(roomID, event string, data any) {
+	if h.webhooks != nil {
+		h.webhooks.Dispatch(roomID, event, data)
+	}
+}
+
+// SetSlackService enables posting reveal summaries to rooms created from Slack
+func (h *WSHandler) SetSlackService(slack *services.SlackService) {
+	h.slack = slack
+}
+
+// SetStoryService enables issue tracker stories, estimated by completed rounds
+func (h *WSHandler) SetStoryService(stories *services.StoryService) {
+	h.stories = stories
+}
+
 // getWebSocketOrigins returns allowed WebSocket origins from environment
 func getWebSocketOrigins() []string {
 	// Check for environment variable
@@ -55,100 +88,140 @@ func getWebSocketOrigins() []string {
 
 // HandleWebSocket is the optimized WebSocket handler using Client architecture
 func (h *WSHandler) HandleWebSocket(re *core.RequestEvent) error {
-	roomID := re.Request.PathValue("roomId")
+	roomID, participantID, status, rejection := h.checkConnection(re)
+	if status != 0 {
+		return re.JSON(status, map[string]string{"error": rejection})
+	}
+
+	// Upgrade to WebSocket with origin validation
+	conn, err := websocket.Accept(re.Response, re.Request, h.originValidator.GetAcceptOptions())
+	if err != nil {
+		logging.ForRoom(roomID, participantID).Warn("WebSocket upgrade failed", logging.Err(err))
+		return err
+	}
+
+	transport := services.NewWebSocketTransport(conn, h.hub.Config().Limits.PongTimeout)
+	h.serveClient(re, services.NewClient(h.hub.Config(), transport, h.hub, roomID, participantID), roomID, participantID)
+	return nil
+}
+
+// checkConnection validates a connection request to a room and identifies its participant
+// from the session cookie. Returns the status and message of the rejection, status 0 when
+// the connection can be accepted.
+func (h *WSHandler) checkConnection(re *core.RequestEvent) (roomID, participantID string, status int, rejection string) {
+	roomID = re.Request.PathValue("roomId")
 
 	// Validate room ID
 	if err := security.ValidateUUID(roomID); err != nil {
-		return re.JSON(400, map[string]string{"error": "Invalid room ID"})
+		return "", "", 400, "Invalid room ID"
 	}
 
 	// Verify room exists
 	_, err := h.roomManager.GetRoom(roomID)
 	if err != nil {
-		return re.JSON(404, map[string]string{"error": "Room not found"})
+		return "", "", 404, "Room not found"
 	}
 
 	// Check if hub can accept new connection
 	if err := h.hub.CanRegister(roomID); err != nil {
-		log.Printf("Connection rejected: %v", err)
+		logging.ForRoom(roomID, "").Warn("Connection rejected", logging.Err(err))
 		if err == services.ErrServerAtCapacity {
-			return re.JSON(503, map[string]string{"error": "Server at capacity. Please try again later."})
+			return "", "", 503, "Server at capacity. Please try again later."
 		}
 		if err == services.ErrRoomFull {
-			return re.JSON(429, map[string]string{"error": "Room is full. Maximum participants reached."})
+			return "", "", 429, "Room is full. Maximum participants reached."
 		}
-		return re.JSON(500, map[string]string{"error": "Unable to accept connection"})
+		return "", "", 500, "Unable to accept connection"
 	}
 
-	// Get participant from session cookie
-	sessionCookie := getParticipantID(re.Request)
-	var participantID string
-	if sessionCookie != "" {
-		participantRecord, err := h.roomManager.GetParticipantBySession(roomID, sessionCookie)
-		if err == nil {
-			participantID = participantRecord.Id
-		}
-	}
+	return roomID, h.sessionParticipant(re.Request, roomID), 0, ""
+}
 
-	// Upgrade to WebSocket with origin validation
-	conn, err := websocket.Accept(re.Response, re.Request, h.originValidator.GetAcceptOptions())
+// sessionParticipant returns the ID of the room participant of the request's session cookie,
+// empty when there is none
+func (h *WSHandler) sessionParticipant(r *http.Request, roomID string) string {
+	sessionCookie := getParticipantID(r)
+	if sessionCookie == "" {
+		return ""
+	}
+	participantRecord, err := h.roomManager.GetParticipantBySession(roomID, sessionCookie)
 	if err != nil {
-		log.Printf("WebSocket upgrade failed: %v", err)
-		return err
+		return ""
 	}
+	return participantRecord.Id
+}
 
-	// Create client instance
-	client := services.NewClient(conn, h.hub, roomID, participantID)
+// serveClient registers an accepted connection with the hub, brings it up to date with the
+// room and runs it until it closes
+func (h *WSHandler) serveClient(re *core.RequestEvent, client *services.Client, roomID, participantID string) {
+	// The client's messages are traced as links of this request
+	ctx := re.Request.Context()
+	client.SetTraceContext(ctx)
 
-	// Update participant connection status to connected
+	// Update participant connection status to connected with their first connection,
+	// a participant stays connected while any of their tabs or devices is
+	var firstConnection bool
 	if participantID != "" {
-		_ = h.roomManager.UpdateParticipantConnection(participantID, true) // Best effort
+		firstConnection = h.hub.OpenParticipantConnection(roomID, participantID, func() {
+			_ = h.roomManager.UpdateParticipantConnection(participantID, true) // Best effort
+		})
 	}
 
 	// Set up cleanup on disconnect
 	defer func() {
-		// Update participant connection status to disconnected
-		if participantID != "" {
+		if participantID == "" {
+			return
+		}
+
+		// Update participant connection status to disconnected with their last connection
+		left := h.hub.CloseParticipantConnection(roomID, participantID, func() {
 			_ = h.roomManager.UpdateParticipantConnection(participantID, false) // Best effort
 
-			// Broadcast participant left event
-			h.hub.BroadcastToRoom(roomID, &models.WSMessage{
-				Type: models.MsgTypeParticipantLeft,
-				Payload: map[string]any{
-					"participantId": participantID,
-				},
+			// Broadcast participant left event, before a later connection announces them again
+			h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
+				Type:    models.MsgTypeParticipantLeft,
+				Payload: models.ParticipantLeftPayload{ParticipantID: participantID},
 			})
+		})
+
+		// A disconnect can complete the round for connection-based reveal rules
+		if left {
+			if config, err := h.aclService.GetRoomConfig(roomID); err == nil {
+				h.evaluateAutoReveal(ctx, roomID, config)
+			}
 		}
 	}()
 
-	// Register client with hub (this queues the registration)
-	h.hub.Register(roomID, client)
+	// Register client with hub. A reconnecting client is sent the events it missed,
+	// or a snapshot below when they are no longer available.
+	resumed := h.hub.Resume(roomID, client, resumePosition(re.Request))
 
-	// Broadcast participant reconnection AFTER registration
-	if participantID != "" {
+	// Broadcast participant reconnection AFTER registration, once for all of their connections
+	if firstConnection {
 		participantRecord, err := h.roomManager.GetParticipant(participantID)
 		if err == nil {
 			participant := &models.Participant{
 				ID:        participantRecord.Id,
 				Name:      participantRecord.GetString("name"),
 				Role:      models.ParticipantRole(participantRecord.GetString("role")),
+				Weight:    services.ParticipantWeight(participantRecord),
 				Connected: true, // Now connected
 				JoinedAt:  participantRecord.GetDateTime("joined_at").Time(),
 			}
 
-			h.hub.BroadcastToRoom(roomID, &models.WSMessage{
-				Type: models.MsgTypeParticipantJoined,
-				Payload: map[string]interface{}{
-					"participant": participant,
-				},
+			h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
+				Type:    models.MsgTypeParticipantJoined,
+				Payload: models.ParticipantJoinedPayload{Participant: participant},
 			})
-			log.Printf("Participant reconnected and broadcast: %s (%s)", participant.Name, participantID)
+			logging.ForRoom(roomID, participantID).Info("Participant reconnected")
 		}
 	}
 
 	// Send initial room state to this client
-	if err := h.sendInitialRoomStateToClient(client, roomID, participantID); err != nil {
-		log.Printf("Failed to send initial room state: %v", err)
+	if !resumed {
+		if err := h.sendInitialRoomStateToClient(client, roomID, participantID); err != nil {
+			logging.ForRoom(roomID, participantID).Error("Failed to send initial room state", logging.Err(err))
+		}
 	}
 
 	// Start client's read and write pumps (these run in separate goroutines)
@@ -158,12 +231,24 @@ func (h *WSHandler) HandleWebSocket(re *core.RequestEvent) error {
 	// Block here to keep the connection alive
 	// The client's readPump will handle disconnection and cleanup
 	<-client.Done()
+}
 
-	return nil
+// resumePosition reads the position of the latest event a reconnecting client has seen,
+// from the eventLog and lastSeq query parameters. Zero for a new client.
+func resumePosition(r *http.Request) services.EventPosition {
+	query := r.URL.Query()
+	seq, err := strconv.ParseUint(query.Get("lastSeq"), 10, 64)
+	if err != nil {
+		return services.EventPosition{}
+	}
+	return services.EventPosition{Log: query.Get("eventLog"), Seq: seq}
 }
 
 // sendInitialRoomStateToClient sends the complete current room state to a newly connected client
 func (h *WSHandler) sendInitialRoomStateToClient(client *services.Client, roomID string, participantID string) error {
+	// Events up to here are part of the state read below
+	position := h.hub.EventLogPosition(roomID)
+
 	// Get all participants for the room
 	participantRecords, err := h.roomManager.GetRoomParticipants(roomID)
 	if err != nil {
@@ -177,6 +262,7 @@ func (h *WSHandler) sendInitialRoomStateToClient(client *services.Client, roomID
 			ID:        pr.Id,
 			Name:      pr.GetString("name"),
 			Role:      models.ParticipantRole(pr.GetString("role")),
+			Weight:    services.ParticipantWeight(pr),
 			Connected: pr.GetBool("connected"),
 			JoinedAt:  pr.GetDateTime("joined_at").Time(),
 		})
@@ -202,47 +288,40 @@ func (h *WSHandler) sendInitialRoomStateToClient(client *services.Client, roomID
 	isCreator := h.roomManager.IsRoomCreator(roomID, participantID)
 
 	// Get permissions for this participant
-	canReset, _ := h.aclService.CanReset(roomID, participantID)
-	canNewRound, _ := h.aclService.CanTriggerNewRound(roomID, participantID)
-	canReveal, _ := h.aclService.CanReveal(roomID, participantID)
-	canChangeVoteAfterReveal, _ := h.aclService.CanChangeVoteAfterReveal(roomID)
-
-	// Prepare room state message
-	stateMessage := &models.WSMessage{
-		Type: models.MsgTypeRoomState,
-		Payload: map[string]any{
-			"participants":         participants,
-			"roomState":            string(roomState),
-			"roundNumber":          nil, // Will be filled if available
-			"voteCount":            voteCount,
-			"isCreator":            isCreator,
-			"currentParticipantId": participantID,
-			"expiresAt":            roomRecord.GetDateTime("expires_at").Time().Format("2006-01-02T15:04:05Z07:00"), // ISO 8601 format
-			"permissions": map[string]any{
-				"canReset":                 canReset,
-				"canNewRound":              canNewRound,
-				"canReveal":                canReveal,
-				"canChangeVoteAfterReveal": canChangeVoteAfterReveal,
-			},
-		},
-	}
-
-	// Get current round number
+	permissions, _ := h.aclService.Permissions(roomID, participantID)
+
+	// Prepare room state payload
+	state := models.RoomStatePayload{
+		Participants:         participants,
+		RoomState:            string(roomState),
+		VoteCount:            voteCount,
+		IsCreator:            isCreator,
+		CurrentParticipantID: participantID,
+		ExpiresAt:            roomRecord.GetDateTime("expires_at").Time().Format(time.RFC3339), // ISO 8601 format
+		Permissions:          permissions,
+		EventLog:             position.Log,
+		Seq:                  position.Seq,
+	}
+
+	// Get current round number (left null if unavailable)
 	if currentRound, err := h.roomManager.GetCurrentRound(roomID); err == nil {
-		stateMessage.Payload.(map[string]any)["roundNumber"] = currentRound
+		state.RoundNumber = &currentRound
 	}
 
 	// Send message via hub
-	h.hub.SendToClient(client, stateMessage)
+	h.hub.SendToClient(client, &models.WSMessage{
+		Type:    models.MsgTypeRoomState,
+		Payload: state,
+	})
 
-	log.Printf("Sent initial room state to new connection in room %s (%d participants, %d votes)", roomID, len(participants), voteCount)
+	logging.ForRoom(roomID, participantID).Debug("Sent initial room state", "participants", len(participants), "votes", voteCount)
 	return nil
 }
 // isRoomExpired checks if a room has expired based on its expires_at timestamp
 func (h *WSHandler) isRoomExpired(roomID string) bool {
 	room, err := h.roomManager.GetRoom(roomID)
 	if err != nil {
-		log.Printf("Error checking room expiration: %v", err)
+		logging.ForRoom(roomID, "").Error("Failed to check room expiration", logging.Err(err))
 		return true // Treat errors as expired for safety
 	}
 
@@ -250,113 +329,181 @@ func (h *WSHandler) isRoomExpired(roomID string) bool {
 	return time.Now().After(expiresAt)
 }
 
-// processMessage is the callback for the hub to process incoming WebSocket messages
-func (h *WSHandler) processMessage(roomID string, participantID string, data []byte) {
-	log.Printf("[DEBUG] Raw WebSocket message received: %s", string(data))
+// processMessage is the callback for the hub to process incoming WebSocket messages.
+// ctx carries the trace started when the message was received.
+func (h *WSHandler) processMessage(ctx context.Context, roomID string, participantID string, data []byte) {
+	start := time.Now()
+	logger := logging.ForRoom(roomID, participantID)
 
-	var msg models.WSMessage
+	ctx, span := tracing.Start(ctx, "WSHandler.handleMessage", tracing.RoomID.String(roomID), tracing.ParticipantID.String(participantID))
+	defer span.End()
+
+	var msg models.IncomingMessage
 	if err := json.Unmarshal(data, &msg); err != nil {
-		log.Printf("Error unmarshaling message: %v, raw data: %s", err, string(data))
+		logger.Warn("Invalid message", logging.Err(err), "size", len(data))
+		tracing.End(span, err)
+		h.reply(ctx, &msg, newCommandError(ErrCodeInvalidPayload, "Invalid message format"))
 		return
 	}
 
 	// Skip HTMX header-only messages (they have no type)
 	if msg.Type == "" {
-		log.Printf("[DEBUG] Skipping HTMX header-only message")
+		logger.Debug("Skipping HTMX header-only message")
 		return
 	}
 
 	// Validate message type
 	if !security.IsValidMessageType(msg.Type) {
-		log.Printf("Invalid message type received: %s", msg.Type)
+		logger.Warn("Invalid message type received", logging.MessageType, msg.Type)
+		tracing.End(span, errors.New("invalid message type"))
+		h.reply(ctx, &msg, newCommandError(ErrCodeInvalidPayload, "Unknown message type"))
 		return
 	}
+	logger = logger.With(logging.MessageType, msg.Type)
+	span.SetAttributes(tracing.MessageType.String(msg.Type))
+	defer func() { h.hub.Metrics().ObserveMessageHandling(msg.Type, time.Since(start)) }()
+
+	// Validate payload structure before decoding it into the typed payload
+	var rawPayload any
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &rawPayload); err != nil {
+			logger.Warn("Invalid message payload", logging.Err(err))
+			tracing.End(span, err)
+			h.reply(ctx, &msg, newCommandError(ErrCodeInvalidPayload, "Invalid message payload"))
+			return
+		}
+	}
+	if err := security.ValidateMessagePayload(msg.Type, rawPayload); err != nil {
+		logger.Warn("Invalid message payload", logging.Err(err))
+		tracing.End(span, err)
+		h.reply(ctx, &msg, newCommandError(ErrCodeInvalidPayload, "%v", err))
+		return
+	}
+
+	logger.Debug("Message received")
+	err := h.handleMessage(ctx, roomID, &msg, participantID)
+	if err != nil {
+		logger.Info("Command rejected", logging.Err(err))
+	}
+	h.reply(ctx, &msg, err)
+}
 
-	// Validate payload structure
-	if err := security.ValidateMessagePayload(msg.Type, msg.Payload); err != nil {
-		log.Printf("Invalid message payload: %v", err)
+// reply answers the sender of msg with an ack, or with an error carrying the code of err
+func (h *WSHandler) reply(ctx context.Context, msg *models.IncomingMessage, err error) {
+	if err == nil {
+		services.Reply(ctx, &models.WSMessage{
+			Type:      models.MsgTypeAck,
+			RequestID: msg.RequestID,
+			Payload:   models.AckPayload{Action: msg.Type},
+		})
 		return
 	}
 
-	log.Printf("[DEBUG] Parsed message - Type: %s, Payload: %+v", msg.Type, msg.Payload)
-	h.handleMessage(roomID, &msg, participantID)
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		cmdErr = newCommandError(ErrCodeInternal, "Internal server error")
+	}
+	services.Reply(ctx, &models.WSMessage{
+		Type:      models.MsgTypeError,
+		RequestID: msg.RequestID,
+		Payload:   models.ErrorPayload{Code: cmdErr.Code, Message: cmdErr.Message, Action: msg.Type},
+	})
 }
 
-func (h *WSHandler) handleMessage(roomID string, msg *models.WSMessage, participantID string) {
+// handleMessage runs a validated command. Errors are *CommandError.
+func (h *WSHandler) handleMessage(ctx context.Context, roomID string, msg *models.IncomingMessage, participantID string) error {
 	// Allow name updates regardless of expiration (non-critical actions)
-	if msg.Type == models.MsgTypeUpdateName || msg.Type == models.MsgTypeUpdateRoomName {
-		switch msg.Type {
-		case models.MsgTypeUpdateName:
-			h.handleUpdateName(roomID, msg, participantID)
-		case models.MsgTypeUpdateRoomName:
-			h.handleUpdateRoomName(roomID, msg, participantID)
-		}
-		return
+	switch msg.Type {
+	case models.MsgTypeUpdateName:
+		return h.handleUpdateName(ctx, roomID, msg, participantID)
+	case models.MsgTypeUpdateRoomName:
+		return h.handleUpdateRoomName(ctx, roomID, msg, participantID)
 	}
 
 	// Check room expiration for critical actions (vote, reveal, reset, next_round)
 	if h.isRoomExpired(roomID) {
-		log.Printf("Action rejected: room %s has expired (type: %s)", roomID, msg.Type)
+		logging.ForCommand(roomID, participantID, msg.Type).Info("Action rejected: room has expired")
 		// Broadcast expiration message to all connections in this room
-		h.hub.BroadcastToRoom(roomID, &models.WSMessage{
-			Type: "room_expired",
-			Payload: map[string]any{
-				"message": "This room has expired. Please create a new room.",
-			},
+		h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
+			Type:    models.MsgTypeRoomExpired,
+			Payload: models.RoomExpiredPayload{Message: "This room has expired. Please create a new room."},
 		})
-		return
+		return newCommandError(ErrCodeRoomExpired, "This room has expired")
 	}
 
 	switch msg.Type {
 	case models.MsgTypeVote:
-		h.handleVote(roomID, msg, participantID)
+		return h.handleVote(ctx, roomID, msg, participantID)
+	case models.MsgTypeUnvote:
+		return h.retractVote(ctx, roomID, participantID)
 	case models.MsgTypeReveal:
-		h.handleReveal(roomID, participantID)
+		return h.revealVotes(ctx, roomID, participantID)
 	case models.MsgTypeReset:
-		h.handleReset(roomID, participantID)
+		return h.resetRound(ctx, roomID, participantID)
 	case models.MsgTypeNextRound:
-		h.handleNextRound(roomID, participantID)
+		_, err := h.advanceRound(ctx, roomID, participantID)
+		return err
 	case models.MsgTypeUpdateConfig:
-		h.handleUpdateConfig(roomID, msg, participantID)
+		return h.handleUpdateConfig(ctx, roomID, msg, participantID)
+	case models.MsgTypeUpdateWeight:
+		return h.handleUpdateWeight(ctx, roomID, msg, participantID)
+	}
+	return nil
+}
+
+func (h *WSHandler) handleVote(ctx context.Context, roomID string, msg *models.IncomingMessage, participantID string) error {
+	var payload models.VotePayload
+	if err := msg.DecodePayload(&payload); err != nil {
+		return newCommandError(ErrCodeInvalidPayload, "invalid vote payload format")
 	}
+	return h.castVote(ctx, roomID, participantID, &payload)
 }
 
-func (h *WSHandler) handleVote(roomID string, msg *models.WSMessage, participantID string) {
-	log.Printf("[DEBUG] handleVote called: roomID=%s, participantID=%s", roomID, participantID)
+// castVote validates and saves a vote, then notifies the room
+// Returns a *CommandError when the vote is rejected
+func (h *WSHandler) castVote(ctx context.Context, roomID, participantID string, payload *models.VotePayload) error {
+	logger := logging.ForCommand(roomID, participantID, models.MsgTypeVote)
 
 	if participantID == "" {
-		log.Printf("Vote rejected: no participant ID")
-		return
+		return newCommandError(ErrCodeUnauthorized, "no participant ID")
 	}
 
-	// Extract vote value from payload
-	payload, ok := msg.Payload.(map[string]any)
-	if !ok {
-		log.Printf("Invalid vote payload format")
-		return
+	if payload == nil {
+		return newCommandError(ErrCodeInvalidPayload, "invalid vote payload format")
 	}
 
-	value, ok := payload["value"].(string)
-	if !ok {
-		log.Printf("Invalid vote value format")
-		return
+	// Optional confidence level (already validated by ValidateMessagePayload)
+	confidence, err := security.ValidateConfidence(payload.Confidence)
+	if err != nil {
+		return newCommandError(ErrCodeInvalidPayload, "invalid vote confidence: %v", err)
 	}
-	log.Printf("[DEBUG] Vote value extracted: %s", value)
 
-	// Verify room exists
-	_, err := h.roomManager.GetRoom(roomID)
+	config, err := h.aclService.GetRoomConfig(roomID)
 	if err != nil {
-		log.Printf("Room not found: %v", err)
-		return
+		return newCommandError(ErrCodeNotFound, "room not found")
+	}
+
+	// Multi-dimensional rooms take one value per dimension and store the combined score as value
+	var value string
+	var dimensionValues map[string]string
+	if config.IsMultiDimensional() {
+		dimensionValues, value, err = h.resolveDimensionVote(payload.Dimensions, config)
+		if err != nil {
+			return newCommandError(ErrCodeInvalidPayload, "%v", err)
+		}
+	} else {
+		value = payload.Value
+		if value == "" {
+			return newCommandError(ErrCodeInvalidPayload, "invalid vote value format")
+		}
 	}
 
 	// Get current room state from round
 	roomState, err := h.getRoomState(roomID)
 	if err != nil {
-		log.Printf("Failed to get room state: %v", err)
-		return
+		return newCommandError(ErrCodeNotFound, "failed to get room state: %v", err)
 	}
-	log.Printf("[DEBUG] Room state: %s", roomState)
+	logger.Debug("Vote received", "room_state", roomState)
 
 	// Check if voting is allowed based on room state and permissions
 	switch roomState {
@@ -366,39 +513,39 @@ func (h *WSHandler) handleVote(roomID string, msg *models.WSMessage, participant
 		// Check if changing votes after reveal is allowed
 		canChange, err := h.aclService.CanChangeVoteAfterReveal(roomID)
 		if err != nil {
-			log.Printf("Failed to check change vote permission: %v", err)
-			return
+			return newCommandError(ErrCodeInternal, "failed to check change vote permission: %v", err)
 		}
 		if !canChange {
-			log.Printf("Vote rejected: changing votes after reveal is not allowed")
-			return
+			return newCommandError(ErrCodeInvalidState, "changing votes after reveal is not allowed")
 		}
 	default:
-		log.Printf("Vote rejected: room not in voting or revealed state (current: %s)", roomState)
-		return
+		return newCommandError(ErrCodeInvalidState, "room not in voting or revealed state (current: %s)", roomState)
 	}
 
 	// Verify participant exists and is a voter
 	participant, err := h.roomManager.GetParticipant(participantID)
-	if err != nil {
-		log.Printf("Vote rejected: participant not found: %v", err)
-		return
+	if err != nil || participant.GetString("room_id") != roomID {
+		return newCommandError(ErrCodeNotFound, "participant not found")
 	}
 
-	participantRole := participant.GetString("role")
-	log.Printf("[DEBUG] Participant role: %s (expected: %s)", participantRole, string(models.RoleVoter))
-	if participantRole != string(models.RoleVoter) {
-		log.Printf("Vote rejected: participant is not a voter")
-		return
+	if participant.GetString("role") != string(models.RoleVoter) {
+		return newCommandError(ErrCodeForbidden, "participant is not a voter")
 	}
 
 	// Save vote to database
-	log.Printf("[DEBUG] Calling CastVote: roomID=%s, participantID=%s, value=%s", roomID, participantID, value)
-	if err := h.roomManager.CastVote(roomID, participantID, value); err != nil {
-		log.Printf("Failed to save vote: %v", err)
-		return
+	if err := h.roomManager.WithContext(ctx).CastDimensionalVote(roomID, participantID, value, dimensionValues, confidence); err != nil {
+		logger.Error("Failed to save vote", logging.Err(err))
+		return newCommandError(ErrCodeInternal, "failed to save vote")
 	}
-	log.Printf("[DEBUG] Vote saved successfully")
+	logger.Debug("Vote saved")
+
+	h.broadcastVote(ctx, roomID, participant, roomState, value, dimensionValues, confidence, config)
+	return nil
+}
+
+// broadcastVote notifies the room of a saved vote and evaluates auto-reveal
+func (h *WSHandler) broadcastVote(ctx context.Context, roomID string, participant *core.Record, roomState models.RoomState, value string, dimensionValues map[string]string, confidence int, config *models.RoomConfig) {
+	participantID := participant.Id
 
 	// If room is in revealed state, broadcast the updated vote with value
 	// Otherwise, just broadcast vote cast notification without value
@@ -406,236 +553,355 @@ func (h *WSHandler) handleVote(roomID string, msg *models.WSMessage, participant
 		// Get participant name for the broadcast
 		participantName := participant.GetString("name")
 
-		h.hub.BroadcastToRoom(roomID, &models.WSMessage{
+		h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
 			Type: models.MsgTypeVoteUpdated,
-			Payload: map[string]any{
-				"participantId":   participantID,
-				"participantName": participantName,
-				"value":           value,
+			Payload: models.VoteUpdatedPayload{
+				ParticipantID:   participantID,
+				ParticipantName: participantName,
+				Value:           value,
+				Dimensions:      dimensionValues,
+				Confidence:      confidence,
 			},
 		})
-		log.Printf("[DEBUG] Vote update broadcast (revealed state)")
+		logging.ForCommand(roomID, participantID, models.MsgTypeVote).Debug("Vote update broadcast")
 	} else {
 		// Broadcast vote cast notification (without revealing the value)
-		h.hub.BroadcastToRoom(roomID, &models.WSMessage{
-			Type: models.MsgTypeVoteCast,
-			Payload: map[string]any{
-				"participantId": participantID,
-				"hasVoted":      true,
-			},
+		h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
+			Type:    models.MsgTypeVoteCast,
+			Payload: models.VoteCastPayload{ParticipantID: participantID, HasVoted: true},
 		})
-		log.Printf("[DEBUG] Vote cast notification broadcast (voting state)")
-
-		// Check if auto-reveal is enabled and all voters have voted
-		config, err := h.aclService.GetRoomConfig(roomID)
-		if err == nil && config.Permissions.AutoReveal {
-			allVoted, err := h.roomManager.HaveAllVotersVoted(roomID)
-			if err == nil && allVoted {
-				log.Printf("[DEBUG] Auto-reveal triggered: all voters have voted")
-				// Trigger countdown and reveal
-				h.hub.BroadcastToRoom(roomID, &models.WSMessage{
-					Type: models.MsgTypeAutoRevealCountdown,
-					Payload: map[string]any{
-						"duration": 1500, // 1.5 seconds in milliseconds
-					},
-				})
-				// Schedule actual reveal after countdown (handled by frontend)
-				// Frontend will send reveal message after countdown completes
-			}
-		}
+		logging.ForCommand(roomID, participantID, models.MsgTypeVote).Debug("Vote cast broadcast")
+
+		// Check if auto-reveal is enabled and the reveal rule is met
+		h.evaluateAutoReveal(ctx, roomID, config)
 	}
 }
 
-func (h *WSHandler) handleReveal(roomID string, participantID string) {
-	// ACL Check: Verify participant has permission
-	canReveal, err := h.aclService.CanReveal(roomID, participantID)
+// evaluateAutoReveal starts the auto-reveal countdown if auto-reveal is enabled,
+// the round is still open and the room's reveal rule is met, and returns whether it did.
+// Called after each vote, retracted vote and voter disconnect.
+func (h *WSHandler) evaluateAutoReveal(ctx context.Context, roomID string, config *models.RoomConfig) bool {
+	if config == nil || !config.Permissions.AutoReveal {
+		return false
+	}
+
+	roomState, err := h.getRoomState(roomID)
+	if err != nil || roomState != models.StateVoting {
+		return false
+	}
+
+	rule := config.GetRevealRule()
+	met, err := h.roomManager.IsRevealRuleMet(roomID, rule)
 	if err != nil {
-		log.Printf("ACL check failed: %v", err)
-		return
+		logging.ForRoom(roomID, "").Error("Failed to evaluate reveal rule", logging.Err(err))
+		return false
+	}
+	if !met {
+		return false
 	}
 
-	if !canReveal {
-		log.Printf("Reveal rejected: participant %s not authorized", participantID)
-		return
+	logging.ForRoom(roomID, "").Debug("Auto-reveal triggered", "reveal_rule", rule.Mode)
+	// Trigger countdown and reveal
+	h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
+		Type:    models.MsgTypeAutoRevealCountdown,
+		Payload: models.AutoRevealCountdownPayload{Duration: 1500}, // 1.5 seconds in milliseconds
+	})
+	// Schedule actual reveal after countdown (handled by frontend)
+	// Frontend will send reveal message after countdown completes
+	return true
+}
+
+// retractVote deletes the participant's vote while voting is open and notifies the room
+func (h *WSHandler) retractVote(ctx context.Context, roomID, participantID string) error {
+	if participantID == "" {
+		return newCommandError(ErrCodeUnauthorized, "no participant ID")
 	}
 
-	// Verify room exists
-	_, err = h.roomManager.GetRoom(roomID)
+	// Votes can only be withdrawn before they are revealed
+	roomState, err := h.getRoomState(roomID)
 	if err != nil {
-		log.Printf("Room not found: %v", err)
-		return
+		return newCommandError(ErrCodeNotFound, "failed to get room state: %v", err)
+	}
+	if roomState != models.StateVoting {
+		return newCommandError(ErrCodeInvalidState, "room not in voting state (current: %s)", roomState)
+	}
+
+	if err := h.roomManager.WithContext(ctx).RetractVote(roomID, participantID); err != nil {
+		return newCommandError(ErrCodeInvalidState, "failed to retract vote: %v", err)
+	}
+
+	h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
+		Type:    models.MsgTypeVoteCast,
+		Payload: models.VoteCastPayload{ParticipantID: participantID, HasVoted: false},
+	})
+
+	// A countdown started by the previous vote must not reveal a round that no longer meets
+	// the reveal rule, one that still does restarts it
+	config, err := h.aclService.GetRoomConfig(roomID)
+	if err == nil && config.Permissions.AutoReveal && !h.evaluateAutoReveal(ctx, roomID, config) {
+		h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
+			Type:    models.MsgTypeAutoRevealCancelled,
+			Payload: models.EmptyPayload{},
+		})
+	}
+
+	return nil
+}
+
+// resolveDimensionVote extracts and validates one value per room dimension from a vote payload
+// Returns the dimension values and the combined score, services.ErrNoNumericDimension if no
+// dimension value is numeric
+func (h *WSHandler) resolveDimensionVote(dimensionValues map[string]string, config *models.RoomConfig) (map[string]string, string, error) {
+	if dimensionValues == nil {
+		return nil, "", fmt.Errorf("multi-dimensional room requires a 'dimensions' object")
+	}
+
+	validator := services.NewVoteValidator()
+	if err := validator.ValidateDimensionVotes(dimensionValues, config.Dimensions); err != nil {
+		return nil, "", err
+	}
+
+	score, ok := validator.CombineDimensionScores(dimensionValues, config.Dimensions, config.GetCombineMethod())
+	if !ok {
+		return nil, "", services.ErrNoNumericDimension
+	}
+	return dimensionValues, validator.FormatScore(score), nil
+}
+
+// revealVotes reveals the current round and broadcasts votes with statistics
+func (h *WSHandler) revealVotes(ctx context.Context, roomID, participantID string) error {
+	// ACL Check: Verify participant has permission
+	canReveal, err := h.aclService.CanReveal(roomID, participantID)
+	if err != nil {
+		return newCommandError(ErrCodeNotFound, "ACL check failed: %v", err)
+	}
+
+	if !canReveal {
+		return newCommandError(ErrCodeForbidden, "participant %s not authorized to reveal", participantID)
 	}
 
 	// Get current room state from round
 	roomState, err := h.getRoomState(roomID)
 	if err != nil {
-		log.Printf("Failed to get room state: %v", err)
-		return
+		return newCommandError(ErrCodeNotFound, "failed to get room state: %v", err)
 	}
 
 	if roomState != models.StateVoting {
-		log.Printf("Reveal rejected: room not in voting state")
-		return
+		return newCommandError(ErrCodeInvalidState, "room not in voting state")
 	}
 
 	// Reveal votes (updates round state to revealed)
-	if err := h.roomManager.RevealVotes(roomID); err != nil {
-		log.Printf("Failed to reveal votes: %v", err)
-		return
+	if err := h.roomManager.WithContext(ctx).RevealVotes(roomID); err != nil {
+		if errors.Is(err, services.ErrRoundStateConflict) {
+			return newCommandError(ErrCodeInvalidState, "room not in voting state")
+		}
+		logging.ForCommand(roomID, participantID, models.MsgTypeReveal).Error("Failed to reveal votes", logging.Err(err))
+		return newCommandError(ErrCodeInternal, "failed to reveal votes")
+	}
+
+	if err := h.broadcastVotesRevealed(ctx, roomID); err != nil {
+		logging.ForCommand(roomID, participantID, models.MsgTypeReveal).Error("Failed to broadcast revealed votes", logging.Err(err))
 	}
+	return nil
+}
 
+// broadcastVotesRevealed sends all votes of the current round with statistics to the room
+func (h *WSHandler) broadcastVotesRevealed(ctx context.Context, roomID string) error {
 	// Get all votes for current round
 	votes, err := h.roomManager.GetRoomVotes(roomID)
 	if err != nil {
-		log.Printf("Failed to get votes: %v", err)
-		return
+		return fmt.Errorf("failed to get votes: %w", err)
 	}
 
 	// Get all participants for this room
 	participants, err := h.roomManager.GetRoomParticipants(roomID)
 	if err != nil {
-		log.Printf("Failed to get participants: %v", err)
-		return
+		return fmt.Errorf("failed to get participants: %w", err)
 	}
 
 	// Build vote results map with participant info
-	voteResults := make([]map[string]any, 0)
+	voteResults := make([]models.RevealedVote, 0, len(votes))
 	voteValueCounts := make(map[string]int)
+	voteValueWeights := make(map[string]float64)
+	weighted := false // Whether any vote counts for more or less than one
+	confidenceLevels := make([]int, 0, len(votes))
+
+	dimensionVotes := make([]map[string]string, 0, len(votes))
 
 	for _, vote := range votes {
 		participantID := vote.GetString("participant_id")
 		value := vote.GetString("value")
+		confidence := vote.GetInt("confidence")
+		dimensionValues := h.roomManager.GetVoteDimensionValues(vote)
 
-		// Find participant name
+		// Find participant name and vote weight
 		var participantName string
+		weight := models.DefaultParticipantWeight
 		for _, p := range participants {
 			if p.Id == participantID {
 				participantName = p.GetString("name")
+				weight = services.ParticipantWeight(p)
 				break
 			}
 		}
 
-		voteResults = append(voteResults, map[string]any{
-			"participantId":   participantID,
-			"participantName": participantName,
-			"value":           value,
+		voteResults = append(voteResults, models.RevealedVote{
+			ParticipantID:   participantID,
+			ParticipantName: participantName,
+			Value:           value,
+			Weight:          weight,
+			Dimensions:      dimensionValues,
+			Confidence:      confidence,
 		})
 
 		// Count values for statistics
 		voteValueCounts[value]++
+		voteValueWeights[value] += weight
+		if weight != models.DefaultParticipantWeight {
+			weighted = true
+		}
+		confidenceLevels = append(confidenceLevels, confidence)
+		if dimensionValues != nil {
+			dimensionVotes = append(dimensionVotes, dimensionValues)
+		}
 	}
 
-	// Calculate statistics
+	// Calculate statistics, each vote counting by its participant's weight
 	var total int
-	var sum float64
+	var sum, totalWeight float64
 	validator := services.NewVoteValidator()
 	for value, count := range voteValueCounts {
 		total += count
+		totalWeight += voteValueWeights[value]
 		// Try to parse as number for average calculation
 		if num, ok := validator.ParseNumericValue(value); ok && num > 0 {
-			sum += num * float64(count)
+			sum += num * voteValueWeights[value]
 		}
 	}
 
-	stats := map[string]any{
-		"total":          total,
-		"valueBreakdown": voteValueCounts,
+	stats := models.RevealStats{
+		Total:          total,
+		TotalWeight:    totalWeight,
+		Weighted:       weighted,
+		ValueBreakdown: voteValueCounts,
+		ValueWeights:   voteValueWeights,
+		Consensus:      len(voteValueCounts) == 1,
+	}
+
+	// Add weighted average if we have numeric values
+	if sum > 0 && totalWeight > 0 {
+		average := sum / totalWeight
+		stats.Average = &average
+	}
+
+	// Add aggregated confidence if any vote carried one
+	if confidence := models.SummarizeConfidence(confidenceLevels); confidence != nil {
+		stats.Confidence = confidence
+		stats.LowConfidenceConsensus = stats.Consensus && confidence.Low
 	}
 
-	// Add average if we have numeric values
-	if sum > 0 && total > 0 {
-		stats["average"] = sum / float64(total)
+	// Add per-dimension statistics for multi-dimensional rooms
+	// The average above is then the average combined score
+	if config, err := h.aclService.GetRoomConfig(roomID); err == nil && config.IsMultiDimensional() {
+		stats.Dimensions = validator.CalculateDimensionStats(dimensionVotes, config.Dimensions)
+		stats.CombineMethod = config.GetCombineMethod()
 	}
 
 	// Broadcast revealed votes with statistics
-	h.hub.BroadcastToRoom(roomID, &models.WSMessage{
-		Type: models.MsgTypeVotesRevealed,
-		Payload: map[string]any{
-			"votes": voteResults,
-			"stats": stats,
-		},
+	revealed := models.VotesRevealedPayload{Votes: voteResults, Stats: stats}
+	h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
+		Type:    models.MsgTypeVotesRevealed,
+		Payload: revealed,
 	})
+	h.notifyWebhooks(roomID, models.WebhookEventVotesRevealed, revealed)
+	if h.slack != nil {
+		h.slack.PostRevealSummary(roomID, revealed)
+	}
+
+	return nil
 }
 
-func (h *WSHandler) handleReset(roomID string, participantID string) {
+// resetRound clears the votes of the current round and notifies the room
+func (h *WSHandler) resetRound(ctx context.Context, roomID, participantID string) error {
 	// ACL Check: Verify participant has permission
 	canReset, err := h.aclService.CanReset(roomID, participantID)
 	if err != nil {
-		log.Printf("ACL check failed: %v", err)
-		return
+		return newCommandError(ErrCodeNotFound, "ACL check failed: %v", err)
 	}
 
 	if !canReset {
-		log.Printf("Reset rejected: participant %s not authorized", participantID)
-		return
-	}
-
-	// Verify room exists
-	_, err = h.roomManager.GetRoom(roomID)
-	if err != nil {
-		log.Printf("Room not found: %v", err)
-		return
+		return newCommandError(ErrCodeForbidden, "participant %s not authorized to reset", participantID)
 	}
 
 	// Reset the round (clears votes, returns to voting state, same round)
-	if err := h.roomManager.ResetRound(roomID); err != nil {
-		log.Printf("Failed to reset round: %v", err)
-		return
+	if err := h.roomManager.WithContext(ctx).ResetRound(roomID); err != nil {
+		if errors.Is(err, services.ErrRoundStateConflict) {
+			return newCommandError(ErrCodeInvalidState, "round can no longer be reset")
+		}
+		logging.ForCommand(roomID, participantID, models.MsgTypeReset).Error("Failed to reset round", logging.Err(err))
+		return newCommandError(ErrCodeInternal, "failed to reset round")
 	}
 
 	// Broadcast room reset
-	h.hub.BroadcastToRoom(roomID, &models.WSMessage{
+	h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
 		Type:    models.MsgTypeRoomReset,
-		Payload: map[string]any{},
+		Payload: models.EmptyPayload{},
 	})
+	return nil
 }
 
-func (h *WSHandler) handleNextRound(roomID string, participantID string) {
+// advanceRound completes the revealed round, starts the next one and notifies the room
+func (h *WSHandler) advanceRound(ctx context.Context, roomID, participantID string) (*core.Record, error) {
 	// ACL Check: Verify participant has permission
 	canTrigger, err := h.aclService.CanTriggerNewRound(roomID, participantID)
 	if err != nil {
-		log.Printf("ACL check failed: %v", err)
-		return
+		return nil, newCommandError(ErrCodeNotFound, "ACL check failed: %v", err)
 	}
 
 	if !canTrigger {
-		log.Printf("Next round rejected: participant %s not authorized", participantID)
-		return
-	}
-
-	// Verify room exists
-	_, err = h.roomManager.GetRoom(roomID)
-	if err != nil {
-		log.Printf("Room not found: %v", err)
-		return
+		return nil, newCommandError(ErrCodeForbidden, "participant %s not authorized to start a new round", participantID)
 	}
 
 	// Get current room state from round
 	roomState, err := h.getRoomState(roomID)
 	if err != nil {
-		log.Printf("Failed to get room state: %v", err)
-		return
+		return nil, newCommandError(ErrCodeNotFound, "failed to get room state: %v", err)
 	}
 
 	if roomState != models.StateRevealed {
-		log.Printf("Next round rejected: room not in revealed state")
-		return
+		return nil, newCommandError(ErrCodeInvalidState, "room not in revealed state")
 	}
 
-	// Create next round (completes current, creates new)
-	newRound, err := h.roomManager.CreateNextRound(roomID)
+	completedRound, err := h.roomManager.GetCurrentRoundRecord(roomID)
 	if err != nil {
-		log.Printf("Failed to create next round: %v", err)
-		return
+		return nil, newCommandError(ErrCodeNotFound, "failed to get current round: %v", err)
+	}
+
+	// Create next round (completes current, creates new) unless another request already did
+	newRound, err := h.roomManager.WithContext(ctx).CreateNextRoundFrom(roomID, completedRound.Id)
+	if err != nil {
+		if errors.Is(err, services.ErrRoundStateConflict) {
+			return nil, newCommandError(ErrCodeInvalidState, "round already completed")
+		}
+		logging.ForCommand(roomID, participantID, models.MsgTypeNextRound).Error("Failed to create next round", logging.Err(err))
+		return nil, newCommandError(ErrCodeInternal, "failed to create next round")
 	}
 
 	// Broadcast round completed
-	h.hub.BroadcastToRoom(roomID, &models.WSMessage{
-		Type: models.MsgTypeRoundCompleted,
-		Payload: map[string]any{
-			"newRoundNumber": newRound.GetInt("round_number"),
-		},
+	completed := models.RoundCompletedPayload{NewRoundNumber: newRound.GetInt("round_number")}
+	h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
+		Type:    models.MsgTypeRoundCompleted,
+		Payload: completed,
 	})
+	h.notifyWebhooks(roomID, models.WebhookEventRoundCompleted, completed)
+
+	// The completed round estimates the room's current story
+	if h.stories != nil {
+		if _, err := h.stories.RecordEstimate(roomID, completedRound.Id); err != nil {
+			logging.ForCommand(roomID, participantID, models.MsgTypeNextRound).Error("Failed to record story estimate", logging.Err(err))
+		}
+	}
+	return newRound, nil
 }
 
 // getRoomState gets the current room state from the current round
@@ -643,181 +909,195 @@ func (h *WSHandler) getRoomState(roomID string) (models.RoomState, error) {
 	return h.roomManager.GetRoomState(roomID)
 }
 
-func (h *WSHandler) handleUpdateName(roomID string, msg *models.WSMessage, participantID string) {
-	// Helper to send error to the client
-	sendError := func(message string) {
-		client := h.hub.GetClient(roomID, participantID)
-		if client != nil {
-			h.hub.SendToClient(client, &models.WSMessage{
-				Type: models.MsgTypeError,
-				Payload: map[string]any{
-					"message": message,
-					"action":  "update_name",
-				},
-			})
-		}
-	}
+func (h *WSHandler) handleUpdateName(ctx context.Context, roomID string, msg *models.IncomingMessage, participantID string) error {
+	logger := logging.ForCommand(roomID, participantID, models.MsgTypeUpdateName)
 
 	if participantID == "" {
-		log.Printf("Update name rejected: no participant ID")
-		sendError("Could not identify participant")
-		return
+		return newCommandError(ErrCodeUnauthorized, "Could not identify participant")
 	}
 
 	// Extract new name from payload
-	payload, ok := msg.Payload.(map[string]any)
-	if !ok {
-		log.Printf("Invalid update name payload format")
-		sendError("Invalid request format")
-		return
-	}
-
-	newName, ok := payload["name"].(string)
-	if !ok {
-		log.Printf("Invalid name value type")
-		sendError("Invalid name format")
-		return
+	var payload models.UpdateNamePayload
+	if err := msg.DecodePayload(&payload); err != nil {
+		return newCommandError(ErrCodeInvalidPayload, "Invalid request format")
 	}
+	newName := payload.Name
 
 	// Validate and sanitize name
 	sanitizedName, err := security.ValidateParticipantName(newName)
 	if err != nil {
-		log.Printf("Invalid participant name: %v", err)
-		sendError(err.Error())
-		return
+		return newCommandError(ErrCodeInvalidPayload, "%v", err)
 	}
 	newName = sanitizedName
 
 	// Update participant name in database
-	if err := h.roomManager.UpdateParticipantName(participantID, newName); err != nil {
-		log.Printf("Failed to update participant name: %v", err)
-		sendError("Failed to update name. Please try again.")
-		return
+	if err := h.roomManager.WithContext(ctx).UpdateParticipantName(participantID, newName); err != nil {
+		logger.Error("Failed to update participant name", logging.Err(err))
+		return newCommandError(ErrCodeInternal, "Failed to update name. Please try again.")
 	}
 
 	// Broadcast name update to all clients in the room
-	h.hub.BroadcastToRoom(roomID, &models.WSMessage{
-		Type: models.MsgTypeNameUpdated,
-		Payload: map[string]any{
-			"participantId": participantID,
-			"name":          newName,
-		},
+	h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
+		Type:    models.MsgTypeNameUpdated,
+		Payload: models.NameUpdatedPayload{ParticipantID: participantID, Name: newName},
 	})
 
-	log.Printf("Participant name updated: %s -> %s", participantID, newName)
+	logger.Info("Participant name updated")
+	return nil
 }
 
-func (h *WSHandler) handleUpdateRoomName(roomID string, msg *models.WSMessage, participantID string) {
-	// Helper to send error to the client
-	sendError := func(message string) {
-		client := h.hub.GetClient(roomID, participantID)
-		if client != nil {
-			h.hub.SendToClient(client, &models.WSMessage{
-				Type: models.MsgTypeError,
-				Payload: map[string]any{
-					"message": message,
-					"action":  "update_room_name",
-				},
-			})
-		}
-	}
+func (h *WSHandler) handleUpdateRoomName(ctx context.Context, roomID string, msg *models.IncomingMessage, participantID string) error {
+	logger := logging.ForCommand(roomID, participantID, models.MsgTypeUpdateRoomName)
 
 	// Verify participant is the room creator
 	if !h.roomManager.IsRoomCreator(roomID, participantID) {
-		log.Printf("Update room name rejected: participant %s is not room creator", participantID)
-		sendError("Only the room creator can change the room name")
-		return
+		return newCommandError(ErrCodeForbidden, "Only the room creator can change the room name")
 	}
 
 	// Extract new name from payload
-	payload, ok := msg.Payload.(map[string]any)
-	if !ok {
-		log.Printf("Invalid update room name payload format")
-		sendError("Invalid request format")
-		return
-	}
-
-	newName, ok := payload["name"].(string)
-	if !ok {
-		log.Printf("Invalid room name value type")
-		sendError("Invalid room name format")
-		return
+	var payload models.UpdateNamePayload
+	if err := msg.DecodePayload(&payload); err != nil {
+		return newCommandError(ErrCodeInvalidPayload, "Invalid request format")
 	}
+	newName := payload.Name
 
 	// Validate and sanitize room name
 	sanitizedName, err := security.ValidateRoomName(newName)
 	if err != nil {
-		log.Printf("Invalid room name: %v", err)
-		sendError(err.Error())
-		return
+		return newCommandError(ErrCodeInvalidPayload, "%v", err)
 	}
 	newName = sanitizedName
 
 	// Update room name in database
-	if err := h.roomManager.UpdateRoomName(roomID, newName); err != nil {
-		log.Printf("Failed to update room name: %v", err)
-		sendError("Failed to update room name. Please try again.")
-		return
+	if err := h.roomManager.WithContext(ctx).UpdateRoomName(roomID, newName); err != nil {
+		logger.Error("Failed to update room name", logging.Err(err))
+		return newCommandError(ErrCodeInternal, "Failed to update room name. Please try again.")
 	}
 
 	// Broadcast room name update to all clients
-	h.hub.BroadcastToRoom(roomID, &models.WSMessage{
-		Type: models.MsgTypeRoomNameUpdated,
-		Payload: map[string]any{
-			"name": newName,
-		},
+	h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
+		Type:    models.MsgTypeRoomNameUpdated,
+		Payload: models.RoomNameUpdatedPayload{Name: newName},
 	})
 
-	log.Printf("Room name updated: %s -> %s", roomID, newName)
+	logger.Info("Room name updated")
+	return nil
 }
 
-func (h *WSHandler) handleUpdateConfig(roomID string, msg *models.WSMessage, participantID string) {
+func (h *WSHandler) handleUpdateWeight(ctx context.Context, roomID string, msg *models.IncomingMessage, participantID string) error {
+	logger := logging.ForCommand(roomID, participantID, models.MsgTypeUpdateWeight)
+
+	// Verify participant is the room creator (facilitator)
+	if !h.roomManager.IsRoomCreator(roomID, participantID) {
+		return newCommandError(ErrCodeForbidden, "Only the room creator can change vote weights")
+	}
+
+	var payload models.UpdateWeightPayload
+	if err := msg.DecodePayload(&payload); err != nil {
+		return newCommandError(ErrCodeInvalidPayload, "Invalid request format")
+	}
+
+	targetID := payload.ParticipantID
+	if err := security.ValidateUUID(targetID); err != nil {
+		return newCommandError(ErrCodeInvalidPayload, "Invalid participant")
+	}
+
+	weight, err := security.ValidateParticipantWeight(payload.Weight)
+	if err != nil {
+		return newCommandError(ErrCodeInvalidPayload, "%v", err)
+	}
+
+	// Target must be a participant of this room
+	target, err := h.roomManager.GetParticipant(targetID)
+	if err != nil || target.GetString("room_id") != roomID {
+		return newCommandError(ErrCodeNotFound, "Participant not found")
+	}
+
+	if err := h.roomManager.WithContext(ctx).UpdateParticipantWeight(targetID, weight); err != nil {
+		logger.Error("Failed to update participant weight", logging.Err(err))
+		return newCommandError(ErrCodeInternal, "Failed to update vote weight. Please try again.")
+	}
+
+	// Broadcast weight update to all clients
+	h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
+		Type:    models.MsgTypeWeightUpdated,
+		Payload: models.WeightUpdatedPayload{ParticipantID: targetID, Weight: weight},
+	})
+
+	logger.Info("Participant weight updated", "target_id", targetID, "weight", weight)
+	return nil
+}
+
+// checkRoundNotStarted returns a *CommandError unless the room is voting and has no votes yet
+func (h *WSHandler) checkRoundNotStarted(roomID string) *CommandError {
+	roomState, err := h.getRoomState(roomID)
+	if err != nil {
+		return newCommandError(ErrCodeNotFound, "failed to get room state: %v", err)
+	}
+	if roomState != models.StateVoting {
+		return newCommandError(ErrCodeInvalidState, "Estimation dimensions can only change before the first vote of a round")
+	}
+	votes, err := h.roomManager.GetRoomVotes(roomID)
+	if err != nil {
+		return newCommandError(ErrCodeInternal, "failed to get votes: %v", err)
+	}
+	if len(votes) > 0 {
+		return newCommandError(ErrCodeInvalidState, "Estimation dimensions can only change before the first vote of a round")
+	}
+	return nil
+}
+
+func (h *WSHandler) handleUpdateConfig(ctx context.Context, roomID string, msg *models.IncomingMessage, participantID string) error {
+	logger := logging.ForCommand(roomID, participantID, models.MsgTypeUpdateConfig)
+
 	// Verify participant is the room creator
 	if !h.roomManager.IsRoomCreator(roomID, participantID) {
-		log.Printf("Config update rejected: participant %s is not room creator", participantID)
-		return
+		return newCommandError(ErrCodeForbidden, "Only the room creator can change the room settings")
 	}
 
 	// Extract config from payload
-	payload, ok := msg.Payload.(map[string]any)
-	if !ok {
-		log.Printf("Invalid update config payload format")
-		return
+	var payload models.UpdateConfigPayload
+	if err := msg.DecodePayload(&payload); err != nil {
+		return newCommandError(ErrCodeInvalidPayload, "Invalid request format")
 	}
+	config := payload.Config
 
-	configData, ok := payload["config"]
-	if !ok {
-		log.Printf("Config data missing from payload")
-		return
+	// Validate estimation dimensions and combine method
+	validator := services.NewVoteValidator()
+	if err := validator.ValidateDimensions(config.Dimensions); err != nil {
+		return newCommandError(ErrCodeInvalidPayload, "%v", err)
+	}
+	if err := validator.ValidateCombineMethod(config.CombineMethod); err != nil {
+		return newCommandError(ErrCodeInvalidPayload, "%v", err)
+	}
+	if err := security.ValidateRevealRule(config.RevealRule); err != nil {
+		return newCommandError(ErrCodeInvalidPayload, "%v", err)
 	}
 
-	// Convert to JSON and parse as RoomConfig
-	configJSON, err := json.Marshal(configData)
+	// Votes of the round were scored with the current dimensions: they only change before the first vote
+	current, err := h.aclService.GetRoomConfig(roomID)
 	if err != nil {
-		log.Printf("Failed to marshal config data: %v", err)
-		return
+		return newCommandError(ErrCodeNotFound, "room not found")
 	}
-
-	var config models.RoomConfig
-	if err := json.Unmarshal(configJSON, &config); err != nil {
-		log.Printf("Failed to parse config: %v", err)
-		return
+	if !current.SameScoring(&config) {
+		if cmdErr := h.checkRoundNotStarted(roomID); cmdErr != nil {
+			return cmdErr
+		}
 	}
 
 	// Update room config
-	if err := h.aclService.UpdateRoomConfig(roomID, participantID, &config); err != nil {
-		log.Printf("Failed to update room config: %v", err)
-		return
+	if err := h.aclService.UpdateRoomConfig(ctx, roomID, participantID, &config); err != nil {
+		logger.Error("Failed to update room config", logging.Err(err))
+		return newCommandError(ErrCodeInternal, "Failed to update room settings. Please try again.")
 	}
 
 	// Broadcast config update to all participants
 	// Clients will recalculate their permissions based on config + isCreator flag
-	h.hub.BroadcastToRoom(roomID, &models.WSMessage{
-		Type: models.MsgTypeConfigUpdated,
-		Payload: map[string]any{
-			"config": config,
-		},
+	h.hub.BroadcastToRoom(ctx, roomID, &models.WSMessage{
+		Type:    models.MsgTypeConfigUpdated,
+		Payload: models.ConfigUpdatedPayload{Config: config},
 	})
 
-	log.Printf("Room config updated for room %s", roomID)
+	logger.Info("Room config updated")
+	return nil
 }
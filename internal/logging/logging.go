@@ -0,0 +1,120 @@
+// Package logging configures the structured logger shared by the application.
+package logging
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Attribute keys used across log lines
+const (
+	RoomID        = "room_id"
+	ParticipantID = "participant_id"
+	MessageType   = "message_type"
+	Error         = "error"
+)
+
+// redacted replaces the value of sensitive attributes
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values never reach the logs: session secrets,
+// vote values, raw message payloads and URLs that embed credentials (Slack, webhooks)
+var sensitiveKeys = map[string]bool{
+	"authorization":  true,
+	"cookie":         true,
+	"password":       true,
+	"payload":        true,
+	"raw":            true,
+	"response_url":   true,
+	"secret":         true,
+	"session":        true,
+	"session_cookie": true,
+	"token":          true,
+	"url":            true,
+	"value":          true,
+	"vote":           true,
+}
+
+// New returns a logger writing to w. level is debug, info, warn or error (default info),
+// format is json or text (default text). Sensitive attributes are redacted.
+func New(w io.Writer, level, format string) *slog.Logger {
+	options := &slog.HandlerOptions{
+		Level:       parseLevel(level),
+		ReplaceAttr: redact,
+	}
+	if strings.EqualFold(format, "json") {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
+}
+
+// Setup installs the logger configured by LOG_LEVEL and LOG_FORMAT as the default,
+// which the standard log package then writes through as well
+func Setup() *slog.Logger {
+	logger := New(os.Stderr, os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+	slog.SetDefault(logger)
+	return logger
+}
+
+// ForRoom returns the default logger with the room and participant attached, when set
+func ForRoom(roomID, participantID string) *slog.Logger {
+	return ForCommand(roomID, participantID, "")
+}
+
+// ForCommand returns the default logger with the room, participant and message type attached, when set
+func ForCommand(roomID, participantID, msgType string) *slog.Logger {
+	attrs := make([]any, 0, 3)
+	if roomID != "" {
+		attrs = append(attrs, slog.String(RoomID, roomID))
+	}
+	if participantID != "" {
+		attrs = append(attrs, slog.String(ParticipantID, participantID))
+	}
+	if msgType != "" {
+		attrs = append(attrs, slog.String(MessageType, msgType))
+	}
+	return slog.Default().With(attrs...)
+}
+
+// Err returns the error attribute of a log line. The URL of a *url.Error is redacted,
+// as webhook and Slack URLs embed credentials.
+func Err(err error) slog.Attr {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.URL != "" {
+		return slog.String(Error, ErrorText(err))
+	}
+	return slog.Any(Error, err)
+}
+
+// ErrorText returns the message of err with the URL of a *url.Error redacted, as Err logs it
+func ErrorText(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.URL != "" {
+		return strings.ReplaceAll(err.Error(), urlErr.URL, redacted)
+	}
+	return err.Error()
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, redacted)
+	}
+	return attr
+}
@@ -0,0 +1,57 @@
+package models
+
+// Confidence levels on a 1-5 scale. Voters may also use the low/medium/high
+// labels, which map onto 1, 3 and 5. Zero means no confidence was given.
+const (
+	ConfidenceNone   = 0
+	ConfidenceLow    = 1
+	ConfidenceMedium = 3
+	ConfidenceHigh   = 5
+
+	MinConfidence = 1
+	MaxConfidence = 5
+
+	// LowConfidenceThreshold: an average strictly below this value is considered low confidence
+	LowConfidenceThreshold = 3.0
+)
+
+// ConfidenceLabels maps the named confidence levels to their numeric value
+var ConfidenceLabels = map[string]int{
+	"low":    ConfidenceLow,
+	"medium": ConfidenceMedium,
+	"high":   ConfidenceHigh,
+}
+
+// ConfidenceSummary aggregates confidence levels submitted with the votes of a round
+type ConfidenceSummary struct {
+	Average   float64     `json:"average"`
+	Count     int         `json:"count"`     // Number of votes that carried a confidence level
+	Breakdown map[int]int `json:"breakdown"` // level -> number of votes
+	Low       bool        `json:"low"`       // True if the average is below LowConfidenceThreshold
+}
+
+// SummarizeConfidence aggregates confidence levels, ignoring votes without one.
+// Returns nil when no vote carried a confidence level.
+func SummarizeConfidence(levels []int) *ConfidenceSummary {
+	summary := &ConfidenceSummary{
+		Breakdown: make(map[int]int),
+	}
+
+	var sum int
+	for _, level := range levels {
+		if level < MinConfidence || level > MaxConfidence {
+			continue
+		}
+		summary.Breakdown[level]++
+		summary.Count++
+		sum += level
+	}
+
+	if summary.Count == 0 {
+		return nil
+	}
+
+	summary.Average = float64(sum) / float64(summary.Count)
+	summary.Low = summary.Average < LowConfidenceThreshold
+	return summary
+}
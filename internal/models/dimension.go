@@ -0,0 +1,37 @@
+package models
+
+// MaxDimensions limits the number of estimation dimensions per room
+const MaxDimensions = 5
+
+// Combined score methods for multi-dimensional estimation
+const (
+	CombineSum      = "sum"      // Sum of all dimension values
+	CombineAverage  = "average"  // Plain average of dimension values
+	CombineWeighted = "weighted" // Weighted average using EstimationDimension.Weight
+	CombineMax      = "max"      // Highest dimension value
+)
+
+// EstimationDimension is a named axis (e.g. complexity, uncertainty, effort)
+// estimated separately with its own deck of values
+type EstimationDimension struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+	Weight float64  `json:"weight,omitempty"` // Weight in the combined score (defaults to 1)
+}
+
+// EffectiveWeight returns the dimension weight, defaulting to 1 when unset
+func (d EstimationDimension) EffectiveWeight() float64 {
+	if d.Weight <= 0 {
+		return 1
+	}
+	return d.Weight
+}
+
+// DimensionStats holds revealed statistics for a single estimation dimension
+type DimensionStats struct {
+	Name           string         `json:"name"`
+	Total          int            `json:"total"`
+	ValueBreakdown map[string]int `json:"valueBreakdown"`
+	Average        *float64       `json:"average,omitempty"` // Nil if no numeric votes
+	Consensus      bool           `json:"consensus"`
+}
@@ -1,21 +1,25 @@
 package models
 
 type WSMessage struct {
-	Type    string      `json:"type"`
-	RoomID  string      `json:"roomId,omitempty"`
-	Payload interface{} `json:"payload,omitempty"`
+	Type      string      `json:"type"`
+	RoomID    string      `json:"roomId,omitempty"`
+	RequestID string      `json:"requestId,omitempty"` // Echoes the requestId of the command answered by ack or error
+	Seq       uint64      `json:"seq,omitempty"`       // Position in the room's event log, stamped by the hub on broadcasts
+	Payload   interface{} `json:"payload,omitempty"`
 }
 
 // Client → Server message types
 const (
 	MsgTypeJoin           = "join"
 	MsgTypeVote           = "vote"
+	MsgTypeUnvote         = "unvote"
 	MsgTypeReveal         = "reveal"
 	MsgTypeReset          = "reset"
 	MsgTypeNextRound      = "next_round"
 	MsgTypeUpdateName     = "update_name"
 	MsgTypeUpdateRoomName = "update_room_name"
 	MsgTypeUpdateConfig   = "update_config"
+	MsgTypeUpdateWeight   = "update_weight"
 )
 
 // Server → Client message types
@@ -31,6 +35,10 @@ const (
 	MsgTypeNameUpdated          = "name_updated"
 	MsgTypeRoomNameUpdated      = "room_name_updated"
 	MsgTypeConfigUpdated        = "config_updated"
+	MsgTypeWeightUpdated        = "weight_updated"
 	MsgTypeAutoRevealCountdown  = "auto_reveal_countdown" // Countdown before auto-reveal
-	MsgTypeError                = "error"                 // Error message to client
+	MsgTypeAutoRevealCancelled  = "auto_reveal_cancelled" // Pending auto-reveal no longer applies
+	MsgTypeRoomExpired          = "room_expired"          // Room expired, actions are rejected
+	MsgTypeAck                  = "ack"                   // Command of this client succeeded
+	MsgTypeError                = "error"                 // Command of this client failed
 )
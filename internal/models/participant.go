@@ -9,10 +9,14 @@ const (
 	RoleSpectator ParticipantRole = "spectator"
 )
 
+// DefaultParticipantWeight is the vote weight of a participant unless a facilitator changes it
+const DefaultParticipantWeight = 1.0
+
 type Participant struct {
 	ID        string
 	Name      string
 	Role      ParticipantRole
+	Weight    float64 // Vote weight in averages and consensus (default 1)
 	Connected bool
 	JoinedAt  time.Time
 }
@@ -22,6 +26,7 @@ func NewParticipant(id, name string, role ParticipantRole) *Participant {
 		ID:        id,
 		Name:      name,
 		Role:      role,
+		Weight:    DefaultParticipantWeight,
 		Connected: false,
 		JoinedAt:  time.Now(),
 	}
@@ -0,0 +1,186 @@
+package models
+
+import "encoding/json"
+
+// IncomingMessage is a client → server message whose payload is decoded
+// into the typed struct matching its Type once the type has been validated
+type IncomingMessage struct {
+	Type      string          `json:"type"`
+	RoomID    string          `json:"roomId,omitempty"`
+	RequestID string          `json:"requestId,omitempty"` // Optional, echoed in the ack or error reply
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+// DecodePayload decodes the raw payload into dst
+func (m *IncomingMessage) DecodePayload(dst any) error {
+	if len(m.Payload) == 0 {
+		return nil
+	}
+	return json.Unmarshal(m.Payload, dst)
+}
+
+// Client → Server payloads
+
+// VotePayload is sent with "vote". Multi-dimensional rooms send Dimensions instead of Value.
+type VotePayload struct {
+	Value      string            `json:"value,omitempty"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+	Confidence any               `json:"confidence,omitempty"` // "low" | "medium" | "high" or 1-5
+}
+
+// EmptyPayload is sent with "unvote", "reveal", "reset" and "next_round",
+// and received with "room_reset" and "auto_reveal_cancelled"
+type EmptyPayload struct{}
+
+// UpdateNamePayload is sent with "update_name" and "update_room_name"
+type UpdateNamePayload struct {
+	Name string `json:"name"`
+}
+
+// UpdateConfigPayload is sent with "update_config" (creator only)
+type UpdateConfigPayload struct {
+	Config RoomConfig `json:"config"`
+}
+
+// UpdateWeightPayload is sent with "update_weight" (creator only)
+type UpdateWeightPayload struct {
+	ParticipantID string  `json:"participantId"`
+	Weight        float64 `json:"weight"`
+}
+
+// Server → Client payloads
+
+// RoomStatePayload is the complete state sync sent on connect/reconnect ("room_state")
+type RoomStatePayload struct {
+	Participants         []*Participant     `json:"participants"`
+	RoomState            string             `json:"roomState"`
+	RoundNumber          *int               `json:"roundNumber"`
+	VoteCount            int                `json:"voteCount"`
+	IsCreator            bool               `json:"isCreator"`
+	CurrentParticipantID string             `json:"currentParticipantId"`
+	ExpiresAt            string             `json:"expiresAt"` // ISO 8601
+	Permissions          ParticipantActions `json:"permissions"`
+	// Position in the room's event log the state includes. A client reconnecting with
+	// ?eventLog=&lastSeq= of its latest event is only sent the events it missed.
+	EventLog string `json:"eventLog"`
+	Seq      uint64 `json:"seq"`
+}
+
+// ParticipantActions lists what the receiving participant is allowed to do
+type ParticipantActions struct {
+	CanReset                 bool `json:"canReset"`
+	CanNewRound              bool `json:"canNewRound"`
+	CanReveal                bool `json:"canReveal"`
+	CanChangeVoteAfterReveal bool `json:"canChangeVoteAfterReveal"`
+}
+
+// ParticipantJoinedPayload is broadcast with "participant_joined"
+type ParticipantJoinedPayload struct {
+	Participant *Participant `json:"participant"`
+}
+
+// ParticipantLeftPayload is broadcast with "participant_left"
+type ParticipantLeftPayload struct {
+	ParticipantID string `json:"participantId"`
+}
+
+// VoteCastPayload is broadcast with "vote_cast" (value hidden); HasVoted is false when a vote was retracted
+type VoteCastPayload struct {
+	ParticipantID string `json:"participantId"`
+	HasVoted      bool   `json:"hasVoted"`
+}
+
+// VoteUpdatedPayload is broadcast with "vote_updated" when a vote changes after reveal
+type VoteUpdatedPayload struct {
+	ParticipantID   string            `json:"participantId"`
+	ParticipantName string            `json:"participantName"`
+	Value           string            `json:"value"`
+	Dimensions      map[string]string `json:"dimensions"`
+	Confidence      int               `json:"confidence"`
+}
+
+// RevealedVote is a single vote in "votes_revealed"
+type RevealedVote struct {
+	ParticipantID   string            `json:"participantId"`
+	ParticipantName string            `json:"participantName"`
+	Value           string            `json:"value"`
+	Weight          float64           `json:"weight"`
+	Dimensions      map[string]string `json:"dimensions"`
+	Confidence      int               `json:"confidence"`
+}
+
+// RevealStats are the round statistics sent with "votes_revealed"
+type RevealStats struct {
+	Total                  int                `json:"total"`
+	TotalWeight            float64            `json:"totalWeight"`
+	Weighted               bool               `json:"weighted"`
+	ValueBreakdown         map[string]int     `json:"valueBreakdown"`
+	ValueWeights           map[string]float64 `json:"valueWeights"`
+	Average                *float64           `json:"average,omitempty"`
+	Consensus              bool               `json:"consensus"`
+	Confidence             *ConfidenceSummary `json:"confidence,omitempty"`
+	LowConfidenceConsensus bool               `json:"lowConfidenceConsensus,omitempty"`
+	Dimensions             []DimensionStats   `json:"dimensions,omitempty"`
+	CombineMethod          string             `json:"combineMethod,omitempty"`
+}
+
+// VotesRevealedPayload is broadcast with "votes_revealed"
+type VotesRevealedPayload struct {
+	Votes []RevealedVote `json:"votes"`
+	Stats RevealStats    `json:"stats"`
+}
+
+// RoundCompletedPayload is broadcast with "round_completed"
+type RoundCompletedPayload struct {
+	NewRoundNumber int `json:"newRoundNumber"`
+}
+
+// NameUpdatedPayload is broadcast with "name_updated"
+type NameUpdatedPayload struct {
+	ParticipantID string `json:"participantId"`
+	Name          string `json:"name"`
+}
+
+// RoomNameUpdatedPayload is broadcast with "room_name_updated"
+type RoomNameUpdatedPayload struct {
+	Name string `json:"name"`
+}
+
+// ConfigUpdatedPayload is broadcast with "config_updated"
+type ConfigUpdatedPayload struct {
+	Config RoomConfig `json:"config"`
+}
+
+// WeightUpdatedPayload is broadcast with "weight_updated"
+type WeightUpdatedPayload struct {
+	ParticipantID string  `json:"participantId"`
+	Weight        float64 `json:"weight"`
+}
+
+// AutoRevealCountdownPayload is broadcast with "auto_reveal_countdown"
+type AutoRevealCountdownPayload struct {
+	Duration int `json:"duration"` // Milliseconds before clients send "reveal"
+}
+
+// RoomExpiredPayload is broadcast with "room_expired"
+type RoomExpiredPayload struct {
+	Message string `json:"message"`
+}
+
+// AckPayload is sent to a single client with "ack"
+type AckPayload struct {
+	Action string `json:"action"` // Message type that succeeded
+}
+
+// ErrorPayload is sent to a single client with "error"
+type ErrorPayload struct {
+	Code    string `json:"code"` // Stable error code, see ErrCodeRateLimited and the handlers ErrCode* constants
+	Message string `json:"message"`
+	Action  string `json:"action,omitempty"` // Message type that failed
+}
+
+// ErrCodeRateLimited is the error code of messages dropped by the connection rate limit
+const ErrCodeRateLimited = "rate_limited"
+
+// ErrCodeRoomBusy is the error code of messages dropped because their room could not keep up
+const ErrCodeRoomBusy = "room_busy"
@@ -0,0 +1,50 @@
+package models
+
+// Reveal rule modes for auto-reveal
+const (
+	RevealModeAll          = "all"           // Every voter has voted (default)
+	RevealModeAllConnected = "all_connected" // Every connected voter has voted, disconnected voters are ignored
+	RevealModeQuorum       = "quorum"        // At least Percentage percent of voters have voted
+	RevealModeMinimum      = "minimum"       // At least MinimumVotes votes have been cast
+)
+
+// RevealRule defines when a round is complete enough to auto-reveal
+type RevealRule struct {
+	Mode string `json:"mode"`
+
+	// Percentage: quorum of voters (1-100) required in quorum mode
+	Percentage int `json:"percentage,omitempty"`
+
+	// MinimumVotes: number of votes required in minimum mode
+	MinimumVotes int `json:"minimum_votes,omitempty"`
+}
+
+// VoteTally counts voters and votes of the current round for reveal rule evaluation
+type VoteTally struct {
+	Voters          int // Participants with the voter role
+	ConnectedVoters int // Voters currently connected
+	Votes           int // Votes cast by voters
+	ConnectedVotes  int // Votes cast by connected voters
+}
+
+// IsSatisfied reports whether the tally meets the rule. A round without votes never does.
+func (r RevealRule) IsSatisfied(tally VoteTally) bool {
+	if tally.Votes == 0 {
+		return false
+	}
+
+	switch r.Mode {
+	case RevealModeAllConnected:
+		return tally.ConnectedVoters > 0 && tally.ConnectedVotes >= tally.ConnectedVoters
+	case RevealModeQuorum:
+		if tally.Voters == 0 || r.Percentage <= 0 {
+			return false
+		}
+		// Integer comparison avoids float rounding: votes/voters >= percentage/100
+		return tally.Votes*100 >= r.Percentage*tally.Voters
+	case RevealModeMinimum:
+		return r.MinimumVotes > 0 && tally.Votes >= r.MinimumVotes
+	default:
+		return tally.Voters > 0 && tally.Votes >= tally.Voters
+	}
+}
@@ -23,8 +23,10 @@ type Room struct {
 	State                      RoomState   // Derived from CurrentRound.State
 	CurrentRound               *Round      // Current round for state derivation
 	Participants               map[string]*Participant
-	Votes                      map[string]string // Current round votes for rendering
-	ConsecutiveConsensusRounds int               // Number of consecutive rounds with 100% agreement
+	Votes                      map[string]string            // Current round votes for rendering
+	Confidences                map[string]int               // Current round vote confidence levels (participantID -> 1-5)
+	DimensionVotes             map[string]map[string]string // Current round per-dimension values (participantID -> dimension -> value)
+	ConsecutiveConsensusRounds int                          // Number of consecutive rounds with 100% agreement
 	CreatedAt                  time.Time
 	LastActivity               time.Time
 	ExpiresAt                  time.Time
@@ -39,6 +41,8 @@ func NewRoom(id, name, pointingMethod string, customValues []string) *Room {
 		State:          StateVoting,
 		Participants:   make(map[string]*Participant),
 		Votes:          make(map[string]string),
+		Confidences:    make(map[string]int),
+		DimensionVotes: make(map[string]map[string]string),
 		CreatedAt:      time.Now(),
 		LastActivity:   time.Now(),
 	}
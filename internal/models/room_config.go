@@ -1,8 +1,70 @@
 package models
 
+import "slices"
+
 // RoomConfig defines permissions and settings for a room
 type RoomConfig struct {
 	Permissions RoomPermissions `json:"permissions"`
+
+	// Dimensions: optional named axes estimated separately, each with its own deck
+	// When empty, the room uses a single deck (custom_values)
+	Dimensions []EstimationDimension `json:"dimensions,omitempty"`
+
+	// CombineMethod: how dimension values are combined into a single score
+	// (sum, average, weighted or max; defaults to average)
+	CombineMethod string `json:"combine_method,omitempty"`
+
+	// RevealRule: when auto-reveal considers the round complete (defaults to all voters)
+	RevealRule RevealRule `json:"reveal_rule"`
+}
+
+// Clone returns a copy that shares no slices with the original
+func (c *RoomConfig) Clone() *RoomConfig {
+	clone := *c
+	if c.Dimensions != nil {
+		clone.Dimensions = make([]EstimationDimension, len(c.Dimensions))
+		for i, dimension := range c.Dimensions {
+			dimension.Values = append([]string(nil), dimension.Values...)
+			clone.Dimensions[i] = dimension
+		}
+	}
+	return &clone
+}
+
+// IsMultiDimensional returns true if the room estimates several dimensions per vote
+func (c *RoomConfig) IsMultiDimensional() bool {
+	return c != nil && len(c.Dimensions) > 0
+}
+
+// SameScoring returns true if both configs turn votes into the same values: the same
+// dimensions (names, decks and weights) and, for multi-dimensional rooms, combine method
+func (c *RoomConfig) SameScoring(other *RoomConfig) bool {
+	if len(c.Dimensions) != len(other.Dimensions) {
+		return false
+	}
+	for i, dimension := range c.Dimensions {
+		o := other.Dimensions[i]
+		if dimension.Name != o.Name || dimension.EffectiveWeight() != o.EffectiveWeight() || !slices.Equal(dimension.Values, o.Values) {
+			return false
+		}
+	}
+	return !c.IsMultiDimensional() || c.GetCombineMethod() == other.GetCombineMethod()
+}
+
+// GetCombineMethod returns the configured combine method, defaulting to average
+func (c *RoomConfig) GetCombineMethod() string {
+	if c == nil || c.CombineMethod == "" {
+		return CombineAverage
+	}
+	return c.CombineMethod
+}
+
+// GetRevealRule returns the configured reveal rule, defaulting to all voters
+func (c *RoomConfig) GetRevealRule() RevealRule {
+	if c == nil || c.RevealRule.Mode == "" {
+		return RevealRule{Mode: RevealModeAll}
+	}
+	return c.RevealRule
 }
 
 // RoomPermissions defines who can perform specific actions
@@ -23,7 +85,7 @@ type RoomPermissions struct {
 	// if false, votes are locked once revealed
 	AllowChangeVoteAfterReveal bool `json:"allow_change_vote_after_reveal"`
 
-	// AutoReveal: if true, automatically reveal votes when all voters have voted
+	// AutoReveal: if true, automatically reveal votes when the reveal rule is met (all voters by default)
 	// if false, manual reveal is required (default behavior)
 	AutoReveal bool `json:"auto_reveal"`
 }
@@ -0,0 +1,22 @@
+package models
+
+// Story estimation states
+const (
+	StoryPending   = "pending"
+	StoryEstimated = "estimated"
+)
+
+// Estimate write-back states
+const (
+	StorySyncPending  = "pending"
+	StorySyncSynced   = "synced"
+	StorySyncFailed   = "failed"
+	StorySyncConflict = "conflict" // The issue already had a different estimate, which was kept
+)
+
+// Issue is an issue fetched from an issue tracker
+type Issue struct {
+	Key   string // Tracker-specific identifier, e.g. "PP-42"
+	Title string
+	URL   string // Link to the issue in the tracker, may be empty
+}
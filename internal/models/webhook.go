@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Webhook event names, matching the WebSocket message types they mirror
+const (
+	WebhookEventVotesRevealed     = MsgTypeVotesRevealed
+	WebhookEventRoundCompleted    = MsgTypeRoundCompleted
+	WebhookEventParticipantJoined = MsgTypeParticipantJoined
+	WebhookEventRoomExpired       = MsgTypeRoomExpired
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []string{
+	WebhookEventVotesRevealed,
+	WebhookEventRoundCompleted,
+	WebhookEventParticipantJoined,
+	WebhookEventRoomExpired,
+}
+
+// IsWebhookEvent checks if an event name can be subscribed to
+func IsWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed" // Gave up after the maximum number of attempts
+)
+
+// WebhookEvent is the signed JSON body POSTed to webhook URLs.
+// Data holds the same typed payload as the matching WebSocket message.
+type WebhookEvent struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	RoomID    string    `json:"roomId"`
+	RoomName  string    `json:"roomName,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data"`
+}
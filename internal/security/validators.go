@@ -2,10 +2,15 @@ package security
 
 import (
 	"fmt"
+	"math"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
+
+	"github.com/damione1/planning-poker/internal/models"
 )
 
 // Input length constraints
@@ -13,6 +18,9 @@ const (
 	MaxRoomNameLength        = 100
 	MaxParticipantNameLength = 50
 	MinNameLength            = 1
+	MaxParticipantWeight     = 10.0
+	MaxRevealMinimumVotes    = 100
+	MaxWebhookURLLength      = 2048
 )
 
 var (
@@ -104,6 +112,115 @@ func ValidateParticipantName(name string) (string, error) {
 	return ValidateName(name, MaxParticipantNameLength)
 }
 
+// ValidateConfidence validates an optional vote confidence level
+// Accepts the labels low/medium/high or a number from 1 to 5 (as JSON number or string)
+// Returns the normalized level, or models.ConfidenceNone if raw is nil
+func ValidateConfidence(raw interface{}) (int, error) {
+	switch v := raw.(type) {
+	case nil:
+		return models.ConfidenceNone, nil
+
+	case float64:
+		level := int(v)
+		if float64(level) != v || level < models.MinConfidence || level > models.MaxConfidence {
+			return 0, fmt.Errorf("confidence must be an integer between %d and %d", models.MinConfidence, models.MaxConfidence)
+		}
+		return level, nil
+
+	case string:
+		label := strings.ToLower(strings.TrimSpace(v))
+		if level, ok := models.ConfidenceLabels[label]; ok {
+			return level, nil
+		}
+		if level, err := strconv.Atoi(label); err == nil && level >= models.MinConfidence && level <= models.MaxConfidence {
+			return level, nil
+		}
+		return 0, fmt.Errorf("confidence must be low, medium, high or a number between %d and %d", models.MinConfidence, models.MaxConfidence)
+
+	default:
+		return 0, fmt.Errorf("confidence must be a string or a number")
+	}
+}
+
+// ValidateParticipantWeight validates a participant vote weight
+// Weights must be greater than zero and at most MaxParticipantWeight
+func ValidateParticipantWeight(weight float64) (float64, error) {
+	if math.IsNaN(weight) || weight <= 0 || weight > MaxParticipantWeight {
+		return 0, fmt.Errorf("weight must be greater than 0 and at most %g", MaxParticipantWeight)
+	}
+	return weight, nil
+}
+
+// ValidateRevealRule validates an auto-reveal rule
+// An empty mode is accepted and means all voters
+func ValidateRevealRule(rule models.RevealRule) error {
+	switch rule.Mode {
+	case "", models.RevealModeAll, models.RevealModeAllConnected:
+		return nil
+	case models.RevealModeQuorum:
+		if rule.Percentage < 1 || rule.Percentage > 100 {
+			return fmt.Errorf("quorum percentage must be between 1 and 100")
+		}
+		return nil
+	case models.RevealModeMinimum:
+		if rule.MinimumVotes < 1 || rule.MinimumVotes > MaxRevealMinimumVotes {
+			return fmt.Errorf("minimum votes must be between 1 and %d", MaxRevealMinimumVotes)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid reveal mode: %s", rule.Mode)
+	}
+}
+
+// ValidateWebhookURL validates a webhook target URL
+// Only absolute http(s) URLs without credentials are accepted, and no internal IP address or
+// localhost unless allowed by WebhookAllowedHostsEnv. See CheckWebhookTarget for host names.
+func ValidateWebhookURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || len(raw) > MaxWebhookURLLength {
+		return "", fmt.Errorf("webhook URL must be between 1 and %d characters", MaxWebhookURLLength)
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid webhook URL")
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", fmt.Errorf("webhook URL must use http or https")
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("webhook URL must have a host")
+	}
+	if parsed.User != nil {
+		return "", fmt.Errorf("webhook URL must not contain credentials")
+	}
+	if err := checkWebhookHost(parsed.Hostname()); err != nil {
+		return "", err
+	}
+	return parsed.String(), nil
+}
+
+// ValidateWebhookEvents validates webhook event subscriptions
+// An empty list subscribes to every event
+func ValidateWebhookEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return append([]string(nil), models.WebhookEvents...), nil
+	}
+
+	seen := make(map[string]bool, len(events))
+	valid := make([]string, 0, len(events))
+	for _, event := range events {
+		if !models.IsWebhookEvent(event) {
+			return nil, fmt.Errorf("unknown webhook event: %s", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			valid = append(valid, event)
+		}
+	}
+	return valid, nil
+}
+
 // SanitizeErrorMessage removes sensitive information from error messages
 // Returns a generic user-friendly error message
 func SanitizeErrorMessage(err error) string {
@@ -0,0 +1,127 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// WebhookAllowedHostsEnv lists the hosts outgoing webhooks may target even though they are
+// internal: comma-separated host names, IP addresses or CIDR ranges. Empty by default, it is
+// meant for a local test server or a receiver on the private network.
+const WebhookAllowedHostsEnv = "WEBHOOK_ALLOWED_HOSTS"
+
+// ErrWebhookTargetForbidden is returned for webhook targets on internal addresses
+var ErrWebhookTargetForbidden = errors.New("webhook URL must not target a loopback, private, link-local or unspecified address")
+
+// sharedAddressSpace is the carrier-grade NAT range, used for metadata services by some clouds
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// internalIP reports whether ip is an address webhooks must not reach from the server
+func internalIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return !ip.IsValid() ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified() ||
+		(ip.Is4() && ip.As4()[0] == 0) || // 0.0.0.0/8 reaches the local host
+		sharedAddressSpace.Contains(ip)
+}
+
+// webhookHostAllowed reports whether host, or ip when valid, is listed in WebhookAllowedHostsEnv
+func webhookHostAllowed(host string, ip netip.Addr) bool {
+	for _, entry := range strings.Split(os.Getenv(WebhookAllowedHostsEnv), ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case strings.EqualFold(entry, host):
+			return true
+		case !ip.IsValid():
+		case strings.Contains(entry, "/"):
+			if prefix, err := netip.ParsePrefix(entry); err == nil && prefix.Contains(ip.Unmap()) {
+				return true
+			}
+		default:
+			if addr, err := netip.ParseAddr(entry); err == nil && addr.Unmap() == ip.Unmap() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkWebhookHost rejects a webhook host that is, without resolving it, an internal address
+func checkWebhookHost(host string) error {
+	if webhookHostAllowed(host, netip.Addr{}) {
+		return nil
+	}
+	if host = strings.ToLower(strings.TrimSuffix(host, ".")); host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrWebhookTargetForbidden
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && internalIP(ip) && !webhookHostAllowed(host, ip) {
+		return ErrWebhookTargetForbidden
+	}
+	return nil
+}
+
+// CheckWebhookTarget resolves the host of a webhook URL validated by ValidateWebhookURL and
+// rejects it when it resolves to an internal address. A host that does not resolve is left
+// to WebhookDialContext, which checks every delivery.
+func CheckWebhookTarget(ctx context.Context, raw string) error {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return errors.New("invalid webhook URL")
+	}
+	host := parsed.Hostname()
+	if err := checkWebhookHost(host); err != nil {
+		return err
+	}
+	if _, err := netip.ParseAddr(host); err == nil || webhookHostAllowed(host, netip.Addr{}) {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if internalIP(addr) && !webhookHostAllowed(host, addr) {
+			return ErrWebhookTargetForbidden
+		}
+	}
+	return nil
+}
+
+// WebhookDialContext dials like dialer but refuses internal addresses, for the transport of
+// webhook deliveries. The address is checked once resolved, right before connecting, so a
+// host can't resolve to a public address when registered and an internal one when delivered
+// to (DNS rebinding), and redirects are covered too.
+func WebhookDialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		guarded := *dialer
+		guarded.Control = func(network, address string, _ syscall.RawConn) error {
+			ipPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if internalIP(ipPort.Addr()) && !webhookHostAllowed(host, ipPort.Addr()) {
+				return ErrWebhookTargetForbidden
+			}
+			return nil
+		}
+		return guarded.DialContext(ctx, network, address)
+	}
+}
@@ -12,12 +12,14 @@ import (
 // WebSocket message type validation
 var validMessageTypes = map[string]bool{
 	models.MsgTypeVote:           true,
+	models.MsgTypeUnvote:         true,
 	models.MsgTypeReveal:         true,
 	models.MsgTypeReset:          true,
 	models.MsgTypeNextRound:      true,
 	models.MsgTypeUpdateName:     true,
 	models.MsgTypeUpdateRoomName: true,
 	models.MsgTypeUpdateConfig:   true,
+	models.MsgTypeUpdateWeight:   true,
 }
 
 // IsValidMessageType checks if a WebSocket message type is valid
@@ -101,9 +103,26 @@ func ValidateMessagePayload(msgType string, payload interface{}) error {
 
 	switch msgType {
 	case models.MsgTypeVote:
-		// Vote must have value field
-		if _, ok := payloadMap["value"].(string); !ok {
-			return fmt.Errorf("vote payload must have string 'value' field")
+		// Vote must have a value field, or one value per dimension in multi-dimensional rooms
+		dimensions, hasDimensions := payloadMap["dimensions"]
+		if !hasDimensions {
+			if _, ok := payloadMap["value"].(string); !ok {
+				return fmt.Errorf("vote payload must have string 'value' field")
+			}
+		} else {
+			dimensionMap, ok := dimensions.(map[string]interface{})
+			if !ok || len(dimensionMap) == 0 || len(dimensionMap) > models.MaxDimensions {
+				return fmt.Errorf("vote 'dimensions' must be an object with 1 to %d entries", models.MaxDimensions)
+			}
+			for name, value := range dimensionMap {
+				if _, ok := value.(string); !ok {
+					return fmt.Errorf("vote dimension '%s' must have a string value", name)
+				}
+			}
+		}
+		// Confidence is optional
+		if _, err := ValidateConfidence(payloadMap["confidence"]); err != nil {
+			return fmt.Errorf("invalid vote confidence: %w", err)
 		}
 
 	case models.MsgTypeUpdateName, models.MsgTypeUpdateRoomName:
@@ -118,7 +137,16 @@ func ValidateMessagePayload(msgType string, payload interface{}) error {
 			return fmt.Errorf("config update payload must have 'config' field")
 		}
 
-	case models.MsgTypeReveal, models.MsgTypeReset, models.MsgTypeNextRound:
+	case models.MsgTypeUpdateWeight:
+		// Weight update must target a participant with a numeric weight
+		if _, ok := payloadMap["participantId"].(string); !ok {
+			return fmt.Errorf("weight update payload must have string 'participantId' field")
+		}
+		if _, ok := payloadMap["weight"].(float64); !ok {
+			return fmt.Errorf("weight update payload must have numeric 'weight' field")
+		}
+
+	case models.MsgTypeUnvote, models.MsgTypeReveal, models.MsgTypeReset, models.MsgTypeNextRound:
 		// These message types don't require specific payload validation
 		// Empty payload is acceptable
 	}
@@ -1,44 +1,123 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
+
+	"github.com/pocketbase/pocketbase/core"
 
 	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/tracing"
 )
 
 // ACLService handles permission checks for room actions
 type ACLService struct {
 	roomManager *RoomManager
+
+	// Parsed room configs, dropped whenever a room's config changes
+	mu         sync.RWMutex
+	configs    map[string]*models.RoomConfig
+	generation uint64 // Bumped on invalidation so loads racing with it are not cached
 }
 
 func NewACLService(rm *RoomManager) *ACLService {
-	return &ACLService{
+	acl := &ACLService{
 		roomManager: rm,
+		configs:     make(map[string]*models.RoomConfig),
 	}
+
+	// Catch config edits saved outside UpdateRoomConfig, e.g. in the PocketBase dashboard
+	rm.app.OnRecordAfterUpdateSuccess("rooms").BindFunc(func(e *core.RecordEvent) error {
+		// Activity timestamps are saved far more often and leave the config alone
+		if e.Record.GetString("config") != e.Record.Original().GetString("config") {
+			acl.invalidate(e.Record.Id)
+		}
+		// The room state store would otherwise keep serving the room as it was
+		if rm.state != nil {
+			rm.state.Invalidate(e.Record.Id)
+		}
+		return e.Next()
+	})
+	rm.app.OnRecordAfterDeleteSuccess("rooms").BindFunc(func(e *core.RecordEvent) error {
+		acl.invalidate(e.Record.Id)
+		if rm.state != nil {
+			rm.state.Invalidate(e.Record.Id) // Dropped once it is found missing
+		}
+		return e.Next()
+	})
+
+	return acl
 }
 
-// GetRoomConfig retrieves and parses room configuration
+// GetRoomConfig retrieves and parses room configuration.
+// The parsed config is cached until the room's config changes; callers get their own copy.
 func (acl *ACLService) GetRoomConfig(roomID string) (*models.RoomConfig, error) {
-	room, err := acl.roomManager.GetRoom(roomID)
+	acl.mu.RLock()
+	config, ok := acl.configs[roomID]
+	generation := acl.generation
+	acl.mu.RUnlock()
+	if ok {
+		return config.Clone(), nil
+	}
+
+	// Read from the database rather than the room state so edits made elsewhere are seen
+	room, err := acl.roomManager.app.FindRecordById("rooms", roomID)
 	if err != nil {
 		return nil, fmt.Errorf("room not found: %w", err)
 	}
+	config = parseRoomConfig(room.GetString("config"))
+
+	acl.mu.Lock()
+	if acl.generation == generation {
+		acl.configs[roomID] = config
+	}
+	acl.mu.Unlock()
 
-	configJSON := room.GetString("config")
+	return config.Clone(), nil
+}
 
+// parseRoomConfig parses a stored room config, falling back to the default
+func parseRoomConfig(configJSON string) *models.RoomConfig {
 	// If no config exists, return default
 	if configJSON == "" {
-		return models.DefaultRoomConfig(), nil
+		return models.DefaultRoomConfig()
 	}
 
 	var config models.RoomConfig
 	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
 		// If parsing fails, return default config
-		return models.DefaultRoomConfig(), nil
+		return models.DefaultRoomConfig()
 	}
 
-	return &config, nil
+	return &config
+}
+
+// invalidate drops a room's cached config
+func (acl *ACLService) invalidate(roomID string) {
+	acl.mu.Lock()
+	delete(acl.configs, roomID)
+	acl.generation++
+	acl.mu.Unlock()
+}
+
+// Permissions returns every action a participant is allowed to take in a room
+func (acl *ACLService) Permissions(roomID, participantID string) (models.ParticipantActions, error) {
+	config, err := acl.GetRoomConfig(roomID)
+	if err != nil {
+		return models.ParticipantActions{}, err
+	}
+
+	// Room creator is always allowed to run the round
+	isCreator := acl.roomManager.IsRoomCreator(roomID, participantID)
+
+	return models.ParticipantActions{
+		CanReset:                 isCreator || config.Permissions.AllowAllReset,
+		CanNewRound:              isCreator || config.Permissions.AllowAllNewRound,
+		CanReveal:                isCreator || config.Permissions.AllowAllReveal,
+		CanChangeVoteAfterReveal: config.Permissions.AllowChangeVoteAfterReveal,
+	}, nil
 }
 
 // CanTriggerNewRound checks if participant can create a new round
@@ -96,27 +175,33 @@ func (acl *ACLService) CanChangeVoteAfterReveal(roomID string) (bool, error) {
 	return config.Permissions.AllowChangeVoteAfterReveal, nil
 }
 
-// UpdateRoomConfig updates room configuration (creator only)
-func (acl *ACLService) UpdateRoomConfig(roomID, participantID string, config *models.RoomConfig) error {
+// UpdateRoomConfig updates room configuration (creator only). ctx carries the trace the update is part of.
+func (acl *ACLService) UpdateRoomConfig(ctx context.Context, roomID, participantID string, config *models.RoomConfig) error {
+	rm := acl.roomManager.WithContext(ctx)
+	defer rm.observe("UpdateRoomConfig", models.MsgTypeUpdateConfig, tracing.RoomID.String(roomID))()
+
 	// Only room creator can update config
-	if !acl.roomManager.IsRoomCreator(roomID, participantID) {
+	if !rm.IsRoomCreator(roomID, participantID) {
 		return fmt.Errorf("unauthorized: only room creator can update config")
 	}
 
-	room, err := acl.roomManager.GetRoom(roomID)
-	if err != nil {
-		return err
-	}
-
 	configJSON, err := json.Marshal(config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	room.Set("config", string(configJSON))
-	if err := acl.roomManager.app.Save(room); err != nil {
-		return fmt.Errorf("failed to save room config: %w", err)
-	}
-
-	return nil
+	err = rm.syncRoom(roomID, func(db *RoomManager) error {
+		room, err := db.GetRoom(roomID)
+		if err != nil {
+			return err
+		}
+
+		room.Set("config", string(configJSON))
+		if err := db.app.Save(room); err != nil {
+			return fmt.Errorf("failed to save room config: %w", err)
+		}
+		return nil
+	})
+	acl.invalidate(roomID)
+	return err
 }
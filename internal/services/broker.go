@@ -0,0 +1,112 @@
+package services
+
+import (
+	"sync"
+)
+
+// Broker relays room messages between hub instances, so participants
+// connected to different replicas see each other's broadcasts.
+// Messages are keyed by room ID; every subscriber of a room receives every message published to it,
+// including messages published by the same instance. The event logs of rooms are kept
+// by the broker too, so that a client can resume on any instance.
+type Broker interface {
+	EventLogs
+
+	// Publish sends a message to every subscriber of the room
+	Publish(roomID string, message []byte) error
+	// Subscribe calls handler for each message published to the room until unsubscribed.
+	// Handlers must not block.
+	Subscribe(roomID string, handler func(message []byte)) (Subscription, error)
+	// CountConnections adds delta to the open connections of a participant of the room on
+	// every instance and returns their new count, never below zero
+	CountConnections(roomID, participantID string, delta int) (int, error)
+	Close() error
+}
+
+// Subscription is an active room subscription
+type Subscription interface {
+	Unsubscribe() error
+}
+
+// MemoryBroker is an in-process Broker for a single node, or for several hubs in one process
+type MemoryBroker struct {
+	*memoryEventLogs
+
+	mu            sync.RWMutex
+	subscriptions map[string]map[*memorySubscription]bool
+	connections   map[participantKey]int
+}
+
+type memorySubscription struct {
+	broker  *MemoryBroker
+	roomID  string
+	handler func(message []byte)
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		memoryEventLogs: newMemoryEventLogs(),
+		subscriptions:   make(map[string]map[*memorySubscription]bool),
+		connections:     make(map[participantKey]int),
+	}
+}
+
+// Publish delivers the message synchronously to the room's subscribers
+func (b *MemoryBroker) Publish(roomID string, message []byte) error {
+	b.mu.RLock()
+	handlers := make([]func([]byte), 0, len(b.subscriptions[roomID]))
+	for sub := range b.subscriptions[roomID] {
+		handlers = append(handlers, sub.handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(roomID string, handler func(message []byte)) (Subscription, error) {
+	sub := &memorySubscription{broker: b, roomID: roomID, handler: handler}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscriptions[roomID] == nil {
+		b.subscriptions[roomID] = make(map[*memorySubscription]bool)
+	}
+	b.subscriptions[roomID][sub] = true
+	return sub, nil
+}
+
+func (b *MemoryBroker) CountConnections(roomID, participantID string, delta int) (int, error) {
+	key := participantKey{roomID: roomID, participantID: participantID}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	count := max(b.connections[key]+delta, 0)
+	if count == 0 {
+		delete(b.connections, key)
+	} else {
+		b.connections[key] = count
+	}
+	return count, nil
+}
+
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions = make(map[string]map[*memorySubscription]bool)
+	return nil
+}
+
+func (s *memorySubscription) Unsubscribe() error {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	subs := s.broker.subscriptions[s.roomID]
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(s.broker.subscriptions, s.roomID)
+	}
+	return nil
+}
@@ -2,18 +2,23 @@ package services
 
 import (
 	"context"
-	"log"
+	"encoding/json"
+	"errors"
+	"io"
 	"sync"
 	"time"
 
-	"github.com/coder/websocket"
 	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/logging"
 	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Client represents a single WebSocket connection with its own send goroutine
+// Client represents a single connection (WebSocket or event stream) with its own send goroutine
 type Client struct {
-	conn          *websocket.Conn
+	limits        config.Limits
+	transport     Transport
 	send          chan []byte
 	hub           *Hub
 	roomID        string
@@ -24,6 +29,15 @@ type Client struct {
 	rateLimitMu   sync.Mutex
 	lastReset     time.Time
 
+	// Tracing: spans of the connection's messages link to the connection's request
+	connection    trace.Link
+
+	// Set by Hub.Resume, closed once the client is registered
+	registered    chan struct{}
+	// Messages held back while Hub.Resume reads the missed events, guarded by closeMu
+	holding       bool
+	held          [][]byte
+
 	// Lifecycle
 	ctx           context.Context
 	cancel        context.CancelFunc
@@ -32,13 +46,14 @@ type Client struct {
 	done          chan struct{}
 }
 
-// NewClient creates a new client instance
-func NewClient(conn *websocket.Conn, hub *Hub, roomID, participantID string) *Client {
+// NewClient creates a new client instance over transport with the timeouts, buffer and rate limit of cfg
+func NewClient(cfg *config.Config, transport Transport, hub *Hub, roomID, participantID string) *Client {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Client{
-		conn:          conn,
-		send:          make(chan []byte, config.ClientSendBufferSize),
+		limits:        cfg.Limits,
+		transport:     transport,
+		send:          make(chan []byte, cfg.Limits.ClientSendBufferSize),
 		hub:           hub,
 		roomID:        roomID,
 		participantID: participantID,
@@ -49,6 +64,12 @@ func NewClient(conn *websocket.Conn, hub *Hub, roomID, participantID string) *Cl
 	}
 }
 
+// SetTraceContext links the spans of the client's messages to the span in ctx, usually
+// the WebSocket upgrade request. Must be called before Start.
+func (c *Client) SetTraceContext(ctx context.Context) {
+	c.connection = trace.LinkFromContext(ctx)
+}
+
 // Start begins the client's read and write pumps
 func (c *Client) Start() {
 	go c.writePump()
@@ -57,7 +78,7 @@ func (c *Client) Start() {
 
 // writePump handles outgoing messages to the WebSocket connection
 func (c *Client) writePump() {
-	ticker := time.NewTicker(config.PingInterval)
+	ticker := time.NewTicker(c.limits.PingInterval)
 	defer func() {
 		ticker.Stop()
 		c.Close()
@@ -68,17 +89,17 @@ func (c *Client) writePump() {
 		case message, ok := <-c.send:
 			if !ok {
 				// Channel closed, connection is closing
-				_ = c.conn.Close(websocket.StatusNormalClosure, "")
+				_ = c.transport.Close()
 				return
 			}
 
 			// Use context with timeout for write operations
-			writeCtx, cancel := context.WithTimeout(c.ctx, config.WriteTimeout)
-			err := c.conn.Write(writeCtx, websocket.MessageText, message)
+			writeCtx, cancel := context.WithTimeout(c.ctx, c.limits.WriteTimeout)
+			err := c.transport.Write(writeCtx, message)
 			cancel()
 
 			if err != nil {
-				log.Printf("❌ Write error (room=%s, participant=%s): %v", c.roomID, c.participantID, err)
+				logging.ForRoom(c.roomID, c.participantID).Warn("Write failed", logging.Err(err))
 				c.hub.metrics.IncrementBroadcastErrors()
 				return
 			}
@@ -86,12 +107,12 @@ func (c *Client) writePump() {
 
 		case <-ticker.C:
 			// Send ping to keep connection alive
-			pingCtx, cancel := context.WithTimeout(c.ctx, config.WriteTimeout)
-			err := c.conn.Ping(pingCtx)
+			pingCtx, cancel := context.WithTimeout(c.ctx, c.limits.WriteTimeout)
+			err := c.transport.Ping(pingCtx)
 			cancel()
 
 			if err != nil {
-				log.Printf("❌ Ping error (room=%s): %v", c.roomID, err)
+				logging.ForRoom(c.roomID, c.participantID).Warn("Ping failed", logging.Err(err))
 				return
 			}
 
@@ -101,7 +122,7 @@ func (c *Client) writePump() {
 	}
 }
 
-// readPump handles incoming messages from the WebSocket connection
+// readPump handles incoming messages from the connection
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.Unregister(c.roomID, c)
@@ -109,14 +130,10 @@ func (c *Client) readPump() {
 	}()
 
 	for {
-		// Use context with timeout for read operations
-		readCtx, cancel := context.WithTimeout(c.ctx, config.PongTimeout)
-		_, message, err := c.conn.Read(readCtx)
-		cancel()
-
+		message, err := c.transport.Read(c.ctx)
 		if err != nil {
-			if websocket.CloseStatus(err) != websocket.StatusNormalClosure {
-				log.Printf("❌ Read error (room=%s, participant=%s): %v", c.roomID, c.participantID, err)
+			if !errors.Is(err, io.EOF) {
+				logging.ForRoom(c.roomID, c.participantID).Warn("Read failed", logging.Err(err))
 				c.hub.metrics.IncrementConnectionErrors()
 			}
 			return
@@ -124,28 +141,54 @@ func (c *Client) readPump() {
 
 		// Rate limiting check
 		if !c.checkRateLimit() {
-			log.Printf("⚠️  Rate limit exceeded (room=%s, participant=%s)", c.roomID, c.participantID)
+			logging.ForRoom(c.roomID, c.participantID).Warn("Rate limit exceeded")
 			c.hub.metrics.IncrementRateLimitViolations()
 
-			// Send rate limit error to client
+			// Send rate limit error to client, answering the dropped command when it has a requestId
+			var dropped models.IncomingMessage
+			_ = json.Unmarshal(message, &dropped) // Best effort
 			errMsg := &models.WSMessage{
-				Type: "error",
-				Payload: map[string]string{
-					"message": "Rate limit exceeded. Please slow down.",
-				},
+				Type:      models.MsgTypeError,
+				RequestID: dropped.RequestID,
+				Payload:   models.ErrorPayload{Code: models.ErrCodeRateLimited, Message: "Rate limit exceeded. Please slow down.", Action: dropped.Type},
 			}
-			c.hub.SendToClient(c, errMsg)
+			c.reply(errMsg)
 			continue
 		}
 
 		c.hub.metrics.IncrementMessagesReceived()
 
-		// Forward message to hub for processing
-		c.hub.handleMessage <- &ClientMessage{
-			Client:  c,
-			Message: message,
+		// Queue message on the room's worker, waiting a while when the room is backed up.
+		// Each message starts a trace of its own, linked to the connection.
+		ctx, span := tracing.Tracer().Start(context.Background(), "ws.receive",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithLinks(c.connection),
+			trace.WithAttributes(tracing.RoomID.String(c.roomID), tracing.ParticipantID.String(c.participantID)),
+		)
+		ctx = WithReply(ctx, c.reply)
+		if err := c.hub.Dispatch(ctx, c.roomID, c.participantID, message); err != nil {
+			logging.ForRoom(c.roomID, c.participantID).Warn("Room queue full, command dropped")
+
+			var dropped models.IncomingMessage
+			_ = json.Unmarshal(message, &dropped) // Best effort
+			c.reply(&models.WSMessage{
+				Type:      models.MsgTypeError,
+				RequestID: dropped.RequestID,
+				Payload:   models.ErrorPayload{Code: models.ErrCodeRoomBusy, Message: "The room is busy. Please try again.", Action: dropped.Type},
+			})
 		}
+		span.End()
+	}
+}
+
+// reply answers a message of the client. Errors go to all of the participant's clients,
+// so that every open tab learns of a failed action.
+func (c *Client) reply(message *models.WSMessage) {
+	if message.Type == models.MsgTypeError && c.participantID != "" {
+		c.hub.SendToParticipant(c.roomID, c.participantID, message)
+		return
 	}
+	c.hub.SendToClient(c, message)
 }
 
 // checkRateLimit verifies the client hasn't exceeded message rate limits
@@ -154,13 +197,13 @@ func (c *Client) checkRateLimit() bool {
 	defer c.rateLimitMu.Unlock()
 
 	now := time.Now()
-	if now.Sub(c.lastReset) > config.RateLimitWindow {
+	if now.Sub(c.lastReset) > c.limits.RateLimitWindow {
 		c.messageCount = 0
 		c.lastReset = now
 	}
 
 	c.messageCount++
-	return c.messageCount <= config.MaxMessagesPerSecond
+	return c.messageCount <= c.limits.MaxMessagesPerSecond
 }
 
 // Send queues a message for sending to the client
@@ -171,19 +214,60 @@ func (c *Client) Send(message []byte) bool {
 	if c.closed {
 		return false
 	}
+	if c.holding {
+		c.held = append(c.held, message)
+		return true
+	}
+	return c.queue(message)
+}
 
+// queue adds a message to the send buffer. Must hold closeMu.
+func (c *Client) queue(message []byte) bool {
 	select {
 	case c.send <- message:
 		return true
 	default:
 		// Channel full, client is too slow
-		log.Printf("⚠️  Send buffer full, closing slow client (room=%s, participant=%s)", c.roomID, c.participantID)
+		logging.ForRoom(c.roomID, c.participantID).Warn("Send buffer full, closing slow client")
 		c.hub.metrics.IncrementBroadcastErrors()
 		go c.Close()
 		return false
 	}
 }
 
+// hold holds back the messages sent to the client until release
+func (c *Client) hold() {
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+	c.holding = true
+}
+
+// release queues the missed events, then the messages held back except the events up to
+// last, which the client already has
+func (c *Client) release(missed [][]byte, last uint64) {
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+
+	held := c.held
+	c.holding, c.held = false, nil
+	if c.closed {
+		return
+	}
+	for _, message := range missed {
+		if !c.queue(message) {
+			return
+		}
+	}
+	for _, message := range held {
+		if seq := seqOf(message); seq != 0 && seq <= last {
+			continue
+		}
+		if !c.queue(message) {
+			return
+		}
+	}
+}
+
 // Close cleanly shuts down the client connection
 func (c *Client) Close() {
 	c.closeMu.Lock()
@@ -197,16 +281,10 @@ func (c *Client) Close() {
 	c.cancel()
 	close(c.send)
 	close(c.done)
-	_ = c.conn.Close(websocket.StatusNormalClosure, "")
+	_ = c.transport.Close()
 }
 
 // Done returns a channel that's closed when the client is done
 func (c *Client) Done() <-chan struct{} {
 	return c.done
 }
-
-// ClientMessage represents a message received from a client
-type ClientMessage struct {
-	Client  *Client
-	Message []byte
-}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventPosition identifies an event in the event log of a room. Sequence numbers
+// only compare within one log: a room gets a new log once its log expired.
+type EventPosition struct {
+	Log string
+	Seq uint64
+}
+
+// EventLogs keep the latest broadcasts of each room, stamped with a sequence number, so
+// that a reconnecting client can catch up on the events it missed. A room's log is
+// kept for a retention period after its latest event, including the moments after its
+// last client left. Brokers share them between instances.
+type EventLogs interface {
+	// AppendEvent stamps an encoded message with the next sequence number of the room's
+	// log, starting a new log when the room has none, and keeps the latest size events.
+	// Returns the stamped message.
+	AppendEvent(roomID string, message []byte, size int, retention time.Duration) ([]byte, error)
+	// EventsSince returns the stamped events of the room after from, oldest first. Returns
+	// false when from is not a position of the room's log or some of the events after it
+	// are no longer kept.
+	EventsSince(roomID string, from EventPosition) ([][]byte, bool, error)
+	// EventLogPosition returns the position of the room's latest event, starting a new
+	// log when the room has none
+	EventLogPosition(roomID string, retention time.Duration) (EventPosition, error)
+}
+
+// eventLog is the log of one room: the latest stamped events
+type eventLog struct {
+	id      string
+	seq     uint64   // Sequence number of the latest event
+	events  [][]byte // Ring of the latest stamped events, the oldest at start once full
+	start   int
+	expires time.Time
+}
+
+func newEventLog(size int) *eventLog {
+	return &eventLog{id: uuid.NewString(), events: make([][]byte, 0, size)}
+}
+
+// position returns the position of the latest event
+func (l *eventLog) position() EventPosition {
+	return EventPosition{Log: l.id, Seq: l.seq}
+}
+
+// append stamps an encoded message with the next sequence number and keeps it.
+// Returns the stamped message.
+func (l *eventLog) append(data []byte) []byte {
+	l.seq++
+	stamped := withSeq(data, l.seq)
+	if len(l.events) < cap(l.events) {
+		l.events = append(l.events, stamped)
+	} else {
+		l.events[l.start] = stamped
+		l.start = (l.start + 1) % len(l.events)
+	}
+	return stamped
+}
+
+// since returns the events after from, oldest first. Returns false when from is not
+// a position of this log or some of the events after it are no longer kept.
+func (l *eventLog) since(from EventPosition) ([][]byte, bool) {
+	if from.Log != l.id || from.Seq > l.seq {
+		return nil, false
+	}
+	missed := int(l.seq - from.Seq)
+	if missed > len(l.events) {
+		return nil, false
+	}
+
+	events := make([][]byte, 0, missed)
+	for i := len(l.events) - missed; i < len(l.events); i++ {
+		events = append(events, l.events[(l.start+i)%len(l.events)])
+	}
+	return events, true
+}
+
+// memoryEventLogs keeps event logs in memory, for the hubs of one process
+type memoryEventLogs struct {
+	mu    sync.Mutex
+	logs  map[string]*eventLog
+	swept time.Time // Expired logs are dropped at most once per retention period
+}
+
+func newMemoryEventLogs() *memoryEventLogs {
+	return &memoryEventLogs{logs: make(map[string]*eventLog)}
+}
+
+func (m *memoryEventLogs) AppendEvent(roomID string, message []byte, size int, retention time.Duration) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	log := m.live(roomID, size, retention)
+	return log.append(message), nil
+}
+
+func (m *memoryEventLogs) EventsSince(roomID string, from EventPosition) ([][]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	log, ok := m.logs[roomID]
+	if !ok || time.Now().After(log.expires) {
+		return nil, false, nil
+	}
+	events, ok := log.since(from)
+	return events, ok, nil
+}
+
+func (m *memoryEventLogs) EventLogPosition(roomID string, retention time.Duration) (EventPosition, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	log, ok := m.logs[roomID]
+	if !ok || time.Now().After(log.expires) {
+		// An empty log: a size is only needed once events are kept, see AppendEvent
+		log = m.live(roomID, 0, retention)
+	}
+	return log.position(), nil
+}
+
+// live returns the unexpired log of a room, extending its retention. Must hold m.mu.
+func (m *memoryEventLogs) live(roomID string, size int, retention time.Duration) *eventLog {
+	now := time.Now()
+	if now.Sub(m.swept) > retention {
+		for id, log := range m.logs {
+			if now.After(log.expires) {
+				delete(m.logs, id)
+			}
+		}
+		m.swept = now
+	}
+
+	log, ok := m.logs[roomID]
+	if !ok || now.After(log.expires) {
+		log = newEventLog(size)
+		m.logs[roomID] = log
+	} else if cap(log.events) == 0 {
+		log.events = make([][]byte, 0, size) // Started by EventLogPosition, still empty
+	}
+	log.expires = now.Add(retention)
+	return log
+}
+
+// withSeq adds "seq" to an encoded models.WSMessage, which is always a JSON object
+func withSeq(data []byte, seq uint64) []byte {
+	stamped := make([]byte, 0, len(data)+len(`"seq":,`)+20)
+	stamped = append(stamped, `{"seq":`...)
+	stamped = strconv.AppendUint(stamped, seq, 10)
+	if len(data) > len("{}") {
+		stamped = append(stamped, ',')
+	}
+	return append(stamped, data[1:]...)
+}
+
+// seqOf returns the sequence number of a message stamped by withSeq, 0 for other messages
+func seqOf(data []byte) uint64 {
+	rest, ok := bytes.CutPrefix(data, []byte(`{"seq":`))
+	if !ok {
+		return 0
+	}
+	end := bytes.IndexAny(rest, ",}")
+	if end < 0 {
+		return 0
+	}
+	seq, _ := strconv.ParseUint(string(rest[:end]), 10, 64)
+	return seq
+}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/models"
+)
+
+// How GitHubTracker stores estimates
+const (
+	GitHubEstimateLabel = "label" // An estimate label such as "points:5"
+	GitHubEstimateField = "field" // A number field of a GitHub Project (Projects v2)
+)
+
+// GitHubConfig configures the GitHub Issues client
+type GitHubConfig struct {
+	BaseURL       string // REST API root, defaults to config.DefaultGitHubAPIURL
+	GraphQLURL    string // GraphQL endpoint, derived from BaseURL by default, see githubGraphQLURL
+	Token         string
+	Repo          string // Default "owner/name" for queries without a repo:, org: or user: qualifier
+	EstimateMode  string // GitHubEstimateLabel (default) or GitHubEstimateField
+	LabelPrefix   string // Estimate label prefix, defaults to config.DefaultGitHubLabelPrefix
+	ProjectNumber int    // Project holding the estimate field (field mode)
+	EstimateField string // Name of the number field (field mode), defaults to config.DefaultGitHubEstimateField
+
+	MaxAttempts  int           // Attempts per request, defaults to config.GitHubMaxAttempts
+	RetryBackoff time.Duration // Delay before the first retry, doubled after each attempt
+}
+
+// GitHubTracker imports issues with GitHub search qualifiers (repo:, label:, milestone:, ...)
+// and writes estimates as labels or as a project field.
+// Issue keys have the form "owner/name#number".
+type GitHubTracker struct {
+	config GitHubConfig
+	client *http.Client
+}
+
+// EstimateConflictError reports an issue that already has a different estimate.
+// The existing estimate is left untouched.
+type EstimateConflictError struct {
+	IssueKey string
+	Current  string
+	Proposed string
+}
+
+func (e *EstimateConflictError) Error() string {
+	return fmt.Sprintf("%s is already estimated as %s, not overwriting with %s", e.IssueKey, e.Current, e.Proposed)
+}
+
+func NewGitHubTracker(cfg GitHubConfig) *GitHubTracker {
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = config.DefaultGitHubAPIURL
+	}
+	if cfg.GraphQLURL == "" {
+		cfg.GraphQLURL = githubGraphQLURL(cfg.BaseURL)
+	}
+	if cfg.EstimateMode == "" {
+		cfg.EstimateMode = GitHubEstimateLabel
+	}
+	if cfg.LabelPrefix == "" {
+		cfg.LabelPrefix = config.DefaultGitHubLabelPrefix
+	}
+	if cfg.EstimateField == "" {
+		cfg.EstimateField = config.DefaultGitHubEstimateField
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = config.GitHubMaxAttempts
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = config.GitHubRetryBackoff
+	}
+	return &GitHubTracker{
+		config: cfg,
+		client: &http.Client{Timeout: config.IssueTrackerTimeout},
+	}
+}
+
+func (g *GitHubTracker) Name() string {
+	return "github"
+}
+
+type githubSearchResponse struct {
+	Items []struct {
+		Number        int    `json:"number"`
+		Title         string `json:"title"`
+		HTMLURL       string `json:"html_url"`
+		RepositoryURL string `json:"repository_url"`
+	} `json:"items"`
+}
+
+// SearchIssues runs a GitHub issue search, e.g. `label:ready milestone:"Sprint 3"`.
+// Only issues are returned; the configured repo is used unless the query names one.
+func (g *GitHubTracker) SearchIssues(query string, limit int) ([]models.Issue, error) {
+	q := query
+	if !strings.Contains(q, "is:issue") {
+		q += " is:issue"
+	}
+	if g.config.Repo != "" && !hasScopeQualifier(q) {
+		q = "repo:" + g.config.Repo + " " + q
+	}
+
+	params := url.Values{}
+	params.Set("q", q)
+	params.Set("per_page", strconv.Itoa(min(limit, 100)))
+
+	var result githubSearchResponse
+	if err := g.do(http.MethodGet, g.config.BaseURL+"/search/issues?"+params.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+
+	issues := make([]models.Issue, 0, len(result.Items))
+	for _, item := range result.Items {
+		repo := strings.TrimPrefix(item.RepositoryURL, g.config.BaseURL+"/repos/")
+		issues = append(issues, models.Issue{
+			Key:   fmt.Sprintf("%s#%d", repo, item.Number),
+			Title: item.Title,
+			URL:   item.HTMLURL,
+		})
+	}
+	return issues, nil
+}
+
+// hasScopeQualifier reports whether a search query restricts the repositories searched
+func hasScopeQualifier(query string) bool {
+	for _, field := range strings.Fields(query) {
+		if strings.HasPrefix(field, "repo:") || strings.HasPrefix(field, "org:") || strings.HasPrefix(field, "user:") {
+			return true
+		}
+	}
+	return false
+}
+
+// SetEstimate writes the estimate as a label or project field.
+// Returns an *EstimateConflictError when the issue already has a different estimate.
+func (g *GitHubTracker) SetEstimate(issueKey string, points float64) error {
+	repo, number, err := parseGitHubIssueKey(issueKey)
+	if err != nil {
+		return err
+	}
+
+	if g.config.EstimateMode == GitHubEstimateField {
+		return g.setEstimateField(issueKey, repo, number, points)
+	}
+	return g.setEstimateLabel(issueKey, repo, number, points)
+}
+
+// parseGitHubIssueKey splits "owner/name#number"
+func parseGitHubIssueKey(issueKey string) (string, int, error) {
+	repo, rawNumber, ok := strings.Cut(issueKey, "#")
+	number, err := strconv.Atoi(rawNumber)
+	if !ok || err != nil || strings.Count(repo, "/") != 1 {
+		return "", 0, fmt.Errorf("invalid github issue key %q", issueKey)
+	}
+	return repo, number, nil
+}
+
+func formatPoints(points float64) string {
+	return strconv.FormatFloat(points, 'f', -1, 64)
+}
+
+func (g *GitHubTracker) setEstimateLabel(issueKey, repo string, number int, points float64) error {
+	issuePath := fmt.Sprintf("/repos/%s/issues/%d", repo, number)
+	label := g.config.LabelPrefix + formatPoints(points)
+
+	var issue struct {
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+	}
+	if err := g.do(http.MethodGet, g.config.BaseURL+issuePath, nil, &issue); err != nil {
+		return err
+	}
+	for _, existing := range issue.Labels {
+		if existing.Name == label {
+			return nil // Already estimated with the same value
+		}
+		if strings.HasPrefix(existing.Name, g.config.LabelPrefix) {
+			return &EstimateConflictError{IssueKey: issueKey, Current: existing.Name, Proposed: label}
+		}
+	}
+
+	// Labels that don't exist yet are created by GitHub
+	return g.do(http.MethodPost, g.config.BaseURL+issuePath+"/labels", map[string]any{"labels": []string{label}}, nil)
+}
+
+const githubProjectItemsQuery = `query($owner: String!, $name: String!, $number: Int!, $field: String!) {
+  repository(owner: $owner, name: $name) {
+    issue(number: $number) {
+      projectItems(first: 20) {
+        nodes {
+          id
+          project { id number field(name: $field) { ... on ProjectV2Field { id dataType } } }
+          fieldValueByName(name: $field) { ... on ProjectV2ItemFieldNumberValue { number } }
+        }
+      }
+    }
+  }
+}`
+
+const githubUpdateFieldMutation = `mutation($project: ID!, $item: ID!, $field: ID!, $value: Float!) {
+  updateProjectV2ItemFieldValue(input: {projectId: $project, itemId: $item, fieldId: $field, value: {number: $value}}) {
+    projectV2Item { id }
+  }
+}`
+
+type githubProjectItems struct {
+	Repository struct {
+		Issue *struct {
+			ProjectItems struct {
+				Nodes []struct {
+					ID      string `json:"id"`
+					Project struct {
+						ID     string `json:"id"`
+						Number int    `json:"number"`
+						Field  *struct {
+							ID       string `json:"id"`
+							DataType string `json:"dataType"`
+						} `json:"field"`
+					} `json:"project"`
+					FieldValueByName *struct {
+						Number *float64 `json:"number"`
+					} `json:"fieldValueByName"`
+				} `json:"nodes"`
+			} `json:"projectItems"`
+		} `json:"issue"`
+	} `json:"repository"`
+}
+
+func (g *GitHubTracker) setEstimateField(issueKey, repo string, number int, points float64) error {
+	owner, name, _ := strings.Cut(repo, "/")
+
+	var items githubProjectItems
+	if err := g.graphQL(githubProjectItemsQuery, map[string]any{
+		"owner": owner, "name": name, "number": number, "field": g.config.EstimateField,
+	}, &items); err != nil {
+		return err
+	}
+	if items.Repository.Issue == nil {
+		return fmt.Errorf("github issue %s not found", issueKey)
+	}
+
+	for _, item := range items.Repository.Issue.ProjectItems.Nodes {
+		if item.Project.Number != g.config.ProjectNumber {
+			continue
+		}
+		if item.Project.Field == nil || item.Project.Field.ID == "" {
+			return fmt.Errorf("project %d has no field %q", g.config.ProjectNumber, g.config.EstimateField)
+		}
+		if item.Project.Field.DataType != "NUMBER" {
+			return fmt.Errorf("project field %q is not a number field", g.config.EstimateField)
+		}
+		if current := item.FieldValueByName; current != nil && current.Number != nil {
+			if *current.Number == points {
+				return nil // Already estimated with the same value
+			}
+			return &EstimateConflictError{
+				IssueKey: issueKey,
+				Current:  g.config.EstimateField + "=" + formatPoints(*current.Number),
+				Proposed: g.config.EstimateField + "=" + formatPoints(points),
+			}
+		}
+
+		return g.graphQL(githubUpdateFieldMutation, map[string]any{
+			"project": item.Project.ID, "item": item.ID, "field": item.Project.Field.ID, "value": points,
+		}, nil)
+	}
+	return fmt.Errorf("%s is not in project %d", issueKey, g.config.ProjectNumber)
+}
+
+// githubGraphQLURL returns the GraphQL endpoint of a REST API root. GitHub.com serves GraphQL
+// under the REST root, GitHub Enterprise Server serves REST at /api/v3 and GraphQL at /api/graphql.
+func githubGraphQLURL(baseURL string) string {
+	if root, ok := strings.CutSuffix(baseURL, "/v3"); ok {
+		return root + "/graphql"
+	}
+	return baseURL + "/graphql"
+}
+
+// graphQL runs a GraphQL query and decodes its data into result, if given
+func (g *GitHubTracker) graphQL(query string, variables map[string]any, result any) error {
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := g.do(http.MethodPost, g.config.GraphQLURL, map[string]any{"query": query, "variables": variables}, &response); err != nil {
+		return err
+	}
+	if len(response.Errors) > 0 {
+		messages := make([]string, 0, len(response.Errors))
+		for _, e := range response.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("github graphql error: %s", strings.Join(messages, "; "))
+	}
+	if result != nil {
+		if err := json.Unmarshal(response.Data, result); err != nil {
+			return fmt.Errorf("invalid github graphql response: %w", err)
+		}
+	}
+	return nil
+}
+
+// githubRetryableError marks failures worth another attempt
+type githubRetryableError struct {
+	err error
+}
+
+func (e *githubRetryableError) Error() string { return e.err.Error() }
+func (e *githubRetryableError) Unwrap() error { return e.err }
+
+// do sends an authenticated request to endpoint, retrying network errors, server errors and rate limits
+func (g *GitHubTracker) do(method, endpoint string, body, result any) error {
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	delay := g.config.RetryBackoff
+	var err error
+	for attempt := 1; attempt <= g.config.MaxAttempts; attempt++ {
+		err = g.attempt(method, endpoint, encoded, result)
+		var retryable *githubRetryableError
+		if err == nil || !errors.As(err, &retryable) {
+			return err
+		}
+		if attempt < g.config.MaxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return fmt.Errorf("github request failed after %d attempts: %w", g.config.MaxAttempts, err)
+}
+
+func (g *GitHubTracker) attempt(method, endpoint string, body []byte, result any) error {
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if g.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.config.Token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return &githubRetryableError{fmt.Errorf("github request failed: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return &githubRetryableError{fmt.Errorf("failed to read github response: %w", err)}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("github returned %d: %s", resp.StatusCode, githubErrorMessage(data))
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || isGitHubRateLimited(resp) {
+			err = &githubRetryableError{err}
+		}
+		return err
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("invalid github response: %w", err)
+		}
+	}
+	return nil
+}
+
+// isGitHubRateLimited detects primary and secondary rate limit responses
+func isGitHubRateLimited(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusForbidden &&
+		(resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != "")
+}
+
+func githubErrorMessage(body []byte) string {
+	var payload struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Message == "" {
+		return strings.TrimSpace(string(body))
+	}
+	return payload.Message
+}
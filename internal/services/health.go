@@ -0,0 +1,34 @@
+package services
+
+import (
+	"time"
+
+	"github.com/damione1/planning-poker/internal/config"
+)
+
+// HealthThresholds decide when the health status degrades
+type HealthThresholds struct {
+	MaxConnections   int64
+	MaxRooms         int64
+	WarningCapacity  float64 // Share of MaxConnections or MaxRooms
+	CriticalCapacity float64
+	ErrorWindow      time.Duration
+	WarningErrors    int64 // Connection and broadcast errors within ErrorWindow
+}
+
+// NewHealthThresholds derives the thresholds from the connection limits and health settings of cfg
+func NewHealthThresholds(cfg *config.Config) HealthThresholds {
+	return HealthThresholds{
+		MaxConnections:   int64(cfg.Limits.MaxTotalConnections),
+		MaxRooms:         int64(cfg.Limits.MaxRoomsPerInstance),
+		WarningCapacity:  cfg.Health.WarningCapacity,
+		CriticalCapacity: cfg.Health.CriticalCapacity,
+		ErrorWindow:      cfg.Health.ErrorWindow,
+		WarningErrors:    int64(cfg.Health.WarningErrors),
+	}
+}
+
+// DefaultHealthThresholds returns the thresholds of the default configuration
+func DefaultHealthThresholds() HealthThresholds {
+	return NewHealthThresholds(config.Default())
+}
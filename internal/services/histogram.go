@@ -0,0 +1,67 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the latency histograms
+var latencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// maxHistogramLabels caps the label values of a histogram vector; further values are counted as "other"
+const maxHistogramLabels = 64
+
+// histogram counts observations per bucket, in the shape of a Prometheus histogram
+type histogram struct {
+	counts []uint64 // Per bucket, not cumulative; the last one is +Inf
+	count  uint64
+	sum    float64 // Seconds
+}
+
+// histogramVec holds one histogram per label value
+type histogramVec struct {
+	mu      sync.Mutex
+	buckets []float64
+	series  map[string]*histogram
+}
+
+func newHistogramVec(buckets []float64) *histogramVec {
+	return &histogramVec{buckets: buckets, series: make(map[string]*histogram)}
+}
+
+// Observe records a duration under a label value
+func (v *histogramVec) Observe(label string, d time.Duration) {
+	seconds := d.Seconds()
+	bucket := sort.SearchFloat64s(v.buckets, seconds) // First bound >= seconds, len for +Inf
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	h, ok := v.series[label]
+	if !ok {
+		if len(v.series) >= maxHistogramLabels {
+			label = "other"
+			h = v.series[label]
+		}
+		if h == nil {
+			h = &histogram{counts: make([]uint64, len(v.buckets)+1)}
+			v.series[label] = h
+		}
+	}
+	h.counts[bucket]++
+	h.count++
+	h.sum += seconds
+}
+
+// snapshot copies the histograms, keyed by label value
+func (v *histogramVec) snapshot() map[string]histogram {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	series := make(map[string]histogram, len(v.series))
+	for label, h := range v.series {
+		series[label] = histogram{counts: append([]uint64(nil), h.counts...), count: h.count, sum: h.sum}
+	}
+	return series
+}
@@ -1,56 +1,118 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"log"
 	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/logging"
 	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/tracing"
 )
 
 var (
 	ErrServerAtCapacity = errors.New("server at maximum capacity")
 	ErrRoomFull         = errors.New("room has reached maximum participants")
 	ErrRoomNotFound     = errors.New("room not found")
+	ErrHubUnresponsive  = errors.New("hub loop is not responding")
 )
 
 // MessageHandler processes incoming WebSocket messages
-type MessageHandler func(roomID string, participantID string, message []byte)
+type MessageHandler func(ctx context.Context, roomID string, participantID string, message []byte)
 
 // Hub manages WebSocket connections and message routing
 type Hub struct {
-	// Rooms: roomID -> set of clients (using sync.Map for fine-grained locking)
+	cfg *config.Config
+	// Rooms: roomID -> set of clients (using sync.Map for fine-grained locking).
+	// Sets are copy-on-write: only the Run loop replaces them, so readers can iterate a loaded set.
 	rooms sync.Map // map[string]map[*Client]bool
+	// Event logs of rooms, kept by the broker when there is one (see event_log.go)
+	logs EventLogs
+	// Open connection counts of connected participants (see participant_connections.go)
+	participantsMu sync.Mutex
+	participants   map[participantKey]*participantConnections
 
 	// Connection tracking
 	totalConnections int64
 	mu               sync.RWMutex
 
 	// Channels
-	register      chan *Client
-	unregister    chan *Client
-	handleMessage chan *ClientMessage
+	register   chan *Client
+	unregister chan *Client
+	ping       chan chan struct{} // Answered by the Run loop, see Ping
 
 	// Message handler
 	messageHandler MessageHandler
 
+	// Per-room command queues (see room_queue.go)
+	queuesMu    sync.Mutex
+	queues      map[string]*roomQueue
+	workerSlots chan struct{} // Bounds the rooms running a command at once
+
+	// Rate limit of commands sent without a connection (see room_command.go)
+	commandRatesMu    sync.Mutex
+	commandRates      map[participantKey]*commandRate
+	commandRatesSwept time.Time
+
+	// Cross-instance fan-out (optional)
+	instanceID      string
+	broker          Broker
+	subscriptionsMu sync.Mutex
+	subscriptions   map[string]*roomSubscription // Rooms with local clients
+
 	// Metrics
 	metrics *Metrics
 }
 
-// NewHub creates a new Hub instance
-func NewHub() *Hub {
+// roomSubscription is the broker subscription of a room with local clients
+type roomSubscription struct {
+	sub   Subscription  // Nil until subscribed, guarded by Hub.subscriptionsMu
+	ready chan struct{} // Closed once subscribed
+	done  chan struct{} // Closed once the room has no local clients
+}
+
+// brokerEnvelope wraps broadcasts relayed through the broker
+type brokerEnvelope struct {
+	Origin  string            `json:"origin"` // Instance that published the message
+	Message json.RawMessage   `json:"message"`
+	Trace   map[string]string `json:"trace,omitempty"` // Trace context of the broadcast, see tracing.Inject
+}
+
+// NewHub creates a new Hub instance with the limits and health settings of cfg
+func NewHub(cfg *config.Config) *Hub {
+	metrics := NewMetrics()
+	metrics.SetHealthThresholds(NewHealthThresholds(cfg))
+
 	return &Hub{
-		register:      make(chan *Client, config.HubRegisterBufferSize),
-		unregister:    make(chan *Client, config.HubUnregisterBufferSize),
-		handleMessage: make(chan *ClientMessage, config.HubBroadcastBufferSize),
-		metrics:       NewMetrics(),
+		cfg:           cfg,
+		instanceID:    uuid.NewString(),
+		logs:          newMemoryEventLogs(),
+		subscriptions: make(map[string]*roomSubscription),
+		participants:  make(map[participantKey]*participantConnections),
+		register:      make(chan *Client, cfg.Limits.HubRegisterBufferSize),
+		unregister:    make(chan *Client, cfg.Limits.HubUnregisterBufferSize),
+		ping:          make(chan chan struct{}),
+		queues:        make(map[string]*roomQueue),
+		commandRates:  make(map[participantKey]*commandRate),
+		workerSlots:   make(chan struct{}, cfg.Limits.MaxConcurrentRoomCommands),
+		metrics:       metrics,
 	}
 }
 
-// Run starts the hub's main event loop
+// Config returns the configuration the hub was created with
+func (h *Hub) Config() *config.Config {
+	return h.cfg
+}
+
+// Run starts the hub's main event loop. Messages are not handled here but on
+// per-room workers, see Dispatch.
 func (h *Hub) Run() {
 	for {
 		select {
@@ -60,15 +122,32 @@ func (h *Hub) Run() {
 		case client := <-h.unregister:
 			h.unregisterClient(client)
 
-		case msg := <-h.handleMessage:
-			// Process message through registered handler
-			if h.messageHandler != nil {
-				h.messageHandler(msg.Client.roomID, msg.Client.participantID, msg.Message)
-			}
+		case reply := <-h.ping:
+			close(reply)
 		}
 	}
 }
 
+// Ping checks that the Run loop answers within timeout, i.e. that it is running
+// and not stuck on a registration
+func (h *Hub) Ping(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	reply := make(chan struct{})
+	select {
+	case h.ping <- reply:
+	case <-timer.C:
+		return ErrHubUnresponsive
+	}
+	select {
+	case <-reply:
+		return nil
+	case <-timer.C:
+		return ErrHubUnresponsive
+	}
+}
+
 // CanRegister checks if a new connection can be registered
 func (h *Hub) CanRegister(roomID string) error {
 	h.mu.RLock()
@@ -76,14 +155,14 @@ func (h *Hub) CanRegister(roomID string) error {
 	h.mu.RUnlock()
 
 	// Check global connection limit
-	if totalConns >= config.MaxTotalConnections {
+	if totalConns >= int64(h.cfg.Limits.MaxTotalConnections) {
 		return ErrServerAtCapacity
 	}
 
 	// Check room-specific limit
 	if value, ok := h.rooms.Load(roomID); ok {
 		clients := value.(map[*Client]bool)
-		if len(clients) >= config.MaxConnectionsPerRoom {
+		if len(clients) >= h.cfg.Limits.MaxConnectionsPerRoom {
 			return ErrRoomFull
 		}
 	}
@@ -95,7 +174,7 @@ func (h *Hub) CanRegister(roomID string) error {
 		return true
 	})
 
-	if roomCount >= config.MaxRoomsPerInstance {
+	if roomCount >= h.cfg.Limits.MaxRoomsPerInstance {
 		return ErrServerAtCapacity
 	}
 
@@ -107,6 +186,54 @@ func (h *Hub) Register(roomID string, client *Client) {
 	h.register <- client
 }
 
+// Resume registers a client that reconnects after the event at position from: the
+// room's events since then are queued to the client before any later broadcast.
+// Returns false when they can't be replayed, because from is unknown to the room's
+// event log or too old for it; the client then needs a snapshot of the room.
+// Blocks until the client is registered and the missed events are queued.
+func (h *Hub) Resume(roomID string, client *Client, from EventPosition) bool {
+	// Broadcasts wait for the missed events, and those already replayed are dropped
+	client.hold()
+	registered := make(chan struct{})
+	client.registered = registered
+	h.register <- client
+	<-registered
+
+	var missed [][]byte
+	replayed := false
+	if from.Log != "" {
+		// Broadcasts of other instances only arrive once the room is subscribed
+		h.awaitSubscription(roomID)
+		var err error
+		missed, replayed, err = h.logs.EventsSince(roomID, from)
+		if err != nil {
+			logging.ForRoom(roomID, client.participantID).Error("Failed to read missed events", logging.Err(err))
+		}
+	}
+
+	var last uint64 // Latest event queued to the client
+	if replayed {
+		last = from.Seq
+		if len(missed) > 0 {
+			last = seqOf(missed[len(missed)-1])
+		}
+	}
+	client.release(missed, last)
+	return replayed
+}
+
+// EventLogPosition returns the position of the latest broadcast to a room, the zero
+// position when the event log can't be read. A snapshot of the room taken after it
+// includes the events up to this position.
+func (h *Hub) EventLogPosition(roomID string) EventPosition {
+	position, err := h.logs.EventLogPosition(roomID, h.cfg.Limits.EventLogRetention)
+	if err != nil {
+		logging.ForRoom(roomID, "").Error("Failed to read event log position", logging.Err(err))
+		return EventPosition{}
+	}
+	return position
+}
+
 // Unregister queues a client for unregistration
 func (h *Hub) Unregister(roomID string, client *Client) {
 	h.unregister <- client
@@ -114,13 +241,18 @@ func (h *Hub) Unregister(roomID string, client *Client) {
 
 // registerClient adds a client to a room
 func (h *Hub) registerClient(client *Client) {
-	// Get or create room's client set
-	value, _ := h.rooms.LoadOrStore(client.roomID, make(map[*Client]bool))
-	clients := value.(map[*Client]bool)
-
-	// Add client to room
+	// Copy the room's client set with the new client added
+	clients := make(map[*Client]bool)
+	if value, ok := h.rooms.Load(client.roomID); ok {
+		for existing := range value.(map[*Client]bool) {
+			clients[existing] = true
+		}
+	}
 	clients[client] = true
 	h.rooms.Store(client.roomID, clients)
+	if client.registered != nil {
+		close(client.registered)
+	}
 
 	// Update global connection count
 	h.mu.Lock()
@@ -132,10 +264,11 @@ func (h *Hub) registerClient(client *Client) {
 	// Increment room count if this is a new room
 	if len(clients) == 1 {
 		h.metrics.IncrementRooms()
+		h.subscribe(client.roomID)
 	}
 
-	log.Printf("✓ Client registered: room=%s participant=%s (room size: %d, total connections: %d)",
-		client.roomID, client.participantID, len(clients), h.totalConnections)
+	logging.ForRoom(client.roomID, client.participantID).Info("Client registered",
+		"room_size", len(clients), "total_connections", h.GetTotalConnections())
 }
 
 // unregisterClient removes a client from a room
@@ -145,13 +278,18 @@ func (h *Hub) unregisterClient(client *Client) {
 		return
 	}
 
-	clients := value.(map[*Client]bool)
-	if _, exists := clients[client]; !exists {
+	current := value.(map[*Client]bool)
+	if _, exists := current[client]; !exists {
 		return
 	}
 
-	// Remove client from room
-	delete(clients, client)
+	// Copy the room's client set without the client
+	clients := make(map[*Client]bool, len(current))
+	for existing := range current {
+		if existing != client {
+			clients[existing] = true
+		}
+	}
 	client.Close()
 
 	// Update global connection count
@@ -161,35 +299,77 @@ func (h *Hub) unregisterClient(client *Client) {
 
 	h.metrics.DecrementConnections()
 
-	// Clean up empty room
+	// Clean up empty room. Its event log stays for the clients that come back, see EventLogs.
 	if len(clients) == 0 {
 		h.rooms.Delete(client.roomID)
 		h.metrics.DecrementRooms()
-		log.Printf("🧹 Room cleaned up: %s", client.roomID)
+		h.unsubscribe(client.roomID)
+		logging.ForRoom(client.roomID, "").Info("Room cleaned up")
 	} else {
 		h.rooms.Store(client.roomID, clients)
 	}
 
-	log.Printf("✓ Client unregistered: room=%s participant=%s (room size: %d, total connections: %d)",
-		client.roomID, client.participantID, len(clients), h.totalConnections)
+	logging.ForRoom(client.roomID, client.participantID).Info("Client unregistered",
+		"room_size", len(clients), "total_connections", h.GetTotalConnections())
 }
 
-// BroadcastToRoom sends a message to all clients in a room (non-blocking)
-func (h *Hub) BroadcastToRoom(roomID string, message *models.WSMessage) {
+// BroadcastToRoom sends a message to all clients in a room (non-blocking).
+// With a broker, the message is also relayed to the room's clients on other instances,
+// along with the trace context of ctx.
+func (h *Hub) BroadcastToRoom(ctx context.Context, roomID string, message *models.WSMessage) {
+	ctx, span := tracing.Tracer().Start(ctx, "hub.BroadcastToRoom",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(tracing.RoomID.String(roomID), tracing.MessageType.String(message.Type)),
+	)
+	defer span.End()
+
 	data, err := json.Marshal(message)
 	if err != nil {
-		log.Printf("❌ Error marshaling message: %v", err)
+		logging.ForCommand(roomID, "", message.Type).Error("Failed to marshal message", logging.Err(err))
+		tracing.End(span, err)
 		return
 	}
 
+	// Stamp the message with the room's next sequence number. Without one, it still goes out.
+	stamped, err := h.logs.AppendEvent(roomID, data, h.cfg.Limits.EventLogSize, h.cfg.Limits.EventLogRetention)
+	if err != nil {
+		logging.ForCommand(roomID, "", message.Type).Error("Failed to append to event log", logging.Err(err))
+	} else {
+		data = stamped
+	}
+
+	h.deliverToRoom(ctx, roomID, data, message.Type)
+
+	if h.broker != nil {
+		envelope, err := json.Marshal(brokerEnvelope{Origin: h.instanceID, Message: data, Trace: tracing.Inject(ctx)})
+		if err == nil {
+			err = h.broker.Publish(roomID, envelope)
+		}
+		if err != nil {
+			logging.ForCommand(roomID, "", message.Type).Error("Failed to publish to broker", logging.Err(err))
+			h.metrics.IncrementBroadcastErrors()
+			tracing.End(span, err)
+		}
+	}
+}
+
+// deliverToRoom sends an encoded message to the room's clients on this instance
+func (h *Hub) deliverToRoom(ctx context.Context, roomID string, data []byte, msgType string) {
+	_, span := tracing.Start(ctx, "hub.deliverToRoom", tracing.RoomID.String(roomID), tracing.MessageType.String(msgType))
+	defer span.End()
+
 	value, ok := h.rooms.Load(roomID)
 	if !ok {
-		log.Printf("⚠️  Room not found: %s", roomID)
+		if h.broker == nil {
+			logging.ForCommand(roomID, "", msgType).Debug("No local clients in room")
+		}
 		return
 	}
 
+	start := time.Now()
+	defer func() { h.metrics.ObserveBroadcastFanout(msgType, time.Since(start)) }()
+
 	clients := value.(map[*Client]bool)
-	log.Printf("📤 Broadcasting to room %s (%d clients): type=%s", roomID, len(clients), message.Type)
 
 	// Send to all clients in parallel (non-blocking)
 	successCount := 0
@@ -199,14 +379,133 @@ func (h *Hub) BroadcastToRoom(roomID string, message *models.WSMessage) {
 		}
 	}
 
-	log.Printf("✓ Broadcast complete: %d/%d clients received message", successCount, len(clients))
+	span.SetAttributes(attribute.Int("clients", len(clients)), attribute.Int("delivered", successCount))
+	logging.ForCommand(roomID, "", msgType).Debug("Broadcast complete", "delivered", successCount, "clients", len(clients))
+}
+
+// SetBroker enables cross-instance fan-out, and event logs shared between instances.
+// Must be called before Run.
+func (h *Hub) SetBroker(broker Broker) {
+	h.broker = broker
+	h.logs = broker
+}
+
+// subscribe starts relaying broker messages for a room that got its first local client.
+// The broker is called outside the Run loop, see keepSubscribed.
+func (h *Hub) subscribe(roomID string) {
+	if h.broker == nil {
+		return
+	}
+
+	rs := &roomSubscription{ready: make(chan struct{}), done: make(chan struct{})}
+	h.subscriptionsMu.Lock()
+	h.subscriptions[roomID] = rs
+	h.subscriptionsMu.Unlock()
+	go h.keepSubscribed(roomID, rs)
+}
+
+// keepSubscribed subscribes to the room's broker messages, retrying with a growing delay
+// until it succeeds or the room has no local clients anymore
+func (h *Hub) keepSubscribed(roomID string, rs *roomSubscription) {
+	delay := config.BrokerSubscribeRetryMin
+	for attempt := 1; ; attempt++ {
+		sub, err := h.broker.Subscribe(roomID, func(envelope []byte) {
+			h.receiveFromBroker(roomID, envelope)
+		})
+		if err == nil {
+			h.subscriptionsMu.Lock()
+			select {
+			case <-rs.done: // The room's clients left meanwhile
+				h.subscriptionsMu.Unlock()
+				h.closeSubscription(roomID, sub)
+			default:
+				rs.sub = sub
+				close(rs.ready)
+				h.subscriptionsMu.Unlock()
+			}
+			return
+		}
+
+		logging.ForRoom(roomID, "").Error("Failed to subscribe to broker, will retry",
+			"attempt", attempt, "retry_in", delay, logging.Err(err))
+		timer := time.NewTimer(delay)
+		select {
+		case <-rs.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		delay = min(2*delay, config.BrokerSubscribeRetryMax)
+	}
+}
+
+// unsubscribe stops relaying broker messages for a room without local clients.
+// Like subscribe, it leaves the broker call to another goroutine.
+func (h *Hub) unsubscribe(roomID string) {
+	h.subscriptionsMu.Lock()
+	rs, ok := h.subscriptions[roomID]
+	if !ok {
+		h.subscriptionsMu.Unlock()
+		return
+	}
+	delete(h.subscriptions, roomID)
+	close(rs.done)
+	sub := rs.sub
+	h.subscriptionsMu.Unlock()
+
+	if sub != nil {
+		go h.closeSubscription(roomID, sub)
+	}
+}
+
+// awaitSubscription waits until the room is subscribed to the broker, no longer than the
+// broker timeout
+func (h *Hub) awaitSubscription(roomID string) {
+	h.subscriptionsMu.Lock()
+	rs, ok := h.subscriptions[roomID]
+	h.subscriptionsMu.Unlock()
+	if !ok {
+		return
+	}
+
+	timer := time.NewTimer(config.BrokerTimeout)
+	defer timer.Stop()
+	select {
+	case <-rs.ready:
+	case <-rs.done:
+	case <-timer.C:
+	}
+}
+
+func (h *Hub) closeSubscription(roomID string, sub Subscription) {
+	if err := sub.Unsubscribe(); err != nil {
+		logging.ForRoom(roomID, "").Error("Failed to unsubscribe from broker", logging.Err(err))
+	}
+}
+
+// receiveFromBroker delivers a message published by another instance
+func (h *Hub) receiveFromBroker(roomID string, data []byte) {
+	var envelope brokerEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		logging.ForRoom(roomID, "").Warn("Invalid broker message", logging.Err(err))
+		return
+	}
+	if envelope.Origin == h.instanceID {
+		return // Already delivered locally
+	}
+
+	var header struct {
+		Type string `json:"type"`
+	}
+	_ = json.Unmarshal(envelope.Message, &header)
+	h.deliverToRoom(tracing.Extract(context.Background(), envelope.Trace), roomID, envelope.Message, header.Type)
 }
 
 // SendToClient sends a message to a specific client
 func (h *Hub) SendToClient(client *Client, message *models.WSMessage) {
 	data, err := json.Marshal(message)
 	if err != nil {
-		log.Printf("❌ Error marshaling message: %v", err)
+		logging.ForCommand(client.roomID, client.participantID, message.Type).Error("Failed to marshal message", logging.Err(err))
 		return
 	}
 
@@ -229,6 +528,11 @@ func (h *Hub) GetMetrics() MetricsSnapshot {
 	return h.metrics.Snapshot()
 }
 
+// Metrics returns the hub's metrics, to be shared with the services it drives
+func (h *Hub) Metrics() *Metrics {
+	return h.metrics
+}
+
 // GetTotalConnections returns the current number of active connections
 func (h *Hub) GetTotalConnections() int64 {
 	h.mu.RLock()
@@ -250,20 +554,3 @@ func (h *Hub) GetRoomCount() int {
 func (h *Hub) SetMessageHandler(handler MessageHandler) {
 	h.messageHandler = handler
 }
-
-// GetClient finds a client in a room by participant ID
-func (h *Hub) GetClient(roomID string, participantID string) *Client {
-	value, ok := h.rooms.Load(roomID)
-	if !ok {
-		return nil
-	}
-
-	clients := value.(map[*Client]bool)
-	for client := range clients {
-		if client.participantID == participantID {
-			return client
-		}
-	}
-
-	return nil
-}
@@ -0,0 +1,14 @@
+package services
+
+import "github.com/damione1/planning-poker/internal/models"
+
+// IssueTracker is an external issue tracker stories are imported from
+// and estimates are written back to
+type IssueTracker interface {
+	// Name identifies the tracker in import requests and story records
+	Name() string
+	// SearchIssues returns up to limit issues matching a tracker-specific query
+	SearchIssues(query string, limit int) ([]models.Issue, error)
+	// SetEstimate writes the final estimate of an issue
+	SetEstimate(issueKey string, points float64) error
+}
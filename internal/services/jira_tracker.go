@@ -0,0 +1,154 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/models"
+)
+
+// JiraConfig configures the Jira REST client
+type JiraConfig struct {
+	BaseURL          string // e.g. https://example.atlassian.net
+	Email            string // Jira Cloud account email; when empty Token is sent as a bearer token (Data Center PAT)
+	Token            string
+	StoryPointsField string // Field estimates are written to, defaults to config.DefaultJiraStoryPointsField
+}
+
+// JiraTracker imports issues with JQL and writes estimates through the Jira REST API v2
+type JiraTracker struct {
+	config JiraConfig
+	client *http.Client
+}
+
+func NewJiraTracker(cfg JiraConfig) *JiraTracker {
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.StoryPointsField == "" {
+		cfg.StoryPointsField = config.DefaultJiraStoryPointsField
+	}
+	return &JiraTracker{
+		config: cfg,
+		client: &http.Client{Timeout: config.IssueTrackerTimeout},
+	}
+}
+
+func (j *JiraTracker) Name() string {
+	return "jira"
+}
+
+type jiraSearchResponse struct {
+	Issues []struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary string `json:"summary"`
+		} `json:"fields"`
+	} `json:"issues"`
+}
+
+// SearchIssues runs a JQL query
+func (j *JiraTracker) SearchIssues(jql string, limit int) ([]models.Issue, error) {
+	params := url.Values{}
+	params.Set("jql", jql)
+	params.Set("fields", "summary")
+	params.Set("maxResults", strconv.Itoa(limit))
+
+	var result jiraSearchResponse
+	if err := j.do(http.MethodGet, "/rest/api/2/search?"+params.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+
+	issues := make([]models.Issue, 0, len(result.Issues))
+	for _, issue := range result.Issues {
+		issues = append(issues, models.Issue{
+			Key:   issue.Key,
+			Title: issue.Fields.Summary,
+			URL:   j.config.BaseURL + "/browse/" + issue.Key,
+		})
+	}
+	return issues, nil
+}
+
+// SetEstimate writes the estimate to the configured story points field
+func (j *JiraTracker) SetEstimate(issueKey string, points float64) error {
+	body := map[string]any{
+		"fields": map[string]any{j.config.StoryPointsField: points},
+	}
+	return j.do(http.MethodPut, "/rest/api/2/issue/"+url.PathEscape(issueKey), body, nil)
+}
+
+// do sends an authenticated request and decodes the JSON response into result, if given
+func (j *JiraTracker) do(method, path string, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, j.config.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if j.config.Email != "" {
+		req.SetBasicAuth(j.config.Email, j.config.Token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+j.config.Token)
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("jira request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read jira response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("jira returned %d: %s", resp.StatusCode, jiraErrorMessage(data))
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("invalid jira response: %w", err)
+		}
+	}
+	return nil
+}
+
+// jiraErrorMessage extracts the messages of a Jira error response
+func jiraErrorMessage(body []byte) string {
+	var payload struct {
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return strings.TrimSpace(string(body))
+	}
+
+	messages := append([]string(nil), payload.ErrorMessages...)
+	fields := make([]string, 0, len(payload.Errors))
+	for field := range payload.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		messages = append(messages, field+": "+payload.Errors[field])
+	}
+	return strings.Join(messages, "; ")
+}
@@ -18,22 +18,53 @@ type Metrics struct {
 	messagesSent        int64
 	lastMessageTime     int64 // Unix timestamp
 
+	// Command queue metrics
+	queuedCommands      int64 // Dispatched, not yet started
+	roomWorkers         int64
+	commandsStarted     int64
+	commandWaitNanos    int64 // Total time from dispatch to start
+	queueFullWaits      int64 // Senders blocked on a full room queue
+	queueFullDrops      int64 // Commands dropped after waiting on a full room queue
+	workerSlotWaits     int64 // Commands delayed by the concurrency bound
+
 	// Error metrics
 	connectionErrors    int64
 	broadcastErrors     int64
 	rateLimitViolations int64
+	recentErrors        *rateWindow // Connection and broadcast errors within the health error window
+
+	// Latency histograms, labelled by message type
+	messageHandling     *histogramVec // Handling of client messages
+	broadcastFanout     *histogramVec // Delivery of a broadcast to a room's clients
+	dbOperations        *histogramVec // Room manager operations behind each message type
 
 	// Resource metrics
 	startTime           time.Time
+
+	// Health thresholds
+	thresholds          HealthThresholds
 }
 
 // NewMetrics creates a new metrics tracker
 func NewMetrics() *Metrics {
+	thresholds := DefaultHealthThresholds()
 	return &Metrics{
-		startTime: time.Now(),
+		recentErrors:    newRateWindow(thresholds.ErrorWindow),
+		messageHandling: newHistogramVec(latencyBuckets),
+		broadcastFanout: newHistogramVec(latencyBuckets),
+		dbOperations:    newHistogramVec(latencyBuckets),
+		startTime:       time.Now(),
+		thresholds:      thresholds,
 	}
 }
 
+// SetHealthThresholds replaces the thresholds of the health status.
+// Must be called before the metrics are used.
+func (m *Metrics) SetHealthThresholds(thresholds HealthThresholds) {
+	m.thresholds = thresholds
+	m.recentErrors = newRateWindow(thresholds.ErrorWindow)
+}
+
 // Connection tracking
 func (m *Metrics) IncrementConnections() {
 	atomic.AddInt64(&m.activeConnections, 1)
@@ -62,13 +93,61 @@ func (m *Metrics) IncrementMessagesSent() {
 	atomic.AddInt64(&m.messagesSent, 1)
 }
 
+// Command queue tracking
+func (m *Metrics) IncrementQueuedCommands() {
+	atomic.AddInt64(&m.queuedCommands, 1)
+}
+
+func (m *Metrics) RecordCommandStarted(wait time.Duration) {
+	atomic.AddInt64(&m.queuedCommands, -1)
+	atomic.AddInt64(&m.commandsStarted, 1)
+	atomic.AddInt64(&m.commandWaitNanos, int64(wait))
+}
+
+func (m *Metrics) IncrementRoomWorkers() {
+	atomic.AddInt64(&m.roomWorkers, 1)
+}
+
+func (m *Metrics) DecrementRoomWorkers() {
+	atomic.AddInt64(&m.roomWorkers, -1)
+}
+
+func (m *Metrics) IncrementQueueFullWaits() {
+	atomic.AddInt64(&m.queueFullWaits, 1)
+}
+
+// RecordQueueFullDrop counts a dispatched command dropped on a full room queue
+func (m *Metrics) RecordQueueFullDrop() {
+	atomic.AddInt64(&m.queuedCommands, -1)
+	atomic.AddInt64(&m.queueFullDrops, 1)
+}
+
+func (m *Metrics) IncrementWorkerSlotWaits() {
+	atomic.AddInt64(&m.workerSlotWaits, 1)
+}
+
+// Latency tracking
+func (m *Metrics) ObserveMessageHandling(msgType string, d time.Duration) {
+	m.messageHandling.Observe(msgType, d)
+}
+
+func (m *Metrics) ObserveBroadcastFanout(msgType string, d time.Duration) {
+	m.broadcastFanout.Observe(msgType, d)
+}
+
+func (m *Metrics) ObserveDBOperation(msgType string, d time.Duration) {
+	m.dbOperations.Observe(msgType, d)
+}
+
 // Error tracking
 func (m *Metrics) IncrementConnectionErrors() {
 	atomic.AddInt64(&m.connectionErrors, 1)
+	m.recentErrors.Add(time.Now())
 }
 
 func (m *Metrics) IncrementBroadcastErrors() {
 	atomic.AddInt64(&m.broadcastErrors, 1)
+	m.recentErrors.Add(time.Now())
 }
 
 func (m *Metrics) IncrementRateLimitViolations() {
@@ -88,10 +167,20 @@ type MetricsSnapshot struct {
 	MessagesPerSecond   float64 `json:"messages_per_second"`
 	LastMessageTime     string  `json:"last_message_time"`
 
+	// Command queue metrics
+	QueuedCommands      int64   `json:"queued_commands"`
+	RoomWorkers         int64   `json:"room_workers"`
+	CommandsProcessed   int64   `json:"commands_processed"`
+	AvgCommandWaitMs    float64 `json:"avg_command_wait_ms"`
+	QueueFullWaits      int64   `json:"queue_full_waits"`
+	QueueFullDrops      int64   `json:"queue_full_drops"`
+	WorkerSlotWaits     int64   `json:"worker_slot_waits"`
+
 	// Error metrics
 	ConnectionErrors    int64   `json:"connection_errors"`
 	BroadcastErrors     int64   `json:"broadcast_errors"`
 	RateLimitViolations int64   `json:"rate_limit_violations"`
+	RecentErrors        int64   `json:"recent_errors"` // Within the health error window
 
 	// Resource metrics
 	UptimeSeconds       int64   `json:"uptime_seconds"`
@@ -117,6 +206,12 @@ func (m *Metrics) Snapshot() MetricsSnapshot {
 		lastMsgTimeStr = time.Unix(lastMsgTime, 0).Format(time.RFC3339)
 	}
 
+	commandsStarted := atomic.LoadInt64(&m.commandsStarted)
+	avgCommandWaitMs := 0.0
+	if commandsStarted > 0 {
+		avgCommandWaitMs = float64(atomic.LoadInt64(&m.commandWaitNanos)) / float64(commandsStarted) / float64(time.Millisecond)
+	}
+
 	snapshot := MetricsSnapshot{
 		ActiveConnections:   atomic.LoadInt64(&m.activeConnections),
 		TotalConnections:    atomic.LoadInt64(&m.totalConnections),
@@ -125,9 +220,17 @@ func (m *Metrics) Snapshot() MetricsSnapshot {
 		MessagesSent:        atomic.LoadInt64(&m.messagesSent),
 		MessagesPerSecond:   messagesPerSec,
 		LastMessageTime:     lastMsgTimeStr,
+		QueuedCommands:      atomic.LoadInt64(&m.queuedCommands),
+		RoomWorkers:         atomic.LoadInt64(&m.roomWorkers),
+		CommandsProcessed:   commandsStarted,
+		AvgCommandWaitMs:    avgCommandWaitMs,
+		QueueFullWaits:      atomic.LoadInt64(&m.queueFullWaits),
+		QueueFullDrops:      atomic.LoadInt64(&m.queueFullDrops),
+		WorkerSlotWaits:     atomic.LoadInt64(&m.workerSlotWaits),
 		ConnectionErrors:    atomic.LoadInt64(&m.connectionErrors),
 		BroadcastErrors:     atomic.LoadInt64(&m.broadcastErrors),
 		RateLimitViolations: atomic.LoadInt64(&m.rateLimitViolations),
+		RecentErrors:        m.recentErrors.Count(time.Now()),
 		UptimeSeconds:       int64(uptime.Seconds()),
 		MemoryUsageMB:       memStats.Alloc / 1024 / 1024,
 		NumGoroutines:       runtime.NumGoroutine(),
@@ -139,17 +242,19 @@ func (m *Metrics) Snapshot() MetricsSnapshot {
 
 // calculateHealthStatus determines overall system health
 func (m *Metrics) calculateHealthStatus() string {
-	activeConns := atomic.LoadInt64(&m.activeConnections)
-	activeRooms := atomic.LoadInt64(&m.activeRooms)
-	errors := atomic.LoadInt64(&m.connectionErrors) + atomic.LoadInt64(&m.broadcastErrors)
+	activeConns := float64(atomic.LoadInt64(&m.activeConnections))
+	activeRooms := float64(atomic.LoadInt64(&m.activeRooms))
+	recentErrors := m.recentErrors.Count(time.Now())
+	t := m.thresholds
 
-	// Critical: Over 90% capacity or high error rate
-	if activeConns > 9000 || activeRooms > 900 {
+	// Critical: close to the connection or room limit
+	if activeConns > t.CriticalCapacity*float64(t.MaxConnections) || activeRooms > t.CriticalCapacity*float64(t.MaxRooms) {
 		return "critical"
 	}
 
-	// Warning: Over 80% capacity or some errors
-	if activeConns > 8000 || activeRooms > 800 || errors > 100 {
+	// Warning: getting close to a limit, or errors piling up in the last window
+	if activeConns > t.WarningCapacity*float64(t.MaxConnections) || activeRooms > t.WarningCapacity*float64(t.MaxRooms) ||
+		recentErrors > t.WarningErrors {
 		return "warning"
 	}
 
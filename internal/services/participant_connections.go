@@ -0,0 +1,129 @@
+package services
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/damione1/planning-poker/internal/logging"
+	"github.com/damione1/planning-poker/internal/models"
+)
+
+// participantKey identifies a participant of a room
+type participantKey struct {
+	roomID        string
+	participantID string
+}
+
+// participantConnections counts the open connections of a participant, who may have
+// several tabs or devices in a room
+type participantConnections struct {
+	mu   sync.Mutex // Serializes the participant's connection changes on this instance
+	open int        // Open connections on this instance, guarded by mu
+	refs int        // Callers holding the entry, guarded by Hub.participantsMu
+}
+
+// OpenParticipantConnection counts a new connection of a participant. When it is the
+// participant's only one on any instance, connected runs before any other connection
+// change of the participant on this instance, e.g. to mark them connected. Returns
+// whether connected ran.
+func (h *Hub) OpenParticipantConnection(roomID, participantID string, connected func()) bool {
+	key := participantKey{roomID: roomID, participantID: participantID}
+	conns := h.acquireParticipant(key)
+	defer h.releaseParticipant(key, conns)
+
+	conns.open++
+	if h.countConnections(key, 1, conns.open) > 1 {
+		return false
+	}
+	connected()
+	return true
+}
+
+// CloseParticipantConnection counts a closed connection of a participant. When it was the
+// participant's last one on every instance, disconnected runs before any other connection
+// change of the participant on this instance, e.g. to mark them disconnected. Returns
+// whether disconnected ran.
+func (h *Hub) CloseParticipantConnection(roomID, participantID string, disconnected func()) bool {
+	key := participantKey{roomID: roomID, participantID: participantID}
+	conns := h.acquireParticipant(key)
+	defer h.releaseParticipant(key, conns)
+
+	conns.open--
+	if h.countConnections(key, -1, conns.open) > 0 {
+		return false
+	}
+	disconnected()
+	return true
+}
+
+// countConnections adds delta to the participant's connections shared by every instance
+// through the broker and returns their count, or local when there is no broker or it fails
+func (h *Hub) countConnections(key participantKey, delta, local int) int {
+	if h.broker == nil {
+		return local
+	}
+	count, err := h.broker.CountConnections(key.roomID, key.participantID, delta)
+	if err != nil {
+		logging.ForRoom(key.roomID, key.participantID).Error("Failed to count participant connections", logging.Err(err))
+		return local
+	}
+	return count
+}
+
+// acquireParticipant returns the locked connection count of a participant
+func (h *Hub) acquireParticipant(key participantKey) *participantConnections {
+	h.participantsMu.Lock()
+	conns, ok := h.participants[key]
+	if !ok {
+		conns = &participantConnections{}
+		h.participants[key] = conns
+	}
+	conns.refs++
+	h.participantsMu.Unlock()
+
+	conns.mu.Lock()
+	return conns
+}
+
+// releaseParticipant unlocks a connection count, dropping it once the participant has no
+// connections left and no other caller holds it
+func (h *Hub) releaseParticipant(key participantKey, conns *participantConnections) {
+	conns.mu.Unlock()
+
+	h.participantsMu.Lock()
+	defer h.participantsMu.Unlock()
+	conns.refs--
+	if conns.refs == 0 && conns.open == 0 {
+		delete(h.participants, key)
+	}
+}
+
+// GetClients returns the clients of a participant in a room on this instance, one per
+// open tab or device
+func (h *Hub) GetClients(roomID string, participantID string) []*Client {
+	value, ok := h.rooms.Load(roomID)
+	if !ok {
+		return nil
+	}
+
+	var clients []*Client
+	for client := range value.(map[*Client]bool) {
+		if client.participantID == participantID {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// SendToParticipant sends a message to every client of a participant on this instance
+func (h *Hub) SendToParticipant(roomID, participantID string, message *models.WSMessage) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		logging.ForCommand(roomID, participantID, message.Type).Error("Failed to marshal message", logging.Err(err))
+		return
+	}
+
+	for _, client := range h.GetClients(roomID, participantID) {
+		client.Send(data)
+	}
+}
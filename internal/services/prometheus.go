@@ -0,0 +1,100 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// prometheusNamespace prefixes every exported metric name
+const prometheusNamespace = "planning_poker_"
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format (version 0.0.4)
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	var b strings.Builder
+
+	// Counters
+	writeSample(&b, "connections_total", "counter", "WebSocket connections accepted", atomic.LoadInt64(&m.totalConnections))
+	writeSample(&b, "messages_received_total", "counter", "Messages received from clients", atomic.LoadInt64(&m.messagesReceived))
+	writeSample(&b, "messages_sent_total", "counter", "Messages sent to clients", atomic.LoadInt64(&m.messagesSent))
+	writeSample(&b, "connection_errors_total", "counter", "WebSocket read errors", atomic.LoadInt64(&m.connectionErrors))
+	writeSample(&b, "broadcast_errors_total", "counter", "Failed deliveries and broker publishes", atomic.LoadInt64(&m.broadcastErrors))
+	writeSample(&b, "rate_limit_violations_total", "counter", "Messages rejected by the rate limit", atomic.LoadInt64(&m.rateLimitViolations))
+	writeSample(&b, "commands_processed_total", "counter", "Commands started by room workers", atomic.LoadInt64(&m.commandsStarted))
+	writeSample(&b, "command_queue_full_waits_total", "counter", "Senders blocked on a full room queue", atomic.LoadInt64(&m.queueFullWaits))
+	writeSample(&b, "command_queue_full_drops_total", "counter", "Commands dropped after waiting on a full room queue", atomic.LoadInt64(&m.queueFullDrops))
+	writeSample(&b, "command_worker_slot_waits_total", "counter", "Commands delayed by the room concurrency bound", atomic.LoadInt64(&m.workerSlotWaits))
+
+	// Gauges
+	writeSample(&b, "active_connections", "gauge", "Open WebSocket connections", atomic.LoadInt64(&m.activeConnections))
+	writeSample(&b, "active_rooms", "gauge", "Rooms with open connections", atomic.LoadInt64(&m.activeRooms))
+	writeSample(&b, "queued_commands", "gauge", "Commands dispatched and not yet started", atomic.LoadInt64(&m.queuedCommands))
+	writeSample(&b, "room_workers", "gauge", "Running room workers", atomic.LoadInt64(&m.roomWorkers))
+	writeSample(&b, "uptime_seconds", "gauge", "Seconds since the server started", int64(time.Since(m.startTime).Seconds()))
+	writeSample(&b, "goroutines", "gauge", "Running goroutines", int64(runtime.NumGoroutine()))
+	writeSample(&b, "memory_alloc_bytes", "gauge", "Allocated heap memory", int64(memStats.Alloc))
+
+	// Histograms
+	writeHistogram(&b, "message_handling_seconds", "Time to handle a client message", m.messageHandling)
+	writeHistogram(&b, "broadcast_fanout_seconds", "Time to deliver a broadcast to a room's clients", m.broadcastFanout)
+	writeHistogram(&b, "db_operation_seconds", "Time of the room manager operation behind a message", m.dbOperations)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric
+func writeHeader(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s%s %s\n", prometheusNamespace, name, help)
+	fmt.Fprintf(b, "# TYPE %s%s %s\n", prometheusNamespace, name, kind)
+}
+
+// writeSample writes a counter or gauge without labels
+func writeSample(b *strings.Builder, name, kind, help string, value int64) {
+	writeHeader(b, name, kind, help)
+	fmt.Fprintf(b, "%s%s %d\n", prometheusNamespace, name, value)
+}
+
+// writeHistogram writes one series per message type, with cumulative buckets
+func writeHistogram(b *strings.Builder, name, help string, vec *histogramVec) {
+	writeHeader(b, name, "histogram", help)
+
+	series := vec.snapshot()
+	labels := make([]string, 0, len(series))
+	for label := range series {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	for _, label := range labels {
+		h := series[label]
+		msgType := escapeLabelValue(label)
+
+		var cumulative uint64
+		for i, bound := range vec.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(b, "%s%s_bucket{type=\"%s\",le=\"%s\"} %d\n", prometheusNamespace, name, msgType, formatFloat(bound), cumulative)
+		}
+		fmt.Fprintf(b, "%s%s_bucket{type=\"%s\",le=\"+Inf\"} %d\n", prometheusNamespace, name, msgType, h.count)
+		fmt.Fprintf(b, "%s%s_sum{type=\"%s\"} %s\n", prometheusNamespace, name, msgType, formatFloat(h.sum))
+		fmt.Fprintf(b, "%s%s_count{type=\"%s\"} %d\n", prometheusNamespace, name, msgType, h.count)
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// rateWindowSlots is the number of slots a rate window is divided into
+const rateWindowSlots = 10
+
+// rateWindow counts events over a sliding window. Events expire one slot
+// (a tenth of the window) at a time.
+type rateWindow struct {
+	mu     sync.Mutex
+	slot   time.Duration
+	counts [rateWindowSlots]int64
+	epochs [rateWindowSlots]int64 // Slot number each count belongs to
+}
+
+func newRateWindow(window time.Duration) *rateWindow {
+	slot := window / rateWindowSlots
+	if slot <= 0 {
+		slot = 1
+	}
+	return &rateWindow{slot: slot}
+}
+
+// Add records an event at now
+func (w *rateWindow) Add(now time.Time) {
+	epoch := now.UnixNano() / int64(w.slot)
+	i := epoch % rateWindowSlots
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.epochs[i] != epoch {
+		w.epochs[i] = epoch
+		w.counts[i] = 0
+	}
+	w.counts[i]++
+}
+
+// Count returns the events recorded within the window ending at now
+func (w *rateWindow) Count(now time.Time) int64 {
+	epoch := now.UnixNano() / int64(w.slot)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var total int64
+	for i, slotEpoch := range w.epochs {
+		if epoch-slotEpoch < rateWindowSlots {
+			total += w.counts[i]
+		}
+	}
+	return total
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/logging"
+)
+
+// RedisBroker relays room messages through Redis pub/sub, one channel per room.
+// All subscriptions of an instance share a single Redis connection. Connection counts
+// are kept in a hash per room, and event logs in a hash and a sorted set per room.
+type RedisBroker struct {
+	client *redis.Client
+	pubsub *redis.PubSub
+	prefix string
+
+	mu            sync.RWMutex
+	subscriptions map[string]map[*redisSubscription]bool
+	done          chan struct{}
+}
+
+// countConnections adds ARGV[2] to the count of participant ARGV[1] in hash KEYS[1], dropping
+// it at zero, and refreshes the hash's expiry to ARGV[3] seconds
+var countConnections = redis.NewScript(`
+local count = redis.call("HINCRBY", KEYS[1], ARGV[1], ARGV[2])
+if count <= 0 then
+	redis.call("HDEL", KEYS[1], ARGV[1])
+	count = 0
+end
+redis.call("EXPIRE", KEYS[1], ARGV[3])
+return count
+`)
+
+// appendEvent adds ARGV[2] to the event log of hash KEYS[1] and sorted set KEYS[2] under
+// the next sequence number, starting the log with ID ARGV[1] when there is none. Keeps
+// the latest ARGV[3] events and refreshes the expiry of both keys to ARGV[4] seconds.
+// Members are "<seq>:<message>", so that equal messages stay apart. Returns the log ID
+// and the sequence number.
+var appendEvent = redis.NewScript(`
+redis.call("HSETNX", KEYS[1], "id", ARGV[1])
+local seq = redis.call("HINCRBY", KEYS[1], "seq", 1)
+redis.call("ZADD", KEYS[2], seq, seq .. ":" .. ARGV[2])
+redis.call("ZREMRANGEBYRANK", KEYS[2], 0, -tonumber(ARGV[3]) - 1)
+redis.call("EXPIRE", KEYS[1], ARGV[4])
+redis.call("EXPIRE", KEYS[2], ARGV[4])
+return {redis.call("HGET", KEYS[1], "id"), seq}
+`)
+
+// eventsSince returns the log ID and latest sequence number of hash KEYS[1], and the
+// members of sorted set KEYS[2] after sequence number ARGV[1]
+var eventsSince = redis.NewScript(`
+local log = redis.call("HMGET", KEYS[1], "id", "seq")
+if not log[1] then
+	return {}
+end
+return {log[1], log[2], redis.call("ZRANGEBYSCORE", KEYS[2], "(" .. ARGV[1], "+inf")}
+`)
+
+// startEventLog starts the event log of hash KEYS[1] with ID ARGV[1] when there is none,
+// expiring in ARGV[2] seconds. Returns the log ID and latest sequence number.
+var startEventLog = redis.NewScript(`
+if redis.call("HSETNX", KEYS[1], "id", ARGV[1]) == 1 then
+	redis.call("HSET", KEYS[1], "seq", 0)
+	redis.call("EXPIRE", KEYS[1], ARGV[2])
+end
+return redis.call("HMGET", KEYS[1], "id", "seq")
+`)
+
+type redisSubscription struct {
+	broker  *RedisBroker
+	roomID  string
+	handler func(message []byte)
+}
+
+// NewRedisBroker connects to the Redis server at url (redis://[:password@]host:port[/db])
+func NewRedisBroker(url string) (*RedisBroker, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+
+	client := redis.NewClient(options)
+	ctx, cancel := context.WithTimeout(context.Background(), config.BrokerTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	b := &RedisBroker{
+		client:        client,
+		pubsub:        client.Subscribe(context.Background()),
+		prefix:        config.BrokerChannelPrefix,
+		subscriptions: make(map[string]map[*redisSubscription]bool),
+		done:          make(chan struct{}),
+	}
+	go b.receive()
+	return b, nil
+}
+
+func (b *RedisBroker) channel(roomID string) string {
+	return b.prefix + roomID
+}
+
+func (b *RedisBroker) Publish(roomID string, message []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), config.BrokerTimeout)
+	defer cancel()
+	return b.client.Publish(ctx, b.channel(roomID), message).Err()
+}
+
+func (b *RedisBroker) CountConnections(roomID, participantID string, delta int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.BrokerTimeout)
+	defer cancel()
+	key := config.BrokerConnectionsPrefix + roomID
+	ttl := int(config.BrokerConnectionsTTL / time.Second)
+	return countConnections.Run(ctx, b.client, []string{key}, participantID, delta, ttl).Int()
+}
+
+func (b *RedisBroker) eventLogKeys(roomID string) []string {
+	key := config.BrokerEventLogPrefix + roomID
+	return []string{key, key + ":events"}
+}
+
+func (b *RedisBroker) AppendEvent(roomID string, message []byte, size int, retention time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.BrokerTimeout)
+	defer cancel()
+	ttl := int(max(retention/time.Second, 1))
+	result, err := appendEvent.Run(ctx, b.client, b.eventLogKeys(roomID), uuid.NewString(), message, size, ttl).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to append to the event log of room %s: %w", roomID, err)
+	}
+	return withSeq(message, uint64(result[1].(int64))), nil
+}
+
+func (b *RedisBroker) EventsSince(roomID string, from EventPosition) ([][]byte, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.BrokerTimeout)
+	defer cancel()
+	result, err := eventsSince.Run(ctx, b.client, b.eventLogKeys(roomID), from.Seq).Slice()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read the event log of room %s: %w", roomID, err)
+	}
+	if len(result) == 0 || result[0] != from.Log {
+		return nil, false, nil
+	}
+	seq, err := strconv.ParseUint(result[1].(string), 10, 64)
+	if err != nil || from.Seq > seq {
+		return nil, false, nil
+	}
+
+	members := result[2].([]any)
+	if uint64(len(members)) != seq-from.Seq {
+		return nil, false, nil // Some of the missed events are no longer kept
+	}
+	events := make([][]byte, 0, len(members))
+	for _, member := range members {
+		seq, message, _ := strings.Cut(member.(string), ":")
+		n, _ := strconv.ParseUint(seq, 10, 64)
+		events = append(events, withSeq([]byte(message), n))
+	}
+	return events, true, nil
+}
+
+func (b *RedisBroker) EventLogPosition(roomID string, retention time.Duration) (EventPosition, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.BrokerTimeout)
+	defer cancel()
+	ttl := int(max(retention/time.Second, 1))
+	result, err := startEventLog.Run(ctx, b.client, b.eventLogKeys(roomID)[:1], uuid.NewString(), ttl).Slice()
+	if err != nil {
+		return EventPosition{}, fmt.Errorf("failed to read the event log of room %s: %w", roomID, err)
+	}
+	log, _ := result[0].(string)
+	seqValue, _ := result[1].(string)
+	seq, _ := strconv.ParseUint(seqValue, 10, 64)
+	return EventPosition{Log: log, Seq: seq}, nil
+}
+
+// Subscribe subscribes the instance to the room's channel on its first subscription
+func (b *RedisBroker) Subscribe(roomID string, handler func(message []byte)) (Subscription, error) {
+	sub := &redisSubscription{broker: b, roomID: roomID, handler: handler}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscriptions[roomID] == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), config.BrokerTimeout)
+		defer cancel()
+		if err := b.pubsub.Subscribe(ctx, b.channel(roomID)); err != nil {
+			return nil, fmt.Errorf("failed to subscribe to room %s: %w", roomID, err)
+		}
+		b.subscriptions[roomID] = make(map[*redisSubscription]bool)
+	}
+	b.subscriptions[roomID][sub] = true
+	return sub, nil
+}
+
+// Unsubscribe unsubscribes the instance from the room's channel with its last subscription
+func (s *redisSubscription) Unsubscribe() error {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subs, ok := b.subscriptions[s.roomID]
+	if !ok || !subs[s] {
+		return nil
+	}
+	delete(subs, s)
+	if len(subs) > 0 {
+		return nil
+	}
+
+	delete(b.subscriptions, s.roomID)
+	ctx, cancel := context.WithTimeout(context.Background(), config.BrokerTimeout)
+	defer cancel()
+	return b.pubsub.Unsubscribe(ctx, b.channel(s.roomID))
+}
+
+// receive dispatches messages from Redis to the room's subscribers
+func (b *RedisBroker) receive() {
+	defer close(b.done)
+	for msg := range b.pubsub.Channel() {
+		roomID := strings.TrimPrefix(msg.Channel, b.prefix)
+		payload := []byte(msg.Payload)
+
+		b.mu.RLock()
+		handlers := make([]func([]byte), 0, len(b.subscriptions[roomID]))
+		for sub := range b.subscriptions[roomID] {
+			handlers = append(handlers, sub.handler)
+		}
+		b.mu.RUnlock()
+
+		for _, handler := range handlers {
+			handler(payload)
+		}
+	}
+}
+
+func (b *RedisBroker) Close() error {
+	if err := b.pubsub.Close(); err != nil {
+		slog.Error("Failed to close redis subscription", logging.Err(err))
+	}
+	<-b.done
+	return b.client.Close()
+}
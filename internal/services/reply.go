@@ -0,0 +1,25 @@
+package services
+
+import (
+	"context"
+
+	"github.com/damione1/planning-poker/internal/models"
+)
+
+// ReplyFunc delivers a response to the sender of a command only
+type ReplyFunc func(message *models.WSMessage)
+
+type replyKey struct{}
+
+// WithReply returns a copy of ctx whose command replies go to reply
+func WithReply(ctx context.Context, reply ReplyFunc) context.Context {
+	return context.WithValue(ctx, replyKey{}, reply)
+}
+
+// Reply sends message to the sender of the command handled with ctx.
+// Does nothing when the command has no sender to answer.
+func Reply(ctx context.Context, message *models.WSMessage) {
+	if reply, ok := ctx.Value(replyKey{}).(ReplyFunc); ok {
+		reply(message)
+	}
+}
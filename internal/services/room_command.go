@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/damione1/planning-poker/internal/models"
+)
+
+// ErrRateLimited is returned by Command for a command over its participant's rate limit
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// commandRate counts the commands a participant sent without a connection in the
+// current rate limit window
+type commandRate struct {
+	count       int
+	windowStart time.Time
+}
+
+// Command runs a message on the room's worker for a sender without a connection (the
+// REST API) and waits for its reply, the ack or error the handler answers with. It goes
+// through the same checks as a message read from a connection: the participant's
+// commands share the connection rate limit (ErrRateLimited), and the room's queue may
+// drop the command (ErrRoomBusy). The command still runs when ctx is cancelled while
+// it waits.
+func (h *Hub) Command(ctx context.Context, roomID, participantID string, message []byte) (*models.WSMessage, error) {
+	if !h.allowCommand(participantKey{roomID: roomID, participantID: participantID}) {
+		h.metrics.IncrementRateLimitViolations()
+		return nil, ErrRateLimited
+	}
+	h.metrics.IncrementMessagesReceived()
+
+	replies := make(chan *models.WSMessage, 1)
+	commandCtx := WithReply(context.WithoutCancel(ctx), func(message *models.WSMessage) {
+		select {
+		case replies <- message:
+		default: // Only the first reply answers the command
+		}
+	})
+	if err := h.Dispatch(commandCtx, roomID, participantID, message); err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply := <-replies:
+		return reply, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// allowCommand counts a command of the participant and reports whether it is within
+// the MaxMessagesPerSecond limit of the current window
+func (h *Hub) allowCommand(key participantKey) bool {
+	h.commandRatesMu.Lock()
+	defer h.commandRatesMu.Unlock()
+
+	now := time.Now()
+	window := h.cfg.Limits.RateLimitWindow
+	// Forget participants whose window is over, so the map only holds recent senders
+	if now.Sub(h.commandRatesSwept) > window {
+		for k, rate := range h.commandRates {
+			if now.Sub(rate.windowStart) > window {
+				delete(h.commandRates, k)
+			}
+		}
+		h.commandRatesSwept = now
+	}
+
+	rate, ok := h.commandRates[key]
+	if !ok || now.Sub(rate.windowStart) > window {
+		rate = &commandRate{windowStart: now}
+		h.commandRates[key] = rate
+	}
+	rate.count++
+	return rate.count <= h.cfg.Limits.MaxMessagesPerSecond
+}
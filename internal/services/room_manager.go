@@ -1,19 +1,31 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/pocketbase/pocketbase/core"
+	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/logging"
 	"github.com/damione1/planning-poker/internal/models"
 	"github.com/damione1/planning-poker/internal/security"
+	"github.com/damione1/planning-poker/internal/tracing"
 )
 
+// ErrRoundStateConflict is returned by round transitions when the current round is not in the state
+// (or not the round) the transition starts from, usually because another participant changed it first
+var ErrRoundStateConflict = errors.New("round is not in the expected state")
+
 type RoomManager struct {
-	app core.App
+	app     core.App
+	state   *RoomStateStore // optional, serves active rooms from memory
+	metrics *Metrics        // optional, times the operations behind client messages
+	ctx     context.Context // optional, parent of the operations' spans, see WithContext
 }
 
 func NewRoomManager(app core.App) *RoomManager {
@@ -22,6 +34,83 @@ func NewRoomManager(app core.App) *RoomManager {
 	}
 }
 
+// SetStateStore serves active rooms from memory with write-behind persistence.
+// Must be called before the room manager is used.
+func (rm *RoomManager) SetStateStore(store *RoomStateStore) {
+	rm.state = store
+}
+
+// SetMetrics records the duration of the operations behind client messages.
+// Must be called before the room manager is used.
+func (rm *RoomManager) SetMetrics(metrics *Metrics) {
+	rm.metrics = metrics
+}
+
+// WithContext returns a room manager sharing rm's state whose operations are traced
+// as children of the span in ctx
+func (rm *RoomManager) WithContext(ctx context.Context) *RoomManager {
+	scoped := *rm
+	scoped.ctx = ctx
+	return &scoped
+}
+
+// context returns the parent context of the room manager's spans
+func (rm *RoomManager) context() context.Context {
+	if rm.ctx == nil {
+		return context.Background()
+	}
+	return rm.ctx
+}
+
+// observe times an operation under the message type it serves and traces it. Call the
+// returned function when the operation ends.
+func (rm *RoomManager) observe(operation, msgType string, attrs ...attribute.KeyValue) func() {
+	start := time.Now()
+	_, span := tracing.Start(rm.context(), "RoomManager."+operation,
+		append(attrs, tracing.MessageType.String(msgType))...)
+
+	return func() {
+		span.End()
+		if rm.metrics != nil {
+			rm.metrics.ObserveDBOperation(msgType, time.Since(start))
+		}
+	}
+}
+
+// syncRoom runs a database update of a room. With a state store, the room's pending writes are
+// persisted first and its cached state is reloaded afterwards. update gets a room manager that
+// reads and writes the database directly.
+func (rm *RoomManager) syncRoom(roomID string, update func(db *RoomManager) error) error {
+	if rm.state == nil {
+		return update(rm)
+	}
+	return rm.state.Sync(roomID, func() error {
+		return update(&RoomManager{app: rm.app, ctx: rm.ctx})
+	})
+}
+
+// transitionRoom runs syncRoom with update inside a database transaction, so a round
+// transition is applied completely or not at all
+func (rm *RoomManager) transitionRoom(roomID string, update func(tx *RoomManager) error) error {
+	return rm.syncRoom(roomID, func(db *RoomManager) error {
+		_, span := tracing.Start(db.context(), "db.transaction", tracing.RoomID.String(roomID))
+		err := db.app.RunInTransaction(func(txApp core.App) error {
+			return update(&RoomManager{app: txApp, ctx: db.ctx})
+		})
+		tracing.End(span, err)
+		return err
+	})
+}
+
+// syncParticipantRoom runs syncRoom for the room of a participant
+func (rm *RoomManager) syncParticipantRoom(participantID string, update func(db *RoomManager) error) error {
+	participant, err := rm.GetParticipant(participantID)
+	if err != nil {
+		return update(rm) // Reports the missing participant
+	}
+	return rm.syncRoom(participant.GetString("room_id"), update)
+}
+
 // CreateRoom creates a new room in the database with initial round
 func (rm *RoomManager) CreateRoom(name, pointingMethod string, customValues []string, config *models.RoomConfig) (*core.Record, error) {
 	collection, err := rm.app.FindCollectionByNameOrId("rooms")
@@ -89,6 +178,14 @@ func (rm *RoomManager) CreateRoom(name, pointingMethod string, customValues []st
 
 // GetRoom retrieves a room by ID from the database
 func (rm *RoomManager) GetRoom(id string) (*core.Record, error) {
+	if rm.state != nil {
+		record, err := rm.state.Room(id)
+		if err != nil {
+			return nil, fmt.Errorf("room not found: %w", err)
+		}
+		return record, nil
+	}
+
 	record, err := rm.app.FindRecordById("rooms", id)
 	if err != nil {
 		return nil, fmt.Errorf("room not found: %w", err)
@@ -98,6 +195,13 @@ func (rm *RoomManager) GetRoom(id string) (*core.Record, error) {
 
 // UpdateRoomActivity updates the last_activity timestamp
 func (rm *RoomManager) UpdateRoomActivity(roomID string) error {
+	if rm.state != nil {
+		if err := rm.state.TouchRoom(roomID); err != nil {
+			return fmt.Errorf("room not found: %w", err)
+		}
+		return nil
+	}
+
 	record, err := rm.GetRoom(roomID)
 	if err != nil {
 		return err
@@ -116,38 +220,40 @@ func (rm *RoomManager) UpdateRoomState(roomID string, state models.RoomState) er
 	return rm.UpdateRoomActivity(roomID)
 }
 
-// RevealVotes updates the current round to revealed state and updates consensus streak
+// RevealVotes updates the current round to revealed state and updates consensus streak.
+// Returns ErrRoundStateConflict unless the round is open for voting.
 func (rm *RoomManager) RevealVotes(roomID string) error {
+	defer rm.observe("RevealVotes", models.MsgTypeReveal, tracing.RoomID.String(roomID))()
+
+	return rm.transitionRoom(roomID, func(tx *RoomManager) error {
+		return tx.revealVotes(roomID)
+	})
+}
+
+func (rm *RoomManager) revealVotes(roomID string) error {
 	currentRound, err := rm.GetCurrentRoundRecord(roomID)
 	if err != nil {
 		return fmt.Errorf("failed to get current round: %w", err)
 	}
 
+	if state := currentRound.GetString("state"); state != string(models.RoundStateVoting) {
+		return fmt.Errorf("%w: cannot reveal a %s round", ErrRoundStateConflict, state)
+	}
+
 	// Get votes to detect consensus
 	votes, err := rm.GetRoomVotes(roomID)
 	if err != nil {
 		return fmt.Errorf("failed to get votes: %w", err)
 	}
 
-	// Detect consensus (100% agreement)
-	consensus := false
-	if len(votes) > 0 {
-		valueBreakdown := make(map[string]int)
-		for _, vote := range votes {
-			value := vote.GetString("value")
-			valueBreakdown[value]++
-		}
-
-		// Find most common value count
-		maxCount := 0
-		for _, count := range valueBreakdown {
-			if count > maxCount {
-				maxCount = count
-			}
-		}
-		consensus = maxCount == len(votes)
+	weights, err := rm.GetParticipantWeights(roomID)
+	if err != nil {
+		return fmt.Errorf("failed to get participant weights: %w", err)
 	}
 
+	// Detect consensus (100% of the vote weight on one value)
+	_, consensus := summarizeWeightedVotes(votes, weights)
+
 	// Update room's consecutive consensus counter immediately on reveal
 	room, err := rm.GetRoom(roomID)
 	if err != nil {
@@ -187,11 +293,15 @@ func (rm *RoomManager) GetRoomState(roomID string) (models.RoomState, error) {
 
 // GetRoomParticipants retrieves all participants for a room
 func (rm *RoomManager) GetRoomParticipants(roomID string) ([]*core.Record, error) {
+	if rm.state != nil {
+		return rm.state.Participants(roomID)
+	}
+
 	records, err := rm.app.FindRecordsByFilter(
 		"participants",
 		"room_id = {:roomId}",
 		"",
-		100,
+		config.RoomMaxParticipants,
 		0,
 		map[string]any{"roomId": roomID},
 	)
@@ -203,6 +313,18 @@ func (rm *RoomManager) GetRoomParticipants(roomID string) ([]*core.Record, error
 
 // AddParticipant creates a new participant in the database
 func (rm *RoomManager) AddParticipant(roomID, name string, role models.ParticipantRole, sessionCookie string) (*core.Record, error) {
+	defer rm.observe("AddParticipant", models.MsgTypeJoin, tracing.RoomID.String(roomID))()
+
+	var record *core.Record
+	err := rm.syncRoom(roomID, func(db *RoomManager) error {
+		var err error
+		record, err = db.addParticipant(roomID, name, role, sessionCookie)
+		return err
+	})
+	return record, err
+}
+
+func (rm *RoomManager) addParticipant(roomID, name string, role models.ParticipantRole, sessionCookie string) (*core.Record, error) {
 	collection, err := rm.app.FindCollectionByNameOrId("participants")
 	if err != nil {
 		return nil, fmt.Errorf("failed to find participants collection: %w", err)
@@ -213,6 +335,7 @@ func (rm *RoomManager) AddParticipant(roomID, name string, role models.Participa
 	record.Set("room_id", roomID)
 	record.Set("name", name)
 	record.Set("role", string(role))
+	record.Set("weight", models.DefaultParticipantWeight)
 	record.Set("connected", true) // Set to true - participant is joining and will connect via WebSocket
 	record.Set("session_cookie", sessionCookie)
 	record.Set("joined_at", time.Now())
@@ -237,6 +360,10 @@ func (rm *RoomManager) AddParticipant(roomID, name string, role models.Participa
 
 // UpdateParticipantConnection updates participant connection status
 func (rm *RoomManager) UpdateParticipantConnection(participantID string, connected bool) error {
+	if rm.state != nil && rm.state.SetParticipantConnected(participantID, connected) {
+		return nil
+	}
+
 	record, err := rm.app.FindRecordById("participants", participantID)
 	if err != nil {
 		return fmt.Errorf("participant not found: %w", err)
@@ -249,6 +376,10 @@ func (rm *RoomManager) UpdateParticipantConnection(participantID string, connect
 
 // GetParticipantBySession retrieves a participant by session cookie and room
 func (rm *RoomManager) GetParticipantBySession(roomID, sessionCookie string) (*core.Record, error) {
+	if rm.state != nil {
+		return rm.state.ParticipantBySession(roomID, sessionCookie)
+	}
+
 	records, err := rm.app.FindRecordsByFilter(
 		"participants",
 		"room_id = {:roomId} && session_cookie = {:session}",
@@ -268,31 +399,30 @@ func (rm *RoomManager) GetParticipantBySession(roomID, sessionCookie string) (*c
 
 // GetParticipant retrieves a participant by ID
 func (rm *RoomManager) GetParticipant(participantID string) (*core.Record, error) {
+	if rm.state != nil {
+		if participant, ok := rm.state.Participant(participantID); ok {
+			return participant, nil
+		}
+	}
 	return rm.app.FindRecordById("participants", participantID)
 }
 
 // GetCurrentRound retrieves the current round number for a room
 func (rm *RoomManager) GetCurrentRound(roomID string) (int, error) {
-	room, err := rm.GetRoom(roomID)
+	round, err := rm.GetCurrentRoundRecord(roomID)
 	if err != nil {
-		return 1, nil
-	}
-
-	currentRoundID := room.GetString("current_round_id")
-	if currentRoundID == "" {
 		return 1, nil // Fallback to round 1
 	}
 
-	round, err := rm.app.FindRecordById("rounds", currentRoundID)
-	if err != nil {
-		return 1, nil
-	}
-
 	return round.GetInt("round_number"), nil
 }
 
 // GetCurrentRoundRecord retrieves the current round record for a room
 func (rm *RoomManager) GetCurrentRoundRecord(roomID string) (*core.Record, error) {
+	if rm.state != nil {
+		return rm.state.CurrentRound(roomID)
+	}
+
 	room, err := rm.GetRoom(roomID)
 	if err != nil {
 		return nil, fmt.Errorf("room not found: %w", err)
@@ -313,7 +443,30 @@ func (rm *RoomManager) GetCurrentRoundRecord(roomID string) (*core.Record, error
 
 // CastVote records or updates a participant's vote in the database
 func (rm *RoomManager) CastVote(roomID, participantID, value string) error {
-	fmt.Printf("[DEBUG] CastVote called: roomID=%s, participantID=%s, value=%s\n", roomID, participantID, value)
+	return rm.CastVoteWithConfidence(roomID, participantID, value, models.ConfidenceNone)
+}
+
+// CastVoteWithConfidence records or updates a participant's vote along with an optional
+// confidence level (models.ConfidenceNone clears any previously submitted confidence)
+func (rm *RoomManager) CastVoteWithConfidence(roomID, participantID, value string, confidence int) error {
+	return rm.saveVote(roomID, participantID, value, nil, confidence)
+}
+
+// CastDimensionalVote records or updates a multi-dimensional vote
+// value holds the combined score so that single-value statistics keep working
+func (rm *RoomManager) CastDimensionalVote(roomID, participantID, value string, dimensionValues map[string]string, confidence int) error {
+	return rm.saveVote(roomID, participantID, value, dimensionValues, confidence)
+}
+
+// saveVote creates or updates the participant's vote record for the current round
+func (rm *RoomManager) saveVote(roomID, participantID, value string, dimensionValues map[string]string, confidence int) error {
+	defer rm.observe("SaveVote", models.MsgTypeVote, tracing.RoomID.String(roomID), tracing.ParticipantID.String(participantID))()
+
+	if rm.state != nil {
+		return rm.state.SaveVote(roomID, participantID, func(vote *core.Record) {
+			setVoteValues(vote, value, dimensionValues, confidence)
+		})
+	}
 
 	// Get current round record
 	currentRound, err := rm.GetCurrentRoundRecord(roomID)
@@ -321,7 +474,6 @@ func (rm *RoomManager) CastVote(roomID, participantID, value string) error {
 		return fmt.Errorf("failed to get current round: %w", err)
 	}
 	currentRoundID := currentRound.Id
-	fmt.Printf("[DEBUG] Current round ID: %s, round number: %d\n", currentRoundID, currentRound.GetInt("round_number"))
 
 	// Check if vote already exists for this participant in this round
 	existingVotes, err := rm.app.FindRecordsByFilter(
@@ -339,11 +491,9 @@ func (rm *RoomManager) CastVote(roomID, participantID, value string) error {
 	var record *core.Record
 	if err == nil && len(existingVotes) > 0 {
 		// Update existing vote
-		fmt.Printf("[DEBUG] Updating existing vote\n")
 		record = existingVotes[0]
 	} else {
 		// Create new vote
-		fmt.Printf("[DEBUG] Creating new vote (err=%v, count=%d)\n", err, len(existingVotes))
 		collection, err := rm.app.FindCollectionByNameOrId("votes")
 		if err != nil {
 			return fmt.Errorf("failed to find votes collection: %w", err)
@@ -356,28 +506,68 @@ func (rm *RoomManager) CastVote(roomID, participantID, value string) error {
 		record.Set("round_number", currentRound.GetInt("round_number"))
 	}
 
+	setVoteValues(record, value, dimensionValues, confidence)
+
+	if err := rm.app.Save(record); err != nil {
+		return fmt.Errorf("failed to save vote: %w", err)
+	}
+	logging.ForCommand(roomID, participantID, models.MsgTypeVote).Debug("Vote saved", "vote_id", record.Id)
+
+	// Update room activity
+	return rm.UpdateRoomActivity(roomID)
+}
+
+// setVoteValues fills in the submitted values of a vote record
+func setVoteValues(record *core.Record, value string, dimensionValues map[string]string, confidence int) {
 	record.Set("value", value)
+	record.Set("confidence", confidence)
+	record.Set("dimension_values", dimensionValues)
 	record.Set("voted_at", time.Now())
+}
 
-	fmt.Printf("[DEBUG] About to save vote record with: participant_id=%s, room_id=%s, round_id=%s, value=%s\n",
-		record.GetString("participant_id"),
-		record.GetString("room_id"),
-		record.GetString("round_id"),
-		record.GetString("value"))
+// RetractVote deletes a participant's vote for the current round
+// Votes can only be retracted while the round is still open for voting
+func (rm *RoomManager) RetractVote(roomID, participantID string) error {
+	defer rm.observe("RetractVote", models.MsgTypeUnvote, tracing.RoomID.String(roomID), tracing.ParticipantID.String(participantID))()
 
-	if err := rm.app.Save(record); err != nil {
-		fmt.Printf("[DEBUG] Failed to save vote: %v\n", err)
-		return fmt.Errorf("failed to save vote: %w", err)
+	if rm.state != nil {
+		return rm.state.RetractVote(roomID, participantID)
 	}
 
-	fmt.Printf("[DEBUG] Vote saved successfully with ID: %s\n", record.Id)
+	currentRound, err := rm.GetCurrentRoundRecord(roomID)
+	if err != nil {
+		return fmt.Errorf("failed to get current round: %w", err)
+	}
+
+	if currentRound.GetString("state") != string(models.RoundStateVoting) {
+		return fmt.Errorf("votes can only be retracted while voting")
+	}
+
+	vote, err := rm.app.FindFirstRecordByFilter(
+		"votes",
+		"participant_id = {:participantId} && round_id = {:roundId}",
+		map[string]any{
+			"participantId": participantID,
+			"roundId":       currentRound.Id,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("no vote to retract")
+	}
+
+	if err := rm.app.Delete(vote); err != nil {
+		return fmt.Errorf("failed to delete vote: %w", err)
+	}
 
-	// Update room activity
 	return rm.UpdateRoomActivity(roomID)
 }
 
 // GetRoomVotes retrieves all votes for a room's current round
 func (rm *RoomManager) GetRoomVotes(roomID string) ([]*core.Record, error) {
+	if rm.state != nil {
+		return rm.state.Votes(roomID)
+	}
+
 	currentRound, err := rm.GetCurrentRoundRecord(roomID)
 	if err != nil {
 		return nil, err
@@ -387,7 +577,7 @@ func (rm *RoomManager) GetRoomVotes(roomID string) ([]*core.Record, error) {
 		"votes",
 		"round_id = {:roundId}",
 		"",
-		100,
+		config.RoomMaxParticipants,
 		0,
 		map[string]any{
 			"roundId": currentRound.Id,
@@ -399,6 +589,15 @@ func (rm *RoomManager) GetRoomVotes(roomID string) ([]*core.Record, error) {
 	return records, nil
 }
 
+// GetVoteDimensionValues returns the per-dimension values of a vote record (nil for single-value votes)
+func (rm *RoomManager) GetVoteDimensionValues(vote *core.Record) map[string]string {
+	var values map[string]string
+	if err := vote.UnmarshalJSONField("dimension_values", &values); err != nil {
+		return nil
+	}
+	return values
+}
+
 // HaveAllVotersVoted checks if all voter participants have submitted a vote for the current round
 func (rm *RoomManager) HaveAllVotersVoted(roomID string) (bool, error) {
 	// Get all participants for the room
@@ -430,27 +629,98 @@ func (rm *RoomManager) HaveAllVotersVoted(roomID string) (bool, error) {
 	return len(votes) == voterCount, nil
 }
 
+// GetVoteTally counts voters, connected voters and their votes for the current round
+func (rm *RoomManager) GetVoteTally(roomID string) (models.VoteTally, error) {
+	var tally models.VoteTally
+
+	participants, err := rm.GetRoomParticipants(roomID)
+	if err != nil {
+		return tally, fmt.Errorf("failed to get participants: %w", err)
+	}
+
+	// Track connection status of voters
+	voters := make(map[string]bool)
+	for _, p := range participants {
+		if p.GetString("role") != string(models.RoleVoter) {
+			continue
+		}
+		connected := p.GetBool("connected")
+		voters[p.Id] = connected
+		tally.Voters++
+		if connected {
+			tally.ConnectedVoters++
+		}
+	}
+
+	votes, err := rm.GetRoomVotes(roomID)
+	if err != nil {
+		return tally, fmt.Errorf("failed to get votes: %w", err)
+	}
+
+	for _, vote := range votes {
+		connected, isVoter := voters[vote.GetString("participant_id")]
+		if !isVoter {
+			continue
+		}
+		tally.Votes++
+		if connected {
+			tally.ConnectedVotes++
+		}
+	}
+
+	return tally, nil
+}
+
+// IsRevealRuleMet checks if the current round satisfies the room's auto-reveal rule
+func (rm *RoomManager) IsRevealRuleMet(roomID string, rule models.RevealRule) (bool, error) {
+	tally, err := rm.GetVoteTally(roomID)
+	if err != nil {
+		return false, err
+	}
+	return rule.IsSatisfied(tally), nil
+}
+
 // ResetRound clears votes for current round and returns to voting state
 // Does NOT create a new round - just clears the current one
 func (rm *RoomManager) ResetRound(roomID string) error {
+	defer rm.observe("ResetRound", models.MsgTypeReset, tracing.RoomID.String(roomID))()
+
+	return rm.transitionRoom(roomID, func(tx *RoomManager) error {
+		return tx.resetRound(roomID)
+	})
+}
+
+func (rm *RoomManager) resetRound(roomID string) error {
 	// Get current round
 	currentRound, err := rm.GetCurrentRoundRecord(roomID)
 	if err != nil {
 		return fmt.Errorf("failed to get current round: %w", err)
 	}
 
-	// Delete all votes for this round
-	votes, err := rm.app.FindRecordsByFilter(
-		"votes",
-		"round_id = {:roundId}",
-		"",
-		1000,
-		0,
-		map[string]any{"roundId": currentRound.Id},
-	)
-	if err == nil {
+	if state := currentRound.GetString("state"); state == string(models.RoundStateCompleted) {
+		return fmt.Errorf("%w: cannot reset a %s round", ErrRoundStateConflict, state)
+	}
+
+	// Delete all votes for this round, a page at a time. A failure rolls the reset back.
+	for {
+		votes, err := rm.app.FindRecordsByFilter(
+			"votes",
+			"round_id = {:roundId}",
+			"",
+			config.RoomMaxParticipants,
+			0,
+			map[string]any{"roundId": currentRound.Id},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to find votes: %w", err)
+		}
 		for _, vote := range votes {
-			_ = rm.app.Delete(vote) // Best effort - cleanup operation
+			if err := rm.app.Delete(vote); err != nil {
+				return fmt.Errorf("failed to delete vote: %w", err)
+			}
+		}
+		if len(votes) < config.RoomMaxParticipants {
+			break
 		}
 	}
 
@@ -475,6 +745,14 @@ func (rm *RoomManager) IsRoomCreator(roomID, participantID string) bool {
 
 // UpdateParticipantName updates a participant's name
 func (rm *RoomManager) UpdateParticipantName(participantID, newName string) error {
+	defer rm.observe("UpdateParticipantName", models.MsgTypeUpdateName, tracing.ParticipantID.String(participantID))()
+
+	return rm.syncParticipantRoom(participantID, func(db *RoomManager) error {
+		return db.updateParticipantName(participantID, newName)
+	})
+}
+
+func (rm *RoomManager) updateParticipantName(participantID, newName string) error {
 	// Validate name (should already be validated by caller, but defense in depth)
 	sanitizedName, err := security.ValidateParticipantName(newName)
 	if err != nil {
@@ -483,21 +761,127 @@ func (rm *RoomManager) UpdateParticipantName(participantID, newName string) erro
 
 	participant, err := rm.GetParticipant(participantID)
 	if err != nil {
-		log.Printf("Failed to get participant %s: %v", participantID, err)
+		logging.ForRoom("", participantID).Warn("Failed to get participant", logging.Err(err))
 		return fmt.Errorf("participant not found")
 	}
 
 	participant.Set("name", sanitizedName)
 	if err := rm.app.Save(participant); err != nil {
-		log.Printf("Failed to save participant name update: %v", err)
+		logging.ForRoom(participant.GetString("room_id"), participantID).Error("Failed to save participant name update", logging.Err(err))
 		return fmt.Errorf("failed to update participant name")
 	}
 
 	return nil
 }
 
+// UpdateParticipantWeight sets the weight a participant's vote carries in averages and consensus
+func (rm *RoomManager) UpdateParticipantWeight(participantID string, weight float64) error {
+	defer rm.observe("UpdateParticipantWeight", models.MsgTypeUpdateWeight, tracing.ParticipantID.String(participantID))()
+
+	return rm.syncParticipantRoom(participantID, func(db *RoomManager) error {
+		return db.updateParticipantWeight(participantID, weight)
+	})
+}
+
+func (rm *RoomManager) updateParticipantWeight(participantID string, weight float64) error {
+	// Validate weight (should already be validated by caller, but defense in depth)
+	weight, err := security.ValidateParticipantWeight(weight)
+	if err != nil {
+		return err
+	}
+
+	participant, err := rm.GetParticipant(participantID)
+	if err != nil {
+		logging.ForRoom("", participantID).Warn("Failed to get participant", logging.Err(err))
+		return fmt.Errorf("participant not found")
+	}
+
+	participant.Set("weight", weight)
+	if err := rm.app.Save(participant); err != nil {
+		logging.ForRoom(participant.GetString("room_id"), participantID).Error("Failed to save participant weight update", logging.Err(err))
+		return fmt.Errorf("failed to update participant weight")
+	}
+
+	return nil
+}
+
+// GetParticipantWeights returns the vote weight of every participant in a room keyed by participant ID
+func (rm *RoomManager) GetParticipantWeights(roomID string) (map[string]float64, error) {
+	participants, err := rm.GetRoomParticipants(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := make(map[string]float64, len(participants))
+	for _, p := range participants {
+		weights[p.Id] = ParticipantWeight(p)
+	}
+	return weights, nil
+}
+
+// ParticipantWeight reads the vote weight of a participant record.
+// Records created before weights existed have no weight and count as the default.
+func ParticipantWeight(record *core.Record) float64 {
+	weight := record.GetFloat("weight")
+	if weight <= 0 {
+		return models.DefaultParticipantWeight
+	}
+	return weight
+}
+
+// summarizeWeightedVotes returns the weighted average of positive numeric votes and whether
+// all of the vote weight went to a single value. Voters missing from weights count as the default.
+func summarizeWeightedVotes(votes []*core.Record, weights map[string]float64) (float64, bool) {
+	var sum, numericWeight, totalWeight float64
+	validator := NewVoteValidator()
+	weightBreakdown := make(map[string]float64)
+
+	for _, vote := range votes {
+		weight, ok := weights[vote.GetString("participant_id")]
+		if !ok || weight <= 0 {
+			weight = models.DefaultParticipantWeight
+		}
+
+		value := vote.GetString("value")
+		weightBreakdown[value] += weight
+		totalWeight += weight
+
+		if num, ok := validator.ParseNumericValue(value); ok && num > 0 {
+			sum += num * weight
+			numericWeight += weight
+		}
+	}
+
+	var avgScore float64
+	if numericWeight > 0 {
+		avgScore = sum / numericWeight
+	}
+
+	// Detect consensus (100% agreement)
+	consensus := false
+	if len(votes) > 0 {
+		maxWeight := 0.0
+		for _, w := range weightBreakdown {
+			if w > maxWeight {
+				maxWeight = w
+			}
+		}
+		consensus = maxWeight == totalWeight
+	}
+
+	return avgScore, consensus
+}
+
 // UpdateRoomName updates a room's name
 func (rm *RoomManager) UpdateRoomName(roomID, newName string) error {
+	defer rm.observe("UpdateRoomName", models.MsgTypeUpdateRoomName, tracing.RoomID.String(roomID))()
+
+	return rm.syncRoom(roomID, func(db *RoomManager) error {
+		return db.updateRoomName(roomID, newName)
+	})
+}
+
+func (rm *RoomManager) updateRoomName(roomID, newName string) error {
 	// Validate name (should already be validated by caller, but defense in depth)
 	sanitizedName, err := security.ValidateRoomName(newName)
 	if err != nil {
@@ -506,20 +890,41 @@ func (rm *RoomManager) UpdateRoomName(roomID, newName string) error {
 
 	room, err := rm.GetRoom(roomID)
 	if err != nil {
-		log.Printf("Failed to get room %s: %v", roomID, err)
+		logging.ForRoom(roomID, "").Warn("Failed to get room", logging.Err(err))
 		return fmt.Errorf("room not found")
 	}
 
 	room.Set("name", sanitizedName)
 	room.Set("last_activity", time.Now())
 	if err := rm.app.Save(room); err != nil {
-		log.Printf("Failed to save room name update: %v", err)
+		logging.ForRoom(roomID, "").Error("Failed to save room name update", logging.Err(err))
 		return fmt.Errorf("failed to update room name")
 	}
 
 	return nil
 }
 
+// SetSlackResponseURL stores the Slack response_url reveal summaries are posted to
+func (rm *RoomManager) SetSlackResponseURL(roomID, responseURL string, expiresAt time.Time) error {
+	return rm.syncRoom(roomID, func(db *RoomManager) error {
+		return db.setSlackResponseURL(roomID, responseURL, expiresAt)
+	})
+}
+
+func (rm *RoomManager) setSlackResponseURL(roomID, responseURL string, expiresAt time.Time) error {
+	room, err := rm.GetRoom(roomID)
+	if err != nil {
+		return fmt.Errorf("room not found")
+	}
+
+	room.Set("slack_response_url", responseURL)
+	room.Set("slack_response_expires_at", expiresAt)
+	if err := rm.app.Save(room); err != nil {
+		return fmt.Errorf("failed to save slack response url: %w", err)
+	}
+	return nil
+}
+
 // CreateRoundForRoom creates a new round for a room
 func (rm *RoomManager) CreateRoundForRoom(roomID string, roundNumber int) (*core.Record, error) {
 	collection, err := rm.app.FindCollectionByNameOrId("rounds")
@@ -540,8 +945,38 @@ func (rm *RoomManager) CreateRoundForRoom(roomID string, roundNumber int) (*core
 	return record, nil
 }
 
+// GetRoomRounds retrieves all rounds of a room ordered by round number
+func (rm *RoomManager) GetRoomRounds(roomID string) ([]*core.Record, error) {
+	records, err := rm.app.FindRecordsByFilter(
+		"rounds",
+		"room_id = {:roomId}",
+		"round_number",
+		500,
+		0,
+		map[string]any{"roomId": roomID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rounds: %w", err)
+	}
+	return records, nil
+}
+
 // CompleteRound marks a round as completed and saves statistics
 func (rm *RoomManager) CompleteRound(roundID string, avgScore float64, totalVotes int, consensus bool) error {
+	if rm.state == nil {
+		return rm.completeRound(roundID, avgScore, totalVotes, consensus)
+	}
+
+	round, err := rm.app.FindRecordById("rounds", roundID)
+	if err != nil {
+		return fmt.Errorf("round not found: %w", err)
+	}
+	return rm.syncRoom(round.GetString("room_id"), func(db *RoomManager) error {
+		return db.completeRound(roundID, avgScore, totalVotes, consensus)
+	})
+}
+
+func (rm *RoomManager) completeRound(roundID string, avgScore float64, totalVotes int, consensus bool) error {
 	round, err := rm.app.FindRecordById("rounds", roundID)
 	if err != nil {
 		return fmt.Errorf("round not found: %w", err)
@@ -562,51 +997,51 @@ func (rm *RoomManager) CompleteRound(roundID string, avgScore float64, totalVote
 
 // CreateNextRound completes the current round and creates a new one
 func (rm *RoomManager) CreateNextRound(roomID string) (*core.Record, error) {
+	return rm.CreateNextRoundFrom(roomID, "")
+}
+
+// CreateNextRoundFrom completes the round roundID and creates a new one.
+// Returns ErrRoundStateConflict if roundID is no longer the current round, so that
+// simultaneous requests advance the room once. An empty roundID advances whatever round is current.
+func (rm *RoomManager) CreateNextRoundFrom(roomID, roundID string) (*core.Record, error) {
+	defer rm.observe("CreateNextRoundFrom", models.MsgTypeNextRound, tracing.RoomID.String(roomID))()
+
+	var newRound *core.Record
+	err := rm.transitionRoom(roomID, func(tx *RoomManager) error {
+		var err error
+		newRound, err = tx.createNextRound(roomID, roundID)
+		return err
+	})
+	return newRound, err
+}
+
+func (rm *RoomManager) createNextRound(roomID, roundID string) (*core.Record, error) {
 	// Get current round
 	currentRound, err := rm.GetCurrentRoundRecord(roomID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current round: %w", err)
 	}
 
+	if roundID != "" && currentRound.Id != roundID {
+		return nil, fmt.Errorf("%w: round %s is no longer current", ErrRoundStateConflict, roundID)
+	}
+	if state := currentRound.GetString("state"); state == string(models.RoundStateCompleted) {
+		return nil, fmt.Errorf("%w: round %s is already %s", ErrRoundStateConflict, currentRound.Id, state)
+	}
+
 	// Get votes to calculate statistics
 	votes, err := rm.GetRoomVotes(roomID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get votes: %w", err)
 	}
 
-	// Calculate average and detect consensus (supports float values)
-	var sum float64
-	var count int
-	validator := NewVoteValidator()
-	valueBreakdown := make(map[string]int)
-
-	for _, vote := range votes {
-		value := vote.GetString("value")
-		valueBreakdown[value]++
-
-		if num, ok := validator.ParseNumericValue(value); ok && num > 0 {
-			sum += num
-			count++
-		}
-	}
-
-	var avgScore float64
-	if count > 0 {
-		avgScore = sum / float64(count)
+	weights, err := rm.GetParticipantWeights(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get participant weights: %w", err)
 	}
 
-	// Detect consensus (100% agreement)
-	consensus := false
-	if len(votes) > 0 {
-		// Find most common value count
-		maxCount := 0
-		for _, count := range valueBreakdown {
-			if count > maxCount {
-				maxCount = count
-			}
-		}
-		consensus = maxCount == len(votes)
-	}
+	// Calculate weighted average and detect consensus (supports float values)
+	avgScore, consensus := summarizeWeightedVotes(votes, weights)
 
 	// Complete current round with stats
 	if err := rm.CompleteRound(currentRound.Id, avgScore, len(votes), consensus); err != nil {
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrRoomBusy is returned by Dispatch for a command dropped because its room's queue
+// stayed full
+var ErrRoomBusy = errors.New("room is busy")
+
+// roomQueue holds the commands of one room, run in order by the room's worker
+type roomQueue struct {
+	commands chan roomCommand
+	pending  int // Commands dispatched but not yet taken by the worker, guarded by Hub.queuesMu
+}
+
+// roomCommand is a message waiting for its room's worker
+type roomCommand struct {
+	ctx           context.Context // Carries the trace of the message
+	participantID string
+	message       []byte
+	queuedAt      time.Time
+}
+
+// Dispatch queues a message for the room's worker. Messages of a room are handled one
+// at a time in dispatch order, while different rooms are handled concurrently, up to
+// the MaxConcurrentRoomCommands limit at once. Waits while the room's queue is full, so
+// a backed-up room slows down its own senders only, but no longer than the
+// RoomQueueFullTimeout limit: the command is then dropped and ErrRoomBusy returned, so
+// that the sender's connection gets back to reading (and answering pings). The handler
+// gets ctx, so the trace of a message continues on the worker.
+func (h *Hub) Dispatch(ctx context.Context, roomID, participantID string, message []byte) error {
+	h.queuesMu.Lock()
+	queue, ok := h.queues[roomID]
+	if !ok {
+		queue = &roomQueue{commands: make(chan roomCommand, h.cfg.Limits.RoomCommandQueueSize)}
+		h.queues[roomID] = queue
+		go h.runRoomQueue(roomID, queue)
+	}
+	queue.pending++
+	h.queuesMu.Unlock()
+
+	h.metrics.IncrementQueuedCommands()
+	command := roomCommand{ctx: ctx, participantID: participantID, message: message, queuedAt: time.Now()}
+	select {
+	case queue.commands <- command:
+		return nil
+	default:
+	}
+
+	h.metrics.IncrementQueueFullWaits()
+	timeout := time.NewTimer(h.cfg.Limits.RoomQueueFullTimeout)
+	defer timeout.Stop()
+	select {
+	case queue.commands <- command:
+		return nil
+	case <-timeout.C:
+		h.queuesMu.Lock()
+		queue.pending--
+		h.queuesMu.Unlock()
+		h.metrics.RecordQueueFullDrop()
+		return ErrRoomBusy
+	}
+}
+
+// runRoomQueue handles a room's commands until the room has been idle for
+// the RoomWorkerIdleTimeout limit
+func (h *Hub) runRoomQueue(roomID string, queue *roomQueue) {
+	h.metrics.IncrementRoomWorkers()
+	defer h.metrics.DecrementRoomWorkers()
+
+	idle := time.NewTimer(h.cfg.Limits.RoomWorkerIdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case command := <-queue.commands:
+			h.queuesMu.Lock()
+			queue.pending--
+			h.queuesMu.Unlock()
+
+			h.runCommand(roomID, command)
+			idle.Reset(h.cfg.Limits.RoomWorkerIdleTimeout)
+
+		case <-idle.C:
+			// A sender that already counted its command is about to queue it: keep going
+			h.queuesMu.Lock()
+			if queue.pending == 0 {
+				delete(h.queues, roomID)
+				h.queuesMu.Unlock()
+				return
+			}
+			h.queuesMu.Unlock()
+			idle.Reset(h.cfg.Limits.RoomWorkerIdleTimeout)
+		}
+	}
+}
+
+// runCommand handles one command once a worker slot is free
+func (h *Hub) runCommand(roomID string, command roomCommand) {
+	select {
+	case h.workerSlots <- struct{}{}:
+	default:
+		h.metrics.IncrementWorkerSlotWaits()
+		h.workerSlots <- struct{}{}
+	}
+	defer func() { <-h.workerSlots }()
+
+	h.metrics.RecordCommandStarted(time.Since(command.queuedAt))
+	if h.messageHandler != nil {
+		h.messageHandler(command.ctx, roomID, command.participantID, command.message)
+	}
+}
@@ -0,0 +1,616 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/logging"
+	"github.com/damione1/planning-poker/internal/models"
+)
+
+// RoomStateStore keeps the state of active rooms in memory: the room record, its current round,
+// participants and current-round votes.
+//
+// Reads are served from memory. Votes are written to the database before they are applied in
+// memory, so an acknowledged vote survives a crash. Activity timestamps and connection changes
+// are applied in memory and written in the background (write-behind); failed writes stay queued
+// and are retried until they succeed. Everything else goes through Sync, which persists the
+// room's pending writes before running the database update and reloads the room afterwards.
+type RoomStateStore struct {
+	app core.App
+
+	mu               sync.Mutex
+	rooms            map[string]*roomState
+	participantRooms map[string]string // participantID -> roomID, filled when a room is loaded
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// roomState is the in-memory copy of one room. All fields are guarded by mu.
+type roomState struct {
+	mu sync.Mutex
+	id string
+
+	loaded     bool
+	evicted    bool        // Removed from the store, holders must look the room up again
+	stale      atomic.Bool // The room record changed in the database, see Invalidate. Set without mu.
+	lastAccess time.Time
+
+	room         *core.Record
+	round        *core.Record // nil when the room has no current round
+	participants []*core.Record
+	votes        []*core.Record // Votes of the current round
+
+	// Write-behind queue of presence and activity changes
+	activity time.Time      // Pending last_activity of the room, written on its own so other room fields are left alone
+	dirty    []*core.Record // Records to save, in order of first change
+	attempts map[string]int // Record ID -> consecutive failed writes
+}
+
+// NewRoomStateStore creates an empty store. Call Run to start background persistence.
+func NewRoomStateStore(app core.App) *RoomStateStore {
+	return &RoomStateStore{
+		app:              app,
+		rooms:            make(map[string]*roomState),
+		participantRooms: make(map[string]string),
+		stop:             make(chan struct{}),
+	}
+}
+
+// Run writes pending changes every config.RoomStateFlushInterval and drops idle rooms until Close is called
+func (s *RoomStateStore) Run() {
+	ticker := time.NewTicker(config.RoomStateFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			_ = s.Flush() // Failed writes stay queued and are logged
+			s.evictIdle(time.Now().Add(-config.RoomStateIdleTimeout))
+		}
+	}
+}
+
+// Close stops background persistence and writes all pending changes
+func (s *RoomStateStore) Close() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	return s.Flush()
+}
+
+// Flush writes the pending changes of every room, returning the first error
+func (s *RoomStateStore) Flush() error {
+	s.mu.Lock()
+	rooms := make([]*roomState, 0, len(s.rooms))
+	for _, rs := range s.rooms {
+		rooms = append(rooms, rs)
+	}
+	s.mu.Unlock()
+
+	var firstErr error
+	for _, rs := range rooms {
+		rs.mu.Lock()
+		err := s.flush(rs)
+		rs.mu.Unlock()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Sync runs a database update of a room. Pending writes are persisted first and the room is
+// reloaded from the database on its next read. Reads and writes of the room wait meanwhile.
+func (s *RoomStateStore) Sync(roomID string, update func() error) error {
+	rs := s.lock(roomID)
+	defer rs.mu.Unlock()
+
+	if err := s.flush(rs); err != nil {
+		return fmt.Errorf("failed to persist pending room changes: %w", err)
+	}
+
+	err := update()
+	rs.unload()
+	return err
+}
+
+// Invalidate marks a room's cached record as outdated, e.g. after an edit in the dashboard:
+// it is read again from the database on the room's next use. Does not wait for the room,
+// so it can run from record hooks, including those of updates made through Sync.
+func (s *RoomStateStore) Invalidate(roomID string) {
+	s.mu.Lock()
+	rs, ok := s.rooms[roomID]
+	s.mu.Unlock()
+	if ok {
+		rs.stale.Store(true)
+	}
+}
+
+// Room returns a copy of the room record
+func (s *RoomStateStore) Room(roomID string) (*core.Record, error) {
+	rs, err := s.lockLoaded(roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.mu.Unlock()
+
+	return rs.room.Clone(), nil
+}
+
+// CurrentRound returns a copy of the room's current round record
+func (s *RoomStateStore) CurrentRound(roomID string) (*core.Record, error) {
+	rs, err := s.lockLoaded(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("room not found: %w", err)
+	}
+	defer rs.mu.Unlock()
+
+	round, err := rs.currentRound()
+	if err != nil {
+		return nil, err
+	}
+	return round.Clone(), nil
+}
+
+// Participants returns copies of the room's participant records
+func (s *RoomStateStore) Participants(roomID string) ([]*core.Record, error) {
+	rs, err := s.lockLoaded(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get participants: %w", err)
+	}
+	defer rs.mu.Unlock()
+
+	return cloneRecords(rs.participants), nil
+}
+
+// Participant returns a copy of a participant record.
+// ok is false when the participant's room is not in memory.
+func (s *RoomStateStore) Participant(participantID string) (*core.Record, bool) {
+	rs, ok := s.lockParticipantRoom(participantID)
+	if !ok {
+		return nil, false
+	}
+	defer rs.mu.Unlock()
+
+	participant := rs.participant(participantID)
+	if participant == nil {
+		return nil, false
+	}
+	return participant.Clone(), true
+}
+
+// ParticipantBySession returns a copy of the room participant owning a session cookie
+func (s *RoomStateStore) ParticipantBySession(roomID, sessionCookie string) (*core.Record, error) {
+	rs, err := s.lockLoaded(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("participant not found")
+	}
+	defer rs.mu.Unlock()
+
+	for _, participant := range rs.participants {
+		if participant.GetString("session_cookie") == sessionCookie {
+			return participant.Clone(), nil
+		}
+	}
+	return nil, fmt.Errorf("participant not found")
+}
+
+// Votes returns copies of the current round's vote records
+func (s *RoomStateStore) Votes(roomID string) ([]*core.Record, error) {
+	rs, err := s.lockLoaded(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("room not found: %w", err)
+	}
+	defer rs.mu.Unlock()
+
+	if _, err := rs.currentRound(); err != nil {
+		return nil, err
+	}
+	return cloneRecords(rs.votes), nil
+}
+
+// SaveVote creates or updates the participant's vote for the current round.
+// set fills in the vote values; the vote is saved to the database before it is served.
+func (s *RoomStateStore) SaveVote(roomID, participantID string, set func(vote *core.Record)) error {
+	rs, err := s.lockLoaded(roomID)
+	if err != nil {
+		return fmt.Errorf("failed to get current round: room not found: %w", err)
+	}
+	defer rs.mu.Unlock()
+
+	round, err := rs.currentRound()
+	if err != nil {
+		return fmt.Errorf("failed to get current round: %w", err)
+	}
+
+	vote := rs.vote(participantID)
+	var previous map[string]any // Values of an existing vote, restored when the save fails
+	if vote != nil {
+		previous = vote.FieldsData()
+	} else {
+		collection, err := s.app.FindCachedCollectionByNameOrId("votes")
+		if err != nil {
+			return fmt.Errorf("failed to find votes collection: %w", err)
+		}
+		vote = core.NewRecord(collection)
+		vote.Set("participant_id", participantID)
+		vote.Set("room_id", roomID)
+		vote.Set("round_id", round.Id)
+		// Keep round_number for backward compatibility during migration
+		vote.Set("round_number", round.GetInt("round_number"))
+	}
+
+	set(vote)
+	if err := s.app.Save(vote); err != nil {
+		if previous != nil {
+			vote.Load(previous)
+		}
+		return fmt.Errorf("failed to save vote: %w", err)
+	}
+
+	if previous == nil {
+		rs.votes = append(rs.votes, vote)
+	}
+	rs.touch()
+	return nil
+}
+
+// RetractVote deletes the participant's vote while the current round is open for voting
+func (s *RoomStateStore) RetractVote(roomID, participantID string) error {
+	rs, err := s.lockLoaded(roomID)
+	if err != nil {
+		return fmt.Errorf("failed to get current round: room not found: %w", err)
+	}
+	defer rs.mu.Unlock()
+
+	round, err := rs.currentRound()
+	if err != nil {
+		return fmt.Errorf("failed to get current round: %w", err)
+	}
+
+	if round.GetString("state") != string(models.RoundStateVoting) {
+		return fmt.Errorf("votes can only be retracted while voting")
+	}
+
+	vote := rs.vote(participantID)
+	if vote == nil {
+		return fmt.Errorf("no vote to retract")
+	}
+
+	if err := s.app.Delete(vote); err != nil {
+		return fmt.Errorf("failed to delete vote: %w", err)
+	}
+	rs.votes = slices.DeleteFunc(rs.votes, func(r *core.Record) bool { return r == vote })
+	rs.touch()
+	return nil
+}
+
+// TouchRoom updates the room's last_activity timestamp
+func (s *RoomStateStore) TouchRoom(roomID string) error {
+	rs, err := s.lockLoaded(roomID)
+	if err != nil {
+		return err
+	}
+	defer rs.mu.Unlock()
+
+	rs.touch()
+	return nil
+}
+
+// SetParticipantConnected updates a participant's connection status.
+// ok is false when the participant's room is not in memory.
+func (s *RoomStateStore) SetParticipantConnected(participantID string, connected bool) bool {
+	rs, ok := s.lockParticipantRoom(participantID)
+	if !ok {
+		return false
+	}
+	defer rs.mu.Unlock()
+
+	participant := rs.participant(participantID)
+	if participant == nil {
+		return false
+	}
+
+	participant.Set("connected", connected)
+	participant.Set("last_seen", time.Now())
+	rs.markDirty(participant)
+	return true
+}
+
+// lock returns the locked entry of a room, loaded or not
+func (s *RoomStateStore) lock(roomID string) *roomState {
+	for {
+		s.mu.Lock()
+		rs, ok := s.rooms[roomID]
+		if !ok {
+			rs = &roomState{id: roomID, attempts: make(map[string]int)}
+			s.rooms[roomID] = rs
+		}
+		s.mu.Unlock()
+
+		rs.mu.Lock()
+		if !rs.evicted {
+			rs.lastAccess = time.Now()
+			return rs
+		}
+		rs.mu.Unlock()
+	}
+}
+
+// lockLoaded returns the locked entry of a room, loading the room from the database when needed
+func (s *RoomStateStore) lockLoaded(roomID string) (*roomState, error) {
+	rs := s.lock(roomID)
+	if rs.loaded && !rs.stale.Load() {
+		return rs, nil
+	}
+
+	if err := s.refresh(rs); err != nil {
+		// Unknown rooms are not kept around
+		rs.evicted = true
+		rs.mu.Unlock()
+		s.mu.Lock()
+		if s.rooms[roomID] == rs {
+			delete(s.rooms, roomID)
+		}
+		s.mu.Unlock()
+		return nil, err
+	}
+	return rs, nil
+}
+
+// lockParticipantRoom returns the locked, loaded entry of a participant's room if it is in memory
+func (s *RoomStateStore) lockParticipantRoom(participantID string) (*roomState, bool) {
+	s.mu.Lock()
+	roomID, ok := s.participantRooms[participantID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	rs, err := s.lockLoaded(roomID)
+	if err != nil {
+		return nil, false
+	}
+	return rs, true
+}
+
+// refresh loads a room, or reads its record again once invalidated. A new current round
+// reloads the whole room, after writing its pending changes. Must be called with rs.mu held.
+func (s *RoomStateStore) refresh(rs *roomState) error {
+	if !rs.loaded {
+		return s.load(rs)
+	}
+
+	rs.stale.Store(false)
+	room, err := s.app.FindRecordById("rooms", rs.id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return err // Deleted
+	}
+	if err != nil {
+		// Keep serving the cached room, the read is tried again on next use
+		rs.stale.Store(true)
+		logging.ForRoom(rs.id, "").Warn("Failed to refresh room", logging.Err(err))
+		return nil
+	}
+	if room.GetString("current_round_id") != rs.room.GetString("current_round_id") {
+		_ = s.flush(rs) // Failed writes stay queued and are logged
+		return s.load(rs)
+	}
+	if !rs.activity.IsZero() {
+		room.Set("last_activity", rs.activity)
+	}
+	rs.room = room
+	return nil
+}
+
+// load reads a room's state from the database. Must be called with rs.mu held.
+func (s *RoomStateStore) load(rs *roomState) error {
+	rs.stale.Store(false) // Changes from here on are read again
+	room, err := s.app.FindRecordById("rooms", rs.id)
+	if err != nil {
+		return err
+	}
+
+	// A missing current round is reported when the round is used
+	var round *core.Record
+	if roundID := room.GetString("current_round_id"); roundID != "" {
+		round, _ = s.app.FindRecordById("rounds", roundID)
+	}
+
+	participants, err := s.app.FindRecordsByFilter(
+		"participants",
+		"room_id = {:roomId}",
+		"",
+		config.RoomMaxParticipants,
+		0,
+		map[string]any{"roomId": rs.id},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get participants: %w", err)
+	}
+	if len(participants) == config.RoomMaxParticipants {
+		logging.ForRoom(rs.id, "").Warn("Room has too many participants, later ones are left out", "limit", config.RoomMaxParticipants)
+	}
+
+	var votes []*core.Record
+	if round != nil {
+		votes, err = s.app.FindRecordsByFilter(
+			"votes",
+			"round_id = {:roundId}",
+			"",
+			config.RoomMaxParticipants,
+			0,
+			map[string]any{"roundId": round.Id},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to get votes: %w", err)
+		}
+	}
+
+	rs.room = room
+	rs.round = round
+	rs.participants = participants
+	rs.votes = votes
+	rs.loaded = true
+
+	s.mu.Lock()
+	for _, participant := range participants {
+		s.participantRooms[participant.Id] = rs.id
+	}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// flush writes a room's pending changes. Must be called with rs.mu held.
+// Failed writes stay queued until they succeed.
+func (s *RoomStateStore) flush(rs *roomState) error {
+	var errs []error
+
+	dirty := rs.dirty[:0]
+	for _, record := range rs.dirty {
+		if err := s.app.Save(record); err != nil {
+			errs = append(errs, err)
+			rs.retry(record, "save", err)
+			dirty = append(dirty, record)
+			continue
+		}
+		delete(rs.attempts, record.Id)
+	}
+	rs.dirty = dirty
+
+	if !rs.activity.IsZero() {
+		if err := s.saveActivity(rs); err != nil {
+			errs = append(errs, err)
+			rs.retry(rs.room, "update", err)
+		} else {
+			delete(rs.attempts, rs.id)
+			rs.activity = time.Time{}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// saveActivity writes the room's pending last_activity. Only that column is updated: the
+// cached room record may be older than the database, e.g. after an edit in the dashboard.
+func (s *RoomStateStore) saveActivity(rs *roomState) error {
+	activity, err := types.ParseDateTime(rs.activity)
+	if err != nil {
+		return err
+	}
+	_, err = s.app.DB().Update("rooms", dbx.Params{"last_activity": activity}, dbx.HashExp{"id": rs.id}).Execute()
+	return err
+}
+
+// evictIdle drops rooms without pending writes that were last used before cutoff.
+// Rooms busy in another goroutine are skipped.
+func (s *RoomStateStore) evictIdle(cutoff time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	evicted := make(map[string]bool)
+	for roomID, rs := range s.rooms {
+		if !rs.mu.TryLock() {
+			continue
+		}
+		if len(rs.dirty) == 0 && rs.activity.IsZero() && rs.lastAccess.Before(cutoff) {
+			rs.evicted = true
+			delete(s.rooms, roomID)
+			evicted[roomID] = true
+		}
+		rs.mu.Unlock()
+	}
+
+	if len(evicted) == 0 {
+		return
+	}
+	for participantID, roomID := range s.participantRooms {
+		if evicted[roomID] {
+			delete(s.participantRooms, participantID)
+		}
+	}
+}
+
+// retry counts a failed write, which is attempted again on the next flush. Writes failing
+// config.RoomStateMaxWriteAttempts times in a row are logged as errors.
+func (rs *roomState) retry(record *core.Record, op string, err error) {
+	rs.attempts[record.Id]++
+	attrs := []any{"op", op, "collection", record.Collection().Name, "record_id", record.Id, "attempts", rs.attempts[record.Id], logging.Err(err)}
+	if rs.attempts[record.Id] < config.RoomStateMaxWriteAttempts {
+		logging.ForRoom(rs.id, "").Warn("Room state write failed, will retry", attrs...)
+		return
+	}
+	logging.ForRoom(rs.id, "").Error("Room state write keeps failing, will retry", attrs...)
+}
+
+// currentRound returns the in-memory current round record
+func (rs *roomState) currentRound() (*core.Record, error) {
+	if rs.round == nil {
+		if rs.room.GetString("current_round_id") == "" {
+			return nil, fmt.Errorf("no current round set for room")
+		}
+		return nil, fmt.Errorf("current round not found")
+	}
+	return rs.round, nil
+}
+
+func (rs *roomState) participant(participantID string) *core.Record {
+	for _, participant := range rs.participants {
+		if participant.Id == participantID {
+			return participant
+		}
+	}
+	return nil
+}
+
+func (rs *roomState) vote(participantID string) *core.Record {
+	for _, vote := range rs.votes {
+		if vote.GetString("participant_id") == participantID {
+			return vote
+		}
+	}
+	return nil
+}
+
+// touch updates the room's last_activity timestamp
+func (rs *roomState) touch() {
+	rs.activity = time.Now()
+	rs.room.Set("last_activity", rs.activity)
+}
+
+// markDirty queues a record to be saved, once per flush
+func (rs *roomState) markDirty(record *core.Record) {
+	if !slices.Contains(rs.dirty, record) {
+		rs.dirty = append(rs.dirty, record)
+	}
+}
+
+// unload drops the in-memory copy so the next read reloads it. Pending writes must be flushed first.
+func (rs *roomState) unload() {
+	rs.loaded = false
+	rs.room = nil
+	rs.round = nil
+	rs.participants = nil
+	rs.votes = nil
+}
+
+func cloneRecords(records []*core.Record) []*core.Record {
+	clones := make([]*core.Record, len(records))
+	for i, record := range records {
+		clones[i] = record.Clone()
+	}
+	return clones
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/logging"
+	"github.com/damione1/planning-poker/internal/models"
+)
+
+// Headers Slack signs requests with
+const (
+	SlackSignatureHeader = "X-Slack-Signature"
+	SlackTimestampHeader = "X-Slack-Request-Timestamp"
+)
+
+// SlackMessage is a Slack message as returned to a slash command or posted to its response_url
+type SlackMessage struct {
+	ResponseType    string       `json:"response_type,omitempty"` // "in_channel" or "ephemeral"
+	ReplaceOriginal bool         `json:"replace_original"`
+	Text            string       `json:"text"`
+	Blocks          []SlackBlock `json:"blocks,omitempty"`
+}
+
+// SlackBlock is a Block Kit section or image block
+type SlackBlock struct {
+	Type     string     `json:"type"`
+	Text     *SlackText `json:"text,omitempty"`
+	ImageURL string     `json:"image_url,omitempty"`
+	AltText  string     `json:"alt_text,omitempty"`
+}
+
+// SlackText is a Block Kit text object
+type SlackText struct {
+	Type string `json:"type"` // "mrkdwn" or "plain_text"
+	Text string `json:"text"`
+}
+
+// SlackSection builds a mrkdwn section block
+func SlackSection(text string) SlackBlock {
+	return SlackBlock{Type: "section", Text: &SlackText{Type: "mrkdwn", Text: text}}
+}
+
+// VerifySlackSignature checks a request signed with Slack's v0 scheme:
+// v0=<hex HMAC-SHA256 of "v0:<timestamp>:<body>">, with a timestamp no older than SlackRequestMaxAge
+func VerifySlackSignature(signingSecret, timestamp, signature string, body []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid request timestamp")
+	}
+	age := now.Sub(time.Unix(ts, 0))
+	if age > config.SlackRequestMaxAge || age < -config.SlackRequestMaxAge {
+		return fmt.Errorf("request timestamp too old")
+	}
+
+	if !hmac.Equal([]byte(SignSlackRequest(signingSecret, timestamp, body)), []byte(signature)) {
+		return fmt.Errorf("invalid request signature")
+	}
+	return nil
+}
+
+// SignSlackRequest computes the X-Slack-Signature value for a request body
+func SignSlackRequest(signingSecret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SlackService posts reveal summaries of rooms created from Slack back to the channel
+type SlackService struct {
+	roomManager *RoomManager
+	client      *http.Client
+}
+
+func NewSlackService(rm *RoomManager) *SlackService {
+	return &SlackService{
+		roomManager: rm,
+		client:      &http.Client{Timeout: config.SlackPostTimeout},
+	}
+}
+
+// RememberResponseURL links a room to the slash command that created it
+func (s *SlackService) RememberResponseURL(roomID, responseURL string) error {
+	return s.roomManager.SetSlackResponseURL(roomID, responseURL, time.Now().Add(config.SlackResponseURLTTL))
+}
+
+// PostRevealSummary posts the revealed round to the room's Slack response_url, if it is still valid.
+// The post is made in the background; failures are logged.
+func (s *SlackService) PostRevealSummary(roomID string, revealed models.VotesRevealedPayload) {
+	room, err := s.roomManager.GetRoom(roomID)
+	if err != nil {
+		return
+	}
+
+	responseURL := room.GetString("slack_response_url")
+	if responseURL == "" || time.Now().After(room.GetDateTime("slack_response_expires_at").Time()) {
+		return
+	}
+
+	roundNumber, _ := s.roomManager.GetCurrentRound(roomID)
+	summary := FormatRevealSummary(room.GetString("name"), roundNumber, revealed)
+	message := SlackMessage{
+		ResponseType: "in_channel",
+		Text:         summary,
+		Blocks:       []SlackBlock{SlackSection(summary)},
+	}
+
+	go func() {
+		if err := s.post(responseURL, message); err != nil {
+			logging.ForRoom(roomID, "").Error("Failed to post reveal summary to Slack", logging.Err(err))
+		}
+	}()
+}
+
+func (s *SlackService) post(responseURL string, message SlackMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Post(responseURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// FormatRevealSummary renders a revealed round as Slack mrkdwn
+func FormatRevealSummary(roomName string, roundNumber int, revealed models.VotesRevealedPayload) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s* – round %d revealed (%d votes)\n", escapeSlackText(roomName), roundNumber, revealed.Stats.Total)
+
+	stats := revealed.Stats
+	if stats.Average != nil {
+		fmt.Fprintf(&b, "Average: *%s*", strconv.FormatFloat(math.Round(*stats.Average*10)/10, 'f', -1, 64))
+		if stats.Weighted {
+			b.WriteString(" (weighted)")
+		}
+		b.WriteString("\n")
+	}
+	switch {
+	case stats.Total == 0:
+		b.WriteString("No votes were cast\n")
+	case stats.Consensus:
+		b.WriteString("Consensus :white_check_mark:\n")
+	default:
+		b.WriteString("No consensus\n")
+	}
+
+	votes := append([]models.RevealedVote(nil), revealed.Votes...)
+	sort.Slice(votes, func(i, j int) bool { return votes[i].ParticipantName < votes[j].ParticipantName })
+	for _, vote := range votes {
+		fmt.Fprintf(&b, "• %s: %s\n", escapeSlackText(vote.ParticipantName), escapeSlackText(vote.Value))
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// escapeSlackText escapes the characters Slack treats as control sequences
+func escapeSlackText(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ErrStreamClosed is returned for commands to an event stream that has ended
+var ErrStreamClosed = errors.New("event stream closed")
+
+// SSETransport is a Transport over a Server-Sent Events stream, for clients that can't
+// upgrade to WebSocket. The stream only goes to the client: its messages arrive with
+// separate requests, handed over with Deliver.
+type SSETransport struct {
+	w        http.ResponseWriter
+	rc       *http.ResponseController
+	done     <-chan struct{} // Closed once the client ends the stream request
+	inbox    chan []byte
+	mu       sync.Mutex // Serializes writes with Close, the response can't be written after
+	closed   bool
+	closedCh chan struct{}
+}
+
+// NewSSETransport starts an event stream as the response w to r
+func NewSSETransport(w http.ResponseWriter, r *http.Request) (*SSETransport, error) {
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no") // Keeps reverse proxies from buffering the stream
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		return nil, err
+	}
+
+	return &SSETransport{
+		w:        w,
+		rc:       rc,
+		done:     r.Context().Done(),
+		inbox:    make(chan []byte),
+		closedCh: make(chan struct{}),
+	}, nil
+}
+
+// WriteEvent sends data as an event named event, or as a plain message when event is empty
+func (t *SSETransport) WriteEvent(ctx context.Context, event string, data []byte) error {
+	frame := make([]byte, 0, len(data)+len(event)+16)
+	if event != "" {
+		frame = append(frame, "event: "...)
+		frame = append(frame, event...)
+		frame = append(frame, '\n')
+	}
+	frame = append(frame, "data: "...)
+	frame = append(frame, data...) // Encoded JSON has no newlines
+	frame = append(frame, "\n\n"...)
+	return t.write(ctx, frame)
+}
+
+// write sends a frame of the stream and flushes it
+func (t *SSETransport) write(ctx context.Context, frame []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return ErrStreamClosed
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = t.rc.SetWriteDeadline(deadline) // Best effort, not every writer supports it
+	}
+	if _, err := t.w.Write(frame); err != nil {
+		return err
+	}
+	return t.rc.Flush()
+}
+
+// Deliver hands a message sent by the client with a separate request over to the
+// reader of the stream. Blocks until it is read, ctx is done or the stream has ended.
+func (t *SSETransport) Deliver(ctx context.Context, message []byte) error {
+	select {
+	case t.inbox <- message:
+		return nil
+	case <-t.closedCh:
+		return ErrStreamClosed
+	case <-t.done:
+		return ErrStreamClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Read waits for the next message handed over with Deliver
+func (t *SSETransport) Read(ctx context.Context) ([]byte, error) {
+	select {
+	case message := <-t.inbox:
+		return message, nil
+	case <-t.closedCh:
+		return nil, io.EOF
+	case <-t.done:
+		return nil, io.EOF
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *SSETransport) Write(ctx context.Context, data []byte) error {
+	return t.WriteEvent(ctx, "", data)
+}
+
+// Ping sends a comment line, which clients ignore
+func (t *SSETransport) Ping(ctx context.Context) error {
+	return t.write(ctx, []byte(": ping\n\n"))
+}
+
+// Close ends the stream. Waits for a write in progress, none happen after.
+func (t *SSETransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.closed {
+		t.closed = true
+		close(t.closedCh)
+	}
+	return nil
+}
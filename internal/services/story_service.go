@@ -0,0 +1,219 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/logging"
+	"github.com/damione1/planning-poker/internal/models"
+)
+
+// StoryService imports issues from issue trackers into rooms as stories and
+// writes the estimate of each completed round back to the tracker.
+// Stories are estimated in import order: a completed round estimates the room's first pending story.
+type StoryService struct {
+	app      core.App
+	trackers map[string]IssueTracker
+}
+
+func NewStoryService(app core.App) *StoryService {
+	return &StoryService{
+		app:      app,
+		trackers: make(map[string]IssueTracker),
+	}
+}
+
+// RegisterTracker makes a tracker available for imports
+func (s *StoryService) RegisterTracker(tracker IssueTracker) {
+	s.trackers[tracker.Name()] = tracker
+}
+
+// TrackerNames returns the registered tracker names, sorted
+func (s *StoryService) TrackerNames() []string {
+	names := make([]string, 0, len(s.trackers))
+	for name := range s.trackers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Tracker returns a registered tracker by name.
+// An empty name selects the only registered tracker.
+func (s *StoryService) Tracker(name string) (IssueTracker, error) {
+	if name == "" {
+		if len(s.trackers) == 1 {
+			for _, tracker := range s.trackers {
+				return tracker, nil
+			}
+		}
+		return nil, fmt.Errorf("tracker is required, one of: %s", strings.Join(s.TrackerNames(), ", "))
+	}
+
+	tracker, ok := s.trackers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tracker %q", name)
+	}
+	return tracker, nil
+}
+
+// ImportStories fetches issues matching query and appends them to the room's stories.
+// Issues already imported into the room are skipped; returns the newly created stories.
+func (s *StoryService) ImportStories(roomID, trackerName, query string, limit int) ([]*core.Record, error) {
+	tracker, err := s.Tracker(trackerName)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.GetRoomStories(roomID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= config.MaxStoriesPerRoom {
+		return nil, fmt.Errorf("a room can have at most %d stories", config.MaxStoriesPerRoom)
+	}
+	limit = min(limit, config.MaxStoriesPerRoom-len(existing))
+
+	issues, err := tracker.SearchIssues(query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := s.app.FindCollectionByNameOrId("stories")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stories collection: %w", err)
+	}
+
+	imported := make(map[string]bool, len(existing))
+	position := 0
+	for _, story := range existing {
+		if story.GetString("tracker") == tracker.Name() {
+			imported[story.GetString("issue_key")] = true
+		}
+		position = max(position, story.GetInt("position"))
+	}
+
+	created := make([]*core.Record, 0, len(issues))
+	for _, issue := range issues {
+		if imported[issue.Key] || len(created) >= limit {
+			continue
+		}
+		imported[issue.Key] = true
+		position++
+
+		record := core.NewRecord(collection)
+		record.Set("room_id", roomID)
+		record.Set("tracker", tracker.Name())
+		record.Set("issue_key", issue.Key)
+		record.Set("title", truncate(issue.Title, 500))
+		record.Set("url", issue.URL)
+		record.Set("position", position)
+		record.Set("status", models.StoryPending)
+		record.Set("created_at", time.Now())
+		if err := s.app.Save(record); err != nil {
+			return created, fmt.Errorf("failed to save story %s: %w", issue.Key, err)
+		}
+		created = append(created, record)
+	}
+
+	return created, nil
+}
+
+// GetRoomStories returns the stories of a room in estimation order
+func (s *StoryService) GetRoomStories(roomID string) ([]*core.Record, error) {
+	return s.app.FindRecordsByFilter(
+		"stories",
+		"room_id = {:roomId}",
+		"position",
+		config.MaxStoriesPerRoom,
+		0,
+		map[string]any{"roomId": roomID},
+	)
+}
+
+// CurrentStory returns the first story of the room that has not been estimated yet
+func (s *StoryService) CurrentStory(roomID string) (*core.Record, error) {
+	return s.app.FindFirstRecordByFilter(
+		"stories",
+		"room_id = {:roomId} && status = {:status}",
+		map[string]any{"roomId": roomID, "status": models.StoryPending},
+	)
+}
+
+// RecordEstimate assigns the average of a completed round to the room's current story
+// and writes it back to the tracker in the background.
+// Rounds without a numeric average leave the story pending. Returns the estimated story, if any.
+func (s *StoryService) RecordEstimate(roomID, roundID string) (*core.Record, error) {
+	story, err := s.CurrentStory(roomID)
+	if err != nil {
+		return nil, nil // No pending story
+	}
+
+	round, err := s.app.FindRecordById("rounds", roundID)
+	if err != nil {
+		return nil, fmt.Errorf("round not found: %w", err)
+	}
+	average := round.GetFloat("average_score")
+	if round.GetInt("total_votes") == 0 || average <= 0 {
+		return nil, nil
+	}
+
+	story.Set("status", models.StoryEstimated)
+	story.Set("estimate", math.Round(average*10)/10)
+	story.Set("round_id", roundID)
+	story.Set("sync_status", models.StorySyncPending)
+	story.Set("sync_error", "")
+	if err := s.app.Save(story); err != nil {
+		return nil, fmt.Errorf("failed to save story estimate: %w", err)
+	}
+
+	go s.writeBack(story.Id)
+	return story, nil
+}
+
+// writeBack sends a story's estimate to its tracker and records the outcome
+func (s *StoryService) writeBack(storyID string) {
+	story, err := s.app.FindRecordById("stories", storyID)
+	if err != nil {
+		return
+	}
+
+	syncStatus, syncError := models.StorySyncSynced, ""
+
+	tracker, ok := s.trackers[story.GetString("tracker")]
+	if !ok {
+		syncStatus, syncError = models.StorySyncFailed, "tracker is no longer configured"
+	} else if err := tracker.SetEstimate(story.GetString("issue_key"), story.GetFloat("estimate")); err != nil {
+		var conflict *EstimateConflictError
+		if errors.As(err, &conflict) {
+			syncStatus = models.StorySyncConflict
+		} else {
+			syncStatus = models.StorySyncFailed
+		}
+		syncError = err.Error()
+		logging.ForRoom(story.GetString("room_id"), "").Warn("Failed to write story estimate",
+			"issue_key", story.GetString("issue_key"), "tracker", tracker.Name(), logging.Err(err))
+	}
+
+	story.Set("sync_status", syncStatus)
+	story.Set("sync_error", truncate(syncError, 1000))
+	if err := s.app.Save(story); err != nil {
+		logging.ForRoom(story.GetString("room_id"), "").Error("Failed to save story sync status", "story_id", story.Id, logging.Err(err))
+	}
+}
+
+// truncate shortens text to at most limit characters
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit])
+}
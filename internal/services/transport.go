@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// Transport carries the messages of a client's connection. Read ends with io.EOF when
+// the client closes the connection normally.
+type Transport interface {
+	// Read waits for the next message sent by the client
+	Read(ctx context.Context) ([]byte, error)
+	// Write sends an encoded message to the client
+	Write(ctx context.Context, data []byte) error
+	// Ping keeps the connection alive through proxies
+	Ping(ctx context.Context) error
+	// Close ends the connection
+	Close() error
+}
+
+// websocketTransport is a Transport over a WebSocket connection
+type websocketTransport struct {
+	conn        *websocket.Conn
+	readTimeout time.Duration
+}
+
+// NewWebSocketTransport creates a Transport over conn. Reads fail once the client has
+// been silent for readTimeout.
+func NewWebSocketTransport(conn *websocket.Conn, readTimeout time.Duration) Transport {
+	return &websocketTransport{conn: conn, readTimeout: readTimeout}
+}
+
+func (t *websocketTransport) Read(ctx context.Context) ([]byte, error) {
+	readCtx, cancel := context.WithTimeout(ctx, t.readTimeout)
+	defer cancel()
+
+	_, message, err := t.conn.Read(readCtx)
+	if err != nil && websocket.CloseStatus(err) == websocket.StatusNormalClosure {
+		return nil, io.EOF
+	}
+	return message, err
+}
+
+func (t *websocketTransport) Write(ctx context.Context, data []byte) error {
+	return t.conn.Write(ctx, websocket.MessageText, data)
+}
+
+func (t *websocketTransport) Ping(ctx context.Context) error {
+	return t.conn.Ping(ctx)
+}
+
+func (t *websocketTransport) Close() error {
+	return t.conn.Close(websocket.StatusNormalClosure, "")
+}
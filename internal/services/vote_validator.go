@@ -1,10 +1,15 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/security"
 )
 
 const (
@@ -12,6 +17,8 @@ const (
 	MaxCustomValues = 20
 	// MaxValueLength limits individual value length
 	MaxValueLength = 10
+	// MaxDimensionNameLength limits estimation dimension name length
+	MaxDimensionNameLength = 20
 
 	// Template constants - Single source of truth for vote templates
 	TemplateModifiedFibonacci = "modified-fibonacci"
@@ -24,6 +31,10 @@ const (
 	TemplateTShirtValues            = "XXS, XS, S, M, L, XL, XXL"
 )
 
+// ErrNoNumericDimension is returned for a multi-dimensional vote without a numeric value,
+// which has no combined score
+var ErrNoNumericDimension = errors.New("at least one dimension needs a numeric value")
+
 // VoteValidator provides secure validation and parsing for vote values
 type VoteValidator struct{}
 
@@ -253,3 +264,195 @@ func (v *VoteValidator) ValidateVoteValue(value string, pointingMethod string, c
 		return fmt.Errorf("unknown pointing method: '%s'", pointingMethod)
 	}
 }
+
+// ParseDimensions parses estimation dimensions, one per line, in the form "Name: v1, v2, v3"
+// Input: "Complexity: 1, 2, 3, 5, 8\nEffort: S, M, L"
+// Output: []models.EstimationDimension{{Name: "Complexity", Values: [...]}, {Name: "Effort", Values: [...]}}
+func (v *VoteValidator) ParseDimensions(input string) ([]models.EstimationDimension, error) {
+	var dimensions []models.EstimationDimension
+
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		name, values, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid dimension '%s' (expected 'Name: value, value')", line)
+		}
+
+		parsedValues, err := v.ParseCustomValues(values)
+		if err != nil {
+			return nil, fmt.Errorf("dimension '%s': %w", strings.TrimSpace(name), err)
+		}
+
+		dimensions = append(dimensions, models.EstimationDimension{
+			Name:   strings.TrimSpace(name),
+			Values: parsedValues,
+		})
+	}
+
+	if err := v.ValidateDimensions(dimensions); err != nil {
+		return nil, err
+	}
+
+	return dimensions, nil
+}
+
+// ValidateDimensions checks dimension names, decks and weights, and that a deck has numeric values
+// An empty list is valid (single-dimension room)
+func (v *VoteValidator) ValidateDimensions(dimensions []models.EstimationDimension) error {
+	if len(dimensions) == 0 {
+		return nil
+	}
+	if len(dimensions) < 2 {
+		return fmt.Errorf("at least 2 dimensions are required (got %d)", len(dimensions))
+	}
+	if len(dimensions) > models.MaxDimensions {
+		return fmt.Errorf("too many dimensions (max %d, got %d)", models.MaxDimensions, len(dimensions))
+	}
+
+	seen := make(map[string]bool)
+	numeric := false
+	for _, dimension := range dimensions {
+		if _, err := security.ValidateName(dimension.Name, MaxDimensionNameLength); err != nil {
+			return fmt.Errorf("invalid dimension name '%s': %w", dimension.Name, err)
+		}
+		if seen[dimension.Name] {
+			return fmt.Errorf("duplicate dimension: '%s'", dimension.Name)
+		}
+		seen[dimension.Name] = true
+
+		if len(dimension.Values) < 2 || len(dimension.Values) > MaxCustomValues {
+			return fmt.Errorf("dimension '%s' must have between 2 and %d values", dimension.Name, MaxCustomValues)
+		}
+		for _, value := range dimension.Values {
+			if err := v.ValidateValue(value); err != nil {
+				return fmt.Errorf("dimension '%s' has invalid value '%s': %w", dimension.Name, value, err)
+			}
+			numeric = numeric || v.IsNumericValue(value)
+		}
+
+		if dimension.Weight < 0 {
+			return fmt.Errorf("dimension '%s' weight cannot be negative", dimension.Name)
+		}
+	}
+
+	// Votes are combined from numeric values, see CombineDimensionScores
+	if !numeric {
+		return fmt.Errorf("at least one dimension needs numeric values")
+	}
+
+	return nil
+}
+
+// ValidateCombineMethod checks that a combine method is supported (empty means default)
+func (v *VoteValidator) ValidateCombineMethod(method string) error {
+	switch method {
+	case "", models.CombineSum, models.CombineAverage, models.CombineWeighted, models.CombineMax:
+		return nil
+	default:
+		return fmt.Errorf("unknown combine method: '%s'", method)
+	}
+}
+
+// ValidateDimensionVotes checks that a vote has exactly one valid value per room dimension
+func (v *VoteValidator) ValidateDimensionVotes(votes map[string]string, dimensions []models.EstimationDimension) error {
+	if len(votes) != len(dimensions) {
+		return fmt.Errorf("expected a value for each of the %d dimensions (got %d)", len(dimensions), len(votes))
+	}
+
+	for _, dimension := range dimensions {
+		value, ok := votes[dimension.Name]
+		if !ok {
+			return fmt.Errorf("missing value for dimension '%s'", dimension.Name)
+		}
+		if err := v.ValidateVoteValue(value, "custom", dimension.Values); err != nil {
+			return fmt.Errorf("dimension '%s': %w", dimension.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// CombineDimensionScores combines the numeric dimension values of a single vote into one score
+// Non-numeric values (t-shirt sizes, ?, ☕) are ignored. Returns false if no value is numeric.
+func (v *VoteValidator) CombineDimensionScores(votes map[string]string, dimensions []models.EstimationDimension, method string) (float64, bool) {
+	var sum, weightedSum, totalWeight, max float64
+	var count int
+
+	for _, dimension := range dimensions {
+		num, ok := v.ParseNumericValue(votes[dimension.Name])
+		if !ok {
+			continue
+		}
+
+		weight := dimension.EffectiveWeight()
+		sum += num
+		weightedSum += num * weight
+		totalWeight += weight
+		if count == 0 || num > max {
+			max = num
+		}
+		count++
+	}
+
+	if count == 0 {
+		return 0, false
+	}
+
+	switch method {
+	case models.CombineSum:
+		return sum, true
+	case models.CombineMax:
+		return max, true
+	case models.CombineWeighted:
+		return weightedSum / totalWeight, true
+	default:
+		return sum / float64(count), true
+	}
+}
+
+// FormatScore formats a combined score for storage as a vote value (rounded to 2 decimals)
+func (v *VoteValidator) FormatScore(score float64) string {
+	return strconv.FormatFloat(math.Round(score*100)/100, 'f', -1, 64)
+}
+
+// CalculateDimensionStats computes per-dimension statistics from the dimension values of each vote
+func (v *VoteValidator) CalculateDimensionStats(votes []map[string]string, dimensions []models.EstimationDimension) []models.DimensionStats {
+	stats := make([]models.DimensionStats, 0, len(dimensions))
+
+	for _, dimension := range dimensions {
+		dimensionStats := models.DimensionStats{
+			Name:           dimension.Name,
+			ValueBreakdown: make(map[string]int),
+		}
+
+		var sum float64
+		var count int
+		for _, vote := range votes {
+			value, ok := vote[dimension.Name]
+			if !ok {
+				continue
+			}
+			dimensionStats.Total++
+			dimensionStats.ValueBreakdown[value]++
+
+			if num, ok := v.ParseNumericValue(value); ok {
+				sum += num
+				count++
+			}
+		}
+
+		if count > 0 {
+			average := sum / float64(count)
+			dimensionStats.Average = &average
+		}
+		dimensionStats.Consensus = dimensionStats.Total > 0 && len(dimensionStats.ValueBreakdown) == 1
+
+		stats = append(stats, dimensionStats)
+	}
+
+	return stats
+}
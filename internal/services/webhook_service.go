@@ -0,0 +1,459 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/logging"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/security"
+)
+
+// Headers sent with every webhook delivery
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature" // sha256=<hex HMAC of "<timestamp>.<body>">
+	WebhookTimestampHeader = "X-Webhook-Timestamp" // Unix seconds, part of the signed content
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// WebhookService queues signed room events for registered webhook URLs and
+// delivers them with exponential backoff. The queue lives in the
+// webhook_deliveries collection, so pending deliveries survive restarts.
+type WebhookService struct {
+	app    core.App
+	client *http.Client
+
+	processMu sync.Mutex // Serializes queue processing
+	wake      chan struct{}
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+func NewWebhookService(app core.App) *WebhookService {
+	// Deliveries can't reach internal addresses, whatever the URL resolves to by then
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // A proxy would be dialed instead of the target
+	transport.DialContext = security.WebhookDialContext(&net.Dialer{Timeout: config.WebhookTimeout})
+
+	return &WebhookService{
+		app:    app,
+		client: &http.Client{Timeout: config.WebhookTimeout, Transport: transport},
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+}
+
+// checkTarget rejects webhook URLs whose host resolves to an internal address
+func checkTarget(url string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), config.WebhookTimeout)
+	defer cancel()
+	return security.CheckWebhookTarget(ctx, url)
+}
+
+// SignWebhookPayload computes the signature header value for a delivery body
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks a signature header value in constant time
+func VerifyWebhookSignature(secret, signature string, timestamp int64, body []byte) bool {
+	expected := SignWebhookPayload(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// WebhookBackoff returns the delay before the next attempt after the given number of failed attempts
+func WebhookBackoff(attempts int) time.Duration {
+	delay := config.WebhookInitialBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= config.WebhookMaxBackoff {
+			return config.WebhookMaxBackoff
+		}
+	}
+	return delay
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// CreateWebhook registers a webhook for a room and generates its signing secret
+// An empty events list subscribes to every event
+func (ws *WebhookService) CreateWebhook(roomID, url string, events []string) (*core.Record, error) {
+	url, err := security.ValidateWebhookURL(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTarget(url); err != nil {
+		return nil, err
+	}
+	events, err = security.ValidateWebhookEvents(events)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := ws.GetRoomWebhooks(roomID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= config.MaxWebhooksPerRoom {
+		return nil, fmt.Errorf("a room can have at most %d webhooks", config.MaxWebhooksPerRoom)
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	return ws.saveWebhook(nil, roomID, url, secret, events)
+}
+
+// EnsureInstanceWebhook registers (or updates) an instance-wide webhook receiving events of every room
+func (ws *WebhookService) EnsureInstanceWebhook(url, secret string, events []string) (*core.Record, error) {
+	url, err := security.ValidateWebhookURL(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTarget(url); err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("instance webhooks require a signing secret")
+	}
+	events, err = security.ValidateWebhookEvents(events)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, _ := ws.app.FindFirstRecordByFilter(
+		"webhooks",
+		"room_id = '' && url = {:url}",
+		map[string]any{"url": url},
+	)
+	return ws.saveWebhook(existing, "", url, secret, events)
+}
+
+func (ws *WebhookService) saveWebhook(record *core.Record, roomID, url, secret string, events []string) (*core.Record, error) {
+	if record == nil {
+		collection, err := ws.app.FindCollectionByNameOrId("webhooks")
+		if err != nil {
+			return nil, fmt.Errorf("failed to find webhooks collection: %w", err)
+		}
+		record = core.NewRecord(collection)
+		record.Set("room_id", roomID)
+		record.Set("created_at", time.Now())
+	}
+
+	eventsJSON, err := json.Marshal(events)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook events: %w", err)
+	}
+	record.Set("url", url)
+	record.Set("secret", secret)
+	record.Set("events", eventsJSON)
+
+	if err := ws.app.Save(record); err != nil {
+		return nil, fmt.Errorf("failed to save webhook: %w", err)
+	}
+	return record, nil
+}
+
+// GetRoomWebhooks returns the webhooks registered for a room (instance webhooks excluded)
+func (ws *WebhookService) GetRoomWebhooks(roomID string) ([]*core.Record, error) {
+	return ws.app.FindRecordsByFilter(
+		"webhooks",
+		"room_id = {:roomId}",
+		"created_at",
+		config.MaxWebhooksPerRoom,
+		0,
+		map[string]any{"roomId": roomID},
+	)
+}
+
+// DeleteWebhook removes a room webhook. Already queued deliveries are still attempted.
+func (ws *WebhookService) DeleteWebhook(roomID, webhookID string) error {
+	record, err := ws.app.FindRecordById("webhooks", webhookID)
+	if err != nil || record.GetString("room_id") != roomID || roomID == "" {
+		return fmt.Errorf("webhook not found")
+	}
+	return ws.app.Delete(record)
+}
+
+// WebhookEvents returns the events a webhook record is subscribed to
+func WebhookEvents(record *core.Record) []string {
+	var events []string
+	if raw := record.GetString("events"); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &events)
+	}
+	return events
+}
+
+// Dispatch queues an event for every room and instance webhook subscribed to it.
+// Errors are logged: webhooks never block or fail the action that triggered them.
+func (ws *WebhookService) Dispatch(roomID, event string, data any) {
+	webhooks, err := ws.app.FindRecordsByFilter(
+		"webhooks",
+		"room_id = {:roomId} || room_id = ''",
+		"",
+		0,
+		0,
+		map[string]any{"roomId": roomID},
+	)
+	if err != nil || len(webhooks) == 0 {
+		return
+	}
+
+	payload := models.WebhookEvent{
+		ID:        uuid.New().String(),
+		Event:     event,
+		RoomID:    roomID,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+	if room, err := ws.app.FindRecordById("rooms", roomID); err == nil {
+		payload.RoomName = room.GetString("name")
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logging.ForRoom(roomID, "").Error("Failed to marshal webhook event", "event", event, logging.Err(err))
+		return
+	}
+
+	queued := 0
+	for _, webhook := range webhooks {
+		if !subscribed(webhook, event) {
+			continue
+		}
+		if err := ws.enqueue(webhook, roomID, event, body, payload.Timestamp.Unix()); err != nil {
+			logging.ForRoom(roomID, "").Error("Failed to queue webhook delivery", "event", event, "webhook_id", webhook.Id, logging.Err(err))
+			continue
+		}
+		queued++
+	}
+
+	if queued > 0 {
+		ws.notify()
+	}
+}
+
+func subscribed(webhook *core.Record, event string) bool {
+	for _, e := range WebhookEvents(webhook) {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue stores a signed delivery, so it can be retried without the webhook record
+func (ws *WebhookService) enqueue(webhook *core.Record, roomID, event string, body []byte, timestamp int64) error {
+	collection, err := ws.app.FindCollectionByNameOrId("webhook_deliveries")
+	if err != nil {
+		return fmt.Errorf("failed to find webhook_deliveries collection: %w", err)
+	}
+
+	now := time.Now()
+	record := core.NewRecord(collection)
+	record.Set("webhook_id", webhook.Id)
+	record.Set("room_id", roomID)
+	record.Set("event", event)
+	record.Set("url", webhook.GetString("url"))
+	record.Set("body", string(body))
+	record.Set("signature", SignWebhookPayload(webhook.GetString("secret"), timestamp, body))
+	record.Set("timestamp", timestamp)
+	record.Set("status", models.DeliveryPending)
+	record.Set("attempts", 0)
+	record.Set("next_attempt_at", now)
+	record.Set("created_at", now)
+
+	return ws.app.Save(record)
+}
+
+// notify wakes the delivery worker without blocking
+func (ws *WebhookService) notify() {
+	select {
+	case ws.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers queued webhooks until Stop is called.
+// Due deliveries are processed when events are queued and on every poll interval.
+func (ws *WebhookService) Run() {
+	ticker := time.NewTicker(config.WebhookPollInterval)
+	defer ticker.Stop()
+
+	ws.ProcessDue()
+	for {
+		select {
+		case <-ws.stop:
+			return
+		case <-ws.wake:
+			ws.ProcessDue()
+		case <-ticker.C:
+			ws.ProcessDue()
+		}
+	}
+}
+
+// Stop ends the delivery worker. Pending deliveries stay queued.
+func (ws *WebhookService) Stop() {
+	ws.stopOnce.Do(func() { close(ws.stop) })
+}
+
+// ProcessDue attempts every pending delivery whose next attempt is due
+// Returns the number of deliveries attempted
+func (ws *WebhookService) ProcessDue() int {
+	ws.processMu.Lock()
+	defer ws.processMu.Unlock()
+
+	deliveries, err := ws.app.FindRecordsByFilter(
+		"webhook_deliveries",
+		"status = {:status} && next_attempt_at <= {:now}",
+		"next_attempt_at",
+		config.WebhookBatchSize,
+		0,
+		map[string]any{"status": models.DeliveryPending, "now": types.NowDateTime().String()},
+	)
+	if err != nil {
+		slog.Error("Failed to load due webhook deliveries", logging.Err(err))
+		return 0
+	}
+
+	for _, delivery := range deliveries {
+		ws.attempt(delivery)
+	}
+	return len(deliveries)
+}
+
+// attempt POSTs one delivery and records the outcome, scheduling a retry on failure
+func (ws *WebhookService) attempt(delivery *core.Record) {
+	attempts := delivery.GetInt("attempts") + 1
+	delivery.Set("attempts", attempts)
+
+	status, err := ws.post(delivery)
+	delivery.Set("response_status", status)
+
+	switch {
+	case err == nil:
+		delivery.Set("status", models.DeliveryDelivered)
+		delivery.Set("delivered_at", time.Now())
+		delivery.Set("last_error", "")
+	case attempts >= config.WebhookMaxAttempts:
+		delivery.Set("status", models.DeliveryFailed)
+		delivery.Set("last_error", deliveryError(err))
+		logging.ForRoom(delivery.GetString("room_id"), "").Warn("Webhook delivery failed permanently",
+			"delivery_id", delivery.Id, "attempts", attempts, logging.Err(err))
+	default:
+		delivery.Set("next_attempt_at", time.Now().Add(WebhookBackoff(attempts)))
+		delivery.Set("last_error", deliveryError(err))
+	}
+
+	if err := ws.app.Save(delivery); err != nil {
+		logging.ForRoom(delivery.GetString("room_id"), "").Error("Failed to update webhook delivery", "delivery_id", delivery.Id, logging.Err(err))
+	}
+}
+
+// deliveryError returns the message of a failed delivery as kept in the delivery log: the URL
+// is redacted, as it may embed credentials, and the message truncated to fit the column
+func deliveryError(err error) string {
+	message := []rune(logging.ErrorText(err))
+	if len(message) > config.WebhookMaxErrorLength {
+		message = append(message[:config.WebhookMaxErrorLength-1], '…')
+	}
+	return string(message)
+}
+
+func (ws *WebhookService) post(delivery *core.Record) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, delivery.GetString("url"), bytes.NewBufferString(delivery.GetString("body")))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "planning-poker-webhooks")
+	req.Header.Set(WebhookSignatureHeader, delivery.GetString("signature"))
+	req.Header.Set(WebhookTimestampHeader, strconv.Itoa(delivery.GetInt("timestamp")))
+	req.Header.Set(WebhookEventHeader, delivery.GetString("event"))
+	req.Header.Set(WebhookDeliveryHeader, delivery.Id)
+
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// GetRoomDeliveries returns the most recent deliveries of a room's events, newest first
+func (ws *WebhookService) GetRoomDeliveries(roomID string, limit int) ([]*core.Record, error) {
+	return ws.app.FindRecordsByFilter(
+		"webhook_deliveries",
+		"room_id = {:roomId}",
+		"-created_at",
+		limit,
+		0,
+		map[string]any{"roomId": roomID},
+	)
+}
+
+// PruneDeliveries deletes finished deliveries created before the cutoff
+// Returns the number of deleted deliveries
+func (ws *WebhookService) PruneDeliveries(before time.Time) (int, error) {
+	cutoff, err := types.ParseDateTime(before)
+	if err != nil {
+		return 0, err
+	}
+
+	deliveries, err := ws.app.FindRecordsByFilter(
+		"webhook_deliveries",
+		"status != {:pending} && created_at < {:before}",
+		"",
+		500,
+		0,
+		map[string]any{"pending": models.DeliveryPending, "before": cutoff.String()},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, delivery := range deliveries {
+		if err := ws.app.Delete(delivery); err == nil {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
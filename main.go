@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pocketbase/pocketbase"
@@ -11,8 +14,12 @@ import (
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/plugins/migratecmd"
 
+	"github.com/damione1/planning-poker/internal/config"
 	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/logging"
+	"github.com/damione1/planning-poker/internal/models"
 	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/internal/tracing"
 	_ "github.com/damione1/planning-poker/pb_migrations"
 )
 
@@ -30,6 +37,21 @@ func main() {
 		fmt.Printf("Built: %s\n", BuildDate)
 		os.Exit(0)
 	}
+	logging.Setup()
+
+	// Limits and settings from the environment and the optional CONFIG_FILE
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("Invalid configuration", logging.Err(err))
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		slog.Error("Failed to set up tracing", logging.Err(err))
+		os.Exit(1)
+	}
+
 	app := pocketbase.New()
 
 	// Register migrate command with automigrate enabled
@@ -40,19 +62,62 @@ func main() {
 	// Initialize services
 	roomManager := services.NewRoomManager(app)
 	aclService := services.NewACLService(roomManager)
-	hub := services.NewHub()
+
+	// Active rooms are served from memory; votes are written through, activity in the background.
+	// Instances sharing a database through Redis keep reading it directly: they are not told
+	// about each other's changes, so a cached room would miss votes cast on another instance.
+	var roomState *services.RoomStateStore
+	if cfg.Broker.RedisURL == "" {
+		roomState = services.NewRoomStateStore(app)
+		roomManager.SetStateStore(roomState)
+		go roomState.Run()
+	} else {
+		slog.Info("Room state disabled: with REDIS_URL set, every instance reads the database directly")
+	}
+
+	hub := services.NewHub(cfg)
+	broker := newBroker(cfg.Broker)
+	hub.SetBroker(broker)
+	roomManager.SetMetrics(hub.Metrics())
 	go hub.Run()
 
 	// Initialize handlers
 	roomHandlers := handlers.NewRoomHandlers(roomManager, hub)
 	wsHandler := handlers.NewWSHandler(hub, roomManager, aclService)
+	apiHandlers := handlers.NewAPIHandlers(roomManager, aclService, hub, wsHandler)
+
+	// Outgoing webhooks for room events
+	webhookService := services.NewWebhookService(app)
+	wsHandler.SetWebhookService(webhookService)
+	roomHandlers.SetWebhookService(webhookService)
+
+	// Issue tracker stories, estimated by completed rounds
+	storyService := services.NewStoryService(app)
+	registerIssueTrackers(storyService)
+	if len(storyService.TrackerNames()) > 0 {
+		wsHandler.SetStoryService(storyService)
+	}
+
+	// Slack slash command, enabled when a signing secret is configured
+	slackSigningSecret := os.Getenv("SLACK_SIGNING_SECRET")
+	slackService := services.NewSlackService(roomManager)
+	if slackSigningSecret != "" {
+		wsHandler.SetSlackService(slackService)
+	}
 
 	// Schedule daily cleanup job for expired rooms (runs at midnight)
 	app.Cron().MustAdd("cleanup_expired_rooms", "0 0 * * *", func() {
-		cleanupExpiredRooms(app)
+		cleanupExpiredRooms(app, webhookService)
 	})
 
 	app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+		// Every request gets a span, the parent of the spans of its handler
+		se.Router.BindFunc(handlers.TraceRequests)
+
+		// Instance-wide webhooks receive events of every room
+		registerInstanceWebhooks(webhookService)
+		go webhookService.Run()
+
 		// Page routes
 		se.Router.GET("/", handlers.Home)
 		se.Router.POST("/room", roomHandlers.CreateRoom)
@@ -61,12 +126,30 @@ func main() {
 		se.Router.GET("/room/{id}/participants", roomHandlers.ParticipantGridFragment)
 		se.Router.GET("/room/{id}/qr", roomHandlers.QRCodeHandler)
 
+		// Slack slash command - form-encoded and signed, so not part of the REST API document
+		if slackSigningSecret != "" {
+			slackHandlers := handlers.NewSlackHandlers(roomManager, slackService, slackSigningSecret, os.Getenv("PUBLIC_URL"))
+			se.Router.POST("/integrations/slack/command", slackHandlers.HandleCommand)
+		}
+
 		// WebSocket route
 		se.Router.GET("/ws/{roomId}", wsHandler.HandleWebSocket)
 
+		// Server-Sent Events fallback for networks that block WebSocket upgrades
+		se.Router.GET("/sse/{roomId}", wsHandler.HandleSSE)
+		se.Router.POST("/sse/{roomId}/{streamId}", wsHandler.HandleSSECommand)
+
+		// REST API routes - versioned under /api/v1 to stay clear of PocketBase's own /api/* routes
 		// Monitoring routes - use /monitoring/* instead of /api/* to avoid conflicts with PocketBase's API
-		se.Router.GET("/monitoring/metrics", handlers.HandleMetrics(hub))
-		se.Router.GET("/monitoring/health", handlers.HandleHealth(hub))
+		routes := append(apiHandlers.Routes(), handlers.MonitoringRoutes(app, hub)...)
+		for _, route := range routes {
+			se.Router.Route(route.Method, route.Path, route.Handler)
+		}
+
+		// Generated OpenAPI (REST) and AsyncAPI (WebSocket) documents
+		docsHandlers := handlers.NewDocsHandlers(Version, routes)
+		se.Router.GET("/monitoring/openapi.json", docsHandlers.OpenAPI)
+		se.Router.GET("/monitoring/asyncapi.json", docsHandlers.AsyncAPI)
 
 		// Static files - must be registered last with wildcard path
 		// Serves files from web/static directory at /static/* URL path
@@ -75,13 +158,105 @@ func main() {
 		return se.Next()
 	})
 
+	app.OnTerminate().BindFunc(func(e *core.TerminateEvent) error {
+		// Persist pending room changes while the database is still open
+		if roomState != nil {
+			if err := roomState.Close(); err != nil {
+				slog.Error("Error persisting room state", logging.Err(err))
+			}
+		}
+		if err := broker.Close(); err != nil {
+			slog.Error("Error closing broker", logging.Err(err))
+		}
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Error flushing traces", logging.Err(err))
+		}
+		return e.Next()
+	})
+
 	if err := app.Start(); err != nil {
-		log.Fatal(err)
+		slog.Error("Server stopped", logging.Err(err))
+		os.Exit(1)
+	}
+}
+
+// newBroker returns the broker relaying broadcasts between instances:
+// Redis pub/sub when REDIS_URL is set, otherwise an in-memory broker for a single instance
+func newBroker(cfg config.Broker) services.Broker {
+	if cfg.RedisURL == "" {
+		return services.NewMemoryBroker()
+	}
+
+	broker, err := services.NewRedisBroker(cfg.RedisURL)
+	if err != nil {
+		slog.Error("Failed to start Redis broker", logging.Err(err))
+		os.Exit(1)
+	}
+	slog.Info("Redis broker enabled: broadcasts are shared between instances")
+	return broker
+}
+
+// registerIssueTrackers registers the issue trackers configured in the environment:
+//   - Jira with JIRA_BASE_URL and JIRA_TOKEN (plus JIRA_EMAIL on Jira Cloud and an optional JIRA_STORY_POINTS_FIELD)
+//   - GitHub with GITHUB_TOKEN, an optional default GITHUB_REPO, GITHUB_API_URL and GITHUB_GRAPHQL_URL (GitHub Enterprise) and
+//     GITHUB_ESTIMATE_MODE: "label" (GITHUB_LABEL_PREFIX) or "field" (GITHUB_PROJECT_NUMBER, GITHUB_ESTIMATE_FIELD)
+func registerIssueTrackers(storyService *services.StoryService) {
+	if baseURL := os.Getenv("JIRA_BASE_URL"); baseURL != "" {
+		storyService.RegisterTracker(services.NewJiraTracker(services.JiraConfig{
+			BaseURL:          baseURL,
+			Email:            os.Getenv("JIRA_EMAIL"),
+			Token:            os.Getenv("JIRA_TOKEN"),
+			StoryPointsField: os.Getenv("JIRA_STORY_POINTS_FIELD"),
+		}))
+		slog.Info("Jira issue tracker enabled", "base_url", baseURL)
+	}
+
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		mode := os.Getenv("GITHUB_ESTIMATE_MODE")
+		if mode != "" && mode != services.GitHubEstimateLabel && mode != services.GitHubEstimateField {
+			slog.Error(fmt.Sprintf("Invalid GITHUB_ESTIMATE_MODE %q: must be %q or %q", mode, services.GitHubEstimateLabel, services.GitHubEstimateField))
+			os.Exit(1)
+		}
+		projectNumber, _ := strconv.Atoi(os.Getenv("GITHUB_PROJECT_NUMBER"))
+		if mode == services.GitHubEstimateField && projectNumber <= 0 {
+			slog.Error("GITHUB_PROJECT_NUMBER is required with GITHUB_ESTIMATE_MODE=" + mode)
+			os.Exit(1)
+		}
+
+		storyService.RegisterTracker(services.NewGitHubTracker(services.GitHubConfig{
+			BaseURL:       os.Getenv("GITHUB_API_URL"),
+			GraphQLURL:    os.Getenv("GITHUB_GRAPHQL_URL"),
+			Token:         token,
+			Repo:          os.Getenv("GITHUB_REPO"),
+			EstimateMode:  mode,
+			LabelPrefix:   os.Getenv("GITHUB_LABEL_PREFIX"),
+			ProjectNumber: projectNumber,
+			EstimateField: os.Getenv("GITHUB_ESTIMATE_FIELD"),
+		}))
+		slog.Info("GitHub issue tracker enabled")
 	}
 }
 
-func cleanupExpiredRooms(app *pocketbase.PocketBase) {
-	log.Printf("[Cleanup] Starting cleanup job at %s", time.Now().Format(time.RFC3339))
+// registerInstanceWebhooks registers the comma-separated WEBHOOK_URLS, signed with WEBHOOK_SECRET
+func registerInstanceWebhooks(webhookService *services.WebhookService) {
+	urls := os.Getenv("WEBHOOK_URLS")
+	if urls == "" {
+		return
+	}
+
+	secret := os.Getenv("WEBHOOK_SECRET")
+	for _, url := range strings.Split(urls, ",") {
+		if url = strings.TrimSpace(url); url == "" {
+			continue
+		}
+		if _, err := webhookService.EnsureInstanceWebhook(url, secret, nil); err != nil {
+			slog.Error("Failed to register instance webhook", logging.Err(err))
+		}
+	}
+}
+
+func cleanupExpiredRooms(app *pocketbase.PocketBase, webhookService *services.WebhookService) {
+	slog.Info("[Cleanup] Starting cleanup job")
 
 	// Delete expired rooms (cascade deletes rounds and votes via database constraints)
 	// PocketBase supports @now macro for current datetime comparison
@@ -94,18 +269,22 @@ func cleanupExpiredRooms(app *pocketbase.PocketBase) {
 	)
 
 	if err != nil {
-		log.Printf("[Cleanup] Error finding expired rooms: %v", err)
+		slog.Error("[Cleanup] Error finding expired rooms", logging.Err(err))
 		return
 	}
 
-	log.Printf("[Cleanup] Found %d expired rooms to delete", len(roomRecords))
+	slog.Info("[Cleanup] Found expired rooms to delete", "count", len(roomRecords))
 
 	for _, room := range roomRecords {
+		// Notify webhooks before the room (and its webhooks) are deleted
+		webhookService.Dispatch(room.Id, models.WebhookEventRoomExpired, models.RoomExpiredPayload{
+			Message: "Room expired at " + room.GetDateTime("expires_at").Time().Format(time.RFC3339),
+		})
+
 		if err := app.Delete(room); err != nil {
-			log.Printf("[Cleanup] Error deleting expired room %s: %v", room.Id, err)
+			logging.ForRoom(room.Id, "").Error("[Cleanup] Error deleting expired room", logging.Err(err))
 		} else {
-			log.Printf("[Cleanup] Deleted expired room: %s (%s), expired at: %s",
-				room.Id, room.GetString("name"), room.GetString("expires_at"))
+			logging.ForRoom(room.Id, "").Info("[Cleanup] Deleted expired room", "expired_at", room.GetString("expires_at"))
 		}
 	}
 
@@ -120,19 +299,26 @@ func cleanupExpiredRooms(app *pocketbase.PocketBase) {
 	)
 
 	if err != nil {
-		log.Printf("[Cleanup] Error finding orphaned participants: %v", err)
+		slog.Error("[Cleanup] Error finding orphaned participants", logging.Err(err))
 		return
 	}
 
-	log.Printf("[Cleanup] Found %d orphaned participants to delete", len(participantRecords))
+	slog.Info("[Cleanup] Found orphaned participants to delete", "count", len(participantRecords))
 
 	for _, participant := range participantRecords {
 		if err := app.Delete(participant); err != nil {
-			log.Printf("[Cleanup] Error deleting orphaned participant %s: %v", participant.Id, err)
+			logging.ForRoom("", participant.Id).Error("[Cleanup] Error deleting orphaned participant", logging.Err(err))
 		} else {
-			log.Printf("[Cleanup] Deleted orphaned participant: %s (%s)", participant.Id, participant.GetString("name"))
+			logging.ForRoom("", participant.Id).Info("[Cleanup] Deleted orphaned participant")
 		}
 	}
 
-	log.Printf("[Cleanup] Cleanup job completed successfully")
+	// Prune the webhook delivery log
+	if deleted, err := webhookService.PruneDeliveries(time.Now().Add(-config.WebhookDeliveryMaxAge)); err != nil {
+		slog.Error("[Cleanup] Error pruning webhook deliveries", logging.Err(err))
+	} else {
+		slog.Info("[Cleanup] Pruned webhook deliveries", "count", deleted)
+	}
+
+	slog.Info("[Cleanup] Cleanup job completed successfully")
 }
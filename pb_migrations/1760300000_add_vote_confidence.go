@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		votes, err := app.FindCollectionByNameOrId("votes")
+		if err != nil {
+			return fmt.Errorf("failed to find votes collection: %w", err)
+		}
+
+		// Add confidence field (0 = not provided, 1-5 otherwise)
+		votes.Fields.Add(&core.NumberField{
+			Name:     "confidence",
+			Required: false,
+			OnlyInt:  true,
+		})
+
+		if err := app.Save(votes); err != nil {
+			return fmt.Errorf("failed to update votes collection: %w", err)
+		}
+
+		return nil
+
+	}, func(app core.App) error {
+		// Down migration - remove field
+		votes, err := app.FindCollectionByNameOrId("votes")
+		if err == nil {
+			votes.Fields.RemoveByName("confidence")
+			_ = app.Save(votes)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		votes, err := app.FindCollectionByNameOrId("votes")
+		if err != nil {
+			return fmt.Errorf("failed to find votes collection: %w", err)
+		}
+
+		// Add dimension_values field (dimension name -> value, for multi-dimensional rooms)
+		votes.Fields.Add(&core.JSONField{
+			Name:     "dimension_values",
+			Required: false,
+			MaxSize:  2048,
+		})
+
+		if err := app.Save(votes); err != nil {
+			return fmt.Errorf("failed to update votes collection: %w", err)
+		}
+
+		return nil
+
+	}, func(app core.App) error {
+		// Down migration - remove field
+		votes, err := app.FindCollectionByNameOrId("votes")
+		if err == nil {
+			votes.Fields.RemoveByName("dimension_values")
+			_ = app.Save(votes)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		participants, err := app.FindCollectionByNameOrId("participants")
+		if err != nil {
+			return fmt.Errorf("failed to find participants collection: %w", err)
+		}
+
+		// Add vote weight field (0 = not set, treated as the default weight of 1)
+		participants.Fields.Add(&core.NumberField{
+			Name:     "weight",
+			Required: false,
+		})
+
+		if err := app.Save(participants); err != nil {
+			return fmt.Errorf("failed to update participants collection: %w", err)
+		}
+
+		return nil
+
+	}, func(app core.App) error {
+		// Down migration - remove field
+		participants, err := app.FindCollectionByNameOrId("participants")
+		if err == nil {
+			participants.Fields.RemoveByName("weight")
+			_ = app.Save(participants)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,125 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		rooms, err := app.FindCollectionByNameOrId("rooms")
+		if err != nil {
+			return fmt.Errorf("failed to find rooms collection: %w", err)
+		}
+
+		// Create webhooks collection
+		webhooks := core.NewBaseCollection("webhooks")
+		webhooks.ListRule = nil
+		webhooks.ViewRule = nil
+		webhooks.CreateRule = nil
+		webhooks.UpdateRule = nil
+		webhooks.DeleteRule = nil
+
+		// room_id relation (empty for instance-wide webhooks)
+		webhooks.Fields.Add(&core.RelationField{
+			Name:          "room_id",
+			Required:      false,
+			MaxSelect:     1,
+			CollectionId:  rooms.Id,
+			CascadeDelete: true,
+		})
+
+		// url field
+		webhooks.Fields.Add(&core.TextField{
+			Name:     "url",
+			Required: true,
+			Max:      2048,
+		})
+
+		// secret field (HMAC signing key)
+		webhooks.Fields.Add(&core.TextField{
+			Name:     "secret",
+			Required: true,
+			Max:      255,
+		})
+
+		// events field (subscribed event names)
+		webhooks.Fields.Add(&core.JSONField{
+			Name:     "events",
+			Required: false,
+			MaxSize:  1024,
+		})
+
+		// created_at field
+		webhooks.Fields.Add(&core.DateField{
+			Name:     "created_at",
+			Required: true,
+		})
+
+		webhooks.Indexes = []string{
+			"CREATE INDEX idx_webhooks_room ON webhooks(room_id)",
+		}
+
+		if err := app.Save(webhooks); err != nil {
+			return fmt.Errorf("failed to create webhooks collection: %w", err)
+		}
+
+		// Create webhook_deliveries collection
+		// Deliveries are signed when queued and keep plain IDs, so the log
+		// survives the deletion of expired rooms until it is pruned.
+		deliveries := core.NewBaseCollection("webhook_deliveries")
+		deliveries.ListRule = nil
+		deliveries.ViewRule = nil
+		deliveries.CreateRule = nil
+		deliveries.UpdateRule = nil
+		deliveries.DeleteRule = nil
+
+		deliveries.Fields.Add(&core.TextField{Name: "webhook_id", Required: true, Max: 50})
+		deliveries.Fields.Add(&core.TextField{Name: "room_id", Required: false, Max: 50})
+		deliveries.Fields.Add(&core.TextField{Name: "event", Required: true, Max: 50})
+		deliveries.Fields.Add(&core.TextField{Name: "url", Required: true, Max: 2048})
+		deliveries.Fields.Add(&core.TextField{Name: "body", Required: true, Max: 1 << 20})
+		deliveries.Fields.Add(&core.TextField{Name: "signature", Required: true, Max: 255})
+		deliveries.Fields.Add(&core.NumberField{Name: "timestamp", Required: true})
+
+		// status field
+		deliveries.Fields.Add(&core.SelectField{
+			Name:      "status",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"pending", "delivered", "failed"},
+		})
+
+		deliveries.Fields.Add(&core.NumberField{Name: "attempts", Required: false})
+		deliveries.Fields.Add(&core.NumberField{Name: "response_status", Required: false})
+		deliveries.Fields.Add(&core.TextField{Name: "last_error", Required: false, Max: 1000})
+		deliveries.Fields.Add(&core.DateField{Name: "next_attempt_at", Required: false})
+		deliveries.Fields.Add(&core.DateField{Name: "delivered_at", Required: false})
+		deliveries.Fields.Add(&core.DateField{Name: "created_at", Required: true})
+
+		deliveries.Indexes = []string{
+			"CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at)",
+			"CREATE INDEX idx_webhook_deliveries_room ON webhook_deliveries(room_id)",
+		}
+
+		if err := app.Save(deliveries); err != nil {
+			return fmt.Errorf("failed to create webhook_deliveries collection: %w", err)
+		}
+
+		return nil
+	}, func(app core.App) error {
+		// Down migration - drop both collections
+		for _, name := range []string{"webhook_deliveries", "webhooks"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				continue
+			}
+			if err := app.Delete(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
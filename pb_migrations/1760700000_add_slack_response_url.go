@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		rooms, err := app.FindCollectionByNameOrId("rooms")
+		if err != nil {
+			return fmt.Errorf("failed to find rooms collection: %w", err)
+		}
+
+		// Slack response_url of the slash command that created the room (reveal summaries are posted there)
+		rooms.Fields.Add(&core.TextField{
+			Name:     "slack_response_url",
+			Required: false,
+			Max:      2048,
+		})
+
+		// Slack only accepts posts to a response_url for a limited time
+		rooms.Fields.Add(&core.DateField{
+			Name:     "slack_response_expires_at",
+			Required: false,
+		})
+
+		if err := app.Save(rooms); err != nil {
+			return fmt.Errorf("failed to update rooms collection: %w", err)
+		}
+
+		return nil
+
+	}, func(app core.App) error {
+		// Down migration - remove fields
+		rooms, err := app.FindCollectionByNameOrId("rooms")
+		if err == nil {
+			rooms.Fields.RemoveByName("slack_response_url")
+			rooms.Fields.RemoveByName("slack_response_expires_at")
+			_ = app.Save(rooms)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,81 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		rooms, err := app.FindCollectionByNameOrId("rooms")
+		if err != nil {
+			return fmt.Errorf("failed to find rooms collection: %w", err)
+		}
+
+		// Create stories collection (issues imported from an issue tracker)
+		stories := core.NewBaseCollection("stories")
+		stories.ListRule = nil
+		stories.ViewRule = nil
+		stories.CreateRule = nil
+		stories.UpdateRule = nil
+		stories.DeleteRule = nil
+
+		stories.Fields.Add(&core.RelationField{
+			Name:          "room_id",
+			Required:      true,
+			MaxSelect:     1,
+			CollectionId:  rooms.Id,
+			CascadeDelete: true,
+		})
+
+		// Source issue
+		stories.Fields.Add(&core.TextField{Name: "tracker", Required: true, Max: 50})
+		stories.Fields.Add(&core.TextField{Name: "issue_key", Required: true, Max: 255})
+		stories.Fields.Add(&core.TextField{Name: "title", Required: false, Max: 500})
+		stories.Fields.Add(&core.TextField{Name: "url", Required: false, Max: 2048})
+
+		// Estimation order within the room
+		stories.Fields.Add(&core.NumberField{Name: "position", Required: false, OnlyInt: true})
+
+		// Estimate from the completed round
+		stories.Fields.Add(&core.SelectField{
+			Name:      "status",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"pending", "estimated"},
+		})
+		stories.Fields.Add(&core.NumberField{Name: "estimate", Required: false})
+		stories.Fields.Add(&core.TextField{Name: "round_id", Required: false, Max: 50})
+
+		// Write-back of the estimate to the tracker
+		stories.Fields.Add(&core.SelectField{
+			Name:      "sync_status",
+			Required:  false,
+			MaxSelect: 1,
+			Values:    []string{"pending", "synced", "failed"},
+		})
+		stories.Fields.Add(&core.TextField{Name: "sync_error", Required: false, Max: 1000})
+
+		stories.Fields.Add(&core.DateField{Name: "created_at", Required: true})
+
+		stories.Indexes = []string{
+			"CREATE INDEX idx_stories_room ON stories(room_id, position)",
+			"CREATE UNIQUE INDEX idx_stories_issue ON stories(room_id, tracker, issue_key)",
+		}
+
+		if err := app.Save(stories); err != nil {
+			return fmt.Errorf("failed to create stories collection: %w", err)
+		}
+
+		return nil
+	}, func(app core.App) error {
+		// Down migration - drop the stories collection
+		stories, err := app.FindCollectionByNameOrId("stories")
+		if err != nil {
+			return nil
+		}
+		return app.Delete(stories)
+	})
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		return setStorySyncStatuses(app, []string{"pending", "synced", "failed", "conflict"})
+	}, func(app core.App) error {
+		// Down migration - conflicts become plain failures
+		if _, err := app.DB().NewQuery("UPDATE stories SET sync_status = 'failed' WHERE sync_status = 'conflict'").Execute(); err != nil {
+			return fmt.Errorf("failed to migrate conflicting stories: %w", err)
+		}
+		return setStorySyncStatuses(app, []string{"pending", "synced", "failed"})
+	})
+}
+
+// setStorySyncStatuses replaces the allowed values of stories.sync_status
+func setStorySyncStatuses(app core.App, values []string) error {
+	stories, err := app.FindCollectionByNameOrId("stories")
+	if err != nil {
+		return fmt.Errorf("failed to find stories collection: %w", err)
+	}
+
+	field, ok := stories.Fields.GetByName("sync_status").(*core.SelectField)
+	if !ok {
+		return fmt.Errorf("stories.sync_status is not a select field")
+	}
+	field.Values = values
+
+	if err := app.Save(stories); err != nil {
+		return fmt.Errorf("failed to update stories collection: %w", err)
+	}
+	return nil
+}
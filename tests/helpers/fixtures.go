@@ -11,7 +11,7 @@ import (
 )
 
 // SetupTestApp creates a test PocketBase app with migrations and returns cleanup function
-func SetupTestApp(t *testing.T) (core.App, func()) {
+func SetupTestApp(t testing.TB) (core.App, func()) {
 	t.Helper()
 	ts := NewTestServerWithData(t)
 	return ts.App, ts.Cleanup
@@ -30,7 +30,7 @@ func CreateTestRoom(t *testing.T, app core.App, name, pointingMethod string, cus
 }
 
 // CreateTestRoomWithParticipants creates a room with N voter participants
-func CreateTestRoomWithParticipants(t *testing.T, app core.App, voterCount int, config *models.RoomConfig) string {
+func CreateTestRoomWithParticipants(t testing.TB, app core.App, voterCount int, config *models.RoomConfig) string {
 	t.Helper()
 
 	rm := services.NewRoomManager(app)
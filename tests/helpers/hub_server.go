@@ -0,0 +1,50 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/coder/websocket"
+
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+// StartHubServer serves WebSocket connections registered straight into hub, without room or session checks.
+// Clients connect to ws://<addr>/ws/{roomId}?participant={participantId}, adding &eventLog=&lastSeq= to
+// resume after an event; returns the ws:// base URL.
+func StartHubServer(t *testing.T, hub *services.Hub) string {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws/{roomId}", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		query := r.URL.Query()
+		client := services.NewClient(hub.Config(), services.NewWebSocketTransport(conn, hub.Config().Limits.PongTimeout), hub, r.PathValue("roomId"), query.Get("participant"))
+		lastSeq, _ := strconv.ParseUint(query.Get("lastSeq"), 10, 64)
+		hub.Resume(r.PathValue("roomId"), client, services.EventPosition{Log: query.Get("eventLog"), Seq: lastSeq})
+		client.Start()
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// ConnectHubClient connects a test client to a room served by StartHubServer
+func ConnectHubClient(t *testing.T, baseURL, roomID, participantID string) *WSClient {
+	t.Helper()
+
+	client := NewWSClient()
+	client.SetParticipantID(participantID)
+	if err := client.Connect(baseURL + "/ws/" + roomID + "?participant=" + participantID); err != nil {
+		t.Fatalf("Failed to connect WebSocket client: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
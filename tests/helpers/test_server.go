@@ -14,7 +14,7 @@ import (
 // TestServer wraps a PocketBase test instance
 type TestServer struct {
 	App core.App
-	t   *testing.T
+	t   testing.TB
 }
 
 // NewTestServer creates a new test PocketBase instance with in-memory database
@@ -42,7 +42,7 @@ func NewTestServer(t *testing.T) *TestServer {
 }
 
 // NewTestServerWithData creates a test server and applies migrations from the project
-func NewTestServerWithData(t *testing.T) *TestServer {
+func NewTestServerWithData(t testing.TB) *TestServer {
 	t.Helper()
 
 	testDir := t.TempDir()
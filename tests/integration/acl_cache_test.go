@@ -0,0 +1,193 @@
+package integration_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+func TestACLService_ConfigIsCached(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 1, nil)
+	rm := services.NewRoomManager(server.App)
+	acl := services.NewACLService(rm)
+
+	_, err := acl.GetRoomConfig(roomID)
+	require.NoError(t, err)
+
+	// Activity updates leave the config alone and keep it cached
+	require.NoError(t, rm.UpdateRoomActivity(roomID))
+	queries := countQueries(server.App)
+	for i := 0; i < 5; i++ {
+		_, err := acl.CanReveal(roomID, "someone")
+		require.NoError(t, err)
+	}
+	_, err = acl.GetRoomConfig(roomID)
+	require.NoError(t, err)
+	// Only the creator checks read the room
+	assert.LessOrEqual(t, queries.Load(), int64(5))
+
+	// Callers get their own copy
+	config, err := acl.GetRoomConfig(roomID)
+	require.NoError(t, err)
+	config.Permissions.AllowAllReveal = false
+	config, err = acl.GetRoomConfig(roomID)
+	require.NoError(t, err)
+	assert.True(t, config.Permissions.AllowAllReveal)
+}
+
+func TestACLService_ConfigInvalidation(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 1, nil)
+	creator := participantIDs(t, server.App, roomID)[0]
+	rm := services.NewRoomManager(server.App)
+	acl := services.NewACLService(rm)
+
+	config, err := acl.GetRoomConfig(roomID)
+	require.NoError(t, err)
+	require.False(t, config.Permissions.AutoReveal)
+
+	t.Run("UpdateRoomConfig", func(t *testing.T) {
+		updated := models.DefaultRoomConfig()
+		updated.Permissions.AutoReveal = true
+		require.NoError(t, acl.UpdateRoomConfig(context.Background(), roomID, creator, updated))
+
+		config, err := acl.GetRoomConfig(roomID)
+		require.NoError(t, err)
+		assert.True(t, config.Permissions.AutoReveal)
+	})
+
+	t.Run("record saved elsewhere", func(t *testing.T) {
+		// As an edit in the PocketBase dashboard would
+		room, err := server.App.FindRecordById("rooms", roomID)
+		require.NoError(t, err)
+		edited := models.DefaultRoomConfig()
+		edited.Permissions.AllowAllReset = false
+		configJSON, err := json.Marshal(edited)
+		require.NoError(t, err)
+		room.Set("config", string(configJSON))
+		require.NoError(t, server.App.Save(room))
+
+		config, err := acl.GetRoomConfig(roomID)
+		require.NoError(t, err)
+		assert.False(t, config.Permissions.AllowAllReset)
+		assert.False(t, config.Permissions.AutoReveal)
+	})
+
+	t.Run("room deleted", func(t *testing.T) {
+		room, err := server.App.FindRecordById("rooms", roomID)
+		require.NoError(t, err)
+		require.NoError(t, server.App.Delete(room))
+
+		_, err = acl.GetRoomConfig(roomID)
+		assert.Error(t, err)
+	})
+}
+
+func TestACLService_ConfigEditsReachRoomState(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 1, nil)
+	voter := participantIDs(t, server.App, roomID)[0]
+	rm, store := newStateRoomManager(server.App)
+	acl := services.NewACLService(rm)
+
+	// Room loaded into memory before the edit
+	_, err := rm.GetRoom(roomID)
+	require.NoError(t, err)
+	_, err = acl.GetRoomConfig(roomID)
+	require.NoError(t, err)
+
+	room, err := server.App.FindRecordById("rooms", roomID)
+	require.NoError(t, err)
+	edited := models.DefaultRoomConfig()
+	edited.Permissions.AllowAllReveal = false
+	configJSON, err := json.Marshal(edited)
+	require.NoError(t, err)
+	room.Set("config", string(configJSON))
+	room.Set("name", "Edited in the dashboard")
+	require.NoError(t, server.App.Save(room))
+
+	canReveal, err := acl.CanReveal(roomID, "someone")
+	require.NoError(t, err)
+	assert.False(t, canReveal)
+
+	// Votes carry on in memory without writing the old room back
+	require.NoError(t, rm.CastVote(roomID, voter, "5"))
+	require.NoError(t, rm.UpdateRoomActivity(roomID))
+	cached, err := rm.GetRoom(roomID)
+	require.NoError(t, err)
+	assert.Equal(t, "Edited in the dashboard", cached.GetString("name"))
+	assert.Equal(t, string(configJSON), cached.GetString("config"))
+
+	require.NoError(t, store.Flush())
+	stored, err := server.App.FindRecordById("rooms", roomID)
+	require.NoError(t, err)
+	assert.Equal(t, "Edited in the dashboard", stored.GetString("name"))
+	assert.Equal(t, string(configJSON), stored.GetString("config"))
+
+	// A room deleted in the dashboard is no longer served
+	require.NoError(t, server.App.Delete(stored))
+	_, err = rm.GetRoom(roomID)
+	assert.Error(t, err)
+}
+
+func TestACLService_Permissions(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	config := models.DefaultRoomConfig()
+	config.Permissions.AllowAllReveal = false
+	config.Permissions.AllowAllReset = false
+	config.Permissions.AllowChangeVoteAfterReveal = true
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 2, config)
+	participants := participantIDs(t, server.App, roomID)
+	acl := services.NewACLService(services.NewRoomManager(server.App))
+
+	t.Run("creator", func(t *testing.T) {
+		permissions, err := acl.Permissions(roomID, participants[0])
+		require.NoError(t, err)
+		assert.Equal(t, models.ParticipantActions{
+			CanReset:                 true,
+			CanNewRound:              true,
+			CanReveal:                true,
+			CanChangeVoteAfterReveal: true,
+		}, permissions)
+	})
+
+	t.Run("other participant", func(t *testing.T) {
+		permissions, err := acl.Permissions(roomID, participants[1])
+		require.NoError(t, err)
+		assert.Equal(t, models.ParticipantActions{
+			CanReset:                 false,
+			CanNewRound:              true,
+			CanReveal:                false,
+			CanChangeVoteAfterReveal: true,
+		}, permissions)
+
+		// Same answers as the individual checks
+		canReveal, err := acl.CanReveal(roomID, participants[1])
+		require.NoError(t, err)
+		assert.Equal(t, canReveal, permissions.CanReveal)
+		canReset, err := acl.CanReset(roomID, participants[1])
+		require.NoError(t, err)
+		assert.Equal(t, canReset, permissions.CanReset)
+	})
+
+	t.Run("unknown room", func(t *testing.T) {
+		_, err := acl.Permissions("doesnotexist123", participants[0])
+		assert.Error(t, err)
+	})
+}
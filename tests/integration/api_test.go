@@ -0,0 +1,210 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// apiCall invokes an API handler directly with a JSON body, room path value and optional participant token
+func apiCall(t testing.TB, app core.App, handler func(*core.RequestEvent) error, method, roomID, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	re, rec := apiRequest(app, method, roomID, token, body)
+	require.NoError(t, handler(re))
+	return rec
+}
+
+// apiRequest builds a request event for calling a handler directly
+func apiRequest(app core.App, method, roomID, token, body string) (*core.RequestEvent, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, "/api/v1/test", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if roomID != "" {
+		req.SetPathValue("id", roomID)
+	}
+	if token != "" {
+		req.Header.Set(handlers.ParticipantTokenHeader, token)
+	}
+
+	rec := httptest.NewRecorder()
+	return &core.RequestEvent{App: app, Event: router.Event{Response: rec, Request: req}}, rec
+}
+
+func decodeBody(t testing.TB, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return body
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	body := decodeBody(t, rec)
+	envelope, ok := body["error"].(map[string]any)
+	require.True(t, ok, "expected error envelope, got %s", rec.Body.String())
+	return envelope["code"].(string)
+}
+
+func TestAPI_RoomLifecycle(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	rm := services.NewRoomManager(server.App)
+	acl := services.NewACLService(rm)
+	hub := services.NewHub(config.Default())
+	go hub.Run()
+	ws := handlers.NewWSHandler(hub, rm, acl)
+	api := handlers.NewAPIHandlers(rm, acl, hub, ws)
+
+	// Create room
+	rec := apiCall(t, server.App, api.CreateRoom, http.MethodPost, "", "",
+		`{"name": "API Room", "pointingMethod": "custom", "customValues": ["1", "2", "3", "5"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	room := decodeBody(t, rec)
+	roomID := room["id"].(string)
+	assert.Equal(t, "voting", room["state"])
+	assert.Equal(t, float64(1), room["roundNumber"])
+
+	// Join as facilitator (first participant) and as a second voter
+	rec = apiCall(t, server.App, api.JoinRoom, http.MethodPost, roomID, "", `{"name": "Alice"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	alice := decodeBody(t, rec)
+	aliceToken := alice["token"].(string)
+	assert.Equal(t, true, alice["participant"].(map[string]any)["isCreator"])
+
+	rec = apiCall(t, server.App, api.JoinRoom, http.MethodPost, roomID, "", `{"name": "Bob", "role": "voter"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	bobToken := decodeBody(t, rec)["token"].(string)
+
+	rec = apiCall(t, server.App, api.ListParticipants, http.MethodGet, roomID, "", "")
+	assert.Len(t, decodeBody(t, rec)["participants"], 2)
+
+	// Cast votes
+	rec = apiCall(t, server.App, api.CastVote, http.MethodPost, roomID, aliceToken, `{"value": "3", "confidence": "high"}`)
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	rec = apiCall(t, server.App, api.CastVote, http.MethodPost, roomID, bobToken, `{"value": "5"}`)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	// Values stay hidden before reveal
+	rec = apiCall(t, server.App, api.GetCurrentRound, http.MethodGet, roomID, "", "")
+	round := decodeBody(t, rec)
+	votes := round["votes"].([]any)
+	assert.Len(t, votes, 2)
+	assert.Nil(t, votes[0].(map[string]any)["value"])
+
+	// Reveal
+	rec = apiCall(t, server.App, api.Reveal, http.MethodPost, roomID, aliceToken, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	round = decodeBody(t, rec)
+	assert.Equal(t, "revealed", round["state"])
+	assert.NotEmpty(t, round["votes"].([]any)[0].(map[string]any)["value"])
+
+	// Advance
+	rec = apiCall(t, server.App, api.NextRound, http.MethodPost, roomID, aliceToken, "")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, float64(2), decodeBody(t, rec)["roundNumber"])
+
+	// History
+	rec = apiCall(t, server.App, api.ListRounds, http.MethodGet, roomID, "", "")
+	rounds := decodeBody(t, rec)["rounds"].([]any)
+	require.Len(t, rounds, 2)
+	first := rounds[0].(map[string]any)
+	assert.Equal(t, "completed", first["state"])
+	assert.InDelta(t, 4.0, first["averageScore"].(float64), 0.001)
+}
+
+func TestAPI_Errors(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	rm := services.NewRoomManager(server.App)
+	acl := services.NewACLService(rm)
+	hub := services.NewHub(config.Default())
+	go hub.Run()
+	ws := handlers.NewWSHandler(hub, rm, acl)
+	api := handlers.NewAPIHandlers(rm, acl, hub, ws)
+
+	room, _ := rm.CreateRoom("Test", "fibonacci", nil, nil)
+
+	t.Run("unknown room", func(t *testing.T) {
+		rec := apiCall(t, server.App, api.GetRoom, http.MethodGet, "abcdefghijklmno", "", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, handlers.ErrCodeNotFound, errorCode(t, rec))
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		rec := apiCall(t, server.App, api.CreateRoom, http.MethodPost, "", "", `{not json`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, handlers.ErrCodeInvalidPayload, errorCode(t, rec))
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		rec := apiCall(t, server.App, api.Reveal, http.MethodPost, room.Id, "", "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, handlers.ErrCodeUnauthorized, errorCode(t, rec))
+	})
+
+	t.Run("permission denied", func(t *testing.T) {
+		_ = apiCall(t, server.App, api.JoinRoom, http.MethodPost, room.Id, "", `{"name": "Owner"}`)
+		rec := apiCall(t, server.App, api.JoinRoom, http.MethodPost, room.Id, "", `{"name": "Viewer", "role": "spectator"}`)
+		viewerToken := decodeBody(t, rec)["token"].(string)
+
+		rec = apiCall(t, server.App, api.CastVote, http.MethodPost, room.Id, viewerToken, `{"value": "3"}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, handlers.ErrCodeForbidden, errorCode(t, rec))
+	})
+
+	t.Run("wrong state", func(t *testing.T) {
+		rec := apiCall(t, server.App, api.JoinRoom, http.MethodPost, room.Id, "", `{"name": "Voter"}`)
+		token := decodeBody(t, rec)["token"].(string)
+
+		rec = apiCall(t, server.App, api.NextRound, http.MethodPost, room.Id, token, "")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, handlers.ErrCodeInvalidState, errorCode(t, rec))
+	})
+}
+
+func TestAPI_CommandsShareRateLimit(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	cfg := config.Default()
+	cfg.Limits.MaxMessagesPerSecond = 2
+	cfg.Limits.RateLimitWindow = time.Hour
+	rm := services.NewRoomManager(server.App)
+	acl := services.NewACLService(rm)
+	hub := services.NewHub(cfg)
+	go hub.Run()
+	api := handlers.NewAPIHandlers(rm, acl, hub, handlers.NewWSHandler(hub, rm, acl))
+
+	room, _ := rm.CreateRoom("Test", "fibonacci", nil, nil)
+	rec := apiCall(t, server.App, api.JoinRoom, http.MethodPost, room.Id, "", `{"name": "Voter"}`)
+	token := decodeBody(t, rec)["token"].(string)
+
+	for _, value := range []string{"3", "5"} {
+		rec = apiCall(t, server.App, api.CastVote, http.MethodPost, room.Id, token, `{"value": "`+value+`"}`)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+	rec = apiCall(t, server.App, api.CastVote, http.MethodPost, room.Id, token, `{"value": "8"}`)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, models.ErrCodeRateLimited, errorCode(t, rec))
+
+	votes, err := rm.GetRoomVotes(room.Id)
+	require.NoError(t, err)
+	require.Len(t, votes, 1)
+	assert.Equal(t, "5", votes[0].GetString("value"), "the dropped vote was not handled")
+}
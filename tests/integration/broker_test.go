@@ -0,0 +1,198 @@
+package integration_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// startHubs runs two hubs, as two instances would, each with its own broker connection
+func startHubs(t *testing.T, brokerA, brokerB services.Broker) (*services.Hub, *services.Hub) {
+	t.Helper()
+	hubA, hubB := services.NewHub(config.Default()), services.NewHub(config.Default())
+	hubA.SetBroker(brokerA)
+	hubB.SetBroker(brokerB)
+	go hubA.Run()
+	go hubB.Run()
+	return hubA, hubB
+}
+
+// waitForRelay broadcasts on one hub until a client on another hub receives it.
+// Broker subscriptions are asynchronous, so the first messages after connecting may not be relayed yet.
+func waitForRelay(t *testing.T, from *services.Hub, roomID string, to ...*helpers.WSClient) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		from.BroadcastToRoom(context.Background(), roomID, &models.WSMessage{Type: models.MsgTypeAutoRevealCancelled, Payload: models.EmptyPayload{}})
+		for _, client := range to {
+			if client.WaitForMessageType(models.MsgTypeAutoRevealCancelled, 50*time.Millisecond) == nil {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+	for _, client := range to {
+		client.ClearMessages()
+	}
+}
+
+func countMessages(client *helpers.WSClient, msgType string) int {
+	count := 0
+	for _, msg := range client.ReceivedMessages() {
+		if msg.Type == msgType {
+			count++
+		}
+	}
+	return count
+}
+
+func testCrossInstanceBroadcast(t *testing.T, brokerA, brokerB services.Broker) {
+	hubA, hubB := startHubs(t, brokerA, brokerB)
+	urlA, urlB := helpers.StartHubServer(t, hubA), helpers.StartHubServer(t, hubB)
+
+	alice := helpers.ConnectHubClient(t, urlA, "room-1", "alice")
+	bob := helpers.ConnectHubClient(t, urlB, "room-1", "bob")
+	carol := helpers.ConnectHubClient(t, urlB, "room-2", "carol")
+	require.Eventually(t, func() bool {
+		return hubA.GetRoomSize("room-1") == 1 && hubB.GetRoomSize("room-1") == 1 && hubB.GetRoomSize("room-2") == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	waitForRelay(t, hubA, "room-1", bob)
+	waitForRelay(t, hubB, "room-1", alice)
+	alice.ClearMessages()
+
+	// A broadcast on one instance reaches the room on both, exactly once
+	hubA.BroadcastToRoom(context.Background(), "room-1", &models.WSMessage{Type: models.MsgTypeRoomReset, Payload: models.EmptyPayload{}})
+	alice.ExpectMessage(t, models.MsgTypeRoomReset, 2*time.Second)
+	bob.ExpectMessage(t, models.MsgTypeRoomReset, 2*time.Second)
+
+	hubB.BroadcastToRoom(context.Background(), "room-1", &models.WSMessage{Type: models.MsgTypeRoomNameUpdated, Payload: models.RoomNameUpdatedPayload{Name: "Renamed"}})
+	alice.ExpectMessage(t, models.MsgTypeRoomNameUpdated, 2*time.Second)
+	bob.ExpectMessage(t, models.MsgTypeRoomNameUpdated, 2*time.Second)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, countMessages(alice, models.MsgTypeRoomReset), "own broadcasts are not delivered twice")
+	assert.Equal(t, 1, countMessages(bob, models.MsgTypeRoomReset))
+	assert.Equal(t, 1, countMessages(bob, models.MsgTypeRoomNameUpdated))
+	assert.Empty(t, carol.ReceivedMessages(), "other rooms receive nothing")
+}
+
+func TestCrossInstanceBroadcastMemoryBroker(t *testing.T) {
+	broker := services.NewMemoryBroker()
+	testCrossInstanceBroadcast(t, broker, broker)
+}
+
+func TestCrossInstanceBroadcastRedisBroker(t *testing.T) {
+	redis := miniredis.RunT(t)
+
+	brokerA, err := services.NewRedisBroker("redis://" + redis.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = brokerA.Close() })
+	brokerB, err := services.NewRedisBroker("redis://" + redis.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = brokerB.Close() })
+
+	testCrossInstanceBroadcast(t, brokerA, brokerB)
+}
+
+func TestRedisBrokerConnectionError(t *testing.T) {
+	_, err := services.NewRedisBroker("redis://127.0.0.1:1")
+	assert.Error(t, err)
+
+	_, err = services.NewRedisBroker("not a url")
+	assert.Error(t, err)
+}
+
+// failingBroker fails the first room subscriptions, or blocks them until release is closed
+type failingBroker struct {
+	*services.MemoryBroker
+	failures atomic.Int64 // Subscriptions left to fail
+	release  chan struct{}
+}
+
+func (b *failingBroker) Subscribe(roomID string, handler func(message []byte)) (services.Subscription, error) {
+	if b.release != nil {
+		<-b.release
+	}
+	if b.failures.Add(-1) >= 0 {
+		return nil, errors.New("connection reset")
+	}
+	return b.MemoryBroker.Subscribe(roomID, handler)
+}
+
+func TestCrossInstanceSubscribeIsRetried(t *testing.T) {
+	memory := services.NewMemoryBroker()
+	failing := &failingBroker{MemoryBroker: memory}
+	failing.failures.Store(2)
+	hubA, hubB := startHubs(t, memory, failing)
+
+	bob := helpers.ConnectHubClient(t, helpers.StartHubServer(t, hubB), "room-1", "bob")
+	require.Eventually(t, func() bool { return hubB.GetRoomSize("room-1") == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	waitForRelay(t, hubA, "room-1", bob)
+	assert.Less(t, failing.failures.Load(), int64(0), "relayed once a retry subscribed")
+}
+
+func TestCrossInstanceSubscribeDoesNotBlockHub(t *testing.T) {
+	memory := services.NewMemoryBroker()
+	stuck := &failingBroker{MemoryBroker: memory, release: make(chan struct{})}
+	hubA, hubB := startHubs(t, memory, stuck)
+	urlB := helpers.StartHubServer(t, hubB)
+
+	bob := helpers.ConnectHubClient(t, urlB, "room-1", "bob")
+	require.Eventually(t, func() bool { return hubB.GetRoomSize("room-1") == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// The hub keeps registering clients while the broker hangs
+	require.NoError(t, hubB.Ping(time.Second))
+	helpers.ConnectHubClient(t, urlB, "room-2", "carol")
+	require.Eventually(t, func() bool { return hubB.GetRoomSize("room-2") == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	close(stuck.release)
+	waitForRelay(t, hubA, "room-1", bob)
+}
+
+// Commands handled by one instance reach participants connected to another
+func TestCrossInstanceRoomCommands(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	t.Cleanup(server.Cleanup)
+
+	broker := services.NewMemoryBroker()
+	hubA, hubB := startHubs(t, broker, broker)
+
+	// Instance A serves the API, the participants' browser is connected to instance B
+	rm := services.NewRoomManager(server.App)
+	acl := services.NewACLService(rm)
+	api := handlers.NewAPIHandlers(rm, acl, hubA, handlers.NewWSHandler(hubA, rm, acl))
+	handlers.NewWSHandler(hubB, rm, acl)
+
+	rec := apiCall(t, server.App, api.CreateRoom, http.MethodPost, "", "", `{"name": "Replicated"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	roomID := decodeBody(t, rec)["id"].(string)
+	rec = apiCall(t, server.App, api.JoinRoom, http.MethodPost, roomID, "", `{"name": "Alice"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	token := decodeBody(t, rec)["token"].(string)
+
+	browser := helpers.ConnectHubClient(t, helpers.StartHubServer(t, hubB), roomID, "observer")
+	require.Eventually(t, func() bool { return hubB.GetRoomSize(roomID) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	rec = apiCall(t, server.App, api.CastVote, http.MethodPost, roomID, token, `{"value": "5"}`)
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	browser.ExpectMessage(t, models.MsgTypeVoteCast, 2*time.Second)
+
+	rec = apiCall(t, server.App, api.Reveal, http.MethodPost, roomID, token, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	revealed := browser.ExpectMessage(t, models.MsgTypeVotesRevealed, 2*time.Second)
+	assert.NotNil(t, revealed.Payload)
+}
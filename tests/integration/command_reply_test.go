@@ -0,0 +1,155 @@
+package integration_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// connectVoter serves a room through the WebSocket handler and connects a voter who is not its creator
+func connectVoter(t *testing.T) *helpers.WSClient {
+	t.Helper()
+	server := helpers.NewTestServerWithData(t)
+	t.Cleanup(server.Cleanup)
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 2, nil)
+	voter := participantIDs(t, server.App, roomID)[1] // The first participant becomes the creator
+
+	hub := services.NewHub(config.Default())
+	go hub.Run()
+	rm := services.NewRoomManager(server.App)
+	handlers.NewWSHandler(hub, rm, services.NewACLService(rm))
+
+	client := helpers.ConnectHubClient(t, helpers.StartHubServer(t, hub), roomID, voter)
+	require.Eventually(t, func() bool { return hub.GetRoomSize(roomID) == 1 }, 5*time.Second, 10*time.Millisecond)
+	return client
+}
+
+// errorPayload decodes the payload of an "error" reply
+func errorPayload(t *testing.T, msg *models.WSMessage) map[string]any {
+	t.Helper()
+	payload, ok := msg.Payload.(map[string]any)
+	require.True(t, ok, "error payload: %#v", msg.Payload)
+	return payload
+}
+
+func TestCommandReply_AckEchoesRequestID(t *testing.T) {
+	client := connectVoter(t)
+
+	require.NoError(t, client.SendMessage(map[string]any{
+		"type":      models.MsgTypeVote,
+		"requestId": "req-1",
+		"payload":   map[string]any{"value": "5"},
+	}))
+
+	ack := client.ExpectMessage(t, models.MsgTypeAck, 2*time.Second)
+	assert.Equal(t, "req-1", ack.RequestID)
+	assert.Equal(t, map[string]any{"action": models.MsgTypeVote}, ack.Payload)
+	client.ExpectMessage(t, models.MsgTypeVoteCast, 2*time.Second)
+}
+
+func TestCommandReply_RejectedCommandHasErrorCode(t *testing.T) {
+	client := connectVoter(t)
+
+	require.NoError(t, client.SendMessage(map[string]any{
+		"type":      models.MsgTypeUpdateRoomName,
+		"requestId": "req-2",
+		"payload":   map[string]any{"name": "Renamed"},
+	}))
+
+	reply := client.ExpectMessage(t, models.MsgTypeError, 2*time.Second)
+	assert.Equal(t, "req-2", reply.RequestID)
+	payload := errorPayload(t, reply)
+	assert.Equal(t, handlers.ErrCodeForbidden, payload["code"])
+	assert.Equal(t, models.MsgTypeUpdateRoomName, payload["action"])
+	assert.NotEmpty(t, payload["message"])
+	assert.Nil(t, client.WaitForMessageType(models.MsgTypeAck, 100*time.Millisecond))
+}
+
+func TestCommandReply_InvalidMessages(t *testing.T) {
+	client := connectVoter(t)
+
+	tests := []struct {
+		name      string
+		message   map[string]any
+		requestID string
+	}{
+		{"unknown type", map[string]any{"type": "shout", "requestId": "req-3"}, "req-3"},
+		{"invalid payload", map[string]any{"type": models.MsgTypeVote, "requestId": "req-4", "payload": map[string]any{"value": 5}}, "req-4"},
+		{"invalid vote", map[string]any{"type": models.MsgTypeVote, "requestId": "req-5", "payload": map[string]any{}}, "req-5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.ClearMessages()
+			require.NoError(t, client.SendMessage(tt.message))
+
+			reply := client.ExpectMessage(t, models.MsgTypeError, 2*time.Second)
+			assert.Equal(t, tt.requestID, reply.RequestID)
+			assert.Equal(t, handlers.ErrCodeInvalidPayload, errorPayload(t, reply)["code"])
+		})
+	}
+}
+
+func TestCommandReply_RateLimitedCommand(t *testing.T) {
+	cfg := config.Default()
+	cfg.Limits.MaxMessagesPerSecond = 1
+	hub := services.NewHub(cfg)
+	go hub.Run()
+
+	client := helpers.ConnectHubClient(t, helpers.StartHubServer(t, hub), "limited-room", "alice")
+	require.Eventually(t, func() bool { return hub.GetRoomSize("limited-room") == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	for _, requestID := range []string{"req-6", "req-7"} {
+		require.NoError(t, client.SendMessage(map[string]any{"type": models.MsgTypeReveal, "requestId": requestID}))
+	}
+
+	reply := client.ExpectMessage(t, models.MsgTypeError, 2*time.Second)
+	assert.Equal(t, "req-7", reply.RequestID)
+	assert.Equal(t, models.ErrCodeRateLimited, errorPayload(t, reply)["code"])
+	assert.Equal(t, models.MsgTypeReveal, errorPayload(t, reply)["action"])
+}
+
+func TestCommandReply_BusyRoomKeepsTheConnection(t *testing.T) {
+	cfg := config.Default()
+	cfg.Limits.RoomCommandQueueSize = 1
+	cfg.Limits.RoomQueueFullTimeout = 50 * time.Millisecond
+	cfg.Limits.WriteTimeout = 200 * time.Millisecond
+	cfg.Limits.PingInterval = 100 * time.Millisecond
+	hub := services.NewHub(cfg)
+	release := make(chan struct{})
+	hub.SetMessageHandler(func(ctx context.Context, roomID, participantID string, message []byte) {
+		<-release // A room stuck on the database
+	})
+	defer close(release)
+	go hub.Run()
+
+	client := helpers.ConnectHubClient(t, helpers.StartHubServer(t, hub), "busy-room", "alice")
+	require.Eventually(t, func() bool { return hub.GetRoomSize("busy-room") == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// One command held by the worker, one queued, the rest dropped
+	for _, requestID := range []string{"req-8", "req-9", "req-10"} {
+		require.NoError(t, client.SendMessage(map[string]any{"type": models.MsgTypeVote, "requestId": requestID}))
+	}
+	reply := client.ExpectMessage(t, models.MsgTypeError, 2*time.Second)
+	assert.Equal(t, "req-10", reply.RequestID)
+	assert.Equal(t, models.ErrCodeRoomBusy, errorPayload(t, reply)["code"])
+	assert.Equal(t, models.MsgTypeVote, errorPayload(t, reply)["action"])
+
+	// The room stays stuck for several write timeouts: pings keep being answered
+	time.Sleep(5 * cfg.Limits.WriteTimeout)
+	client.ClearMessages()
+	require.NoError(t, client.SendMessage(map[string]any{"type": models.MsgTypeVote, "requestId": "req-11"}))
+	reply = client.ExpectMessage(t, models.MsgTypeError, 2*time.Second)
+	assert.Equal(t, "req-11", reply.RequestID)
+	assert.True(t, client.IsConnected())
+	assert.Equal(t, 1, hub.GetRoomSize("busy-room"))
+}
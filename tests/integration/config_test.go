@@ -0,0 +1,50 @@
+package integration_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// The hub enforces the limits of its configuration, not built-in ones
+func TestConfig_HubUsesConfiguredLimits(t *testing.T) {
+	cfg := config.Default()
+	cfg.Limits.MaxConnectionsPerRoom = 1
+	cfg.Limits.MaxTotalConnections = 2
+
+	hub := services.NewHub(cfg)
+	go hub.Run()
+	baseURL := helpers.StartHubServer(t, hub)
+
+	helpers.ConnectHubClient(t, baseURL, "room-1", "alice")
+	require.Eventually(t, func() bool { return hub.GetRoomSize("room-1") == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, hub.CanRegister("room-1"), services.ErrRoomFull)
+	assert.NoError(t, hub.CanRegister("room-2"))
+
+	helpers.ConnectHubClient(t, baseURL, "room-2", "bob")
+	require.Eventually(t, func() bool { return hub.GetTotalConnections() == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, hub.CanRegister("room-3"), services.ErrServerAtCapacity)
+}
+
+func TestConfig_Endpoint(t *testing.T) {
+	cfg := config.Default()
+	cfg.Limits.MaxMessagesPerSecond = 25
+	cfg.Broker.RedisURL = "redis://:hunter2@redis:6379/0"
+
+	code, body := probe(t, handlers.HandleConfig(services.NewHub(cfg)))
+	assert.Equal(t, http.StatusOK, code)
+
+	settings, ok := body["settings"].(map[string]any)
+	require.True(t, ok, "expected settings, got %v", body)
+	assert.Equal(t, "25", settings["MAX_MESSAGES_PER_SECOND"])
+	assert.Equal(t, "30s", settings["PING_INTERVAL"])
+	assert.Equal(t, "redis://:xxxxx@redis:6379/0", settings["REDIS_URL"])
+}
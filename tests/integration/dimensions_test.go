@@ -0,0 +1,116 @@
+package integration_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+func dimensionsConfig() *models.RoomConfig {
+	cfg := models.DefaultRoomConfig()
+	cfg.Dimensions = []models.EstimationDimension{
+		{Name: "Complexity", Values: []string{"1", "2", "3", "5", "8"}},
+		{Name: "Effort", Values: []string{"S", "M", "L"}},
+	}
+	return cfg
+}
+
+// newDimensionsRoom creates a multi-dimensional room served by the WebSocket handler.
+// Returns the hub, the room and its participants, the first one being the creator.
+func newDimensionsRoom(t *testing.T) (*services.Hub, string, []string) {
+	t.Helper()
+	server := helpers.NewTestServerWithData(t)
+	t.Cleanup(server.Cleanup)
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 2, dimensionsConfig())
+	hub := services.NewHub(config.Default())
+	go hub.Run()
+	rm := services.NewRoomManager(server.App)
+	handlers.NewWSHandler(hub, rm, services.NewACLService(rm))
+	return hub, roomID, participantIDs(t, server.App, roomID)
+}
+
+// runCommand runs a room command as a participant and returns the code of its error reply,
+// empty when it is acknowledged
+func runCommand(t *testing.T, hub *services.Hub, roomID, participantID, msgType string, payload any) string {
+	t.Helper()
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+	message, err := json.Marshal(models.IncomingMessage{Type: msgType, RequestID: "req", Payload: data})
+	require.NoError(t, err)
+
+	reply, err := hub.Command(context.Background(), roomID, participantID, message)
+	require.NoError(t, err)
+	if reply.Type != models.MsgTypeError {
+		return ""
+	}
+	errPayload, ok := reply.Payload.(models.ErrorPayload)
+	require.True(t, ok, "error payload: %#v", reply.Payload)
+	return errPayload.Code
+}
+
+func TestDimensions_VoteWithoutNumericValueIsRejected(t *testing.T) {
+	hub, roomID, participants := newDimensionsRoom(t)
+
+	code := runCommand(t, hub, roomID, participants[1], models.MsgTypeVote,
+		map[string]any{"dimensions": map[string]string{"Complexity": "?", "Effort": "M"}})
+	assert.Equal(t, handlers.ErrCodeInvalidPayload, code)
+
+	code = runCommand(t, hub, roomID, participants[1], models.MsgTypeVote,
+		map[string]any{"dimensions": map[string]string{"Complexity": "3", "Effort": "M"}})
+	assert.Empty(t, code)
+}
+
+func TestDimensions_ChangeOnlyBeforeTheFirstVote(t *testing.T) {
+	hub, roomID, participants := newDimensionsRoom(t)
+	creator, voter := participants[0], participants[1]
+
+	changed := dimensionsConfig()
+	changed.Dimensions[0].Values = []string{"1", "2", "3"}
+	require.Empty(t, runCommand(t, hub, roomID, creator, models.MsgTypeUpdateConfig,
+		models.UpdateConfigPayload{Config: *changed}), "no vote yet")
+
+	require.Empty(t, runCommand(t, hub, roomID, voter, models.MsgTypeVote,
+		map[string]any{"dimensions": map[string]string{"Complexity": "3", "Effort": "M"}}))
+
+	t.Run("rejects new dimensions", func(t *testing.T) {
+		changed := dimensionsConfig()
+		changed.Dimensions[1].Name = "Risk"
+		code := runCommand(t, hub, roomID, creator, models.MsgTypeUpdateConfig, models.UpdateConfigPayload{Config: *changed})
+		assert.Equal(t, handlers.ErrCodeInvalidState, code)
+	})
+
+	t.Run("rejects a new combine method", func(t *testing.T) {
+		changed := dimensionsConfig()
+		changed.Dimensions[0].Values = []string{"1", "2", "3"}
+		changed.CombineMethod = models.CombineMax
+		code := runCommand(t, hub, roomID, creator, models.MsgTypeUpdateConfig, models.UpdateConfigPayload{Config: *changed})
+		assert.Equal(t, handlers.ErrCodeInvalidState, code)
+	})
+
+	t.Run("allows other settings", func(t *testing.T) {
+		changed := dimensionsConfig()
+		changed.Dimensions[0].Values = []string{"1", "2", "3"}
+		changed.Permissions.AutoReveal = true
+		code := runCommand(t, hub, roomID, creator, models.MsgTypeUpdateConfig, models.UpdateConfigPayload{Config: *changed})
+		assert.Empty(t, code)
+	})
+
+	t.Run("allows new dimensions once the round is reset", func(t *testing.T) {
+		require.Empty(t, runCommand(t, hub, roomID, creator, models.MsgTypeReset, map[string]any{}))
+
+		changed := dimensionsConfig()
+		changed.Dimensions[1].Name = "Risk"
+		code := runCommand(t, hub, roomID, creator, models.MsgTypeUpdateConfig, models.UpdateConfigPayload{Config: *changed})
+		assert.Empty(t, code)
+	})
+}
@@ -0,0 +1,201 @@
+package integration_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// startReplayHub runs a hub keeping eventLogSize events per room, with bob connected
+// to "replay-room" so that the room and its event log outlive the other connections
+func startReplayHub(t *testing.T, eventLogSize int) (*services.Hub, string) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Limits.EventLogSize = eventLogSize
+	hub := services.NewHub(cfg)
+	go hub.Run()
+
+	baseURL := helpers.StartHubServer(t, hub)
+	helpers.ConnectHubClient(t, baseURL, "replay-room", "bob")
+	require.Eventually(t, func() bool { return hub.GetRoomSize("replay-room") == 1 }, 5*time.Second, 10*time.Millisecond)
+	return hub, baseURL
+}
+
+// broadcastNames broadcasts one name_updated per name to "replay-room"
+func broadcastNames(hub *services.Hub, names ...string) {
+	for _, name := range names {
+		hub.BroadcastToRoom(context.Background(), "replay-room", &models.WSMessage{
+			Type:    models.MsgTypeNameUpdated,
+			Payload: models.NameUpdatedPayload{ParticipantID: "bob", Name: name},
+		})
+	}
+}
+
+// reconnect connects alice to "replay-room" after the event at position from, the room
+// then having roomSize clients
+func reconnect(t *testing.T, hub *services.Hub, baseURL string, from services.EventPosition, roomSize int) *helpers.WSClient {
+	t.Helper()
+	client := helpers.NewWSClient()
+	url := fmt.Sprintf("%s/ws/replay-room?participant=alice&eventLog=%s&lastSeq=%d", baseURL, from.Log, from.Seq)
+	require.NoError(t, client.Connect(url))
+	t.Cleanup(client.Close)
+	require.Eventually(t, func() bool { return hub.GetRoomSize("replay-room") == roomSize }, 5*time.Second, 10*time.Millisecond)
+	return client
+}
+
+func TestEventReplay_BroadcastsAreSequenced(t *testing.T) {
+	hub, baseURL := startReplayHub(t, 10)
+	alice := helpers.ConnectHubClient(t, baseURL, "replay-room", "alice")
+	require.Eventually(t, func() bool { return hub.GetRoomSize("replay-room") == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	broadcastNames(hub, "one", "two", "three")
+
+	require.Eventually(t, func() bool { return len(alice.ReceivedMessages()) == 3 }, 2*time.Second, 10*time.Millisecond)
+	first := alice.ReceivedMessages()[0].Seq
+	for i, msg := range alice.ReceivedMessages() {
+		assert.Equal(t, first+uint64(i), msg.Seq)
+	}
+	assert.Equal(t, first+2, hub.EventLogPosition("replay-room").Seq)
+	assert.NotEmpty(t, hub.EventLogPosition("replay-room").Log)
+
+	// A room without broadcasts gets an empty log of its own
+	quiet := hub.EventLogPosition("quiet-room")
+	assert.Equal(t, uint64(0), quiet.Seq)
+	assert.NotEqual(t, hub.EventLogPosition("replay-room").Log, quiet.Log)
+}
+
+func TestEventReplay_ReconnectReceivesMissedEvents(t *testing.T) {
+	hub, baseURL := startReplayHub(t, 10)
+	broadcastNames(hub, "one", "two")
+	seen := hub.EventLogPosition("replay-room")
+
+	broadcastNames(hub, "three", "four")
+	alice := reconnect(t, hub, baseURL, seen, 2)
+	broadcastNames(hub, "five")
+
+	require.Eventually(t, func() bool { return len(alice.ReceivedMessages()) == 3 }, 2*time.Second, 10*time.Millisecond)
+	var names []string
+	for i, msg := range alice.ReceivedMessages() {
+		assert.Equal(t, seen.Seq+uint64(i)+1, msg.Seq)
+		names = append(names, msg.Payload.(map[string]any)["name"].(string))
+	}
+	assert.Equal(t, []string{"three", "four", "five"}, names, "missed events come first, in order")
+}
+
+func TestEventReplay_NothingReplayedWithoutTheEvents(t *testing.T) {
+	hub, baseURL := startReplayHub(t, 2)
+	broadcastNames(hub, "one")
+	seen := hub.EventLogPosition("replay-room")
+
+	tests := []struct {
+		name string
+		from services.EventPosition
+	}{
+		{"gap larger than the log", seen},
+		{"other event log", services.EventPosition{Log: "another-log", Seq: seen.Seq + 3}},
+		{"ahead of the log", services.EventPosition{Log: seen.Log, Seq: seen.Seq + 10}},
+	}
+	broadcastNames(hub, "two", "three", "four")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alice := reconnect(t, hub, baseURL, tt.from, 2)
+			assert.Nil(t, alice.WaitForMessage(200*time.Millisecond), "the client needs a snapshot instead")
+			alice.Close()
+			require.Eventually(t, func() bool { return hub.GetRoomSize("replay-room") == 1 }, 5*time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestEventReplay_SingleClientReconnects(t *testing.T) {
+	hub := services.NewHub(config.Default())
+	go hub.Run()
+	baseURL := helpers.StartHubServer(t, hub)
+
+	alice := helpers.ConnectHubClient(t, baseURL, "replay-room", "alice")
+	require.Eventually(t, func() bool { return hub.GetRoomSize("replay-room") == 1 }, 5*time.Second, 10*time.Millisecond)
+	broadcastNames(hub, "one")
+	seen := hub.EventLogPosition("replay-room")
+
+	// The room has no client left while events keep coming, e.g. from the REST API
+	alice.Close()
+	require.Eventually(t, func() bool { return hub.GetRoomSize("replay-room") == 0 }, 5*time.Second, 10*time.Millisecond)
+	broadcastNames(hub, "two", "three")
+
+	alice = reconnect(t, hub, baseURL, seen, 1)
+	require.Eventually(t, func() bool { return len(alice.ReceivedMessages()) == 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, seen.Seq+2, alice.ReceivedMessages()[1].Seq)
+}
+
+func TestEventReplay_LogExpiresAfterRetention(t *testing.T) {
+	cfg := config.Default()
+	cfg.Limits.EventLogRetention = 50 * time.Millisecond
+	hub := services.NewHub(cfg)
+
+	broadcastNames(hub, "one")
+	before := hub.EventLogPosition("replay-room")
+	time.Sleep(100 * time.Millisecond)
+
+	assert.NotEqual(t, before.Log, hub.EventLogPosition("replay-room").Log)
+}
+
+// A client resumes on another instance from the event log shared through the broker
+func TestEventReplay_ResumeOnAnotherInstance(t *testing.T) {
+	redis := miniredis.RunT(t)
+	redisBroker := func() services.Broker {
+		broker, err := services.NewRedisBroker("redis://" + redis.Addr())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = broker.Close() })
+		return broker
+	}
+	memory := services.NewMemoryBroker()
+
+	for _, bc := range []struct {
+		name             string
+		brokerA, brokerB services.Broker
+	}{
+		{"memory", memory, memory},
+		{"redis", redisBroker(), redisBroker()},
+	} {
+		t.Run(bc.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Limits.EventLogSize = 3
+			hubA, hubB := services.NewHub(cfg), services.NewHub(cfg)
+			hubA.SetBroker(bc.brokerA)
+			hubB.SetBroker(bc.brokerB)
+			go hubA.Run()
+			go hubB.Run()
+			urlB := helpers.StartHubServer(t, hubB)
+
+			broadcastNames(hubA, "one")
+			seen := hubA.EventLogPosition("replay-room")
+			broadcastNames(hubA, "two", "three")
+			assert.Equal(t, seen.Log, hubB.EventLogPosition("replay-room").Log, "one log for both instances")
+
+			alice := reconnect(t, hubB, urlB, seen, 1)
+			require.Eventually(t, func() bool { return len(alice.ReceivedMessages()) == 2 }, 2*time.Second, 10*time.Millisecond)
+			var names []string
+			for _, msg := range alice.ReceivedMessages() {
+				names = append(names, msg.Payload.(map[string]any)["name"].(string))
+			}
+			assert.Equal(t, []string{"two", "three"}, names)
+
+			// Events no longer kept can't be replayed
+			alice.Close()
+			require.Eventually(t, func() bool { return hubB.GetRoomSize("replay-room") == 0 }, 5*time.Second, 10*time.Millisecond)
+			broadcastNames(hubA, "four", "five")
+			alice = reconnect(t, hubB, urlB, seen, 1)
+			assert.Nil(t, alice.WaitForMessage(200*time.Millisecond), "the client needs a snapshot instead")
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// probe calls a monitoring handler and decodes its JSON body
+func probe(t *testing.T, handler func(*core.RequestEvent) error) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/monitoring/health", nil)
+	require.NoError(t, handler(&core.RequestEvent{Event: router.Event{Response: rec, Request: req}}))
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return rec.Code, body
+}
+
+func TestHealthProbes(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	hub := services.NewHub(config.Default())
+
+	t.Run("liveness", func(t *testing.T) {
+		code, body := probe(t, handlers.HandleLiveness(hub))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "alive", body["status"])
+	})
+
+	t.Run("not ready without the hub loop", func(t *testing.T) {
+		code, body := probe(t, handlers.HandleReadiness(server.App, hub))
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "not_ready", body["status"])
+		checks := body["checks"].(map[string]any)
+		assert.Equal(t, "ok", checks["database"])
+		assert.Equal(t, services.ErrHubUnresponsive.Error(), checks["hub"])
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		go hub.Run()
+		code, body := probe(t, handlers.HandleReadiness(server.App, hub))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ready", body["status"])
+		assert.Equal(t, map[string]any{"database": "ok", "hub": "ok", "capacity": "ok"}, body["checks"])
+	})
+
+	t.Run("not ready at capacity", func(t *testing.T) {
+		thresholds := services.DefaultHealthThresholds()
+		thresholds.MaxRooms = 1
+		hub.Metrics().SetHealthThresholds(thresholds)
+		hub.Metrics().IncrementRooms()
+		hub.Metrics().IncrementRooms()
+
+		code, body := probe(t, handlers.HandleReadiness(server.App, hub))
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "critical", body["checks"].(map[string]any)["capacity"])
+	})
+}
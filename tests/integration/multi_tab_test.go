@@ -0,0 +1,142 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// noStreamMessage fails if a message of type msgType arrives on the stream within wait
+func noStreamMessage(t *testing.T, events <-chan sseEvent, msgType string, wait time.Duration) {
+	t.Helper()
+	timeout := time.After(wait)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			var msg models.WSMessage
+			require.NoError(t, json.Unmarshal([]byte(event.data), &msg))
+			assert.NotEqual(t, msgType, msg.Type, "unexpected %s", msgType)
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// participantConnected reads the participant's connection status from the database
+func participantConnected(t *testing.T, room *sseRoom, participantID string) bool {
+	t.Helper()
+	record, err := services.NewRoomManager(room.app).GetParticipant(participantID)
+	require.NoError(t, err)
+	return record.GetBool("connected")
+}
+
+func TestMultiTab_ParticipantStaysConnectedWhileATabIsOpen(t *testing.T) {
+	room := startSSERoom(t)
+	voter := participantIDs(t, room.app, room.roomID)[1]
+	observer := room.open(t, "session-1")
+	expectStreamMessage(t, observer.events, models.MsgTypeParticipantJoined) // The observer itself
+
+	first := room.open(t, "session-2")
+	joined := expectStreamMessage(t, observer.events, models.MsgTypeParticipantJoined)
+	assert.Equal(t, voter, joined.Payload.(map[string]any)["participant"].(map[string]any)["ID"])
+	second := room.open(t, "session-2")
+	require.Eventually(t, func() bool { return room.hub.GetRoomSize(room.roomID) == 3 }, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, room.hub.GetClients(room.roomID, voter), 2)
+	noStreamMessage(t, observer.events, models.MsgTypeParticipantJoined, 200*time.Millisecond)
+
+	first.body.Close()
+	require.Eventually(t, func() bool { return room.hub.GetRoomSize(room.roomID) == 2 }, 5*time.Second, 10*time.Millisecond)
+	noStreamMessage(t, observer.events, models.MsgTypeParticipantLeft, 200*time.Millisecond)
+	assert.True(t, participantConnected(t, room, voter), "the second tab is still open")
+
+	second.body.Close()
+	left := expectStreamMessage(t, observer.events, models.MsgTypeParticipantLeft)
+	assert.Equal(t, map[string]any{"participantId": voter}, left.Payload)
+	assert.False(t, participantConnected(t, room, voter))
+}
+
+func TestMultiTab_ErrorRepliesReachEveryTab(t *testing.T) {
+	room := startSSERoom(t)
+	first := room.open(t, "session-2")
+	second := room.open(t, "session-2")
+	other := room.open(t, "session-1")
+
+	status := room.post(t, first.streamID, "session-2", "application/json",
+		`{"type":"update_room_name","requestId":"tab1-1","payload":{"name":"Renamed"}}`)
+	require.Equal(t, http.StatusAccepted, status)
+
+	for _, tab := range []*sseConn{first, second} {
+		reply := expectStreamMessage(t, tab.events, models.MsgTypeError)
+		assert.Equal(t, "tab1-1", reply.RequestID)
+		assert.Equal(t, handlers.ErrCodeForbidden, errorPayload(t, reply)["code"])
+	}
+	noStreamMessage(t, other.events, models.MsgTypeError, 200*time.Millisecond)
+
+	status = room.post(t, first.streamID, "session-2", "application/json",
+		`{"type":"vote","requestId":"tab1-2","payload":{"value":"5"}}`)
+	require.Equal(t, http.StatusAccepted, status)
+
+	ack := expectStreamMessage(t, first.events, models.MsgTypeAck)
+	assert.Equal(t, "tab1-2", ack.RequestID)
+	noStreamMessage(t, second.events, models.MsgTypeAck, 200*time.Millisecond)
+}
+
+func testParticipantConnectedAcrossInstances(t *testing.T, brokerA, brokerB services.Broker) {
+	server := helpers.NewTestServerWithData(t)
+	t.Cleanup(server.Cleanup)
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 2, nil)
+	voter := participantIDs(t, server.App, roomID)[1]
+	hubA, hubB := startHubs(t, brokerA, brokerB)
+	instanceA := serveSSERoom(t, server.App, roomID, hubA)
+	instanceB := serveSSERoom(t, server.App, roomID, hubB)
+
+	observer := instanceA.open(t, "session-1")
+	expectStreamMessage(t, observer.events, models.MsgTypeParticipantJoined) // The observer itself
+	onA := instanceA.open(t, "session-2")
+	expectStreamMessage(t, observer.events, models.MsgTypeParticipantJoined)
+	onB := instanceB.open(t, "session-2")
+	require.Eventually(t, func() bool { return hubB.GetRoomSize(roomID) == 1 }, 5*time.Second, 10*time.Millisecond)
+	noStreamMessage(t, observer.events, models.MsgTypeParticipantJoined, 200*time.Millisecond)
+
+	// The tab on the other instance keeps the participant connected
+	onA.body.Close()
+	require.Eventually(t, func() bool { return hubA.GetRoomSize(roomID) == 1 }, 5*time.Second, 10*time.Millisecond)
+	noStreamMessage(t, observer.events, models.MsgTypeParticipantLeft, 200*time.Millisecond)
+	assert.True(t, participantConnected(t, instanceA, voter), "a tab is still open on the other instance")
+
+	onB.body.Close()
+	left := expectStreamMessage(t, observer.events, models.MsgTypeParticipantLeft)
+	assert.Equal(t, map[string]any{"participantId": voter}, left.Payload)
+	assert.False(t, participantConnected(t, instanceA, voter))
+}
+
+func TestMultiTab_ParticipantConnectedAcrossInstancesMemoryBroker(t *testing.T) {
+	broker := services.NewMemoryBroker()
+	testParticipantConnectedAcrossInstances(t, broker, broker)
+}
+
+func TestMultiTab_ParticipantConnectedAcrossInstancesRedisBroker(t *testing.T) {
+	redis := miniredis.RunT(t)
+
+	brokerA, err := services.NewRedisBroker("redis://" + redis.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = brokerA.Close() })
+	brokerB, err := services.NewRedisBroker("redis://" + redis.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = brokerB.Close() })
+
+	testParticipantConnectedAcrossInstances(t, brokerA, brokerB)
+}
@@ -0,0 +1,61 @@
+package integration_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// A vote over WebSocket is timed end to end: handling, database operation and broadcast
+func TestPrometheusMetrics_VoteIsTimed(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 1, nil)
+	voter := participantIDs(t, server.App, roomID)[0]
+
+	hub := services.NewHub(config.Default())
+	go hub.Run()
+	rm := services.NewRoomManager(server.App)
+	rm.SetMetrics(hub.Metrics())
+	handlers.NewWSHandler(hub, rm, services.NewACLService(rm))
+
+	client := helpers.ConnectHubClient(t, helpers.StartHubServer(t, hub), roomID, voter)
+	require.Eventually(t, func() bool { return hub.GetRoomSize(roomID) == 1 }, 5*time.Second, 10*time.Millisecond)
+	client.SendVote(t, "5")
+	client.ExpectMessage(t, models.MsgTypeVoteCast, 2*time.Second)
+
+	scrape := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/monitoring/metrics/prometheus", nil)
+		_ = handlers.HandlePrometheusMetrics(hub)(&core.RequestEvent{Event: router.Event{Response: rec, Request: req}})
+		return rec
+	}
+
+	// The handler returns after the broadcast the client waited for
+	var body string
+	require.Eventually(t, func() bool {
+		body = scrape().Body.String()
+		return strings.Contains(body, `planning_poker_message_handling_seconds_count{type="vote"} 1`)
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Contains(t, body, "planning_poker_messages_received_total 1\n")
+	assert.Contains(t, body, `planning_poker_db_operation_seconds_count{type="vote"} 1`)
+	assert.Contains(t, body, `planning_poker_broadcast_fanout_seconds_count{type="vote_cast"} 1`)
+
+	rec := scrape()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+}
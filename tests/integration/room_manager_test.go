@@ -325,6 +325,105 @@ func TestRoomManager_CastVote(t *testing.T) {
 	})
 }
 
+func TestRoomManager_CastVoteWithConfidence(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	rm := services.NewRoomManager(server.App)
+	room, _ := rm.CreateRoom("Test", "fibonacci", nil, nil)
+	participant, _ := rm.AddParticipant(room.Id, "Alice", models.RoleVoter, "s1")
+
+	t.Run("persists confidence level", func(t *testing.T) {
+		err := rm.CastVoteWithConfidence(room.Id, participant.Id, "5", models.ConfidenceHigh)
+
+		assert.NoError(t, err)
+
+		votes, _ := rm.GetRoomVotes(room.Id)
+		assert.Len(t, votes, 1)
+		assert.Equal(t, models.ConfidenceHigh, votes[0].GetInt("confidence"))
+	})
+
+	t.Run("vote without confidence clears previous level", func(t *testing.T) {
+		_ = rm.CastVoteWithConfidence(room.Id, participant.Id, "5", models.ConfidenceLow)
+
+		err := rm.CastVote(room.Id, participant.Id, "8")
+
+		assert.NoError(t, err)
+
+		votes, _ := rm.GetRoomVotes(room.Id)
+		assert.Len(t, votes, 1)
+		assert.Equal(t, "8", votes[0].GetString("value"))
+		assert.Equal(t, models.ConfidenceNone, votes[0].GetInt("confidence"))
+	})
+}
+
+func TestRoomManager_CastDimensionalVote(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	rm := services.NewRoomManager(server.App)
+	room, _ := rm.CreateRoom("Test", "fibonacci", nil, nil)
+	participant, _ := rm.AddParticipant(room.Id, "Alice", models.RoleVoter, "s1")
+
+	err := rm.CastDimensionalVote(room.Id, participant.Id, "4", map[string]string{"Complexity": "3", "Effort": "5"}, models.ConfidenceNone)
+	assert.NoError(t, err)
+
+	votes, _ := rm.GetRoomVotes(room.Id)
+	assert.Len(t, votes, 1)
+	assert.Equal(t, "4", votes[0].GetString("value"))
+	assert.Equal(t, map[string]string{"Complexity": "3", "Effort": "5"}, rm.GetVoteDimensionValues(votes[0]))
+
+	// A single-value vote clears the dimension values
+	_ = rm.CastVote(room.Id, participant.Id, "8")
+	votes, _ = rm.GetRoomVotes(room.Id)
+	assert.Nil(t, rm.GetVoteDimensionValues(votes[0]))
+}
+
+func TestRoomManager_RetractVote(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	rm := services.NewRoomManager(server.App)
+	room, _ := rm.CreateRoom("Test", "fibonacci", nil, nil)
+	p1, _ := rm.AddParticipant(room.Id, "Alice", models.RoleVoter, "s1")
+	p2, _ := rm.AddParticipant(room.Id, "Bob", models.RoleVoter, "s2")
+
+	t.Run("removes vote while voting", func(t *testing.T) {
+		_ = rm.CastVote(room.Id, p1.Id, "5")
+		_ = rm.CastVote(room.Id, p2.Id, "8")
+
+		allVoted, _ := rm.HaveAllVotersVoted(room.Id)
+		assert.True(t, allVoted)
+
+		err := rm.RetractVote(room.Id, p1.Id)
+		assert.NoError(t, err)
+
+		votes, _ := rm.GetRoomVotes(room.Id)
+		assert.Len(t, votes, 1)
+		assert.Equal(t, p2.Id, votes[0].GetString("participant_id"))
+
+		// Auto-reveal condition no longer holds
+		allVoted, _ = rm.HaveAllVotersVoted(room.Id)
+		assert.False(t, allVoted)
+	})
+
+	t.Run("fails without a vote", func(t *testing.T) {
+		err := rm.RetractVote(room.Id, p1.Id)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("fails after reveal", func(t *testing.T) {
+		_ = rm.RevealVotes(room.Id)
+
+		err := rm.RetractVote(room.Id, p2.Id)
+
+		assert.Error(t, err)
+		votes, _ := rm.GetRoomVotes(room.Id)
+		assert.Len(t, votes, 1)
+	})
+}
+
 func TestRoomManager_GetRoomVotes(t *testing.T) {
 	server := helpers.NewTestServerWithData(t)
 	defer server.Cleanup()
@@ -408,6 +507,44 @@ func TestRoomManager_GetRoomState(t *testing.T) {
 	})
 }
 
+func TestRoomManager_GetVoteTally(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	rm := services.NewRoomManager(server.App)
+	room, _ := rm.CreateRoom("Test", "fibonacci", nil, nil)
+	p1, _ := rm.AddParticipant(room.Id, "Alice", models.RoleVoter, "s1")
+	p2, _ := rm.AddParticipant(room.Id, "Bob", models.RoleVoter, "s2")
+	p3, _ := rm.AddParticipant(room.Id, "Carol", models.RoleVoter, "s3")
+	_, _ = rm.AddParticipant(room.Id, "Dave", models.RoleSpectator, "s4")
+
+	_ = rm.CastVote(room.Id, p1.Id, "5")
+	_ = rm.CastVote(room.Id, p2.Id, "8")
+	_ = rm.UpdateParticipantConnection(p3.Id, false)
+
+	t.Run("counts voters and connected voters", func(t *testing.T) {
+		tally, err := rm.GetVoteTally(room.Id)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.VoteTally{Voters: 3, ConnectedVoters: 2, Votes: 2, ConnectedVotes: 2}, tally)
+	})
+
+	t.Run("evaluates reveal rules", func(t *testing.T) {
+		met, err := rm.IsRevealRuleMet(room.Id, models.RevealRule{Mode: models.RevealModeAll})
+		assert.NoError(t, err)
+		assert.False(t, met)
+
+		met, _ = rm.IsRevealRuleMet(room.Id, models.RevealRule{Mode: models.RevealModeAllConnected})
+		assert.True(t, met)
+
+		met, _ = rm.IsRevealRuleMet(room.Id, models.RevealRule{Mode: models.RevealModeQuorum, Percentage: 60})
+		assert.True(t, met)
+
+		met, _ = rm.IsRevealRuleMet(room.Id, models.RevealRule{Mode: models.RevealModeMinimum, MinimumVotes: 3})
+		assert.False(t, met)
+	})
+}
+
 func TestRoomManager_ResetRound(t *testing.T) {
 	server := helpers.NewTestServerWithData(t)
 	defer server.Cleanup()
@@ -493,6 +630,54 @@ func TestRoomManager_CreateNextRound(t *testing.T) {
 	})
 }
 
+func TestRoomManager_UpdateParticipantWeight(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	rm := services.NewRoomManager(server.App)
+	room, _ := rm.CreateRoom("Test", "fibonacci", nil, nil)
+	p1, _ := rm.AddParticipant(room.Id, "Alice", models.RoleVoter, "s1")
+	p2, _ := rm.AddParticipant(room.Id, "Bob", models.RoleVoter, "s2")
+
+	t.Run("new participants have default weight", func(t *testing.T) {
+		weights, err := rm.GetParticipantWeights(room.Id)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.DefaultParticipantWeight, weights[p1.Id])
+		assert.Equal(t, models.DefaultParticipantWeight, weights[p2.Id])
+	})
+
+	t.Run("updates weight", func(t *testing.T) {
+		err := rm.UpdateParticipantWeight(p1.Id, 3)
+
+		assert.NoError(t, err)
+
+		updated, _ := rm.GetParticipant(p1.Id)
+		assert.Equal(t, 3.0, services.ParticipantWeight(updated))
+	})
+
+	t.Run("rejects invalid weight", func(t *testing.T) {
+		assert.Error(t, rm.UpdateParticipantWeight(p1.Id, 0))
+		assert.Error(t, rm.UpdateParticipantWeight(p1.Id, -1))
+		assert.Error(t, rm.UpdateParticipantWeight(p1.Id, 100))
+	})
+
+	t.Run("weights average of completed round", func(t *testing.T) {
+		_ = rm.CastVote(room.Id, p1.Id, "8")
+		_ = rm.CastVote(room.Id, p2.Id, "2")
+		_ = rm.RevealVotes(room.Id)
+		oldRound, _ := rm.GetCurrentRoundRecord(room.Id)
+
+		_, err := rm.CreateNextRound(room.Id)
+		assert.NoError(t, err)
+
+		// (8*3 + 2*1) / 4 = 6.5
+		completed, _ := server.App.FindRecordById("rounds", oldRound.Id)
+		assert.InDelta(t, 6.5, completed.GetFloat("average_score"), 0.001)
+		assert.False(t, completed.GetBool("consensus"))
+	})
+}
+
 func TestRoomManager_GetCurrentRound(t *testing.T) {
 	server := helpers.NewTestServerWithData(t)
 	defer server.Cleanup()
@@ -0,0 +1,52 @@
+package integration_test
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// BenchmarkVoteCommand compares votes served from the database with votes served by the
+// in-memory room state. Run with: go test ./tests/integration -run '^$' -bench VoteCommand
+// queries/op counts every SQL statement, including the background activity writes of the room state.
+// Single instance only: with REDIS_URL set the room state is disabled and every vote costs what
+// the database case does.
+func BenchmarkVoteCommand(b *testing.B) {
+	log.SetOutput(io.Discard) // Broadcast logs would dominate the output
+	defer log.SetOutput(os.Stderr)
+
+	for _, bc := range []struct {
+		name      string
+		withState bool
+	}{
+		{"database", false},
+		{"room_state", true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			server := helpers.NewTestServerWithData(b)
+			defer server.Cleanup()
+
+			api, store, roomID := newVoteAPI(b, server.App, bc.withState)
+			castAPIVotes(b, server.App, api, roomID, 1) // Warm up: loads the room into memory
+			if store != nil {
+				go store.Run()
+				defer store.Close()
+			}
+
+			queries := countQueries(server.App)
+			b.ResetTimer()
+			castAPIVotes(b, server.App, api, roomID, b.N)
+			if store != nil {
+				require.NoError(b, store.Flush())
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(queries.Load())/float64(b.N), "queries/op")
+		})
+	}
+}
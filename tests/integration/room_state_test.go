@@ -0,0 +1,386 @@
+package integration_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// newStateRoomManager returns a room manager serving rooms from a state store.
+// The store is not running: tests flush it explicitly.
+func newStateRoomManager(app core.App) (*services.RoomManager, *services.RoomStateStore) {
+	rm := services.NewRoomManager(app)
+	store := services.NewRoomStateStore(app)
+	rm.SetStateStore(store)
+	return rm, store
+}
+
+// participantIDs returns the IDs of a room's participants in join order
+func participantIDs(t testing.TB, app core.App, roomID string) []string {
+	t.Helper()
+	records, err := app.FindRecordsByFilter("participants", "room_id = {:roomId}", "joined_at", 0, 0,
+		map[string]any{"roomId": roomID})
+	require.NoError(t, err)
+
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i] = record.Id
+	}
+	return ids
+}
+
+// storedVotes reads the votes of a round straight from the database
+func storedVotes(t *testing.T, app core.App, roundID string) []*core.Record {
+	t.Helper()
+	votes, err := app.FindRecordsByFilter("votes", "round_id = {:roundId}", "", 0, 0,
+		map[string]any{"roundId": roundID})
+	require.NoError(t, err)
+	return votes
+}
+
+// countQueries counts the SQL statements the app runs from now on
+func countQueries(app core.App) *atomic.Int64 {
+	var count atomic.Int64
+	for _, builder := range []dbx.Builder{app.ConcurrentDB(), app.NonconcurrentDB()} {
+		db := builder.(*dbx.DB)
+		db.QueryLogFunc = func(ctx context.Context, t time.Duration, sql string, rows *sql.Rows, err error) {
+			count.Add(1)
+		}
+		db.ExecLogFunc = func(ctx context.Context, t time.Duration, sql string, result sql.Result, err error) {
+			count.Add(1)
+		}
+	}
+	return &count
+}
+
+func TestRoomState_VotesAreWrittenThrough(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 2, nil)
+	voters := participantIDs(t, server.App, roomID)
+	rm, _ := newStateRoomManager(server.App)
+
+	round, err := rm.GetCurrentRoundRecord(roomID)
+	require.NoError(t, err)
+
+	// Stored before the vote is acknowledged, without a flush
+	require.NoError(t, rm.CastVote(roomID, voters[0], "5"))
+	stored := storedVotes(t, server.App, round.Id)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "5", stored[0].GetString("value"))
+
+	// A changed vote updates the same row
+	require.NoError(t, rm.CastVote(roomID, voters[0], "8"))
+	stored = storedVotes(t, server.App, round.Id)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "8", stored[0].GetString("value"))
+
+	votes, err := rm.GetRoomVotes(roomID)
+	require.NoError(t, err)
+	require.Len(t, votes, 1)
+	assert.Equal(t, stored[0].Id, votes[0].Id)
+}
+
+func TestRoomState_RefusedVoteIsNotServed(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 1, nil)
+	voter := participantIDs(t, server.App, roomID)[0]
+	rm, _ := newStateRoomManager(server.App)
+	require.NoError(t, rm.CastVote(roomID, voter, "3"))
+
+	var refuse atomic.Bool
+	refuse.Store(true)
+	server.App.OnRecordUpdate("votes").BindFunc(func(e *core.RecordEvent) error {
+		if refuse.Load() {
+			return errors.New("disk full")
+		}
+		return e.Next()
+	})
+
+	assert.Error(t, rm.CastVote(roomID, voter, "8"))
+	votes, err := rm.GetRoomVotes(roomID)
+	require.NoError(t, err)
+	require.Len(t, votes, 1)
+	assert.Equal(t, "3", votes[0].GetString("value"), "the failed change is not served")
+
+	refuse.Store(false)
+	require.NoError(t, rm.CastVote(roomID, voter, "8"))
+}
+
+func TestRoomState_FailedPresenceWritesStayQueued(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 1, nil)
+	voter := participantIDs(t, server.App, roomID)[0]
+	rm, store := newStateRoomManager(server.App)
+	_, err := rm.GetRoom(roomID)
+	require.NoError(t, err)
+
+	var refuse atomic.Bool
+	refuse.Store(true)
+	server.App.OnRecordUpdate("participants").BindFunc(func(e *core.RecordEvent) error {
+		if refuse.Load() {
+			return errors.New("database is locked")
+		}
+		return e.Next()
+	})
+
+	require.NoError(t, rm.UpdateParticipantConnection(voter, true))
+	for i := 0; i < 2*config.RoomStateMaxWriteAttempts; i++ {
+		assert.Error(t, store.Flush())
+	}
+
+	refuse.Store(false)
+	require.NoError(t, store.Flush())
+	participant, err := server.App.FindRecordById("participants", voter)
+	require.NoError(t, err)
+	assert.True(t, participant.GetBool("connected"))
+}
+
+func TestRoomState_RetractVote(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 1, nil)
+	voter := participantIDs(t, server.App, roomID)[0]
+	rm, _ := newStateRoomManager(server.App)
+
+	round, err := rm.GetCurrentRoundRecord(roomID)
+	require.NoError(t, err)
+
+	t.Run("with a vote", func(t *testing.T) {
+		require.NoError(t, rm.CastVote(roomID, voter, "3"))
+		require.Len(t, storedVotes(t, server.App, round.Id), 1)
+
+		require.NoError(t, rm.RetractVote(roomID, voter))
+		assert.Empty(t, storedVotes(t, server.App, round.Id))
+
+		// The participant can vote again under the same unique key
+		require.NoError(t, rm.CastVote(roomID, voter, "5"))
+		assert.Len(t, storedVotes(t, server.App, round.Id), 1)
+	})
+
+	t.Run("without a vote", func(t *testing.T) {
+		require.NoError(t, rm.RetractVote(roomID, voter))
+		assert.Error(t, rm.RetractVote(roomID, voter))
+	})
+}
+
+func TestRoomState_RoundTransitionsSeePendingVotes(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 2, nil)
+	voters := participantIDs(t, server.App, roomID)
+	rm, _ := newStateRoomManager(server.App)
+
+	firstRound, err := rm.GetCurrentRoundRecord(roomID)
+	require.NoError(t, err)
+
+	require.NoError(t, rm.CastVote(roomID, voters[0], "5"))
+	require.NoError(t, rm.CastVote(roomID, voters[1], "5"))
+
+	// Reveal persists the pending votes before computing consensus
+	require.NoError(t, rm.RevealVotes(roomID))
+	state, err := rm.GetRoomState(roomID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StateRevealed, state)
+
+	room, err := server.App.FindRecordById("rooms", roomID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, room.GetInt("consecutive_consensus_rounds"))
+
+	newRound, err := rm.CreateNextRound(roomID)
+	require.NoError(t, err)
+
+	completed, err := server.App.FindRecordById("rounds", firstRound.Id)
+	require.NoError(t, err)
+	assert.Equal(t, string(models.RoundStateCompleted), completed.GetString("state"))
+	assert.Equal(t, 2, completed.GetInt("total_votes"))
+	assert.InDelta(t, 5.0, completed.GetFloat("average_score"), 0.001)
+
+	// The reloaded state follows the new round
+	current, err := rm.GetCurrentRoundRecord(roomID)
+	require.NoError(t, err)
+	assert.Equal(t, newRound.Id, current.Id)
+	votes, err := rm.GetRoomVotes(roomID)
+	require.NoError(t, err)
+	assert.Empty(t, votes)
+}
+
+func TestRoomState_SyncedUpdatesAreVisible(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 1, nil)
+	creator := participantIDs(t, server.App, roomID)[0]
+	rm, _ := newStateRoomManager(server.App)
+	acl := services.NewACLService(rm)
+
+	// Load the room into memory
+	_, err := rm.GetRoomParticipants(roomID)
+	require.NoError(t, err)
+
+	require.NoError(t, rm.UpdateParticipantName(creator, "Renamed"))
+	participant, err := rm.GetParticipant(creator)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", participant.GetString("name"))
+
+	require.NoError(t, rm.UpdateRoomName(roomID, "New Name"))
+	room, err := rm.GetRoom(roomID)
+	require.NoError(t, err)
+	assert.Equal(t, "New Name", room.GetString("name"))
+
+	config := models.DefaultRoomConfig()
+	config.Permissions.AllowAllReveal = false
+	require.NoError(t, acl.UpdateRoomConfig(context.Background(), roomID, creator, config))
+	loaded, err := acl.GetRoomConfig(roomID)
+	require.NoError(t, err)
+	assert.False(t, loaded.Permissions.AllowAllReveal)
+
+	joined, err := rm.AddParticipant(roomID, "Latecomer", models.RoleVoter, "late-session")
+	require.NoError(t, err)
+	participants, err := rm.GetRoomParticipants(roomID)
+	require.NoError(t, err)
+	assert.Len(t, participants, 2)
+	bySession, err := rm.GetParticipantBySession(roomID, "late-session")
+	require.NoError(t, err)
+	assert.Equal(t, joined.Id, bySession.Id)
+}
+
+func TestRoomState_ActivityLeavesOutsideEditsAlone(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 1, nil)
+	voter := participantIDs(t, server.App, roomID)[0]
+	rm, store := newStateRoomManager(server.App)
+
+	// Load the room into memory, then edit it behind the store's back
+	_, err := rm.GetRoom(roomID)
+	require.NoError(t, err)
+	edited, err := server.App.FindRecordById("rooms", roomID)
+	require.NoError(t, err)
+	before := edited.GetDateTime("last_activity")
+	edited.Set("name", "Edited")
+	edited.Set("config", `{"permissions":{"allowAllReveal":false}}`)
+	require.NoError(t, server.App.Save(edited))
+
+	require.NoError(t, rm.CastVote(roomID, voter, "5"))
+	require.NoError(t, rm.UpdateRoomActivity(roomID))
+	require.NoError(t, store.Flush())
+
+	stored, err := server.App.FindRecordById("rooms", roomID)
+	require.NoError(t, err)
+	assert.Equal(t, "Edited", stored.GetString("name"))
+	assert.Equal(t, edited.GetString("config"), stored.GetString("config"))
+	assert.True(t, stored.GetDateTime("last_activity").After(before), "activity is still written")
+}
+
+func TestRoomState_ClosePersistsPendingWrites(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 1, nil)
+	voter := participantIDs(t, server.App, roomID)[0]
+	rm, store := newStateRoomManager(server.App)
+	go store.Run()
+
+	round, err := rm.GetCurrentRoundRecord(roomID)
+	require.NoError(t, err)
+
+	require.NoError(t, rm.CastVote(roomID, voter, "13"))
+	require.NoError(t, rm.UpdateParticipantConnection(voter, false))
+	require.NoError(t, store.Close())
+
+	stored := storedVotes(t, server.App, round.Id)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "13", stored[0].GetString("value"))
+
+	participant, err := server.App.FindRecordById("participants", voter)
+	require.NoError(t, err)
+	assert.False(t, participant.GetBool("connected"))
+}
+
+func TestRoomState_UnknownRoom(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	rm, _ := newStateRoomManager(server.App)
+
+	_, err := rm.GetRoom("doesnotexist123")
+	assert.Error(t, err)
+	assert.Error(t, rm.CastVote("doesnotexist123", "someone", "3"))
+}
+
+// castAPIVotes votes through the REST command path, which runs the same checks as WebSocket votes
+func castAPIVotes(t testing.TB, app core.App, api *handlers.APIHandlers, roomID string, votes int) {
+	t.Helper()
+	values := []string{"1", "2", "3", "5", "8"}
+	for i := 0; i < votes; i++ {
+		body := fmt.Sprintf(`{"value": %q}`, values[i%len(values)])
+		rec := apiCall(t, app, api.CastVote, http.MethodPost, roomID, "session-1", body)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+}
+
+// newVoteAPI returns REST handlers for a room with five voters, optionally backed by a state store
+func newVoteAPI(t testing.TB, app core.App, withState bool) (*handlers.APIHandlers, *services.RoomStateStore, string) {
+	t.Helper()
+	roomID := helpers.CreateTestRoomWithParticipants(t, app, 5, nil)
+
+	rm := services.NewRoomManager(app)
+	var store *services.RoomStateStore
+	if withState {
+		rm, store = newStateRoomManager(app)
+	}
+	acl := services.NewACLService(rm)
+	cfg := config.Default()
+	cfg.Limits.MaxMessagesPerSecond = math.MaxInt // One participant votes as fast as it can
+	hub := services.NewHub(cfg)
+	api := handlers.NewAPIHandlers(rm, acl, hub, handlers.NewWSHandler(hub, rm, acl))
+	return api, store, roomID
+}
+
+func TestRoomState_VotesUseFewerQueries(t *testing.T) {
+	const votes = 20
+
+	measure := func(withState bool) int64 {
+		server := helpers.NewTestServerWithData(t)
+		defer server.Cleanup()
+
+		api, store, roomID := newVoteAPI(t, server.App, withState)
+		queries := countQueries(server.App)
+		castAPIVotes(t, server.App, api, roomID, votes)
+		if store != nil {
+			require.NoError(t, store.Flush())
+		}
+		return queries.Load()
+	}
+
+	direct := measure(false)
+	cached := measure(true)
+	t.Logf("queries for %d votes: %d from the database, %d with room state", votes, direct, cached)
+	assert.Less(t, cached*3, direct, "room state should cut vote queries by more than two thirds")
+}
@@ -0,0 +1,275 @@
+package integration_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// runConcurrently starts n calls of fn at once and returns their errors
+func runConcurrently(n int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+// requireOneWinner checks that exactly one call succeeded and every other one lost the race
+func requireOneWinner(t *testing.T, errs []error) {
+	t.Helper()
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(t, err, services.ErrRoundStateConflict)
+	}
+	require.Equal(t, 1, succeeded)
+}
+
+// assertRoundsConsistent checks that round numbers are unique and the only open round is the current one
+func assertRoundsConsistent(t *testing.T, app core.App, roomID string) {
+	t.Helper()
+
+	rounds, err := app.FindRecordsByFilter("rounds", "room_id = {:roomId}", "round_number", 0, 0,
+		map[string]any{"roomId": roomID})
+	require.NoError(t, err)
+
+	var open []string
+	for i, round := range rounds {
+		assert.Equal(t, i+1, round.GetInt("round_number"), "round numbers must be unique and contiguous")
+		if round.GetString("state") != string(models.RoundStateCompleted) {
+			open = append(open, round.Id)
+		}
+	}
+	require.Len(t, open, 1, "exactly one round must be open")
+
+	room, err := app.FindRecordById("rooms", roomID)
+	require.NoError(t, err)
+	assert.Equal(t, open[0], room.GetString("current_round_id"))
+}
+
+func TestRoundTransitions_SimultaneousNextRound(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 2, nil)
+	voters := participantIDs(t, server.App, roomID)
+	rm := services.NewRoomManager(server.App)
+
+	require.NoError(t, rm.CastVote(roomID, voters[0], "3"))
+	require.NoError(t, rm.CastVote(roomID, voters[1], "5"))
+	require.NoError(t, rm.RevealVotes(roomID))
+	round, err := rm.GetCurrentRoundRecord(roomID)
+	require.NoError(t, err)
+
+	errs := runConcurrently(10, func(int) error {
+		_, err := rm.CreateNextRoundFrom(roomID, round.Id)
+		return err
+	})
+	requireOneWinner(t, errs)
+
+	assertRoundsConsistent(t, server.App, roomID)
+	number, err := rm.GetCurrentRound(roomID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, number)
+
+	completed, err := server.App.FindRecordById("rounds", round.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 2, completed.GetInt("total_votes"))
+}
+
+func TestRoundTransitions_SimultaneousReveal(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 2, nil)
+	voters := participantIDs(t, server.App, roomID)
+	rm := services.NewRoomManager(server.App)
+
+	require.NoError(t, rm.CastVote(roomID, voters[0], "5"))
+	require.NoError(t, rm.CastVote(roomID, voters[1], "5"))
+
+	errs := runConcurrently(10, func(int) error {
+		return rm.RevealVotes(roomID)
+	})
+	requireOneWinner(t, errs)
+
+	// The consensus streak counts the round once
+	room, err := server.App.FindRecordById("rooms", roomID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, room.GetInt("consecutive_consensus_rounds"))
+}
+
+func TestRoundTransitions_StartStates(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 1, nil)
+	rm := services.NewRoomManager(server.App)
+
+	// Reset is allowed while voting and once revealed
+	require.NoError(t, rm.ResetRound(roomID))
+	require.NoError(t, rm.RevealVotes(roomID))
+	require.NoError(t, rm.ResetRound(roomID))
+
+	// Reveal only starts from voting
+	require.NoError(t, rm.RevealVotes(roomID))
+	assert.ErrorIs(t, rm.RevealVotes(roomID), services.ErrRoundStateConflict)
+}
+
+// insertVotes stores count votes in the room's current round, each of a participant of its own.
+// Rows are inserted directly, much faster than through the room manager.
+func insertVotes(t *testing.T, app core.App, roomID, roundID string, count int) {
+	t.Helper()
+	require.NoError(t, app.RunInTransaction(func(txApp core.App) error {
+		for i := 0; i < count; i++ {
+			participantID := fmt.Sprintf("bulkvoter%06d", i)
+			if _, err := txApp.DB().Insert("participants", dbx.Params{
+				"id": participantID, "room_id": roomID, "name": participantID, "session_cookie": participantID,
+				"role": string(models.RoleVoter),
+			}).Execute(); err != nil {
+				return err
+			}
+			if _, err := txApp.DB().Insert("votes", dbx.Params{
+				"id": fmt.Sprintf("bulkvote%07d", i), "room_id": roomID, "round_id": roundID,
+				"participant_id": participantID, "value": "3", "round_number": 1,
+			}).Execute(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+func TestRoundTransitions_ResetDeletesEveryVote(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 0, nil)
+	rm := services.NewRoomManager(server.App)
+	round, err := rm.GetCurrentRoundRecord(roomID)
+	require.NoError(t, err)
+
+	// More votes than a page of the reset
+	insertVotes(t, server.App, roomID, round.Id, config.RoomMaxParticipants+5)
+	require.NoError(t, rm.ResetRound(roomID))
+	assert.Empty(t, storedVotes(t, server.App, round.Id))
+}
+
+func TestRoundTransitions_FailedResetRollsBack(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 3, nil)
+	rm := services.NewRoomManager(server.App)
+	for i, participantID := range participantIDs(t, server.App, roomID) {
+		require.NoError(t, rm.CastVote(roomID, participantID, fmt.Sprint(i+1)))
+	}
+	require.NoError(t, rm.RevealVotes(roomID))
+	round, err := rm.GetCurrentRoundRecord(roomID)
+	require.NoError(t, err)
+
+	deleted := 0
+	server.App.OnRecordDelete("votes").BindFunc(func(e *core.RecordEvent) error {
+		if deleted++; deleted == 2 {
+			return errors.New("database is locked")
+		}
+		return e.Next()
+	})
+
+	assert.Error(t, rm.ResetRound(roomID))
+	assert.Len(t, storedVotes(t, server.App, round.Id), 3, "the deleted vote is restored")
+	round, err = rm.GetCurrentRoundRecord(roomID)
+	require.NoError(t, err)
+	assert.Equal(t, string(models.RoundStateRevealed), round.GetString("state"))
+}
+
+func TestRoundTransitions_SimultaneousCommands(t *testing.T) {
+	for _, bc := range []struct {
+		name      string
+		withState bool
+	}{
+		{"database", false},
+		{"room_state", true},
+	} {
+		t.Run(bc.name, func(t *testing.T) {
+			server := helpers.NewTestServerWithData(t)
+			defer server.Cleanup()
+
+			api, _, roomID := newVoteAPI(t, server.App, bc.withState)
+			commands := []func(*core.RequestEvent) error{api.Reveal, api.Reset, api.NextRound}
+
+			for iteration := 0; iteration < 10; iteration++ {
+				// Three of each command at once, all from the facilitator
+				codes := make([]int, 9)
+				runConcurrently(len(codes), func(i int) error {
+					re, rec := apiRequest(server.App, http.MethodPost, roomID, "session-1", "")
+					err := commands[i%len(commands)](re)
+					codes[i] = rec.Code
+					return err
+				})
+
+				for _, code := range codes {
+					assert.Contains(t, []int{http.StatusOK, http.StatusCreated, http.StatusNoContent, http.StatusConflict}, code)
+				}
+				assertRoundsConsistent(t, server.App, roomID)
+			}
+		})
+	}
+}
+
+func TestRoundTransitions_ConflictIsReportedToClients(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	api, _, roomID := newVoteAPI(t, server.App, false)
+	castAPIVotes(t, server.App, api, roomID, 1)
+
+	rec := apiCall(t, server.App, api.Reveal, http.MethodPost, roomID, "session-1", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = apiCall(t, server.App, api.Reveal, http.MethodPost, roomID, "session-1", "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "invalid_state", errorCode(t, rec))
+
+	var errs []error
+	for _, err := range runConcurrently(2, func(int) error {
+		re, rec := apiRequest(server.App, http.MethodPost, roomID, "session-1", "")
+		if err := api.NextRound(re); err != nil {
+			return err
+		}
+		if rec.Code != http.StatusCreated {
+			return errors.New(rec.Body.String())
+		}
+		return nil
+	}) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	assert.Len(t, errs, 1, "only one of two simultaneous next_round commands advances the room")
+	assertRoundsConsistent(t, server.App, roomID)
+}
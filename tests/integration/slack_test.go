@@ -0,0 +1,185 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+const (
+	testSlackSecret  = "slack-signing-secret"
+	testSlackBaseURL = "https://poker.example.com"
+)
+
+type slackFixture struct {
+	app   core.App
+	rm    *services.RoomManager
+	api   *handlers.APIHandlers
+	slack *handlers.SlackHandlers
+}
+
+func newSlackFixture(t *testing.T) *slackFixture {
+	t.Helper()
+	server := helpers.NewTestServerWithData(t)
+	t.Cleanup(server.Cleanup)
+
+	rm := services.NewRoomManager(server.App)
+	acl := services.NewACLService(rm)
+	hub := services.NewHub(config.Default())
+	go hub.Run()
+	ws := handlers.NewWSHandler(hub, rm, acl)
+	slackService := services.NewSlackService(rm)
+	ws.SetSlackService(slackService)
+
+	return &slackFixture{
+		app:   server.App,
+		rm:    rm,
+		api:   handlers.NewAPIHandlers(rm, acl, hub, ws),
+		slack: handlers.NewSlackHandlers(rm, slackService, testSlackSecret, testSlackBaseURL),
+	}
+}
+
+// command posts a slash command form, signed with secret at the given time
+func (f *slackFixture) command(t *testing.T, form url.Values, secret string, at time.Time) *httptest.ResponseRecorder {
+	t.Helper()
+	body := form.Encode()
+	ts := strconv.FormatInt(at.Unix(), 10)
+
+	req := httptest.NewRequest(http.MethodPost, "/integrations/slack/command", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set(services.SlackTimestampHeader, ts)
+	req.Header.Set(services.SlackSignatureHeader, services.SignSlackRequest(secret, ts, []byte(body)))
+
+	rec := httptest.NewRecorder()
+	re := &core.RequestEvent{App: f.app, Event: router.Event{Response: rec, Request: req}}
+	require.NoError(t, f.slack.HandleCommand(re))
+	return rec
+}
+
+func decodeSlackMessage(t *testing.T, rec *httptest.ResponseRecorder) services.SlackMessage {
+	t.Helper()
+	var message services.SlackMessage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &message))
+	return message
+}
+
+func TestSlackCommandCreatesRoom(t *testing.T) {
+	f := newSlackFixture(t)
+
+	rec := f.command(t, url.Values{"command": {"/poker"}, "text": {"t-shirt Sprint 42"}, "user_name": {"alice"}}, testSlackSecret, time.Now())
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	message := decodeSlackMessage(t, rec)
+	assert.Equal(t, "in_channel", message.ResponseType)
+	assert.Contains(t, message.Text, "Sprint 42")
+	require.Len(t, message.Blocks, 2)
+	assert.Equal(t, "image", message.Blocks[1].Type)
+
+	// The room link and QR code point at the new room
+	qrURL := message.Blocks[1].ImageURL
+	require.True(t, strings.HasPrefix(qrURL, testSlackBaseURL+"/room/"), qrURL)
+	roomID := strings.TrimSuffix(strings.TrimPrefix(qrURL, testSlackBaseURL+"/room/"), "/qr")
+	assert.Contains(t, message.Text, "<"+testSlackBaseURL+"/room/"+roomID+"|")
+
+	room, err := f.rm.GetRoom(roomID)
+	require.NoError(t, err)
+	assert.Equal(t, "Sprint 42", room.GetString("name"))
+	assert.Equal(t, "custom", room.GetString("pointing_method"))
+	assert.JSONEq(t, `["XXS","XS","S","M","L","XL","XXL"]`, room.GetString("custom_values"))
+}
+
+func TestSlackCommandDeckParsing(t *testing.T) {
+	f := newSlackFixture(t)
+
+	tests := []struct {
+		text   string
+		name   string
+		method string
+	}{
+		{text: "", name: "Planning Poker", method: "custom"},
+		{text: "Backlog grooming", name: "Backlog grooming", method: "custom"},
+		{text: "fibonacci", name: "Planning Poker", method: "fibonacci"},
+		{text: "1,2,4,8 Powers", name: "Powers", method: "custom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			rec := f.command(t, url.Values{"text": {tt.text}}, testSlackSecret, time.Now())
+			message := decodeSlackMessage(t, rec)
+			require.Equal(t, "in_channel", message.ResponseType, message.Text)
+
+			roomID := strings.TrimSuffix(strings.TrimPrefix(message.Blocks[1].ImageURL, testSlackBaseURL+"/room/"), "/qr")
+			room, err := f.rm.GetRoom(roomID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.name, room.GetString("name"))
+			assert.Equal(t, tt.method, room.GetString("pointing_method"))
+		})
+	}
+
+	// Help and invalid decks are answered privately without creating a room
+	for _, text := range []string{"help", "1,1 Duplicates"} {
+		message := decodeSlackMessage(t, f.command(t, url.Values{"text": {text}}, testSlackSecret, time.Now()))
+		assert.Equal(t, "ephemeral", message.ResponseType, text)
+		assert.Contains(t, message.Text, "Usage", text)
+	}
+}
+
+func TestSlackCommandRejectsInvalidSignature(t *testing.T) {
+	f := newSlackFixture(t)
+	form := url.Values{"text": {"Sprint"}}
+
+	rec := f.command(t, form, "wrong-secret", time.Now())
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = f.command(t, form, testSlackSecret, time.Now().Add(-time.Hour))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "stale timestamps are rejected")
+}
+
+func TestSlackRevealSummaryPostedToResponseURL(t *testing.T) {
+	f := newSlackFixture(t)
+	receiver := newWebhookReceiver(t)
+
+	rec := f.command(t, url.Values{"text": {"fibonacci Sprint"}, "response_url": {receiver.URL + "/commands/1"}}, testSlackSecret, time.Now())
+	message := decodeSlackMessage(t, rec)
+	require.Equal(t, "in_channel", message.ResponseType, message.Text)
+	roomID := strings.TrimSuffix(strings.TrimPrefix(message.Blocks[1].ImageURL, testSlackBaseURL+"/room/"), "/qr")
+
+	rec = apiCall(t, f.app, f.api.JoinRoom, http.MethodPost, roomID, "", `{"name": "Alice"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	aliceToken := decodeBody(t, rec)["token"].(string)
+	rec = apiCall(t, f.app, f.api.JoinRoom, http.MethodPost, roomID, "", `{"name": "Bob"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	bobToken := decodeBody(t, rec)["token"].(string)
+
+	rec = apiCall(t, f.app, f.api.CastVote, http.MethodPost, roomID, aliceToken, `{"value": "3"}`)
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	rec = apiCall(t, f.app, f.api.CastVote, http.MethodPost, roomID, bobToken, `{"value": "5"}`)
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	rec = apiCall(t, f.app, f.api.Reveal, http.MethodPost, roomID, aliceToken, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	require.Eventually(t, func() bool { return len(receiver.received()) == 1 }, 5*time.Second, 20*time.Millisecond)
+
+	var posted services.SlackMessage
+	require.NoError(t, json.Unmarshal(receiver.received()[0].Body, &posted))
+	assert.Equal(t, "in_channel", posted.ResponseType)
+	assert.Contains(t, posted.Text, "*Sprint* – round 1 revealed (2 votes)")
+	assert.Contains(t, posted.Text, "Average: *4*")
+	assert.Contains(t, posted.Text, "• Alice: 3")
+	assert.Contains(t, posted.Text, "• Bob: 5")
+}
@@ -0,0 +1,225 @@
+package integration_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// sseEvent is one event read from an event stream
+type sseEvent struct {
+	name string
+	data string
+}
+
+// sseRoom is a room served through the event stream handlers
+type sseRoom struct {
+	baseURL string
+	roomID  string
+	app     core.App
+	hub     *services.Hub
+}
+
+// sseConn is an open event stream
+type sseConn struct {
+	streamID string
+	events   <-chan sseEvent
+	body     io.Closer // Closing it ends the stream
+}
+
+// startSSERoom serves a room with two voters, "session-1" being the creator's session
+func startSSERoom(t *testing.T) *sseRoom {
+	t.Helper()
+	server := helpers.NewTestServerWithData(t)
+	t.Cleanup(server.Cleanup)
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 2, nil)
+	hub := services.NewHub(config.Default())
+	go hub.Run()
+	return serveSSERoom(t, server.App, roomID, hub)
+}
+
+// serveSSERoom serves a room through the event stream handlers of hub
+func serveSSERoom(t *testing.T, app core.App, roomID string, hub *services.Hub) *sseRoom {
+	t.Helper()
+	rm := services.NewRoomManager(app)
+	ws := handlers.NewWSHandler(hub, rm, services.NewACLService(rm))
+
+	serve := func(handler func(*core.RequestEvent) error) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			_ = handler(&core.RequestEvent{App: app, Event: router.Event{Response: w, Request: r}})
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sse/{roomId}", serve(ws.HandleSSE))
+	mux.HandleFunc("POST /sse/{roomId}/{streamId}", serve(ws.HandleSSECommand))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return &sseRoom{baseURL: srv.URL, roomID: roomID, app: app, hub: hub}
+}
+
+// open opens the room's event stream for a session
+func (s *sseRoom) open(t *testing.T, session string) *sseConn {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, s.baseURL+"/sse/"+s.roomID, nil)
+	require.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "pp_participant_id", Value: session})
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan sseEvent, 64)
+	go func() {
+		defer close(events)
+		var event sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if event.data != "" {
+					events <- event
+				}
+				event = sseEvent{}
+			case strings.HasPrefix(line, "event: "):
+				event.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+
+	first := nextEvent(t, events)
+	require.Equal(t, handlers.SSEStreamEvent, first.name)
+	var stream handlers.SSEStreamPayload
+	require.NoError(t, json.Unmarshal([]byte(first.data), &stream))
+	require.NotEmpty(t, stream.StreamID)
+	require.Eventually(t, func() bool { return s.hub.GetRoomSize(s.roomID) > 0 }, 5*time.Second, 10*time.Millisecond)
+	return &sseConn{streamID: stream.StreamID, events: events, body: resp.Body}
+}
+
+// post posts a command to an event stream and returns the response status
+func (s *sseRoom) post(t *testing.T, streamID, session, contentType, body string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/sse/%s/%s", s.baseURL, s.roomID, streamID), strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	req.AddCookie(&http.Cookie{Name: "pp_participant_id", Value: session})
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "event stream ended")
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+		return sseEvent{}
+	}
+}
+
+// expectStreamMessage reads messages from the stream until one of type msgType
+func expectStreamMessage(t *testing.T, events <-chan sseEvent, msgType string) *models.WSMessage {
+	t.Helper()
+	for {
+		event := nextEvent(t, events)
+		assert.Empty(t, event.name, "messages are plain events")
+		var msg models.WSMessage
+		require.NoError(t, json.Unmarshal([]byte(event.data), &msg))
+		if msg.Type == msgType {
+			return &msg
+		}
+	}
+}
+
+func TestSSE_StreamCarriesRoomMessages(t *testing.T) {
+	room := startSSERoom(t)
+	conn := room.open(t, "session-2")
+
+	state := expectStreamMessage(t, conn.events, models.MsgTypeRoomState)
+	assert.NotNil(t, state.Payload)
+}
+
+func TestSSE_PostedCommandIsAnsweredOnTheStream(t *testing.T) {
+	room := startSSERoom(t)
+	conn := room.open(t, "session-2")
+
+	status := room.post(t, conn.streamID, "session-2", "application/json",
+		`{"type":"vote","requestId":"req-1","payload":{"value":"5"}}`)
+	require.Equal(t, http.StatusAccepted, status)
+
+	ack := expectStreamMessage(t, conn.events, models.MsgTypeAck)
+	assert.Equal(t, "req-1", ack.RequestID)
+
+	status = room.post(t, conn.streamID, "session-2", "application/json",
+		`{"type":"update_room_name","requestId":"req-2","payload":{"name":"Renamed"}}`)
+	require.Equal(t, http.StatusAccepted, status)
+
+	reply := expectStreamMessage(t, conn.events, models.MsgTypeError)
+	assert.Equal(t, "req-2", reply.RequestID)
+	assert.Equal(t, handlers.ErrCodeForbidden, errorPayload(t, reply)["code"])
+}
+
+func TestSSE_RejectedCommands(t *testing.T) {
+	room := startSSERoom(t)
+	streamID := room.open(t, "session-2").streamID
+	vote := `{"type":"vote","payload":{"value":"5"}}`
+
+	tests := []struct {
+		name        string
+		streamID    string
+		session     string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"unknown stream", "not-a-stream", "session-2", "application/json", vote, http.StatusNotFound},
+		{"other session", streamID, "session-1", "application/json", vote, http.StatusForbidden},
+		{"no session", streamID, "", "application/json", vote, http.StatusForbidden},
+		{"not JSON", streamID, "session-2", "text/plain", vote, http.StatusUnsupportedMediaType},
+		{"too large", streamID, "session-2", "application/json", strings.Repeat("x", 64<<10), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, room.post(t, tt.streamID, tt.session, tt.contentType, tt.body))
+		})
+	}
+}
+
+func TestSSE_ClosedStreamLeavesTheRoom(t *testing.T) {
+	room := startSSERoom(t)
+
+	req, err := http.NewRequest(http.MethodGet, room.baseURL+"/sse/"+room.roomID, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return room.hub.GetRoomSize(room.roomID) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	resp.Body.Close()
+	require.Eventually(t, func() bool { return room.hub.GetRoomSize(room.roomID) == 0 }, 5*time.Second, 10*time.Millisecond)
+}
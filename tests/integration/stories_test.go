@@ -0,0 +1,310 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// fakeJira is a local Jira REST server with a fixed set of issues
+type fakeJira struct {
+	*httptest.Server
+	mu        sync.Mutex
+	queries   []string
+	estimates map[string]float64
+	failPut   bool
+}
+
+func newFakeJira(t *testing.T) *fakeJira {
+	t.Helper()
+	jira := &fakeJira{estimates: make(map[string]float64)}
+	jira.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jira.mu.Lock()
+		defer jira.mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/api/2/search":
+			jira.queries = append(jira.queries, r.URL.Query().Get("jql"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"issues": [
+				{"key": "PP-1", "fields": {"summary": "Login page"}},
+				{"key": "PP-2", "fields": {"summary": "Password reset"}}
+			]}`))
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/rest/api/2/issue/"):
+			if jira.failPut {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errorMessages": [], "errors": {"customfield_10016": "Field cannot be set"}}`))
+				return
+			}
+			var body struct {
+				Fields map[string]float64 `json:"fields"`
+			}
+			data, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(data, &body)
+			jira.estimates[strings.TrimPrefix(r.URL.Path, "/rest/api/2/issue/")] = body.Fields["customfield_10016"]
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(jira.Close)
+	return jira
+}
+
+func (j *fakeJira) searched() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]string(nil), j.queries...)
+}
+
+func (j *fakeJira) tracker() services.IssueTracker {
+	return services.NewJiraTracker(services.JiraConfig{BaseURL: j.URL, Token: "pat"})
+}
+
+func (j *fakeJira) estimate(key string) (float64, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	value, ok := j.estimates[key]
+	return value, ok
+}
+
+type storyFixture struct {
+	app     core.App
+	stories *services.StoryService
+	api     *handlers.APIHandlers
+}
+
+func newStoryFixture(t *testing.T, trackers ...services.IssueTracker) *storyFixture {
+	t.Helper()
+	server := helpers.NewTestServerWithData(t)
+	t.Cleanup(server.Cleanup)
+
+	rm := services.NewRoomManager(server.App)
+	acl := services.NewACLService(rm)
+	hub := services.NewHub(config.Default())
+	go hub.Run()
+	ws := handlers.NewWSHandler(hub, rm, acl)
+
+	var stories *services.StoryService
+	if len(trackers) > 0 {
+		stories = services.NewStoryService(server.App)
+		for _, tracker := range trackers {
+			stories.RegisterTracker(tracker)
+		}
+		ws.SetStoryService(stories)
+	}
+
+	return &storyFixture{
+		app:     server.App,
+		stories: stories,
+		api:     handlers.NewAPIHandlers(rm, acl, hub, ws),
+	}
+}
+
+// setupRoom creates a room through the API and joins a facilitator and a voter
+func (f *storyFixture) setupRoom(t *testing.T) (roomID, facilitatorToken, voterToken string) {
+	t.Helper()
+	rec := apiCall(t, f.app, f.api.CreateRoom, http.MethodPost, "", "", `{"name": "Story Room"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	roomID = decodeBody(t, rec)["id"].(string)
+
+	rec = apiCall(t, f.app, f.api.JoinRoom, http.MethodPost, roomID, "", `{"name": "Alice"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	facilitatorToken = decodeBody(t, rec)["token"].(string)
+
+	rec = apiCall(t, f.app, f.api.JoinRoom, http.MethodPost, roomID, "", `{"name": "Bob"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	voterToken = decodeBody(t, rec)["token"].(string)
+	return roomID, facilitatorToken, voterToken
+}
+
+// completeRound votes, reveals and starts the next round
+func (f *storyFixture) completeRound(t *testing.T, roomID, facilitatorToken, voterToken, vote1, vote2 string) {
+	t.Helper()
+	rec := apiCall(t, f.app, f.api.CastVote, http.MethodPost, roomID, facilitatorToken, `{"value": "`+vote1+`"}`)
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	rec = apiCall(t, f.app, f.api.CastVote, http.MethodPost, roomID, voterToken, `{"value": "`+vote2+`"}`)
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	rec = apiCall(t, f.app, f.api.Reveal, http.MethodPost, roomID, facilitatorToken, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = apiCall(t, f.app, f.api.NextRound, http.MethodPost, roomID, facilitatorToken, "")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+}
+
+func (f *storyFixture) listStories(t *testing.T, roomID string) []map[string]any {
+	t.Helper()
+	rec := apiCall(t, f.app, f.api.ListStories, http.MethodGet, roomID, "", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var body struct {
+		Stories []map[string]any `json:"stories"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return body.Stories
+}
+
+func TestImportStoriesFromJira(t *testing.T) {
+	jira := newFakeJira(t)
+	f := newStoryFixture(t, jira.tracker())
+	roomID, facilitatorToken, voterToken := f.setupRoom(t)
+
+	rec := apiCall(t, f.app, f.api.ImportStories, http.MethodPost, roomID, voterToken, `{"query": "project = PP"}`)
+	assert.Equal(t, http.StatusForbidden, rec.Code, "only the facilitator imports stories")
+
+	rec = apiCall(t, f.app, f.api.ImportStories, http.MethodPost, roomID, facilitatorToken, `{"tracker": "github", "query": "project = PP"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, handlers.ErrCodeInvalidPayload, errorCode(t, rec))
+
+	rec = apiCall(t, f.app, f.api.ImportStories, http.MethodPost, roomID, facilitatorToken, `{"query": "project = PP ORDER BY rank"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Len(t, decodeBody(t, rec)["stories"], 2)
+	assert.Equal(t, []string{"project = PP ORDER BY rank"}, jira.searched())
+
+	// Re-importing skips issues already in the room
+	rec = apiCall(t, f.app, f.api.ImportStories, http.MethodPost, roomID, facilitatorToken, `{"tracker": "jira", "query": "project = PP"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Empty(t, decodeBody(t, rec)["stories"])
+
+	stories := f.listStories(t, roomID)
+	require.Len(t, stories, 2)
+	assert.Equal(t, "PP-1", stories[0]["issueKey"])
+	assert.Equal(t, "Login page", stories[0]["title"])
+	assert.Equal(t, jira.URL+"/browse/PP-1", stories[0]["url"])
+	assert.Equal(t, models.StoryPending, stories[0]["status"])
+	assert.Equal(t, "PP-2", stories[1]["issueKey"])
+}
+
+func TestCompletedRoundWritesEstimateToJira(t *testing.T) {
+	jira := newFakeJira(t)
+	f := newStoryFixture(t, jira.tracker())
+	roomID, facilitatorToken, voterToken := f.setupRoom(t)
+
+	rec := apiCall(t, f.app, f.api.ImportStories, http.MethodPost, roomID, facilitatorToken, `{"query": "project = PP"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	// Each completed round estimates the next pending story, in order
+	f.completeRound(t, roomID, facilitatorToken, voterToken, "5", "5")
+	f.completeRound(t, roomID, facilitatorToken, voterToken, "2", "3")
+
+	require.Eventually(t, func() bool {
+		_, ok1 := jira.estimate("PP-1")
+		_, ok2 := jira.estimate("PP-2")
+		return ok1 && ok2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	estimate, _ := jira.estimate("PP-1")
+	assert.Equal(t, 5.0, estimate)
+	estimate, _ = jira.estimate("PP-2")
+	assert.Equal(t, 2.5, estimate)
+
+	require.Eventually(t, func() bool {
+		stories := f.listStories(t, roomID)
+		return stories[0]["syncStatus"] == models.StorySyncSynced && stories[1]["syncStatus"] == models.StorySyncSynced
+	}, 5*time.Second, 20*time.Millisecond)
+
+	stories := f.listStories(t, roomID)
+	assert.Equal(t, models.StoryEstimated, stories[0]["status"])
+	assert.Equal(t, 5.0, stories[0]["estimate"])
+	assert.NotEmpty(t, stories[0]["roundId"])
+
+	// With every story estimated, further rounds leave the stories alone
+	f.completeRound(t, roomID, facilitatorToken, voterToken, "8", "8")
+	estimate, _ = jira.estimate("PP-1")
+	assert.Equal(t, 5.0, estimate)
+}
+
+func TestFailedWriteBackIsRecorded(t *testing.T) {
+	jira := newFakeJira(t)
+	jira.failPut = true
+	f := newStoryFixture(t, jira.tracker())
+	roomID, facilitatorToken, voterToken := f.setupRoom(t)
+
+	rec := apiCall(t, f.app, f.api.ImportStories, http.MethodPost, roomID, facilitatorToken, `{"query": "project = PP"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	f.completeRound(t, roomID, facilitatorToken, voterToken, "3", "3")
+
+	require.Eventually(t, func() bool {
+		return f.listStories(t, roomID)[0]["syncStatus"] == models.StorySyncFailed
+	}, 5*time.Second, 20*time.Millisecond)
+
+	story := f.listStories(t, roomID)[0]
+	assert.Equal(t, models.StoryEstimated, story["status"], "the estimate is kept when the write-back fails")
+	assert.Contains(t, story["syncError"], "Field cannot be set")
+}
+
+func TestImportStoriesWithoutTracker(t *testing.T) {
+	f := newStoryFixture(t)
+	roomID, facilitatorToken, _ := f.setupRoom(t)
+
+	rec := apiCall(t, f.app, f.api.ImportStories, http.MethodPost, roomID, facilitatorToken, `{"query": "project = PP"}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, f.listStories(t, roomID))
+}
+
+func TestGitHubEstimateConflictIsReported(t *testing.T) {
+	var mu sync.Mutex
+	var labelsAdded []string
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/search/issues":
+			_, _ = w.Write([]byte(`{"items": [
+				{"number": 1, "title": "Already estimated", "html_url": "https://github.com/acme/app/issues/1", "repository_url": "http://` + r.Host + `/repos/acme/app"},
+				{"number": 2, "title": "New", "html_url": "https://github.com/acme/app/issues/2", "repository_url": "http://` + r.Host + `/repos/acme/app"}
+			]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/app/issues/1":
+			_, _ = w.Write([]byte(`{"labels": [{"name": "points:13"}]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/app/issues/2":
+			_, _ = w.Write([]byte(`{"labels": []}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/app/issues/2/labels":
+			mu.Lock()
+			labelsAdded = append(labelsAdded, r.URL.Path)
+			mu.Unlock()
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(github.Close)
+
+	jira := newFakeJira(t)
+	f := newStoryFixture(t, jira.tracker(), services.NewGitHubTracker(services.GitHubConfig{BaseURL: github.URL, Token: "gh", Repo: "acme/app"}))
+	roomID, facilitatorToken, voterToken := f.setupRoom(t)
+
+	// With several trackers configured, the tracker must be named
+	rec := apiCall(t, f.app, f.api.ImportStories, http.MethodPost, roomID, facilitatorToken, `{"query": "label:ready"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = apiCall(t, f.app, f.api.ImportStories, http.MethodPost, roomID, facilitatorToken, `{"tracker": "github", "query": "label:ready"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	f.completeRound(t, roomID, facilitatorToken, voterToken, "5", "5")
+	f.completeRound(t, roomID, facilitatorToken, voterToken, "3", "3")
+
+	require.Eventually(t, func() bool {
+		stories := f.listStories(t, roomID)
+		return stories[0]["syncStatus"] == models.StorySyncConflict && stories[1]["syncStatus"] == models.StorySyncSynced
+	}, 5*time.Second, 20*time.Millisecond)
+
+	story := f.listStories(t, roomID)[0]
+	assert.Equal(t, "acme/app#1", story["issueKey"])
+	assert.Equal(t, "acme/app#1 is already estimated as points:13, not overwriting with points:5", story["syncError"])
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"/repos/acme/app/issues/2/labels"}, labelsAdded, "only the unestimated issue is labelled")
+}
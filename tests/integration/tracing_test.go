@@ -0,0 +1,149 @@
+package integration_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/hook"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/internal/tracing"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// spanRecorder installs a recording tracer provider, once for the package: the global
+// provider can't be swapped back, so tests find their spans by attribute instead
+var spanRecorder = sync.OnceValue(func() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return recorder
+})
+
+// findSpan returns the ended span with the name and attribute, nil if there is none yet
+func findSpan(recorder *tracetest.SpanRecorder, name string, attr attribute.KeyValue) sdktrace.ReadOnlySpan {
+	for _, span := range recorder.Ended() {
+		if span.Name() != name {
+			continue
+		}
+		for _, kv := range span.Attributes() {
+			if kv == attr {
+				return span
+			}
+		}
+	}
+	return nil
+}
+
+// A WebSocket vote is one trace: receive, handling, database operation and broadcast
+func TestTracing_VoteIsOneTrace(t *testing.T) {
+	recorder := spanRecorder()
+	server := helpers.NewTestServerWithData(t)
+	defer server.Cleanup()
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 1, nil)
+	voter := participantIDs(t, server.App, roomID)[0]
+
+	hub := services.NewHub(config.Default())
+	go hub.Run()
+	rm := services.NewRoomManager(server.App)
+	handlers.NewWSHandler(hub, rm, services.NewACLService(rm))
+
+	client := helpers.ConnectHubClient(t, helpers.StartHubServer(t, hub), roomID, voter)
+	require.Eventually(t, func() bool { return hub.GetRoomSize(roomID) == 1 }, 5*time.Second, 10*time.Millisecond)
+	client.SendVote(t, "5")
+	client.ExpectMessage(t, models.MsgTypeVoteCast, 2*time.Second)
+
+	inRoom := tracing.RoomID.String(roomID)
+	var handled sdktrace.ReadOnlySpan
+	require.Eventually(t, func() bool {
+		handled = findSpan(recorder, "WSHandler.handleMessage", inRoom)
+		return handled != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Contains(t, handled.Attributes(), tracing.MessageType.String(models.MsgTypeVote))
+
+	received := findSpan(recorder, "ws.receive", inRoom)
+	require.NotNil(t, received)
+	assert.Equal(t, received.SpanContext().SpanID(), handled.Parent().SpanID(), "the handler continues the trace of the receive")
+
+	saved := findSpan(recorder, "RoomManager.SaveVote", inRoom)
+	require.NotNil(t, saved)
+	assert.Equal(t, handled.SpanContext().SpanID(), saved.Parent().SpanID())
+
+	broadcast := findSpan(recorder, "hub.BroadcastToRoom", tracing.MessageType.String(models.MsgTypeVoteCast))
+	require.NotNil(t, broadcast)
+	assert.Equal(t, handled.SpanContext().SpanID(), broadcast.Parent().SpanID())
+	assert.Equal(t, received.SpanContext().TraceID(), broadcast.SpanContext().TraceID())
+}
+
+// A broadcast relayed through the broker is delivered under the trace of the publishing instance
+func TestTracing_BrokerCarriesTraceContext(t *testing.T) {
+	recorder := spanRecorder()
+	broker := services.NewMemoryBroker()
+	hubA, hubB := startHubs(t, broker, broker)
+	bob := helpers.ConnectHubClient(t, helpers.StartHubServer(t, hubB), "traced-room", "bob")
+	require.Eventually(t, func() bool { return hubB.GetRoomSize("traced-room") == 1 }, 5*time.Second, 10*time.Millisecond)
+	waitForRelay(t, hubA, "traced-room", bob)
+
+	ctx, parent := tracing.Start(context.Background(), "test.broadcast")
+	hubA.BroadcastToRoom(ctx, "traced-room", &models.WSMessage{Type: models.MsgTypeRoomReset, Payload: models.EmptyPayload{}})
+	parent.End()
+	bob.ExpectMessage(t, models.MsgTypeRoomReset, 2*time.Second)
+
+	var delivered []sdktrace.ReadOnlySpan
+	require.Eventually(t, func() bool {
+		delivered = delivered[:0]
+		for _, span := range recorder.Ended() {
+			if span.Name() == "hub.deliverToRoom" && span.SpanContext().TraceID() == parent.SpanContext().TraceID() {
+				delivered = append(delivered, span)
+			}
+		}
+		return len(delivered) == 2 // Locally on A, then relayed to B
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+// An HTTP request gets a server span continuing the caller's trace
+func TestTracing_RequestSpan(t *testing.T) {
+	recorder := spanRecorder()
+
+	callerTrace, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/rooms/abc/reveal", nil)
+	req.Pattern = "POST /api/v1/rooms/{id}/reveal"
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	re := &core.RequestEvent{Event: router.Event{Response: httptest.NewRecorder(), Request: req}}
+
+	chain := &hook.Hook[*core.RequestEvent]{}
+	chain.BindFunc(handlers.TraceRequests)
+	err = chain.Trigger(re, func(e *core.RequestEvent) error {
+		assert.Equal(t, callerTrace, trace.SpanContextFromContext(e.Request.Context()).TraceID())
+		return e.ForbiddenError("Only the room creator can reveal", nil)
+	})
+	require.Error(t, err)
+
+	span := findSpan(recorder, "POST /api/v1/rooms/{id}/reveal", attribute.String("url.path", "/api/v1/rooms/abc/reveal"))
+	require.NotNil(t, span)
+	assert.Equal(t, callerTrace, span.SpanContext().TraceID())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusForbidden))
+	assert.Equal(t, codes.Unset, span.Status().Code, "client errors are not span errors")
+}
@@ -0,0 +1,53 @@
+package integration_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+func TestUnvote_CancelsAutoRevealOnlyBelowTheRule(t *testing.T) {
+	server := helpers.NewTestServerWithData(t)
+	t.Cleanup(server.Cleanup)
+
+	roomConfig := models.DefaultRoomConfig()
+	roomConfig.Permissions.AutoReveal = true
+	roomConfig.RevealRule = models.RevealRule{Mode: models.RevealModeMinimum, MinimumVotes: 2}
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 3, roomConfig)
+	hub := services.NewHub(config.Default())
+	go hub.Run()
+	room := serveSSERoom(t, server.App, roomID, hub)
+
+	conns := map[string]*sseConn{}
+	for _, session := range []string{"session-1", "session-2", "session-3"} {
+		conns[session] = room.open(t, session)
+	}
+	observer := conns["session-1"]
+	command := func(session, msgType, payload string) {
+		t.Helper()
+		status := room.post(t, conns[session].streamID, session, "application/json",
+			`{"type":"`+msgType+`","payload":`+payload+`}`)
+		require.Equal(t, http.StatusAccepted, status)
+	}
+
+	command("session-1", models.MsgTypeVote, `{"value":"3"}`)
+	command("session-2", models.MsgTypeVote, `{"value":"5"}`)
+	expectStreamMessage(t, observer.events, models.MsgTypeAutoRevealCountdown)
+	command("session-3", models.MsgTypeVote, `{"value":"8"}`)
+	expectStreamMessage(t, observer.events, models.MsgTypeAutoRevealCountdown)
+
+	// Two votes still meet the rule: the countdown restarts instead of being cancelled
+	command("session-3", models.MsgTypeUnvote, `{}`)
+	expectStreamMessage(t, observer.events, models.MsgTypeAutoRevealCountdown)
+	noStreamMessage(t, observer.events, models.MsgTypeAutoRevealCancelled, 200*time.Millisecond)
+
+	command("session-2", models.MsgTypeUnvote, `{}`)
+	expectStreamMessage(t, observer.events, models.MsgTypeAutoRevealCancelled)
+}
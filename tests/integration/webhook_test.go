@@ -0,0 +1,410 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/security"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// webhookReceiver is a local HTTP server recording webhook deliveries
+type webhookReceiver struct {
+	*httptest.Server
+	mu         sync.Mutex
+	requests   []receivedWebhook
+	failNext   int // Number of upcoming requests answered with 500
+	statusCode int
+}
+
+type receivedWebhook struct {
+	Header http.Header
+	Body   []byte
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	t.Helper()
+	t.Setenv(security.WebhookAllowedHostsEnv, "127.0.0.1") // The receiver is a local test server
+	receiver := &webhookReceiver{statusCode: http.StatusOK}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.requests = append(receiver.requests, receivedWebhook{Header: r.Header.Clone(), Body: body})
+		if receiver.failNext > 0 {
+			receiver.failNext--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(receiver.statusCode)
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func (r *webhookReceiver) received() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.requests...)
+}
+
+func (r *webhookReceiver) events(t *testing.T) []models.WebhookEvent {
+	t.Helper()
+	events := make([]models.WebhookEvent, 0)
+	for _, req := range r.received() {
+		var event models.WebhookEvent
+		require.NoError(t, json.Unmarshal(req.Body, &event))
+		events = append(events, event)
+	}
+	return events
+}
+
+type webhookFixture struct {
+	app      core.App
+	rm       *services.RoomManager
+	webhooks *services.WebhookService
+	api      *handlers.APIHandlers
+}
+
+func newWebhookFixture(t *testing.T) *webhookFixture {
+	t.Helper()
+	server := helpers.NewTestServerWithData(t)
+	t.Cleanup(server.Cleanup)
+
+	rm := services.NewRoomManager(server.App)
+	acl := services.NewACLService(rm)
+	hub := services.NewHub(config.Default())
+	go hub.Run()
+	ws := handlers.NewWSHandler(hub, rm, acl)
+	webhooks := services.NewWebhookService(server.App)
+	ws.SetWebhookService(webhooks)
+
+	return &webhookFixture{
+		app:      server.App,
+		rm:       rm,
+		webhooks: webhooks,
+		api:      handlers.NewAPIHandlers(rm, acl, hub, ws),
+	}
+}
+
+// setupRoom creates a room through the API and joins a facilitator and a voter
+func (f *webhookFixture) setupRoom(t *testing.T) (roomID, facilitatorToken, voterToken string) {
+	t.Helper()
+	rec := apiCall(t, f.app, f.api.CreateRoom, http.MethodPost, "", "", `{"name": "Webhook Room"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	roomID = decodeBody(t, rec)["id"].(string)
+
+	rec = apiCall(t, f.app, f.api.JoinRoom, http.MethodPost, roomID, "", `{"name": "Alice"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	facilitatorToken = decodeBody(t, rec)["token"].(string)
+
+	rec = apiCall(t, f.app, f.api.JoinRoom, http.MethodPost, roomID, "", `{"name": "Bob"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	voterToken = decodeBody(t, rec)["token"].(string)
+	return roomID, facilitatorToken, voterToken
+}
+
+func (f *webhookFixture) deliveries(t *testing.T, roomID string) []*core.Record {
+	t.Helper()
+	records, err := f.webhooks.GetRoomDeliveries(roomID, 100)
+	require.NoError(t, err)
+	return records
+}
+
+func TestWebhooks_SignedDeliveryOfRoomEvents(t *testing.T) {
+	f := newWebhookFixture(t)
+	receiver := newWebhookReceiver(t)
+	roomID, aliceToken, bobToken := f.setupRoom(t)
+
+	// Register through the API as facilitator
+	rec := apiCall(t, f.app, f.api.CreateWebhook, http.MethodPost, roomID, aliceToken,
+		`{"url": "`+receiver.URL+`", "events": ["votes_revealed", "round_completed", "participant_joined"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	webhook := decodeBody(t, rec)
+	secret := webhook["secret"].(string)
+	require.Len(t, secret, 64)
+
+	// Listing never exposes the secret
+	rec = apiCall(t, f.app, f.api.ListWebhooks, http.MethodGet, roomID, aliceToken, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	listed := decodeBody(t, rec)["webhooks"].([]any)
+	require.Len(t, listed, 1)
+	assert.Nil(t, listed[0].(map[string]any)["secret"])
+
+	// Trigger events: join, votes, reveal, next round
+	rec = apiCall(t, f.app, f.api.JoinRoom, http.MethodPost, roomID, "", `{"name": "Carol"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	apiCall(t, f.app, f.api.CastVote, http.MethodPost, roomID, aliceToken, `{"value": "3"}`)
+	apiCall(t, f.app, f.api.CastVote, http.MethodPost, roomID, bobToken, `{"value": "5"}`)
+	rec = apiCall(t, f.app, f.api.Reveal, http.MethodPost, roomID, aliceToken, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = apiCall(t, f.app, f.api.NextRound, http.MethodPost, roomID, aliceToken, "")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	assert.Equal(t, 3, f.webhooks.ProcessDue())
+
+	requests := receiver.received()
+	require.Len(t, requests, 3)
+	for _, req := range requests {
+		timestamp, err := strconv.ParseInt(req.Header.Get(services.WebhookTimestampHeader), 10, 64)
+		require.NoError(t, err)
+		assert.True(t, services.VerifyWebhookSignature(secret, req.Header.Get(services.WebhookSignatureHeader), timestamp, req.Body),
+			"signature must verify for %s", req.Header.Get(services.WebhookEventHeader))
+		assert.False(t, services.VerifyWebhookSignature("wrong-secret", req.Header.Get(services.WebhookSignatureHeader), timestamp, req.Body))
+		assert.NotEmpty(t, req.Header.Get(services.WebhookDeliveryHeader))
+	}
+
+	events := receiver.events(t)
+	assert.Equal(t, models.WebhookEventParticipantJoined, events[0].Event)
+	assert.Equal(t, models.WebhookEventVotesRevealed, events[1].Event)
+	assert.Equal(t, models.WebhookEventRoundCompleted, events[2].Event)
+	assert.Equal(t, roomID, events[1].RoomID)
+	assert.Equal(t, "Webhook Room", events[1].RoomName)
+
+	revealed := events[1].Data.(map[string]any)
+	assert.Len(t, revealed["votes"], 2)
+	assert.InDelta(t, 4.0, revealed["stats"].(map[string]any)["average"].(float64), 0.001)
+
+	// Delivery log is visible to the facilitator only
+	rec = apiCall(t, f.app, f.api.ListWebhookDeliveries, http.MethodGet, roomID, aliceToken, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	deliveries := decodeBody(t, rec)["deliveries"].([]any)
+	require.Len(t, deliveries, 3)
+	for _, d := range deliveries {
+		delivery := d.(map[string]any)
+		assert.Equal(t, models.DeliveryDelivered, delivery["status"])
+		assert.Equal(t, float64(1), delivery["attempts"])
+		assert.Equal(t, float64(http.StatusOK), delivery["responseStatus"])
+	}
+
+	rec = apiCall(t, f.app, f.api.ListWebhookDeliveries, http.MethodGet, roomID, bobToken, "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, handlers.ErrCodeForbidden, errorCode(t, rec))
+}
+
+func TestWebhooks_RetryWithBackoff(t *testing.T) {
+	f := newWebhookFixture(t)
+	receiver := newWebhookReceiver(t)
+	receiver.failNext = 1
+	roomID, _, _ := f.setupRoom(t)
+
+	_, err := f.webhooks.CreateWebhook(roomID, receiver.URL, []string{models.WebhookEventRoundCompleted})
+	require.NoError(t, err)
+
+	f.webhooks.Dispatch(roomID, models.WebhookEventRoundCompleted, models.RoundCompletedPayload{NewRoundNumber: 2})
+	// Not subscribed: no delivery queued
+	f.webhooks.Dispatch(roomID, models.WebhookEventVotesRevealed, models.VotesRevealedPayload{})
+
+	// First attempt fails and is rescheduled with backoff
+	assert.Equal(t, 1, f.webhooks.ProcessDue())
+	deliveries := f.deliveries(t, roomID)
+	require.Len(t, deliveries, 1)
+	delivery := deliveries[0]
+	assert.Equal(t, models.DeliveryPending, delivery.GetString("status"))
+	assert.Equal(t, 1, delivery.GetInt("attempts"))
+	assert.Equal(t, http.StatusInternalServerError, delivery.GetInt("response_status"))
+	assert.NotEmpty(t, delivery.GetString("last_error"))
+	assert.WithinDuration(t, time.Now().Add(config.WebhookInitialBackoff), delivery.GetDateTime("next_attempt_at").Time(), 2*time.Second)
+
+	// Not due yet
+	assert.Equal(t, 0, f.webhooks.ProcessDue())
+
+	// Once due, the retry succeeds with the same signed body
+	delivery.Set("next_attempt_at", time.Now().Add(-time.Second))
+	require.NoError(t, f.app.Save(delivery))
+	assert.Equal(t, 1, f.webhooks.ProcessDue())
+
+	delivery, err = f.app.FindRecordById("webhook_deliveries", delivery.Id)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryDelivered, delivery.GetString("status"))
+	assert.Equal(t, 2, delivery.GetInt("attempts"))
+
+	requests := receiver.received()
+	require.Len(t, requests, 2)
+	assert.Equal(t, requests[0].Body, requests[1].Body)
+	assert.Equal(t, requests[0].Header.Get(services.WebhookSignatureHeader), requests[1].Header.Get(services.WebhookSignatureHeader))
+}
+
+func TestWebhooks_GiveUpAfterMaxAttempts(t *testing.T) {
+	f := newWebhookFixture(t)
+	receiver := newWebhookReceiver(t)
+	receiver.statusCode = http.StatusBadGateway
+	roomID, _, _ := f.setupRoom(t)
+
+	_, err := f.webhooks.CreateWebhook(roomID, receiver.URL, nil)
+	require.NoError(t, err)
+	f.webhooks.Dispatch(roomID, models.WebhookEventRoomExpired, models.RoomExpiredPayload{Message: "expired"})
+
+	for i := 0; i < config.WebhookMaxAttempts; i++ {
+		delivery := f.deliveries(t, roomID)[0]
+		delivery.Set("next_attempt_at", time.Now().Add(-time.Second))
+		require.NoError(t, f.app.Save(delivery))
+		require.Equal(t, 1, f.webhooks.ProcessDue())
+	}
+
+	delivery := f.deliveries(t, roomID)[0]
+	assert.Equal(t, models.DeliveryFailed, delivery.GetString("status"))
+	assert.Equal(t, config.WebhookMaxAttempts, delivery.GetInt("attempts"))
+	assert.Equal(t, 0, f.webhooks.ProcessDue())
+
+	// Finished deliveries are pruned from the log
+	deleted, err := f.webhooks.PruneDeliveries(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	assert.Empty(t, f.deliveries(t, roomID))
+}
+
+func TestWebhooks_InstanceWebhookReceivesEveryRoom(t *testing.T) {
+	f := newWebhookFixture(t)
+	receiver := newWebhookReceiver(t)
+
+	_, err := f.webhooks.EnsureInstanceWebhook(receiver.URL, "instance-secret", nil)
+	require.NoError(t, err)
+	// Registering again updates instead of duplicating
+	_, err = f.webhooks.EnsureInstanceWebhook(receiver.URL, "instance-secret", nil)
+	require.NoError(t, err)
+
+	first, _, _ := f.setupRoom(t)
+	second, _, _ := f.setupRoom(t)
+	f.webhooks.ProcessDue()
+
+	// Two joins per room
+	events := receiver.events(t)
+	require.Len(t, events, 4)
+	rooms := map[string]int{}
+	for _, event := range events {
+		assert.Equal(t, models.WebhookEventParticipantJoined, event.Event)
+		rooms[event.RoomID]++
+	}
+	assert.Equal(t, 2, rooms[first])
+	assert.Equal(t, 2, rooms[second])
+
+	// Instance webhooks are not listed or removable per room
+	records, err := f.webhooks.GetRoomWebhooks(first)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestWebhooks_InternalTargetsAreRejected(t *testing.T) {
+	f := newWebhookFixture(t)
+	roomID, aliceToken, _ := f.setupRoom(t)
+
+	for _, target := range []string{
+		"http://169.254.169.254/latest/meta-data",
+		"http://127.0.0.1:8090/api",
+		"http://localhost/hook",
+		"http://10.0.0.5/hook",
+		"http://[::1]/hook",
+	} {
+		rec := apiCall(t, f.app, f.api.CreateWebhook, http.MethodPost, roomID, aliceToken, `{"url": "`+target+`"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+		_, err := f.webhooks.EnsureInstanceWebhook(target, "instance-secret", nil)
+		assert.ErrorIs(t, err, security.ErrWebhookTargetForbidden, target)
+	}
+}
+
+func TestWebhooks_DeliveriesRecheckTheTarget(t *testing.T) {
+	f := newWebhookFixture(t)
+	receiver := newWebhookReceiver(t)
+	roomID, _, _ := f.setupRoom(t)
+
+	_, err := f.webhooks.CreateWebhook(roomID, receiver.URL, []string{models.WebhookEventRoundCompleted})
+	require.NoError(t, err)
+
+	// As if the host resolved to an internal address once registered (DNS rebinding)
+	t.Setenv(security.WebhookAllowedHostsEnv, "")
+	f.webhooks.Dispatch(roomID, models.WebhookEventRoundCompleted, models.RoundCompletedPayload{NewRoundNumber: 2})
+	assert.Equal(t, 1, f.webhooks.ProcessDue())
+
+	delivery := f.deliveries(t, roomID)[0]
+	assert.Equal(t, models.DeliveryPending, delivery.GetString("status"))
+	assert.Contains(t, delivery.GetString("last_error"), security.ErrWebhookTargetForbidden.Error())
+	assert.Empty(t, receiver.received())
+}
+
+func TestWebhooks_LongURLErrorsAreRecorded(t *testing.T) {
+	f := newWebhookFixture(t)
+	receiver := newWebhookReceiver(t)
+	roomID, _, _ := f.setupRoom(t)
+
+	// Longer than the last_error column, and the error of a failed request embeds the URL
+	target := receiver.URL + "/hook?token=" + strings.Repeat("s", 1500)
+	_, err := f.webhooks.CreateWebhook(roomID, target, []string{models.WebhookEventRoundCompleted})
+	require.NoError(t, err)
+	receiver.Close()
+
+	f.webhooks.Dispatch(roomID, models.WebhookEventRoundCompleted, models.RoundCompletedPayload{NewRoundNumber: 2})
+	assert.Equal(t, 1, f.webhooks.ProcessDue())
+
+	// The failed attempt is recorded and rescheduled, not retried right away
+	delivery := f.deliveries(t, roomID)[0]
+	assert.Equal(t, models.DeliveryPending, delivery.GetString("status"))
+	assert.Equal(t, 1, delivery.GetInt("attempts"))
+	assert.True(t, delivery.GetDateTime("next_attempt_at").Time().After(time.Now()))
+	lastError := delivery.GetString("last_error")
+	assert.NotEmpty(t, lastError)
+	assert.LessOrEqual(t, len([]rune(lastError)), config.WebhookMaxErrorLength)
+	assert.NotContains(t, lastError, "token=")
+	assert.Equal(t, 0, f.webhooks.ProcessDue())
+}
+
+func TestWebhooks_Registration(t *testing.T) {
+	f := newWebhookFixture(t)
+	roomID, aliceToken, bobToken := f.setupRoom(t)
+
+	t.Run("non-facilitator is forbidden", func(t *testing.T) {
+		rec := apiCall(t, f.app, f.api.CreateWebhook, http.MethodPost, roomID, bobToken, `{"url": "https://example.com/hook"}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("invalid URL and event are rejected", func(t *testing.T) {
+		rec := apiCall(t, f.app, f.api.CreateWebhook, http.MethodPost, roomID, aliceToken, `{"url": "ftp://example.com"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = apiCall(t, f.app, f.api.CreateWebhook, http.MethodPost, roomID, aliceToken, `{"url": "https://example.com", "events": ["vote_cast"]}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("empty events subscribe to everything", func(t *testing.T) {
+		record, err := f.webhooks.CreateWebhook(roomID, "https://example.com/all", nil)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, models.WebhookEvents, services.WebhookEvents(record))
+	})
+
+	t.Run("per-room limit", func(t *testing.T) {
+		for i := 1; i < config.MaxWebhooksPerRoom; i++ {
+			_, err := f.webhooks.CreateWebhook(roomID, "https://example.com/"+strconv.Itoa(i), nil)
+			require.NoError(t, err)
+		}
+		rec := apiCall(t, f.app, f.api.CreateWebhook, http.MethodPost, roomID, aliceToken, `{"url": "https://example.com/extra"}`)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		records, err := f.webhooks.GetRoomWebhooks(roomID)
+		require.NoError(t, err)
+		require.NotEmpty(t, records)
+
+		assert.Error(t, f.webhooks.DeleteWebhook("otherroom123456", records[0].Id))
+		require.NoError(t, f.webhooks.DeleteWebhook(roomID, records[0].Id))
+
+		remaining, err := f.webhooks.GetRoomWebhooks(roomID)
+		require.NoError(t, err)
+		assert.Len(t, remaining, len(records)-1)
+	})
+}
@@ -0,0 +1,69 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/damione1/planning-poker/tests/helpers"
+)
+
+// revealWithWeights reveals a round of two votes, 3 and 5, cast with the given weights
+func revealWithWeights(t *testing.T, first, second float64) models.RevealStats {
+	t.Helper()
+	server := helpers.NewTestServerWithData(t)
+	t.Cleanup(server.Cleanup)
+
+	roomID := helpers.CreateTestRoomWithParticipants(t, server.App, 2, nil)
+	rm := services.NewRoomManager(server.App)
+	ids := participantIDs(t, server.App, roomID)
+	require.NoError(t, rm.UpdateParticipantWeight(ids[0], first))
+	require.NoError(t, rm.UpdateParticipantWeight(ids[1], second))
+
+	hub := services.NewHub(config.Default())
+	go hub.Run()
+	room := serveSSERoom(t, server.App, roomID, hub)
+	creator := room.open(t, "session-1")
+	voter := room.open(t, "session-2")
+
+	for _, command := range []struct {
+		conn          *sseConn
+		session, body string
+	}{
+		{creator, "session-1", `{"type":"vote","payload":{"value":"3"}}`},
+		{voter, "session-2", `{"type":"vote","payload":{"value":"5"}}`},
+		{creator, "session-1", `{"type":"reveal","payload":{}}`},
+	} {
+		require.Equal(t, http.StatusAccepted, room.post(t, command.conn.streamID, command.session, "application/json", command.body))
+	}
+
+	revealed := expectStreamMessage(t, creator.events, models.MsgTypeVotesRevealed)
+	data, err := json.Marshal(revealed.Payload)
+	require.NoError(t, err)
+	var payload models.VotesRevealedPayload
+	require.NoError(t, json.Unmarshal(data, &payload))
+	return payload.Stats
+}
+
+func TestWeightedReveal_WeightedFlag(t *testing.T) {
+	t.Run("weights summing to the vote count", func(t *testing.T) {
+		stats := revealWithWeights(t, 0.5, 1.5)
+		assert.True(t, stats.Weighted)
+		assert.Equal(t, 2.0, stats.TotalWeight)
+		require.NotNil(t, stats.Average)
+		assert.InDelta(t, 4.5, *stats.Average, 0.001) // (3*0.5 + 5*1.5) / 2
+	})
+
+	t.Run("default weights", func(t *testing.T) {
+		stats := revealWithWeights(t, 1, 1)
+		assert.False(t, stats.Weighted)
+		require.NotNil(t, stats.Average)
+		assert.InDelta(t, 4.0, *stats.Average, 0.001)
+	})
+}
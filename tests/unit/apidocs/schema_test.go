@@ -0,0 +1,149 @@
+package apidocs_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/apidocs"
+	"github.com/damione1/planning-poker/internal/models"
+)
+
+type sampleChild struct {
+	Label string `json:"label"`
+}
+
+type sampleEmbedded struct {
+	Shared string `json:"shared"`
+}
+
+type sample struct {
+	sampleEmbedded
+	Name     string             `json:"name"`
+	Count    int                `json:"count,omitempty"`
+	Score    *float64           `json:"score,omitempty"`
+	Tags     []string           `json:"tags"`
+	Weights  map[string]float64 `json:"weights"`
+	Child    sampleChild        `json:"child"`
+	Children []*sampleChild     `json:"children"`
+	At       time.Time          `json:"at"`
+	Raw      json.RawMessage    `json:"raw"`
+	Anything any                `json:"anything"`
+	Hidden   string             `json:"-"`
+	internal string
+}
+
+func TestRegistry_SchemaFor(t *testing.T) {
+	registry := apidocs.NewRegistry()
+
+	ref := registry.SchemaFor(sample{})
+	assert.Equal(t, "#/components/schemas/Sample", ref["$ref"])
+
+	components := registry.Components()
+	require.Contains(t, components, "Sample")
+	require.Contains(t, components, "SampleChild")
+
+	schema := components["Sample"]
+	properties := schema["properties"].(map[string]apidocs.Schema)
+
+	t.Run("maps Go kinds to JSON types", func(t *testing.T) {
+		assert.Equal(t, "string", properties["name"]["type"])
+		assert.Equal(t, "integer", properties["count"]["type"])
+		assert.Equal(t, "number", properties["score"]["type"])
+		assert.Equal(t, true, properties["score"]["nullable"])
+		assert.Equal(t, "array", properties["tags"]["type"])
+		assert.Equal(t, apidocs.Schema{"type": "number"}, properties["weights"]["additionalProperties"])
+		assert.Equal(t, apidocs.Schema{"type": "string", "format": "date-time"}, properties["at"])
+		assert.Equal(t, apidocs.Schema{}, properties["raw"])
+		assert.Equal(t, apidocs.Schema{}, properties["anything"])
+	})
+
+	t.Run("references named structs", func(t *testing.T) {
+		assert.Equal(t, "#/components/schemas/SampleChild", properties["child"]["$ref"])
+		items := properties["children"]["items"].(apidocs.Schema)
+		assert.Equal(t, "#/components/schemas/SampleChild", items["$ref"])
+	})
+
+	t.Run("flattens embedded structs and skips hidden fields", func(t *testing.T) {
+		assert.Contains(t, properties, "shared")
+		assert.NotContains(t, properties, "-")
+		assert.NotContains(t, properties, "Hidden")
+		assert.NotContains(t, properties, "internal")
+	})
+
+	t.Run("omitempty fields are optional", func(t *testing.T) {
+		required := schema["required"].([]string)
+		assert.Contains(t, required, "name")
+		assert.Contains(t, required, "shared")
+		assert.NotContains(t, required, "count")
+		assert.NotContains(t, required, "score")
+	})
+}
+
+func TestRegistry_NilValue(t *testing.T) {
+	assert.Nil(t, apidocs.NewRegistry().SchemaFor(nil))
+}
+
+type apiWidget struct{}
+
+func TestComponentName(t *testing.T) {
+	assert.Equal(t, "APIWidget", apidocs.ComponentName(reflect.TypeOf(apiWidget{})))
+	assert.Equal(t, "RoomConfig", apidocs.ComponentName(reflect.TypeOf(models.RoomConfig{})))
+}
+
+func TestOpenAPI(t *testing.T) {
+	doc := apidocs.OpenAPI(apidocs.Info{Title: "Test", Version: "1.0"}, []apidocs.Operation{
+		{Method: "POST", Path: "/rooms/{id}/votes", Summary: "Vote", Request: models.VotePayload{}, Headers: []string{"X-Token"}},
+		{Method: "GET", Path: "/rooms/{id}", Summary: "Get", Response: models.RoomConfig{}},
+		{Method: "GET", Path: "/metrics", Summary: "Metrics", Response: "", ContentType: "text/plain"},
+	})
+
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	paths := doc["paths"].(map[string]map[string]any)
+
+	post := paths["/rooms/{id}/votes"]["post"].(map[string]any)
+	assert.Equal(t, "post_rooms_id_votes", post["operationId"])
+	assert.Contains(t, post, "requestBody")
+	assert.Contains(t, post["responses"], "204")
+	assert.Len(t, post["parameters"], 2)
+
+	get := paths["/rooms/{id}"]["get"].(map[string]any)
+	assert.Contains(t, get["responses"], "200")
+	assert.NotContains(t, get, "requestBody")
+
+	metrics := paths["/metrics"]["get"].(map[string]any)["responses"].(map[string]any)["200"].(map[string]any)
+	assert.Contains(t, metrics["content"], "text/plain")
+	assert.NotContains(t, metrics["content"], "application/json")
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]apidocs.Schema)
+	assert.Contains(t, schemas, "VotePayload")
+	assert.Contains(t, schemas, "RoomConfig")
+	assert.Contains(t, schemas, "RevealRule")
+
+	_, err := json.Marshal(doc)
+	assert.NoError(t, err)
+}
+
+func TestAsyncAPI(t *testing.T) {
+	doc := apidocs.AsyncAPI(apidocs.Info{Title: "Test", Version: "1.0"}, "/ws/{roomId}",
+		[]apidocs.Message{{Type: models.MsgTypeVote, Payload: models.VotePayload{}}},
+		[]apidocs.Message{{Type: models.MsgTypeVoteCast, Payload: models.VoteCastPayload{}}},
+	)
+
+	assert.Equal(t, "2.6.0", doc["asyncapi"])
+	channel := doc["channels"].(map[string]any)["/ws/{roomId}"].(map[string]any)
+	assert.Contains(t, channel["parameters"], "roomId")
+
+	messages := doc["components"].(map[string]any)["messages"].(map[string]any)
+	require.Contains(t, messages, models.MsgTypeVote)
+	require.Contains(t, messages, models.MsgTypeVoteCast)
+
+	envelope := messages[models.MsgTypeVote].(map[string]any)["payload"].(apidocs.Schema)
+	properties := envelope["properties"].(map[string]apidocs.Schema)
+	assert.Equal(t, []string{models.MsgTypeVote}, properties["type"]["enum"])
+	assert.Equal(t, "#/components/schemas/VotePayload", properties["payload"]["$ref"])
+}
@@ -0,0 +1,84 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/handlers"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/security"
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+func serveDoc(t *testing.T, handler func(*core.RequestEvent) error) map[string]any {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/monitoring/doc.json", nil)
+	require.NoError(t, handler(&core.RequestEvent{Event: router.Event{Response: rec, Request: req}}))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	return doc
+}
+
+func TestDocs_OpenAPICoversRoutes(t *testing.T) {
+	api := handlers.NewAPIHandlers(nil, nil, nil, nil)
+	routes := append(api.Routes(), handlers.MonitoringRoutes(nil, services.NewHub(config.Default()))...)
+	docs := handlers.NewDocsHandlers("test", routes)
+
+	doc := serveDoc(t, docs.OpenAPI)
+	paths := doc["paths"].(map[string]any)
+
+	for _, route := range routes {
+		assert.NotNil(t, route.Handler, route.Path)
+		item, ok := paths[route.Path].(map[string]any)
+		require.True(t, ok, "missing path %s", route.Path)
+		assert.Contains(t, item, map[string]string{
+			http.MethodGet: "get", http.MethodPost: "post", http.MethodDelete: "delete",
+		}[route.Method], route.Path)
+	}
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	assert.Contains(t, schemas, "APIRoom")
+	assert.Contains(t, schemas, "APIErrorEnvelope")
+	assert.Contains(t, schemas, "MetricsSnapshot")
+}
+
+func TestDocs_AsyncAPICoversMessages(t *testing.T) {
+	docs := handlers.NewDocsHandlers("test", nil)
+
+	doc := serveDoc(t, docs.AsyncAPI)
+	messages := doc["components"].(map[string]any)["messages"].(map[string]any)
+
+	clientTypes := []string{
+		models.MsgTypeVote, models.MsgTypeUnvote, models.MsgTypeReveal, models.MsgTypeReset,
+		models.MsgTypeNextRound, models.MsgTypeUpdateName, models.MsgTypeUpdateRoomName,
+		models.MsgTypeUpdateConfig, models.MsgTypeUpdateWeight,
+	}
+	for _, msgType := range clientTypes {
+		assert.True(t, security.IsValidMessageType(msgType), msgType)
+		assert.Contains(t, messages, msgType)
+	}
+
+	serverTypes := []string{
+		models.MsgTypeRoomState, models.MsgTypeParticipantJoined, models.MsgTypeParticipantLeft,
+		models.MsgTypeVoteCast, models.MsgTypeVotesRevealed, models.MsgTypeVoteUpdated,
+		models.MsgTypeRoomReset, models.MsgTypeRoundCompleted, models.MsgTypeNameUpdated,
+		models.MsgTypeRoomNameUpdated, models.MsgTypeConfigUpdated, models.MsgTypeWeightUpdated,
+		models.MsgTypeAutoRevealCountdown, models.MsgTypeAutoRevealCancelled,
+		models.MsgTypeRoomExpired, models.MsgTypeAck, models.MsgTypeError,
+	}
+	for _, msgType := range serverTypes {
+		assert.Contains(t, messages, msgType)
+	}
+}
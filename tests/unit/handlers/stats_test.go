@@ -124,3 +124,52 @@ func TestCalculateStats_ConsensusDetection(t *testing.T) {
 		assert.InDelta(t, 0.5, stats["average"].(float64), 0.01)
 	})
 }
+
+func TestCalculateWeightedStats(t *testing.T) {
+	t.Run("weights agreement and average", func(t *testing.T) {
+		votes := map[string]string{
+			"alice": "8",
+			"bob":   "2",
+			"carol": "2",
+		}
+		weights := map[string]float64{"alice": 3, "bob": 1, "carol": 1}
+
+		stats := handlers.CalculateWeightedStatsForTest(votes, weights)
+
+		assert.NotNil(t, stats)
+		assert.Equal(t, 3, stats["total"])
+		// alice holds 3 of 5 weight units
+		assert.InDelta(t, 60.0, stats["agreementPercentage"].(float64), 0.01)
+		assert.Equal(t, "8", stats["mostCommonValue"])
+		assert.Equal(t, false, stats["consensus"])
+		// (8*3 + 2 + 2) / 5 = 5.6
+		assert.InDelta(t, 5.6, stats["average"].(float64), 0.01)
+		assert.Equal(t, true, stats["weighted"])
+		assert.Equal(t, 5.0, stats["totalWeight"])
+	})
+
+	t.Run("missing weights count as default", func(t *testing.T) {
+		votes := map[string]string{
+			"alice": "5",
+			"bob":   "3",
+		}
+
+		stats := handlers.CalculateWeightedStatsForTest(votes, map[string]float64{"alice": 1})
+
+		assert.InDelta(t, 4.0, stats["average"].(float64), 0.01)
+		assert.Nil(t, stats["weighted"])
+	})
+
+	t.Run("weighted unanimous vote is consensus", func(t *testing.T) {
+		votes := map[string]string{
+			"alice": "5",
+			"bob":   "5",
+		}
+		weights := map[string]float64{"alice": 0.5, "bob": 2}
+
+		stats := handlers.CalculateWeightedStatsForTest(votes, weights)
+
+		assert.Equal(t, 100.0, stats["agreementPercentage"])
+		assert.Equal(t, true, stats["consensus"])
+	})
+}
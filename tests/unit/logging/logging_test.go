@@ -0,0 +1,115 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/logging"
+)
+
+// decode returns the single JSON log line written to buf
+func decode(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	return line
+}
+
+func TestNew_Level(t *testing.T) {
+	tests := []struct {
+		level string
+		debug bool
+		info  bool
+		warn  bool
+	}{
+		{"", false, true, true},
+		{"debug", true, true, true},
+		{"INFO", false, true, true},
+		{"warning", false, false, true},
+		{"error", false, false, false},
+		{"unknown", false, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			logger := logging.New(&bytes.Buffer{}, tt.level, "text")
+			ctx := t.Context()
+			assert.Equal(t, tt.debug, logger.Enabled(ctx, slog.LevelDebug))
+			assert.Equal(t, tt.info, logger.Enabled(ctx, slog.LevelInfo))
+			assert.Equal(t, tt.warn, logger.Enabled(ctx, slog.LevelWarn))
+		})
+	}
+}
+
+func TestNew_Format(t *testing.T) {
+	var buf bytes.Buffer
+	logging.New(&buf, "info", "json").Info("Client registered", logging.RoomID, "room-1")
+	line := decode(t, &buf)
+	assert.Equal(t, "Client registered", line["msg"])
+	assert.Equal(t, "room-1", line[logging.RoomID])
+
+	buf.Reset()
+	logging.New(&buf, "info", "text").Info("Client registered", logging.RoomID, "room-1")
+	assert.Contains(t, buf.String(), `msg="Client registered" room_id=room-1`)
+}
+
+func TestNew_RedactsSensitiveAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logging.New(&buf, "info", "json").Info("Vote received",
+		"value", "13",
+		"session_cookie", "abc123",
+		"Token", "secret-token",
+		logging.ParticipantID, "p1",
+	)
+
+	line := decode(t, &buf)
+	assert.Equal(t, "[REDACTED]", line["value"])
+	assert.Equal(t, "[REDACTED]", line["session_cookie"])
+	assert.Equal(t, "[REDACTED]", line["Token"])
+	assert.Equal(t, "p1", line[logging.ParticipantID])
+	assert.NotContains(t, buf.String(), "abc123")
+}
+
+func TestErr_RedactsURL(t *testing.T) {
+	err := &url.Error{
+		Op:  "Post",
+		URL: "https://hooks.slack.com/services/T000/B000/XXXX",
+		Err: errors.New("connection refused"),
+	}
+
+	attr := logging.Err(err)
+	assert.Equal(t, logging.Error, attr.Key)
+	assert.NotContains(t, attr.Value.String(), "hooks.slack.com")
+	assert.Contains(t, attr.Value.String(), "connection refused")
+
+	plain := logging.Err(errors.New("record not found"))
+	assert.Equal(t, "record not found", plain.Value.String())
+}
+
+func TestForCommand_AttachesAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&buf, "info", "json"))
+	defer slog.SetDefault(previous)
+
+	logging.ForCommand("room-1", "p1", "vote").Info("Command handled")
+	line := decode(t, &buf)
+	assert.Equal(t, "room-1", line[logging.RoomID])
+	assert.Equal(t, "p1", line[logging.ParticipantID])
+	assert.Equal(t, "vote", line[logging.MessageType])
+
+	// Unset values are left out rather than logged empty
+	buf.Reset()
+	logging.ForRoom("room-1", "").Info("Room loaded")
+	line = decode(t, &buf)
+	assert.Equal(t, "room-1", line[logging.RoomID])
+	assert.NotContains(t, line, logging.ParticipantID)
+	assert.NotContains(t, line, logging.MessageType)
+}
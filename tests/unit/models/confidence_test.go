@@ -0,0 +1,34 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeConfidence(t *testing.T) {
+	t.Run("returns nil when no vote has a confidence level", func(t *testing.T) {
+		assert.Nil(t, models.SummarizeConfidence(nil))
+		assert.Nil(t, models.SummarizeConfidence([]int{models.ConfidenceNone, models.ConfidenceNone}))
+	})
+
+	t.Run("ignores votes without confidence", func(t *testing.T) {
+		summary := models.SummarizeConfidence([]int{models.ConfidenceHigh, models.ConfidenceNone, models.ConfidenceMedium})
+
+		assert.NotNil(t, summary)
+		assert.Equal(t, 2, summary.Count)
+		assert.Equal(t, 4.0, summary.Average)
+		assert.Equal(t, 1, summary.Breakdown[models.ConfidenceHigh])
+		assert.Equal(t, 1, summary.Breakdown[models.ConfidenceMedium])
+		assert.False(t, summary.Low)
+	})
+
+	t.Run("flags low average confidence", func(t *testing.T) {
+		summary := models.SummarizeConfidence([]int{models.ConfidenceLow, 2, models.ConfidenceMedium})
+
+		assert.NotNil(t, summary)
+		assert.Equal(t, 2.0, summary.Average)
+		assert.True(t, summary.Low)
+	})
+}
@@ -0,0 +1,50 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevealRule_IsSatisfied(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  models.RevealRule
+		tally models.VoteTally
+		want  bool
+	}{
+		{"all: everyone voted", models.RevealRule{Mode: models.RevealModeAll}, models.VoteTally{Voters: 3, ConnectedVoters: 3, Votes: 3, ConnectedVotes: 3}, true},
+		{"all: one missing", models.RevealRule{Mode: models.RevealModeAll}, models.VoteTally{Voters: 3, ConnectedVoters: 2, Votes: 2, ConnectedVotes: 2}, false},
+		{"empty mode behaves like all", models.RevealRule{}, models.VoteTally{Voters: 2, ConnectedVoters: 2, Votes: 2, ConnectedVotes: 2}, true},
+		{"all_connected: disconnected voter ignored", models.RevealRule{Mode: models.RevealModeAllConnected}, models.VoteTally{Voters: 3, ConnectedVoters: 2, Votes: 2, ConnectedVotes: 2}, true},
+		{"all_connected: connected voter missing", models.RevealRule{Mode: models.RevealModeAllConnected}, models.VoteTally{Voters: 3, ConnectedVoters: 3, Votes: 2, ConnectedVotes: 2}, false},
+		{"all_connected: nobody connected", models.RevealRule{Mode: models.RevealModeAllConnected}, models.VoteTally{Voters: 2, ConnectedVoters: 0, Votes: 1, ConnectedVotes: 0}, false},
+		{"quorum: reached", models.RevealRule{Mode: models.RevealModeQuorum, Percentage: 75}, models.VoteTally{Voters: 4, ConnectedVoters: 4, Votes: 3, ConnectedVotes: 3}, true},
+		{"quorum: not reached", models.RevealRule{Mode: models.RevealModeQuorum, Percentage: 75}, models.VoteTally{Voters: 5, ConnectedVoters: 5, Votes: 3, ConnectedVotes: 3}, false},
+		{"minimum: reached", models.RevealRule{Mode: models.RevealModeMinimum, MinimumVotes: 2}, models.VoteTally{Voters: 6, ConnectedVoters: 6, Votes: 2, ConnectedVotes: 2}, true},
+		{"minimum: not reached", models.RevealRule{Mode: models.RevealModeMinimum, MinimumVotes: 3}, models.VoteTally{Voters: 6, ConnectedVoters: 6, Votes: 2, ConnectedVotes: 2}, false},
+		{"no votes never satisfies", models.RevealRule{Mode: models.RevealModeAllConnected}, models.VoteTally{Voters: 2}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.rule.IsSatisfied(tt.tally))
+		})
+	}
+}
+
+func TestRoomConfig_GetRevealRule(t *testing.T) {
+	t.Run("defaults to all voters", func(t *testing.T) {
+		config := models.DefaultRoomConfig()
+
+		assert.Equal(t, models.RevealModeAll, config.GetRevealRule().Mode)
+	})
+
+	t.Run("returns configured rule", func(t *testing.T) {
+		config := models.DefaultRoomConfig()
+		config.RevealRule = models.RevealRule{Mode: models.RevealModeQuorum, Percentage: 60}
+
+		assert.Equal(t, config.RevealRule, config.GetRevealRule())
+	})
+}
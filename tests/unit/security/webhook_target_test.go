@@ -0,0 +1,42 @@
+package security_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/security"
+)
+
+func TestCheckWebhookTarget(t *testing.T) {
+	ctx := context.Background()
+
+	assert.NoError(t, security.CheckWebhookTarget(ctx, "https://93.184.216.34/hook"))
+	assert.ErrorIs(t, security.CheckWebhookTarget(ctx, "http://169.254.169.254/"), security.ErrWebhookTargetForbidden)
+	assert.ErrorIs(t, security.CheckWebhookTarget(ctx, "http://localhost:8090/"), security.ErrWebhookTargetForbidden)
+	// Unresolvable hosts are left to the delivery, which checks what it connects to
+	assert.NoError(t, security.CheckWebhookTarget(ctx, "https://planning-poker.invalid/hook"))
+}
+
+func TestWebhookDialContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	transport := &http.Transport{DialContext: security.WebhookDialContext(&net.Dialer{})}
+	client := &http.Client{Transport: transport}
+	defer transport.CloseIdleConnections()
+
+	_, err := client.Get(server.URL)
+	assert.ErrorIs(t, err, security.ErrWebhookTargetForbidden, "loopback is refused when connecting")
+
+	t.Setenv(security.WebhookAllowedHostsEnv, "127.0.0.0/8")
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
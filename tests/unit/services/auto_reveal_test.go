@@ -1,6 +1,7 @@
 package services_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/damione1/planning-poker/internal/models"
@@ -171,7 +172,7 @@ func TestAutoRevealConfig_UpdateConfig(t *testing.T) {
 	newConfig := models.DefaultRoomConfig()
 	newConfig.Permissions.AutoReveal = true
 
-	err := aclService.UpdateRoomConfig(room.Id, creator.Id, newConfig)
+	err := aclService.UpdateRoomConfig(context.Background(), room.Id, creator.Id, newConfig)
 	assert.NoError(t, err)
 
 	// Verify config was updated
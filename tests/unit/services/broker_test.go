@@ -0,0 +1,52 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+func TestMemoryBroker(t *testing.T) {
+	broker := services.NewMemoryBroker()
+
+	var first, second, other []string
+	sub1, err := broker.Subscribe("room-1", func(message []byte) { first = append(first, string(message)) })
+	require.NoError(t, err)
+	_, err = broker.Subscribe("room-1", func(message []byte) { second = append(second, string(message)) })
+	require.NoError(t, err)
+	_, err = broker.Subscribe("room-2", func(message []byte) { other = append(other, string(message)) })
+	require.NoError(t, err)
+
+	require.NoError(t, broker.Publish("room-1", []byte("a")))
+	assert.Equal(t, []string{"a"}, first)
+	assert.Equal(t, []string{"a"}, second)
+	assert.Empty(t, other, "messages are keyed by room")
+
+	require.NoError(t, sub1.Unsubscribe())
+	require.NoError(t, sub1.Unsubscribe(), "unsubscribing twice is harmless")
+	require.NoError(t, broker.Publish("room-1", []byte("b")))
+	assert.Equal(t, []string{"a"}, first)
+	assert.Equal(t, []string{"a", "b"}, second)
+
+	require.NoError(t, broker.Publish("room-3", []byte("nobody listens")))
+}
+
+func TestMemoryBroker_CountConnections(t *testing.T) {
+	broker := services.NewMemoryBroker()
+
+	count := func(roomID, participantID string, delta int) int {
+		n, err := broker.CountConnections(roomID, participantID, delta)
+		require.NoError(t, err)
+		return n
+	}
+	assert.Equal(t, 1, count("room-1", "p1", 1))
+	assert.Equal(t, 2, count("room-1", "p1", 1))
+	assert.Equal(t, 1, count("room-2", "p1", 1), "rooms count apart")
+	assert.Equal(t, 1, count("room-1", "p1", -1))
+	assert.Equal(t, 0, count("room-1", "p1", -1))
+	assert.Equal(t, 0, count("room-1", "p1", -1), "never below zero")
+	assert.Equal(t, 1, count("room-1", "p1", 1))
+}
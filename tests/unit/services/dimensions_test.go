@@ -0,0 +1,113 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func testDimensions() []models.EstimationDimension {
+	return []models.EstimationDimension{
+		{Name: "Complexity", Values: []string{"1", "2", "3", "5", "8"}, Weight: 2},
+		{Name: "Effort", Values: []string{"1", "2", "3", "5", "8"}},
+	}
+}
+
+func TestVoteValidator_ParseDimensions(t *testing.T) {
+	v := services.NewVoteValidator()
+
+	t.Run("parses one dimension per line", func(t *testing.T) {
+		dims, err := v.ParseDimensions("Complexity: 1, 2, 3\nEffort: S, M, L\n")
+
+		assert.NoError(t, err)
+		assert.Len(t, dims, 2)
+		assert.Equal(t, "Complexity", dims[0].Name)
+		assert.Equal(t, []string{"1", "2", "3"}, dims[0].Values)
+		assert.Equal(t, "Effort", dims[1].Name)
+		assert.Equal(t, []string{"S", "M", "L"}, dims[1].Values)
+	})
+
+	t.Run("rejects line without separator", func(t *testing.T) {
+		_, err := v.ParseDimensions("Complexity 1, 2, 3\nEffort: 1, 2")
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects single dimension", func(t *testing.T) {
+		_, err := v.ParseDimensions("Complexity: 1, 2, 3")
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects duplicate dimension", func(t *testing.T) {
+		_, err := v.ParseDimensions("Effort: 1, 2\nEffort: 3, 5")
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects dimensions without numeric values", func(t *testing.T) {
+		_, err := v.ParseDimensions("Effort: S, M, L\nRisk: low, high")
+		assert.Error(t, err)
+	})
+}
+
+func TestVoteValidator_ValidateDimensionVotes(t *testing.T) {
+	v := services.NewVoteValidator()
+	dims := testDimensions()
+
+	assert.NoError(t, v.ValidateDimensionVotes(map[string]string{"Complexity": "3", "Effort": "?"}, dims))
+	assert.Error(t, v.ValidateDimensionVotes(map[string]string{"Complexity": "3"}, dims), "missing dimension")
+	assert.Error(t, v.ValidateDimensionVotes(map[string]string{"Complexity": "4", "Effort": "3"}, dims), "value not in deck")
+	assert.Error(t, v.ValidateDimensionVotes(map[string]string{"Complexity": "3", "Risk": "3"}, dims), "unknown dimension")
+}
+
+func TestVoteValidator_CombineDimensionScores(t *testing.T) {
+	v := services.NewVoteValidator()
+	dims := testDimensions()
+	votes := map[string]string{"Complexity": "8", "Effort": "2"}
+
+	tests := []struct {
+		method string
+		want   float64
+	}{
+		{models.CombineAverage, 5},
+		{models.CombineSum, 10},
+		{models.CombineMax, 8},
+		{models.CombineWeighted, 6}, // (8*2 + 2*1) / 3
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			got, ok := v.CombineDimensionScores(votes, dims, tt.method)
+			assert.True(t, ok)
+			assert.InDelta(t, tt.want, got, 0.001)
+		})
+	}
+
+	t.Run("ignores non-numeric values", func(t *testing.T) {
+		got, ok := v.CombineDimensionScores(map[string]string{"Complexity": "?", "Effort": "3"}, dims, models.CombineSum)
+		assert.True(t, ok)
+		assert.Equal(t, 3.0, got)
+	})
+
+	t.Run("no numeric values", func(t *testing.T) {
+		_, ok := v.CombineDimensionScores(map[string]string{"Complexity": "?", "Effort": "☕"}, dims, models.CombineSum)
+		assert.False(t, ok)
+	})
+}
+
+func TestVoteValidator_CalculateDimensionStats(t *testing.T) {
+	v := services.NewVoteValidator()
+
+	stats := v.CalculateDimensionStats([]map[string]string{
+		{"Complexity": "3", "Effort": "5"},
+		{"Complexity": "3", "Effort": "8"},
+	}, testDimensions())
+
+	assert.Len(t, stats, 2)
+	assert.Equal(t, "Complexity", stats[0].Name)
+	assert.Equal(t, 2, stats[0].Total)
+	assert.True(t, stats[0].Consensus)
+	assert.InDelta(t, 3.0, *stats[0].Average, 0.001)
+	assert.False(t, stats[1].Consensus)
+	assert.InDelta(t, 6.5, *stats[1].Average, 0.001)
+}
@@ -0,0 +1,230 @@
+package services_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+// githubStub is a stubbed GitHub API recording the requests it receives
+type githubStub struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string          // "METHOD path"
+	bodies   []json.RawMessage // Request bodies, in order
+	handle   func(w http.ResponseWriter, r *http.Request, body []byte)
+}
+
+func newGitHubStub(t *testing.T, handle func(w http.ResponseWriter, r *http.Request, body []byte)) *githubStub {
+	t.Helper()
+	stub := &githubStub{handle: handle}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer gh-token", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+
+		stub.mu.Lock()
+		stub.requests = append(stub.requests, r.Method+" "+r.URL.Path)
+		stub.bodies = append(stub.bodies, body)
+		stub.mu.Unlock()
+
+		stub.handle(w, r, body)
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func (s *githubStub) tracker(cfg services.GitHubConfig) *services.GitHubTracker {
+	cfg.BaseURL = s.URL
+	cfg.Token = "gh-token"
+	cfg.RetryBackoff = time.Millisecond
+	return services.NewGitHubTracker(cfg)
+}
+
+func (s *githubStub) recorded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func TestGitHubTrackerSearchIssues(t *testing.T) {
+	var gotQuery string
+	stub := newGitHubStub(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		gotQuery = r.URL.Query().Get("q")
+		_, _ = w.Write([]byte(`{"items": [
+			{"number": 12, "title": "Dark mode", "html_url": "https://github.com/acme/app/issues/12", "repository_url": "` + "http://" + r.Host + `/repos/acme/app"}
+		]}`))
+	})
+
+	tracker := stub.tracker(services.GitHubConfig{Repo: "acme/app"})
+	issues, err := tracker.SearchIssues(`label:ready milestone:"Sprint 3"`, 20)
+	require.NoError(t, err)
+
+	assert.Equal(t, `repo:acme/app label:ready milestone:"Sprint 3" is:issue`, gotQuery)
+	assert.Equal(t, []models.Issue{{Key: "acme/app#12", Title: "Dark mode", URL: "https://github.com/acme/app/issues/12"}}, issues)
+
+	// A repo in the query replaces the default one
+	_, err = tracker.SearchIssues("repo:acme/api is:issue is:open", 20)
+	require.NoError(t, err)
+	assert.Equal(t, "repo:acme/api is:issue is:open", gotQuery)
+}
+
+func TestGitHubTrackerSetEstimateLabel(t *testing.T) {
+	labels := `[{"name": "bug"}]`
+	stub := newGitHubStub(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"labels": ` + labels + `}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`[]`))
+	})
+	tracker := stub.tracker(services.GitHubConfig{})
+
+	require.NoError(t, tracker.SetEstimate("acme/app#12", 5))
+	assert.Equal(t, []string{"GET /repos/acme/app/issues/12", "POST /repos/acme/app/issues/12/labels"}, stub.recorded())
+	assert.JSONEq(t, `{"labels": ["points:5"]}`, string(stub.bodies[1]))
+
+	// The same estimate is not written twice
+	labels = `[{"name": "points:5"}]`
+	require.NoError(t, tracker.SetEstimate("acme/app#12", 5))
+	assert.Len(t, stub.recorded(), 3)
+
+	// A different estimate is reported as a conflict and kept
+	labels = `[{"name": "points:3"}]`
+	err := tracker.SetEstimate("acme/app#12", 2.5)
+	var conflict *services.EstimateConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "points:3", conflict.Current)
+	assert.Equal(t, "points:2.5", conflict.Proposed)
+	assert.Len(t, stub.recorded(), 4, "no label is added on conflict")
+
+	assert.Error(t, tracker.SetEstimate("not-a-key", 5))
+}
+
+func TestGitHubTrackerRetries(t *testing.T) {
+	failures := 2
+	stub := newGitHubStub(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"items": []}`))
+	})
+
+	tracker := stub.tracker(services.GitHubConfig{Repo: "acme/app", MaxAttempts: 3})
+	_, err := tracker.SearchIssues("label:ready", 10)
+	require.NoError(t, err, "server errors are retried")
+	assert.Len(t, stub.recorded(), 3)
+
+	// Rate limits are retried too, until attempts run out
+	limited := newGitHubStub(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+	})
+	_, err = limited.tracker(services.GitHubConfig{MaxAttempts: 2}).SearchIssues("repo:acme/app", 10)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "after 2 attempts")
+	assert.Contains(t, err.Error(), "API rate limit exceeded")
+	assert.Len(t, limited.recorded(), 2)
+
+	// Client errors are not retried
+	invalid := newGitHubStub(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"message": "Validation Failed"}`))
+	})
+	_, err = invalid.tracker(services.GitHubConfig{MaxAttempts: 3}).SearchIssues("repo:acme/app", 10)
+	require.EqualError(t, err, "github returned 422: Validation Failed")
+	assert.Len(t, invalid.recorded(), 1)
+}
+
+func TestGitHubTrackerSetEstimateField(t *testing.T) {
+	currentValue := "null"
+	stub := newGitHubStub(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		require.Equal(t, "/graphql", r.URL.Path)
+		if strings.Contains(string(body), "mutation") {
+			_, _ = w.Write([]byte(`{"data": {"updateProjectV2ItemFieldValue": {"projectV2Item": {"id": "ITEM_2"}}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"projectItems": {"nodes": [
+			{"id": "ITEM_1", "project": {"id": "PROJ_1", "number": 1, "field": null}, "fieldValueByName": null},
+			{"id": "ITEM_2", "project": {"id": "PROJ_7", "number": 7, "field": {"id": "FIELD_9", "dataType": "NUMBER"}},
+			 "fieldValueByName": ` + currentValue + `}
+		]}}}}}`))
+	})
+	tracker := stub.tracker(services.GitHubConfig{EstimateMode: services.GitHubEstimateField, ProjectNumber: 7})
+
+	require.NoError(t, tracker.SetEstimate("acme/app#12", 8))
+	require.Len(t, stub.recorded(), 2)
+
+	var query struct {
+		Variables map[string]any `json:"variables"`
+	}
+	require.NoError(t, json.Unmarshal(stub.bodies[0], &query))
+	assert.Equal(t, map[string]any{"owner": "acme", "name": "app", "number": 12.0, "field": "Estimate"}, query.Variables)
+
+	var mutation struct {
+		Variables map[string]any `json:"variables"`
+	}
+	require.NoError(t, json.Unmarshal(stub.bodies[1], &mutation))
+	assert.Equal(t, map[string]any{"project": "PROJ_7", "item": "ITEM_2", "field": "FIELD_9", "value": 8.0}, mutation.Variables)
+
+	// An existing different value is a conflict
+	currentValue = `{"number": 5}`
+	var conflict *services.EstimateConflictError
+	require.ErrorAs(t, tracker.SetEstimate("acme/app#12", 8), &conflict)
+	assert.Equal(t, "Estimate=5", conflict.Current)
+	assert.Len(t, stub.recorded(), 3, "no mutation on conflict")
+
+	// Issues outside the project are reported
+	other := stub.tracker(services.GitHubConfig{EstimateMode: services.GitHubEstimateField, ProjectNumber: 3})
+	assert.ErrorContains(t, other.SetEstimate("acme/app#12", 8), "not in project 3")
+}
+
+func TestGitHubTrackerGraphQLEndpoint(t *testing.T) {
+	// GitHub Enterprise Server serves REST at /api/v3 and GraphQL at /api/graphql
+	stub := newGitHubStub(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		switch r.URL.Path {
+		case "/api/graphql", "/custom/graphql":
+			_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"projectItems": {"nodes": [
+				{"id": "ITEM_2", "project": {"id": "PROJ_7", "number": 7, "field": {"id": "FIELD_9", "dataType": "NUMBER"}},
+				 "fieldValueByName": {"number": 8}}
+			]}}}}}`))
+		case "/api/v3/search/issues":
+			_, _ = w.Write([]byte(`{"items": []}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	field := services.GitHubConfig{Token: "gh-token", EstimateMode: services.GitHubEstimateField, ProjectNumber: 7}
+
+	enterprise := field
+	enterprise.BaseURL = stub.URL + "/api/v3/"
+	tracker := services.NewGitHubTracker(enterprise)
+	_, err := tracker.SearchIssues("repo:acme/app", 10)
+	require.NoError(t, err)
+	require.NoError(t, tracker.SetEstimate("acme/app#12", 8))
+
+	explicit := field
+	explicit.BaseURL = stub.URL + "/api/v3"
+	explicit.GraphQLURL = stub.URL + "/custom/graphql"
+	require.NoError(t, services.NewGitHubTracker(explicit).SetEstimate("acme/app#12", 8))
+
+	assert.Equal(t, []string{
+		"GET /api/v3/search/issues",
+		"POST /api/graphql",
+		"POST /custom/graphql",
+	}, stub.recorded())
+}
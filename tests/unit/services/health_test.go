@@ -0,0 +1,94 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+func TestMetrics_HealthThresholds(t *testing.T) {
+	t.Run("thresholds follow the connection limits", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Limits.MaxTotalConnections = 500
+		cfg.Limits.MaxRoomsPerInstance = 20
+		cfg.Health.WarningErrors = 7
+
+		thresholds := services.NewHealthThresholds(cfg)
+		assert.Equal(t, int64(500), thresholds.MaxConnections)
+		assert.Equal(t, int64(20), thresholds.MaxRooms)
+		assert.Equal(t, int64(7), thresholds.WarningErrors)
+		assert.Equal(t, cfg.Health.ErrorWindow, thresholds.ErrorWindow)
+	})
+
+	t.Run("capacity", func(t *testing.T) {
+		metrics := services.NewMetrics()
+		thresholds := services.DefaultHealthThresholds()
+		thresholds.MaxConnections = 10
+		metrics.SetHealthThresholds(thresholds)
+
+		for i := 0; i < 8; i++ {
+			metrics.IncrementConnections()
+		}
+		assert.Equal(t, "healthy", metrics.Snapshot().HealthStatus)
+
+		metrics.IncrementConnections()
+		assert.Equal(t, "warning", metrics.Snapshot().HealthStatus)
+
+		metrics.IncrementConnections()
+		assert.Equal(t, "critical", metrics.Snapshot().HealthStatus)
+
+		metrics.DecrementConnections()
+		metrics.DecrementConnections()
+		assert.Equal(t, "healthy", metrics.Snapshot().HealthStatus)
+	})
+
+	t.Run("rooms", func(t *testing.T) {
+		metrics := services.NewMetrics()
+		thresholds := services.DefaultHealthThresholds()
+		thresholds.MaxRooms = 10
+		metrics.SetHealthThresholds(thresholds)
+
+		for i := 0; i < 10; i++ {
+			metrics.IncrementRooms()
+		}
+		assert.Equal(t, "critical", metrics.Snapshot().HealthStatus)
+	})
+}
+
+func TestMetrics_ErrorsDecay(t *testing.T) {
+	metrics := services.NewMetrics()
+	thresholds := services.DefaultHealthThresholds()
+	thresholds.ErrorWindow = 200 * time.Millisecond
+	thresholds.WarningErrors = 2
+	metrics.SetHealthThresholds(thresholds)
+
+	metrics.IncrementConnectionErrors()
+	metrics.IncrementBroadcastErrors()
+	assert.Equal(t, "healthy", metrics.Snapshot().HealthStatus)
+
+	metrics.IncrementBroadcastErrors()
+	snapshot := metrics.Snapshot()
+	assert.Equal(t, "warning", snapshot.HealthStatus)
+	assert.Equal(t, int64(3), snapshot.RecentErrors)
+
+	// Once the window has passed, only the totals remember the errors
+	assert.Eventually(t, func() bool {
+		return metrics.Snapshot().HealthStatus == "healthy"
+	}, time.Second, 10*time.Millisecond)
+	snapshot = metrics.Snapshot()
+	assert.Zero(t, snapshot.RecentErrors)
+	assert.Equal(t, int64(2), snapshot.BroadcastErrors)
+	assert.Equal(t, int64(1), snapshot.ConnectionErrors)
+}
+
+func TestHub_Ping(t *testing.T) {
+	hub := services.NewHub(config.Default())
+	assert.ErrorIs(t, hub.Ping(50*time.Millisecond), services.ErrHubUnresponsive, "Run is not started")
+
+	go hub.Run()
+	assert.NoError(t, hub.Ping(time.Second))
+}
@@ -3,19 +3,20 @@ package services_test
 import (
 	"testing"
 
+	"github.com/damione1/planning-poker/internal/config"
 	"github.com/damione1/planning-poker/internal/services"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestHub_Initialization(t *testing.T) {
 	t.Run("creates new hub", func(t *testing.T) {
-		hub := services.NewHub()
+		hub := services.NewHub(config.Default())
 
 		assert.NotNil(t, hub)
 	})
 
 	t.Run("hub can be started", func(t *testing.T) {
-		hub := services.NewHub()
+		hub := services.NewHub(config.Default())
 
 		// Start hub in background
 		go hub.Run()
@@ -0,0 +1,89 @@
+package services_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+func TestJiraTrackerSearchIssues(t *testing.T) {
+	var gotQuery, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/rest/api/2/search", r.URL.Path)
+		gotQuery = r.URL.Query().Get("jql")
+		gotAuth = r.Header.Get("Authorization")
+		assert.Equal(t, "10", r.URL.Query().Get("maxResults"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"issues": [
+			{"key": "PP-1", "fields": {"summary": "Login page"}},
+			{"key": "PP-2", "fields": {"summary": "Logout"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	tracker := services.NewJiraTracker(services.JiraConfig{BaseURL: server.URL + "/", Token: "pat"})
+	issues, err := tracker.SearchIssues("project = PP AND sprint in openSprints()", 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, "project = PP AND sprint in openSprints()", gotQuery)
+	assert.Equal(t, "Bearer pat", gotAuth)
+	assert.Equal(t, []models.Issue{
+		{Key: "PP-1", Title: "Login page", URL: server.URL + "/browse/PP-1"},
+		{Key: "PP-2", Title: "Logout", URL: server.URL + "/browse/PP-2"},
+	}, issues)
+}
+
+func TestJiraTrackerSetEstimate(t *testing.T) {
+	var gotMethod, gotPath string
+	var gotBody map[string]map[string]float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.Path
+		user, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "dev@example.com", user)
+		assert.Equal(t, "token", password)
+
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &gotBody))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	tracker := services.NewJiraTracker(services.JiraConfig{
+		BaseURL:          server.URL,
+		Email:            "dev@example.com",
+		Token:            "token",
+		StoryPointsField: "customfield_10028",
+	})
+	require.NoError(t, tracker.SetEstimate("PP-1", 5))
+
+	assert.Equal(t, http.MethodPut, gotMethod)
+	assert.Equal(t, "/rest/api/2/issue/PP-1", gotPath)
+	assert.Equal(t, map[string]map[string]float64{"fields": {"customfield_10028": 5}}, gotBody)
+}
+
+func TestJiraTrackerErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"errorMessages": ["Field cannot be set"], "errors": {"customfield_10016": "not on screen"}}`))
+	}))
+	defer server.Close()
+
+	tracker := services.NewJiraTracker(services.JiraConfig{BaseURL: server.URL, Token: "pat"})
+
+	err := tracker.SetEstimate("PP-1", 3)
+	require.Error(t, err)
+	assert.Equal(t, "jira returned 400: Field cannot be set; customfield_10016: not on screen", err.Error())
+
+	_, err = tracker.SearchIssues("project = PP", 5)
+	assert.Error(t, err)
+}
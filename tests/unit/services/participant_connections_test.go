@@ -0,0 +1,59 @@
+package services_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+func TestParticipantConnections_FirstAndLastOnly(t *testing.T) {
+	hub := services.NewHub(config.Default())
+
+	var events []string
+	connected := func() { events = append(events, "connected") }
+	disconnected := func() { events = append(events, "disconnected") }
+
+	assert.True(t, hub.OpenParticipantConnection("room-1", "p1", connected), "first tab")
+	assert.False(t, hub.OpenParticipantConnection("room-1", "p1", connected), "second tab")
+	assert.True(t, hub.OpenParticipantConnection("room-2", "p1", connected), "rooms count apart")
+	assert.False(t, hub.CloseParticipantConnection("room-1", "p1", disconnected), "a tab is still open")
+	assert.True(t, hub.CloseParticipantConnection("room-1", "p1", disconnected), "last tab")
+	assert.True(t, hub.OpenParticipantConnection("room-1", "p1", connected), "back after leaving")
+
+	assert.Equal(t, []string{"connected", "connected", "disconnected", "connected"}, events)
+}
+
+func TestParticipantConnections_ChangesAreSerialized(t *testing.T) {
+	hub := services.NewHub(config.Default())
+
+	// Tabs opening and closing at once must never leave the participant marked disconnected
+	// while a tab is open, or the other way around
+	var mu sync.Mutex
+	status := false
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hub.OpenParticipantConnection("room-1", "p1", func() {
+				mu.Lock()
+				defer mu.Unlock()
+				assert.False(t, status, "connected twice")
+				status = true
+			})
+			hub.CloseParticipantConnection("room-1", "p1", func() {
+				mu.Lock()
+				defer mu.Unlock()
+				assert.True(t, status, "disconnected twice")
+				status = false
+			})
+		}()
+	}
+	wg.Wait()
+
+	assert.False(t, status)
+}
@@ -0,0 +1,99 @@
+package services_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+// writePrometheus returns the exposition lines of a metrics set
+func writePrometheus(t *testing.T, metrics *services.Metrics) []string {
+	t.Helper()
+	var b strings.Builder
+	require.NoError(t, metrics.WritePrometheus(&b))
+	return strings.Split(strings.TrimSpace(b.String()), "\n")
+}
+
+func TestMetrics_WritePrometheus(t *testing.T) {
+	metrics := services.NewMetrics()
+	metrics.IncrementConnections()
+	metrics.IncrementMessagesReceived()
+	metrics.IncrementMessagesReceived()
+
+	metrics.ObserveMessageHandling(models.MsgTypeVote, 2*time.Millisecond)
+	metrics.ObserveMessageHandling(models.MsgTypeVote, 30*time.Millisecond)
+	metrics.ObserveMessageHandling(models.MsgTypeReveal, 10*time.Second)
+	metrics.ObserveBroadcastFanout(models.MsgTypeVoteCast, 100*time.Microsecond)
+	metrics.ObserveDBOperation(models.MsgTypeVote, 4*time.Millisecond)
+
+	lines := writePrometheus(t, metrics)
+
+	assert.Contains(t, lines, "# TYPE planning_poker_messages_received_total counter")
+	assert.Contains(t, lines, "planning_poker_messages_received_total 2")
+	assert.Contains(t, lines, "# TYPE planning_poker_active_connections gauge")
+	assert.Contains(t, lines, "planning_poker_active_connections 1")
+
+	// Buckets are cumulative and end with +Inf
+	assert.Contains(t, lines, "# TYPE planning_poker_message_handling_seconds histogram")
+	assert.Contains(t, lines, `planning_poker_message_handling_seconds_bucket{type="vote",le="0.001"} 0`)
+	assert.Contains(t, lines, `planning_poker_message_handling_seconds_bucket{type="vote",le="0.0025"} 1`)
+	assert.Contains(t, lines, `planning_poker_message_handling_seconds_bucket{type="vote",le="0.05"} 2`)
+	assert.Contains(t, lines, `planning_poker_message_handling_seconds_bucket{type="vote",le="+Inf"} 2`)
+	assert.Contains(t, lines, `planning_poker_message_handling_seconds_sum{type="vote"} 0.032`)
+	assert.Contains(t, lines, `planning_poker_message_handling_seconds_count{type="vote"} 2`)
+	assert.Contains(t, lines, `planning_poker_message_handling_seconds_bucket{type="reveal",le="5"} 0`)
+	assert.Contains(t, lines, `planning_poker_message_handling_seconds_bucket{type="reveal",le="+Inf"} 1`)
+
+	assert.Contains(t, lines, `planning_poker_broadcast_fanout_seconds_count{type="vote_cast"} 1`)
+	assert.Contains(t, lines, `planning_poker_db_operation_seconds_count{type="vote"} 1`)
+
+	// Every sample belongs to a declared metric
+	declared := make(map[string]bool)
+	for _, line := range lines {
+		if name, ok := strings.CutPrefix(line, "# TYPE "); ok {
+			declared[strings.Fields(name)[0]] = true
+		}
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := strings.FieldsFunc(line, func(r rune) bool { return r == '{' || r == ' ' })[0]
+		for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+			if base, ok := strings.CutSuffix(name, suffix); ok && declared[base] {
+				name = base
+			}
+		}
+		assert.True(t, declared[name], "undeclared sample: %s", line)
+	}
+}
+
+func TestMetrics_HistogramLabelsAreBounded(t *testing.T) {
+	metrics := services.NewMetrics()
+	for i := 0; i < 100; i++ {
+		metrics.ObserveBroadcastFanout(fmt.Sprintf("type-%d", i), time.Millisecond)
+	}
+
+	series := 0
+	for _, line := range writePrometheus(t, metrics) {
+		if strings.HasPrefix(line, "planning_poker_broadcast_fanout_seconds_count") {
+			series++
+		}
+	}
+	assert.LessOrEqual(t, series, 65)
+	assert.Contains(t, writePrometheus(t, metrics), `planning_poker_broadcast_fanout_seconds_count{type="other"} 36`)
+}
+
+func TestMetrics_LabelValuesAreEscaped(t *testing.T) {
+	metrics := services.NewMetrics()
+	metrics.ObserveDBOperation("quoted \"type\"\n", time.Millisecond)
+
+	assert.Contains(t, writePrometheus(t, metrics), `planning_poker_db_operation_seconds_count{type="quoted \"type\"\n"} 1`)
+}
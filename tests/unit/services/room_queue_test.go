@@ -0,0 +1,278 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+func TestHubDispatch_KeepsRoomOrder(t *testing.T) {
+	hub := services.NewHub(config.Default())
+
+	var mu sync.Mutex
+	received := make(map[string][]string)
+	var wg sync.WaitGroup
+	hub.SetMessageHandler(func(ctx context.Context, roomID, participantID string, message []byte) {
+		mu.Lock()
+		received[roomID] = append(received[roomID], string(message))
+		mu.Unlock()
+		wg.Done()
+	})
+
+	const messages = 200
+	var want []string
+	for i := 0; i < messages; i++ {
+		want = append(want, fmt.Sprint(i))
+	}
+
+	wg.Add(2 * messages)
+	for i := 0; i < messages; i++ {
+		hub.Dispatch(context.Background(), "room-1", "p1", []byte(fmt.Sprint(i)))
+		hub.Dispatch(context.Background(), "room-2", "p2", []byte(fmt.Sprint(i)))
+	}
+	wg.Wait()
+
+	assert.Equal(t, want, received["room-1"])
+	assert.Equal(t, want, received["room-2"])
+}
+
+func TestHubDispatch_SlowRoomDoesNotStallOthers(t *testing.T) {
+	hub := services.NewHub(config.Default())
+
+	release := make(chan struct{})
+	handled := make(chan string, 10)
+	hub.SetMessageHandler(func(ctx context.Context, roomID, participantID string, message []byte) {
+		if roomID == "slow" {
+			<-release // A write stuck on the database
+		}
+		handled <- roomID
+	})
+	defer close(release)
+
+	hub.Dispatch(context.Background(), "slow", "p1", []byte("vote"))
+	hub.Dispatch(context.Background(), "fast", "p2", []byte("vote"))
+
+	select {
+	case roomID := <-handled:
+		assert.Equal(t, "fast", roomID)
+	case <-time.After(time.Second):
+		t.Fatal("a slow room blocked another room's command")
+	}
+}
+
+func TestHubDispatch_BoundsConcurrency(t *testing.T) {
+	cfg := config.Default()
+	cfg.Limits.MaxConcurrentRoomCommands = 4
+	hub := services.NewHub(cfg)
+
+	var running, peak atomic.Int64
+	var wg sync.WaitGroup
+	hub.SetMessageHandler(func(ctx context.Context, roomID, participantID string, message []byte) {
+		defer wg.Done()
+		now := running.Add(1)
+		for {
+			seen := peak.Load()
+			if now <= seen || peak.CompareAndSwap(seen, now) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+	})
+
+	rooms := 3 * cfg.Limits.MaxConcurrentRoomCommands
+	wg.Add(rooms)
+	for i := 0; i < rooms; i++ {
+		hub.Dispatch(context.Background(), fmt.Sprintf("room-%d", i), "p1", []byte("vote"))
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, peak.Load(), int64(cfg.Limits.MaxConcurrentRoomCommands))
+	assert.Greater(t, peak.Load(), int64(1), "rooms run concurrently")
+
+	metrics := hub.GetMetrics()
+	assert.Equal(t, int64(rooms), metrics.CommandsProcessed)
+	assert.Positive(t, metrics.WorkerSlotWaits)
+	assert.Zero(t, metrics.QueuedCommands)
+}
+
+func TestHubDispatch_FullQueueBlocksSender(t *testing.T) {
+	cfg := config.Default()
+	cfg.Limits.RoomCommandQueueSize = 8
+	hub := services.NewHub(cfg)
+
+	release := make(chan struct{})
+	var handled atomic.Int64
+	hub.SetMessageHandler(func(ctx context.Context, roomID, participantID string, message []byte) {
+		<-release
+		handled.Add(1)
+	})
+
+	// One command held by the worker, a full queue behind it, then one more
+	total := cfg.Limits.RoomCommandQueueSize + 2
+	sent := make(chan struct{})
+	go func() {
+		for i := 0; i < total; i++ {
+			hub.Dispatch(context.Background(), "busy", "p1", []byte("vote"))
+		}
+		close(sent)
+	}()
+
+	require.Eventually(t, func() bool {
+		return hub.GetMetrics().QueuedCommands == int64(total-1)
+	}, time.Second, 5*time.Millisecond)
+	assert.Positive(t, hub.GetMetrics().QueueFullWaits)
+	select {
+	case <-sent:
+		t.Fatal("the sender went past a full queue")
+	default:
+	}
+
+	close(release)
+	<-sent
+	require.Eventually(t, func() bool {
+		return handled.Load() == int64(total)
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestHubDispatch_FullQueueDropsAfterTimeout(t *testing.T) {
+	cfg := config.Default()
+	cfg.Limits.RoomCommandQueueSize = 1
+	cfg.Limits.RoomQueueFullTimeout = 50 * time.Millisecond
+	hub := services.NewHub(cfg)
+
+	release := make(chan struct{})
+	var handled atomic.Int64
+	hub.SetMessageHandler(func(ctx context.Context, roomID, participantID string, message []byte) {
+		<-release
+		handled.Add(1)
+	})
+
+	// One command held by the worker and one queued behind it
+	require.NoError(t, hub.Dispatch(context.Background(), "busy", "p1", []byte("vote")))
+	require.Eventually(t, func() bool {
+		return hub.GetMetrics().QueuedCommands == 0
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, hub.Dispatch(context.Background(), "busy", "p1", []byte("vote")))
+
+	start := time.Now()
+	err := hub.Dispatch(context.Background(), "busy", "p1", []byte("vote"))
+	assert.ErrorIs(t, err, services.ErrRoomBusy)
+	assert.GreaterOrEqual(t, time.Since(start), cfg.Limits.RoomQueueFullTimeout)
+
+	metrics := hub.GetMetrics()
+	assert.Equal(t, int64(1), metrics.QueueFullDrops)
+	assert.Equal(t, int64(1), metrics.QueuedCommands, "the dropped command is not counted")
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return handled.Load() == 2
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, hub.Dispatch(context.Background(), "busy", "p1", []byte("vote")))
+}
+
+func TestHubCommand_WaitsForReply(t *testing.T) {
+	hub := services.NewHub(config.Default())
+	hub.SetMessageHandler(func(ctx context.Context, roomID, participantID string, message []byte) {
+		time.Sleep(10 * time.Millisecond) // Handled after Command started waiting
+		services.Reply(ctx, &models.WSMessage{Type: models.MsgTypeAck, Payload: string(message)})
+	})
+
+	reply, err := hub.Command(context.Background(), "room-1", "p1", []byte("vote"))
+	require.NoError(t, err)
+	assert.Equal(t, models.MsgTypeAck, reply.Type)
+	assert.Equal(t, "vote", reply.Payload)
+}
+
+func TestHubCommand_SharesConnectionRateLimit(t *testing.T) {
+	cfg := config.Default()
+	cfg.Limits.MaxMessagesPerSecond = 2
+	cfg.Limits.RateLimitWindow = time.Hour
+	hub := services.NewHub(cfg)
+	hub.SetMessageHandler(func(ctx context.Context, roomID, participantID string, message []byte) {
+		services.Reply(ctx, &models.WSMessage{Type: models.MsgTypeAck})
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := hub.Command(context.Background(), "room-1", "p1", []byte("vote"))
+		require.NoError(t, err)
+	}
+	_, err := hub.Command(context.Background(), "room-1", "p1", []byte("vote"))
+	assert.ErrorIs(t, err, services.ErrRateLimited)
+	assert.Equal(t, int64(1), hub.GetMetrics().RateLimitViolations)
+
+	// Other participants have limits of their own
+	_, err = hub.Command(context.Background(), "room-1", "p2", []byte("vote"))
+	assert.NoError(t, err)
+}
+
+func TestHubCommand_FullQueue(t *testing.T) {
+	cfg := config.Default()
+	cfg.Limits.RoomCommandQueueSize = 1
+	cfg.Limits.RoomQueueFullTimeout = 50 * time.Millisecond
+	hub := services.NewHub(cfg)
+
+	release := make(chan struct{})
+	defer close(release)
+	hub.SetMessageHandler(func(ctx context.Context, roomID, participantID string, message []byte) {
+		<-release
+		services.Reply(ctx, &models.WSMessage{Type: models.MsgTypeAck})
+	})
+
+	// One command held by the worker and one queued behind it
+	require.NoError(t, hub.Dispatch(context.Background(), "busy", "p1", []byte("vote")))
+	require.Eventually(t, func() bool {
+		return hub.GetMetrics().QueuedCommands == 0
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, hub.Dispatch(context.Background(), "busy", "p1", []byte("vote")))
+
+	_, err := hub.Command(context.Background(), "busy", "p2", []byte("vote"))
+	assert.ErrorIs(t, err, services.ErrRoomBusy)
+}
+
+// BenchmarkHubDispatch sends one command to each of hundreds of rooms and waits for all of
+// them, with the handler sleeping like a database write. Handled one at a time, an op would
+// take rooms × write time. Run with: go test ./tests/unit/services -run '^$' -bench HubDispatch
+func BenchmarkHubDispatch(b *testing.B) {
+	const writeTime = time.Millisecond
+
+	for _, rooms := range []int{100, 500} {
+		b.Run(fmt.Sprintf("rooms=%d", rooms), func(b *testing.B) {
+			hub := services.NewHub(config.Default())
+			var wg sync.WaitGroup
+			hub.SetMessageHandler(func(ctx context.Context, roomID, participantID string, message []byte) {
+				time.Sleep(writeTime)
+				wg.Done()
+			})
+
+			roomIDs := make([]string, rooms)
+			for i := range roomIDs {
+				roomIDs[i] = fmt.Sprintf("room-%d", i)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				wg.Add(rooms)
+				for _, roomID := range roomIDs {
+					hub.Dispatch(context.Background(), roomID, "p1", []byte("vote"))
+				}
+				wg.Wait()
+			}
+			b.StopTimer()
+
+			serial := time.Duration(rooms) * writeTime
+			b.ReportMetric(float64(b.Elapsed())/float64(b.N)/float64(serial), "x_serial_time")
+			b.ReportMetric(hub.GetMetrics().AvgCommandWaitMs, "avg_wait_ms")
+		})
+	}
+}
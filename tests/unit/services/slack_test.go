@@ -0,0 +1,51 @@
+package services_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/damione1/planning-poker/internal/models"
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+func TestVerifySlackSignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	body := []byte("command=%2Fpoker&text=fibonacci+Sprint")
+	signature := services.SignSlackRequest("secret", ts, body)
+
+	assert.Regexp(t, `^v0=[0-9a-f]{64}$`, signature)
+	assert.NoError(t, services.VerifySlackSignature("secret", ts, signature, body, now))
+	assert.NoError(t, services.VerifySlackSignature("secret", ts, signature, body, now.Add(time.Minute)))
+
+	assert.Error(t, services.VerifySlackSignature("other", ts, signature, body, now), "wrong secret")
+	assert.Error(t, services.VerifySlackSignature("secret", ts, signature, []byte("text=x"), now), "body is signed")
+	assert.Error(t, services.VerifySlackSignature("secret", ts, signature, body, now.Add(10*time.Minute)), "stale timestamp")
+	assert.Error(t, services.VerifySlackSignature("secret", "not-a-number", signature, body, now), "invalid timestamp")
+	assert.Error(t, services.VerifySlackSignature("secret", ts, "", body, now), "missing signature")
+}
+
+func TestFormatRevealSummary(t *testing.T) {
+	average := 4.3333
+	summary := services.FormatRevealSummary("Sprint <42>", 3, models.VotesRevealedPayload{
+		Votes: []models.RevealedVote{
+			{ParticipantName: "Carol", Value: "5"},
+			{ParticipantName: "Alice", Value: "3"},
+			{ParticipantName: "Bob", Value: "5"},
+		},
+		Stats: models.RevealStats{Total: 3, Average: &average},
+	})
+
+	assert.Equal(t, "*Sprint &lt;42&gt;* – round 3 revealed (3 votes)\n"+
+		"Average: *4.3*\n"+
+		"No consensus\n"+
+		"• Alice: 3\n"+
+		"• Bob: 5\n"+
+		"• Carol: 5", summary)
+
+	empty := services.FormatRevealSummary("Room", 1, models.VotesRevealedPayload{})
+	assert.Contains(t, empty, "No votes were cast")
+}
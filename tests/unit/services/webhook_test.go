@@ -0,0 +1,30 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/damione1/planning-poker/internal/config"
+	"github.com/damione1/planning-poker/internal/services"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"event":"votes_revealed"}`)
+	signature := services.SignWebhookPayload("secret", 1700000000, body)
+
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, signature)
+	assert.Equal(t, signature, services.SignWebhookPayload("secret", 1700000000, body), "signing is deterministic")
+
+	assert.True(t, services.VerifyWebhookSignature("secret", signature, 1700000000, body))
+	assert.False(t, services.VerifyWebhookSignature("other", signature, 1700000000, body), "wrong secret")
+	assert.False(t, services.VerifyWebhookSignature("secret", signature, 1700000001, body), "timestamp is signed")
+	assert.False(t, services.VerifyWebhookSignature("secret", signature, 1700000000, []byte(`{}`)), "body is signed")
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	assert.Equal(t, config.WebhookInitialBackoff, services.WebhookBackoff(1))
+	assert.Equal(t, 2*config.WebhookInitialBackoff, services.WebhookBackoff(2))
+	assert.Equal(t, 4*config.WebhookInitialBackoff, services.WebhookBackoff(3))
+	assert.Equal(t, config.WebhookMaxBackoff, services.WebhookBackoff(50))
+}
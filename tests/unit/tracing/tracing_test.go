@@ -0,0 +1,63 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/damione1/planning-poker/internal/tracing"
+)
+
+func TestSetup_Exporter(t *testing.T) {
+	tests := []struct {
+		exporter string
+		wantErr  bool
+	}{
+		{"", false},
+		{"none", false},
+		{"stdout", false},
+		{"console", false},
+		{"jaeger", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.exporter, func(t *testing.T) {
+			t.Setenv("OTEL_TRACES_EXPORTER", tt.exporter)
+
+			shutdown, err := tracing.Setup(context.Background())
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "OTEL_TRACES_EXPORTER")
+				return
+			}
+			require.NoError(t, err)
+			assert.NoError(t, shutdown(context.Background()))
+		})
+	}
+}
+
+func TestInjectExtract_RoundTrip(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "none")
+	_, err := tracing.Setup(context.Background())
+	require.NoError(t, err)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	parent := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled})
+	ctx := trace.ContextWithSpanContext(context.Background(), parent)
+
+	carrier := tracing.Inject(ctx)
+	require.NotEmpty(t, carrier)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", carrier["traceparent"])
+
+	extracted := trace.SpanContextFromContext(tracing.Extract(context.Background(), carrier))
+	assert.Equal(t, traceID, extracted.TraceID())
+	assert.True(t, extracted.IsRemote())
+
+	// Without a trace there is nothing to carry
+	assert.Nil(t, tracing.Inject(context.Background()))
+	assert.Equal(t, context.Background(), tracing.Extract(context.Background(), nil))
+}
@@ -29,10 +29,13 @@ document.addEventListener('alpine:init', () => {
 		// Vote tracking
 		votes: new Map(), // participantId -> value
 		currentUserVote: null,
+		currentUserConfidence: null, // 'low' | 'medium' | 'high' | null (optional)
+		currentUserDimensionVotes: {}, // dimension name -> value (multi-dimensional rooms)
 
 		// Auto-reveal countdown
 		showCountdown: false,
 		countdownNumber: 3,
+		countdownInterval: null,
 
 		// WebSocket connection management
 		socketWrapper: null,
@@ -40,6 +43,13 @@ document.addEventListener('alpine:init', () => {
 		reconnectAttempts: 0,
 		hasEverConnected: false, // Track if we've successfully connected before
 		pendingMessages: [], // Queue messages during reconnection
+		nextRequestId: 1,
+		requestIdPrefix: Math.random().toString(36).slice(2, 10), // Errors reach all tabs of the participant, only ours match
+		pendingRequests: new Map(), // requestId -> message type, until the server answers with ack or error
+		eventLog: null, // Room event log of the latest room_state
+		lastSeq: 0, // Sequence number of the latest room event, sent on reconnect to receive only missed events
+		eventSource: null, // Server-Sent Events fallback, while WebSocket can't connect
+		eventSourceRetry: null,
 
 		// Computed properties (getters)
 		get voteCount() {
@@ -121,6 +131,54 @@ document.addEventListener('alpine:init', () => {
 			}
 		},
 
+		// Falls back to Server-Sent Events when WebSocket can't connect, commands are POSTed to the stream
+		openEventStream() {
+			if (this.eventSource || typeof EventSource === 'undefined') return;
+			console.log('📡 WebSocket unavailable, falling back to Server-Sent Events');
+
+			const source = new EventSource(this.resumeUrl(`/sse/${this.roomId}`));
+			this.eventSource = source;
+
+			source.addEventListener('stream', (event) => {
+				const { streamId } = JSON.parse(event.data);
+				const commandUrl = `/sse/${this.roomId}/${streamId}`;
+				this.setSocketWrapper({
+					send: (data) => fetch(commandUrl, {
+						method: 'POST',
+						headers: { 'Content-Type': 'application/json' },
+						credentials: 'same-origin',
+						body: data
+					}).then((response) => {
+						if (!response.ok) console.error('Command rejected by event stream:', response.status);
+					})
+				});
+			});
+
+			source.onmessage = (event) => {
+				try {
+					this.handleMessage(JSON.parse(event.data));
+				} catch (err) {
+					console.error('Failed to parse event stream message:', err);
+				}
+			};
+
+			// Reopen the stream ourselves, so that it resumes after the latest room event
+			source.onerror = () => {
+				this.closeEventStream();
+				this.setConnectionState('reconnecting');
+				this.eventSourceRetry = setTimeout(() => this.openEventStream(), 2000);
+			};
+		},
+
+		closeEventStream() {
+			clearTimeout(this.eventSourceRetry);
+			this.eventSourceRetry = null;
+			if (this.eventSource) {
+				this.eventSource.close();
+				this.eventSource = null;
+			}
+		},
+
 		processPendingMessages() {
 			if (this.pendingMessages.length === 0) return;
 
@@ -178,6 +236,8 @@ document.addEventListener('alpine:init', () => {
 		clearVotes() {
 			this.votes.clear();
 			this.currentUserVote = null;
+			this.currentUserConfidence = null;
+			this.currentUserDimensionVotes = {};
 			console.log('🧹 All votes cleared');
 		},
 
@@ -188,9 +248,19 @@ document.addEventListener('alpine:init', () => {
 			console.log('🆕 Reset for new round:', newRoundNumber);
 		},
 
+		// URL to reconnect to, resuming after the latest room event seen
+		resumeUrl(url) {
+			if (!this.eventLog) return url;
+			const separator = url.includes('?') ? '&' : '?';
+			return `${url}${separator}eventLog=${encodeURIComponent(this.eventLog)}&lastSeq=${this.lastSeq}`;
+		},
+
 		// WebSocket message handlers
 		handleMessage(message) {
 			console.log('📨 Global state handling message:', message.type);
+			if (message.seq) {
+				this.lastSeq = message.seq;
+			}
 
 			switch (message.type) {
 				case 'room_state':
@@ -227,15 +297,58 @@ document.addEventListener('alpine:init', () => {
 				case 'config_updated':
 					this.handleConfigUpdatedMessage(message.payload);
 					break;
+				case 'weight_updated':
+					this.handleWeightUpdatedMessage(message.payload);
+					break;
 				case 'auto_reveal_countdown':
 					this.handleAutoRevealCountdownMessage(message.payload);
 					break;
+				case 'auto_reveal_cancelled':
+					this.handleAutoRevealCancelledMessage();
+					break;
+				case 'ack':
+					this.pendingRequests.delete(message.requestId);
+					break;
+				case 'error':
+					this.handleErrorMessage(message);
+					break;
+			}
+		},
+
+		handleErrorMessage(message) {
+			const payload = message.payload || {};
+			const action = this.pendingRequests.get(message.requestId) || payload.action;
+			this.pendingRequests.delete(message.requestId);
+			console.warn('❌ Command failed:', action, payload.code, payload.message);
+			this.showToast(this.errorText(payload), 'error');
+		},
+
+		errorText(payload) {
+			switch (payload.code) {
+				case 'forbidden':
+					return payload.message || 'You are not allowed to do that in this room';
+				case 'invalid_state':
+					return 'The room has moved on, please try again';
+				case 'room_expired':
+					return 'This room has expired. Please create a new room.';
+				case 'rate_limited':
+					return 'Too many actions, please slow down';
+				case 'room_busy':
+					return 'The room is busy, please try again';
+				case 'internal_error':
+					return 'Something went wrong. Please try again.';
+				default:
+					return payload.message || 'Action failed';
 			}
 		},
 
 		handleRoomStateMessage(payload) {
 			console.log('📊 Room state message:', payload);
 
+			// The snapshot includes the room events up to payload.seq
+			this.eventLog = payload.eventLog || null;
+			this.lastSeq = payload.seq || 0;
+
 			// Update core room state
 			if (payload.roomState) {
 				this.updateRoomState(payload.roomState);
@@ -283,7 +396,14 @@ document.addEventListener('alpine:init', () => {
 
 		handleVoteCastMessage(payload) {
 			console.log('🗳️ Vote cast message:', payload);
-			if (payload.participantId && payload.hasVoted) {
+			if (payload.participantId && payload.hasVoted === false) {
+				// Vote retracted
+				this.votes.delete(payload.participantId);
+				if (payload.participantId === this.currentParticipantId) {
+					this.currentUserVote = null;
+					this.currentUserDimensionVotes = {};
+				}
+			} else if (payload.participantId && payload.hasVoted) {
 				// Don't overwrite current user's vote (they already have the actual value)
 				if (payload.participantId !== this.currentParticipantId) {
 					this.addVote(payload.participantId, 'voted'); // Value hidden until reveal
@@ -377,6 +497,11 @@ document.addEventListener('alpine:init', () => {
 			}
 		},
 
+		handleWeightUpdatedMessage(payload) {
+			console.log('⚖️ Weight updated message:', payload);
+			this.refreshParticipants();
+		},
+
 		handleAutoRevealCountdownMessage(payload) {
 			console.log('⏱️ Auto-reveal countdown triggered:', payload);
 			const duration = payload.duration || 1500; // Default to 1.5 seconds
@@ -389,10 +514,12 @@ document.addEventListener('alpine:init', () => {
 			// Each number shows for 0.5 seconds
 			const intervalTime = duration / 3;
 
-			const countdownInterval = setInterval(() => {
+			clearInterval(this.countdownInterval);
+			this.countdownInterval = setInterval(() => {
 				this.countdownNumber--;
 				if (this.countdownNumber <= 0) {
-					clearInterval(countdownInterval);
+					clearInterval(this.countdownInterval);
+					this.countdownInterval = null;
 					this.showCountdown = false;
 					// Trigger reveal
 					this.sendReveal();
@@ -400,6 +527,15 @@ document.addEventListener('alpine:init', () => {
 			}, intervalTime);
 		},
 
+		handleAutoRevealCancelledMessage() {
+			console.log('⏱️ Auto-reveal cancelled');
+			if (this.countdownInterval) {
+				clearInterval(this.countdownInterval);
+				this.countdownInterval = null;
+			}
+			this.showCountdown = false;
+		},
+
 		updatePermissionsFromConfig(config) {
 			// Recalculate permissions based on config and whether current user is creator
 			const isCreator = this.isCreator;
@@ -438,7 +574,7 @@ document.addEventListener('alpine:init', () => {
 		// WebSocket send methods
 		sendMessage(type, payload = {}) {
 			// Check expiration for critical actions
-			const criticalActions = ['vote', 'reveal', 'reset', 'next_round'];
+			const criticalActions = ['vote', 'unvote', 'reveal', 'reset', 'next_round'];
 			if (criticalActions.includes(type) && this.isExpired) {
 				console.warn('⏰ Action blocked: room has expired');
 				alert('This room has expired. Please create a new room.');
@@ -446,8 +582,6 @@ document.addEventListener('alpine:init', () => {
 				return false;
 			}
 
-			const message = JSON.stringify({ type, payload });
-
 			// If not connected, queue the message for later
 			if (!this.socketWrapper || !this.isConnected) {
 				console.warn('⏳ Connection not available, queueing message:', type);
@@ -456,18 +590,76 @@ document.addEventListener('alpine:init', () => {
 				return false;
 			}
 
-			// Send immediately if connected
-			console.log('📤 Sending message:', type, payload);
-			this.socketWrapper.send(message);
+			// Send immediately if connected, the server answers the requestId with ack or error
+			const requestId = `${this.requestIdPrefix}-${this.nextRequestId++}`;
+			this.pendingRequests.set(requestId, type);
+			console.log('📤 Sending message:', type, requestId, payload);
+			this.socketWrapper.send(JSON.stringify({ type, requestId, payload }));
 			return true;
 		},
 
+		promptWeight(participantId, currentWeight) {
+			const input = prompt('Vote weight for this participant (e.g. 0.5, 1, 2):', currentWeight);
+			if (input === null) return;
+
+			const weight = parseFloat(input);
+			if (isNaN(weight) || weight <= 0 || weight > 10) {
+				this.showToast('Weight must be greater than 0 and at most 10', 'error');
+				return;
+			}
+
+			this.sendMessage('update_weight', { participantId, weight });
+		},
+
 		sendVote(value) {
-			if (this.sendMessage('vote', { value })) {
+			const payload = { value };
+			if (this.currentUserConfidence) {
+				payload.confidence = this.currentUserConfidence;
+			}
+			if (this.sendMessage('vote', payload)) {
 				this.currentUserVote = value;
 			}
 		},
 
+		sendUnvote() {
+			if (this.sendMessage('unvote')) {
+				this.currentUserVote = null;
+				this.currentUserDimensionVotes = {};
+			}
+		},
+
+		setDimensionVote(dimension, value) {
+			this.currentUserDimensionVotes = { ...this.currentUserDimensionVotes, [dimension]: value };
+			this.sendDimensionVote();
+		},
+
+		sendDimensionVote() {
+			// Only send once every dimension of the room has a value
+			const dimensions = (Alpine.store('roomSettings').config.dimensions || []).map(d => d.name);
+			if (!dimensions.every(name => this.currentUserDimensionVotes[name] !== undefined)) {
+				return;
+			}
+
+			const payload = { dimensions: this.currentUserDimensionVotes };
+			if (this.currentUserConfidence) {
+				payload.confidence = this.currentUserConfidence;
+			}
+			if (this.sendMessage('vote', payload)) {
+				this.currentUserVote = 'voted';
+			}
+		},
+
+		setConfidence(level) {
+			// Toggle off when the same level is selected again
+			this.currentUserConfidence = this.currentUserConfidence === level ? null : level;
+			// Re-send the current vote so the server records the new confidence
+			if (Object.keys(this.currentUserDimensionVotes).length > 0) {
+				this.sendDimensionVote();
+			} else if (this.currentUserVote) {
+				this.sendVote(this.currentUserVote);
+			}
+		},
+
 		sendReveal() {
 			this.sendMessage('reveal');
 		},
@@ -510,7 +702,8 @@ document.addEventListener('alpine:init', () => {
 					allow_all_new_round: true,
 					allow_change_vote_after_reveal: false,
 					auto_reveal: false
-				}
+				},
+				reveal_rule: { mode: 'all', percentage: 75, minimum_votes: 3 }
 			},
 
 			updateCustomValues() {
@@ -538,12 +731,19 @@ document.addEventListener('alpine:init', () => {
 				this.$store.roomState.init(roomId);
 			}
 
+			// Reconnections resume after the latest room event instead of reloading the room
+			if (!htmx.createWebSocket.resumes) {
+				const createWebSocket = htmx.createWebSocket;
+				htmx.createWebSocket = (url) => createWebSocket(this.$store.roomState.resumeUrl(url));
+				htmx.createWebSocket.resumes = true;
+			}
+
 			// Listen for HTMX WebSocket events
 			document.body.addEventListener("htmx:wsConnecting", (event) => {
 				console.log('[DEBUG] HTMX WebSocket connecting');
 				// Only set to 'reconnecting' if we've connected before
 				// Otherwise, keep it as 'connecting' for the initial connection
-				if (this.$store.roomState.hasEverConnected) {
+				if (this.$store.roomState.hasEverConnected && !this.$store.roomState.eventSource) {
 					this.$store.roomState.setConnectionState('reconnecting');
 					this.$store.roomState.reconnectAttempts++;
 				}
@@ -552,6 +752,7 @@ document.addEventListener('alpine:init', () => {
 			document.body.addEventListener("htmx:wsOpen", (event) => {
 				console.log('[DEBUG] HTMX WebSocket opened');
 				if (event.detail && event.detail.socketWrapper) {
+					this.$store.roomState.closeEventStream(); // WebSocket is preferred once it connects
 					this.$store.roomState.setSocketWrapper(event.detail.socketWrapper);
 				}
 			});
@@ -568,16 +769,28 @@ document.addEventListener('alpine:init', () => {
 
 			document.body.addEventListener("htmx:wsClose", (event) => {
 				console.log('[DEBUG] HTMX WebSocket closed', event.detail);
-				// Set to reconnecting - htmx will automatically retry
-				this.$store.roomState.setConnectionState('reconnecting');
+				this.fallBackOrReconnect();
 			});
 
 			document.body.addEventListener("htmx:wsError", (event) => {
 				console.error('[ERROR] HTMX WebSocket error:', event.detail);
-				this.$store.roomState.setConnectionState('reconnecting');
+				this.fallBackOrReconnect();
 			});
 		},
 
+		// htmx retries a failed WebSocket, which never connecting means it is blocked:
+		// the room then goes on over Server-Sent Events in the meantime
+		fallBackOrReconnect() {
+			const roomState = this.$store.roomState;
+			if (!roomState.hasEverConnected) {
+				roomState.openEventStream();
+			}
+			if (!roomState.eventSource) {
+				// Set to reconnecting - htmx will automatically retry
+				roomState.setConnectionState('reconnecting');
+			}
+		},
+
 		sendMessage(type, payload = {}) {
 			return this.$store.roomState.sendMessage(type, payload);
 		}
@@ -613,10 +826,28 @@ document.addEventListener('alpine:init', () => {
 				return;
 			}
 
+			// Clicking the selected card again retracts the vote while voting is open
+			if (this.selected === value && this.$store.roomState.roomState === 'voting') {
+				this.$store.roomState.sendUnvote();
+				return;
+			}
+
 			// Send the vote
 			this.$store.roomState.sendVote(value);
 		},
 
+		dimensionSelected(dimension) {
+			return this.$store.roomState.currentUserDimensionVotes[dimension];
+		},
+
+		selectDimensionCard(dimension, value) {
+			if (!this.isVoting) {
+				console.warn('Cannot vote: voting is disabled');
+				return;
+			}
+			this.$store.roomState.setDimensionVote(dimension, value);
+		},
+
 		get isVoting() {
 			// Check if voting is allowed based on room state and permissions
 			if (this.$store.roomState.isExpired) return false;
@@ -636,6 +867,27 @@ document.addEventListener('alpine:init', () => {
 		}
 	}));
 
+	// ===================================================================
+	// COMPONENT: Confidence Selector (Optional confidence sent with votes)
+	// ===================================================================
+	Alpine.data("confidenceSelector", () => ({
+		get selected() {
+			return this.$store.roomState.currentUserConfidence;
+		},
+
+		get isVoting() {
+			const store = this.$store.roomState;
+			if (store.isExpired || !store.isConnected) return false;
+			if (store.roomState === 'voting') return true;
+			return store.roomState === 'revealed' && store.permissions.canChangeVoteAfterReveal;
+		},
+
+		selectLevel(level) {
+			if (!this.isVoting) return;
+			this.$store.roomState.setConfidence(level);
+		}
+	}));
+
 	// ===================================================================
 	// COMPONENT: Room Controls (Reveal, Reset, Next Round buttons)
 	// ===================================================================
@@ -700,18 +952,25 @@ document.addEventListener('alpine:init', () => {
 				allow_all_new_round: true,
 				allow_change_vote_after_reveal: false,
 				auto_reveal: false
-			}
+			},
+			reveal_rule: { mode: 'all' }
 		},
 
 		init(initialConfig) {
 			if (initialConfig && initialConfig.permissions) {
-				this.config = initialConfig;
+				this.config = this.withRevealRule(initialConfig);
 			}
 		},
 
 		updateFromServer(serverConfig) {
-			this.config = serverConfig;
+			this.config = this.withRevealRule(serverConfig);
 			console.log('⚙️ Room config updated from server:', this.config);
+		},
+
+		// Rooms created before reveal rules existed have no mode; treat them as "all voters"
+		withRevealRule(config) {
+			const rule = config.reveal_rule || {};
+			return { ...config, reveal_rule: { ...rule, mode: rule.mode || 'all' } };
 		}
 	});
 